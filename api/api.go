@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"net/url"
@@ -10,6 +11,9 @@ import (
 	"time"
 
 	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+	"github.com/freehandle/synergy/social/diff"
+	"github.com/freehandle/synergy/social/state"
 )
 
 func FormToI(r *http.Request, field string) int {
@@ -31,6 +35,32 @@ func FormToB(r *http.Request, field string) byte {
 	return byteValue
 }
 
+// prazo das propostas informado em dias no formulario, convertido em epochs
+func FormToExpiry(r *http.Request, field string) uint64 {
+	if r == nil {
+		log.Print("PANIC BUG: FormToExpiry called with nil request ")
+		return 0
+	}
+	days, _ := strconv.Atoi(r.FormValue(field))
+	if days <= 0 {
+		return 0
+	}
+	return uint64(days) * 24 * 60 * 60
+}
+
+// data e hora informadas no formulario convertidas em epoch (0 se vazio)
+func FormToEpoch(r *http.Request, field string, genesis time.Time) uint64 {
+	if r == nil {
+		log.Print("PANIC BUG: FormToEpoch called with nil request ")
+		return 0
+	}
+	t := FormToTime(r, field)
+	if t.IsZero() || !t.After(genesis) {
+		return 0
+	}
+	return uint64(t.Sub(genesis) / time.Second)
+}
+
 func FormToHash(r *http.Request, field string) crypto.Hash {
 	if r == nil {
 		log.Print("PANIC BUG: FormToHash called with nil request ")
@@ -120,15 +150,23 @@ func FormToBool(r *http.Request, field string) bool {
 	return r.FormValue(field) == "on"
 }
 
-func FormToPolicy(r *http.Request) Policy {
+func FormToPolicy(r *http.Request, handles map[string]crypto.Token) Policy {
 	if r == nil {
 		log.Print("PANIC BUG: FormToPolicy called with nil request ")
 		return Policy{}
 	}
-	return Policy{
+	policy := Policy{
 		Majority:      FormToI(r, "policyMajority"),
 		SuperMajority: FormToI(r, "policySupermajority"),
 	}
+	if r.FormValue("policyKind") == "quorum" {
+		policy.Kind = actions.QuorumPolicy
+		policy.Quorum = FormToI(r, "policyQuorum")
+	}
+	if veto := FormToTokenArray(r, "policyVeto", handles); len(veto) > 0 {
+		policy.Veto = veto
+	}
+	return policy
 }
 
 func FormToTime(r *http.Request, field string) time.Time {
@@ -174,6 +212,23 @@ func CancelEventForm(r *http.Request) CancelEvent {
 		ID:      FormToI(r, "id"),
 		Hash:    FormToHash(r, "hash"),
 	}
+	if occurrence := FormToI(r, "occurrence"); occurrence > 0 {
+		action.Occurrence = uint64(occurrence)
+	}
+	return action
+}
+
+func CancelCheckinEventForm(r *http.Request) CancelCheckinEvent {
+	if r == nil {
+		log.Print("PANIC BUG: CancelCheckinEventForm called with nil request ")
+		return CancelCheckinEvent{}
+	}
+	action := CancelCheckinEvent{
+		Action:    "CancelCheckinEvent",
+		ID:        FormToI(r, "id"),
+		Reasons:   r.FormValue("reasons"),
+		EventHash: FormToHash(r, "eventhash"),
+	}
 	return action
 }
 
@@ -192,6 +247,23 @@ func CheckinEventForm(r *http.Request, ephemeralToken crypto.Token) CheckinEvent
 	return action
 }
 
+func CommentForm(r *http.Request) Comment {
+	if r == nil {
+		log.Print("PANIC BUG: CommentForm called with nil request ")
+		return Comment{}
+	}
+	action := Comment{
+		Action:     "Comment",
+		ID:         FormToI(r, "id"),
+		Reasons:    r.FormValue("reasons"),
+		OnBehalfOf: r.FormValue("onBehalfOf"),
+		Target:     FormToHash(r, "target"),
+		ReplyTo:    FormToHash(r, "replyTo"),
+		Body:       r.FormValue("body"),
+	}
+	return action
+}
+
 func CreateBoardForm(r *http.Request) CreateBoard {
 	if r == nil {
 		log.Print("PANIC BUG: CreateBoardForm called with nil request ")
@@ -210,7 +282,24 @@ func CreateBoardForm(r *http.Request) CreateBoard {
 	return action
 }
 
-func CreateCollectiveForm(r *http.Request) CreateCollective {
+func CreateJournalForm(r *http.Request) CreateJournal {
+	if r == nil {
+		log.Print("PANIC BUG: CreateJournalForm called with nil request ")
+		return CreateJournal{}
+	}
+	action := CreateJournal{
+		Action:      "CreateJournal",
+		ID:          FormToI(r, "id"),
+		Reasons:     r.FormValue("reasons"),
+		OnBehalfOf:  r.FormValue("onBehalfOf"),
+		Name:        r.FormValue("name"),
+		Description: r.FormValue("description"),
+		Keywords:    FormToStringArray(r, "keywords"),
+	}
+	return action
+}
+
+func CreateCollectiveForm(r *http.Request, handles map[string]crypto.Token) CreateCollective {
 	if r == nil {
 		log.Print("PANIC BUG: CreateCollectiveForm called with nil request ")
 		return CreateCollective{}
@@ -221,12 +310,13 @@ func CreateCollectiveForm(r *http.Request) CreateCollective {
 		Reasons:     r.FormValue("reasons"),
 		Name:        r.FormValue("name"),
 		Description: r.FormValue("description"),
-		Policy:      FormToPolicy(r),
+		Policy:      FormToPolicy(r, handles),
+		Expiry:      FormToExpiry(r, "expiry"),
 	}
 	return action
 }
 
-func CreateEventForm(r *http.Request, handles map[string]crypto.Token, token crypto.Token) CreateEvent {
+func CreateEventForm(r *http.Request, handles map[string]crypto.Token, token crypto.Token, genesis time.Time) CreateEvent {
 	if r == nil {
 		log.Print("PANIC BUG: CreateEventForm called with nil request ")
 		return CreateEvent{}
@@ -247,6 +337,17 @@ func CreateEventForm(r *http.Request, handles map[string]crypto.Token, token cry
 		Open:            FormToBool(r, "open"),
 		Public:          FormToBool(r, "public"),
 		ManagerMajority: FormToI(r, "managerMajority"),
+		RSVPClose:       FormToEpoch(r, "rsvpClose", genesis),
+	}
+	if capacity := FormToI(r, "capacity"); capacity > 0 {
+		action.Capacity = uint64(capacity)
+	}
+	if frequency := r.FormValue("frequency"); frequency != "" && frequency != "none" {
+		action.Recurrence = &Recurrence{
+			Frequency: frequency,
+			Interval:  FormToI(r, "interval"),
+			Count:     FormToI(r, "count"),
+		}
 	}
 	if s := r.FormValue("managers"); s == "" {
 		action.Managers = []crypto.Token{token}
@@ -314,6 +415,9 @@ func EditForm(r *http.Request, handles map[string]crypto.Token, file []byte, ext
 		ContentType: FileType(r.FormValue("fileName")),
 		File:        file,
 	}
+	if action.ContentType == "diff" {
+		action.ContentType = diff.PatchType
+	}
 	if r.FormValue("onBehalfOf") != "" {
 		action.OnBehalfOf = r.FormValue("onBehalfOf")
 		return action
@@ -325,6 +429,29 @@ func EditForm(r *http.Request, handles map[string]crypto.Token, file []byte, ext
 	return action
 }
 
+// PatchEdit replaces the revised file of an edit by the patch from the edited
+// draft to it.
+func PatchEdit(s *state.State, edit Edit) (Edit, error) {
+	draft, ok := s.Drafts[edit.EditedDraft]
+	if !ok {
+		return edit, errors.New("unkown draft")
+	}
+	if !isTextType(draft.DraftType) || !isTextType(edit.ContentType) {
+		return edit, ErrDiffNotText
+	}
+	content, ok := s.GetMedia(draft.DraftHash)
+	if !ok {
+		return edit, ErrDiffVersionNotFound
+	}
+	hunks := diff.Hunks(diff.Text(string(content), string(edit.File)), diffContext)
+	if len(hunks) == 0 {
+		return edit, errors.New("edit does not change the draft")
+	}
+	edit.File = []byte(diff.Format(hunks))
+	edit.ContentType = diff.PatchType
+	return edit, nil
+}
+
 func GreetCheckinEventForm(r *http.Request, handles map[string]crypto.Token) MultiGreetCheckinEvent {
 	if r == nil {
 		log.Print("PANIC BUG: GreetCheckinEventForm called with nil request ")
@@ -370,6 +497,50 @@ func ImprintStampForm(r *http.Request) ImprintStamp {
 	return action
 }
 
+func JournalEditorForm(r *http.Request, handles map[string]crypto.Token) JournalEditor {
+	if r == nil {
+		log.Print("PANIC BUG: JournalEditorForm called with nil request ")
+		return JournalEditor{}
+	}
+	if handles == nil {
+		log.Print("PANIC BUG: JournalEditorForm called with nil handles ")
+		return JournalEditor{}
+	}
+	action := JournalEditor{
+		Action:  "JournalEditor",
+		ID:      FormToI(r, "id"),
+		Reasons: r.FormValue("reasons"),
+		Journal: r.FormValue("journal"),
+		Editor:  FormToToken(r, "editor", handles),
+		Insert:  FormToBool(r, "insert"),
+	}
+	return action
+}
+
+// os lancamentos do numero vem de checkboxes "drafts", na ordem do formulario
+func JournalIssueForm(r *http.Request) JournalIssue {
+	if r == nil {
+		log.Print("PANIC BUG: JournalIssueForm called with nil request ")
+		return JournalIssue{}
+	}
+	action := JournalIssue{
+		Action:      "JournalIssue",
+		ID:          FormToI(r, "id"),
+		Reasons:     r.FormValue("reasons"),
+		Journal:     r.FormValue("journal"),
+		Title:       r.FormValue("title"),
+		Description: r.FormValue("description"),
+		Drafts:      make([]crypto.Hash, 0),
+	}
+	for _, caption := range r.Form["drafts"] {
+		var hash crypto.Hash
+		if hash.UnmarshalText([]byte(caption)) == nil {
+			action.Drafts = append(action.Drafts, hash)
+		}
+	}
+	return action
+}
+
 func PinForm(r *http.Request) Pin {
 	if r == nil {
 		log.Print("PANIC BUG: PinForm called with nil request ")
@@ -435,6 +606,31 @@ func RemoveMemberForm(r *http.Request, handles map[string]crypto.Token) RemoveMe
 	return action
 }
 
+func DelegateForm(r *http.Request, handles map[string]crypto.Token) Delegate {
+	if r == nil {
+		log.Print("PANIC BUG: DelegateForm called with nil request ")
+		return Delegate{}
+	}
+	if handles == nil {
+		log.Print("PANIC BUG: DelegateForm called with nil handles ")
+		return Delegate{}
+	}
+	action := Delegate{
+		Action:     "Delegate",
+		ID:         FormToI(r, "id"),
+		Reasons:    r.FormValue("reasons"),
+		Collective: r.FormValue("collective"),
+		Delegate:   FormToToken(r, "delegate", handles),
+		Revoke:     FormToBool(r, "revoke"),
+	}
+	for _, value := range r.Form["kinds"] {
+		if kind, err := strconv.Atoi(value); err == nil && kind >= 0 && kind < 256 {
+			action.Kinds = append(action.Kinds, byte(kind))
+		}
+	}
+	return action
+}
+
 func RequestMembershipForm(r *http.Request) RequestMembership {
 	if r == nil {
 		log.Print("PANIC BUG: RequestMembershipForm called with nil request ")
@@ -504,10 +700,13 @@ func UpdateCollectiveForm(r *http.Request) UpdateCollective {
 		supermajority := FormToB(r, "supermajority")
 		action.SuperMajority = &supermajority
 	}
+	if expiry := FormToExpiry(r, "expiry"); expiry > 0 {
+		action.Expiry = &expiry
+	}
 	return action
 }
 
-func UpdateEventForm(r *http.Request, handles map[string]crypto.Token) UpdateEvent {
+func UpdateEventForm(r *http.Request, handles map[string]crypto.Token, genesis time.Time) UpdateEvent {
 	if r == nil {
 		log.Print("PANIC BUG: UpdateEventForm called with nil request ")
 		return UpdateEvent{}
@@ -522,6 +721,17 @@ func UpdateEventForm(r *http.Request, handles map[string]crypto.Token) UpdateEve
 		Reasons:   r.FormValue("reasons"),
 		EventHash: FormToHash(r, "eventHash"),
 	}
+	if occurrence := FormToI(r, "occurrence"); occurrence > 0 {
+		action.Occurrence = uint64(occurrence)
+	}
+	if s := r.FormValue("startAt"); s != "" {
+		startAt := FormToTime(r, "startAt")
+		action.StartAt = &startAt
+	}
+	if s := r.FormValue("estimatedEnd"); s != "" {
+		estimatedEnd := FormToTime(r, "estimatedEnd")
+		action.EstimatedEnd = &estimatedEnd
+	}
 	if s := r.FormValue("description"); s != "" {
 		action.Description = &s
 	}
@@ -544,6 +754,17 @@ func UpdateEventForm(r *http.Request, handles map[string]crypto.Token) UpdateEve
 		managers := FormToTokenArray(r, "managers", handles)
 		action.Managers = &managers
 	}
+	if s := r.FormValue("capacity"); s != "" {
+		var capacity uint64
+		if n := FormToI(r, "capacity"); n > 0 {
+			capacity = uint64(n)
+		}
+		action.Capacity = &capacity
+	}
+	if s := r.FormValue("rsvpClose"); s != "" {
+		deadline := FormToEpoch(r, "rsvpClose", genesis)
+		action.RSVPClose = &deadline
+	}
 	text, _ := json.Marshal(action)
 	log.Println(string(text))
 	return action
@@ -560,6 +781,8 @@ func VoteForm(r *http.Request) Vote {
 		Reasons: r.FormValue("reasons"),
 		Hash:    FormToHash(r, "hash"),
 		Approve: FormToBool(r, "approve"),
+		Abstain: r.FormValue("approve") == "abstain",
+		Retract: r.FormValue("approve") == "retract",
 	}
 	return action
 }
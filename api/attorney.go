@@ -39,7 +39,7 @@ type AttorneyGeneral struct {
 	Token         crypto.Token
 	signin        *SigninManager
 	wallet        crypto.PrivateKey
-	pending       map[crypto.Hash]actions.Action
+	mempool       *Mempool // actions sent and not yet included
 	gateway       chan []byte
 	state         *state.State
 	templates     *template.Template
@@ -53,6 +53,15 @@ type AttorneyGeneral struct {
 	hostname      string
 	safe          int                      // optional link to safe for direct onbboarding
 	inviteUser    map[crypto.Hash]struct{} // map of invite user hash to token
+	notifications *NotificationHub         // real time updates for open sessions
+	health        HealthReporter           // connection to the chain (optional)
+	// snapshot of the state: blocks up to restoredEpoch are already
+	// incorporated on the restored state and are skipped on replay
+	snapshotPath     string
+	snapshotInterval uint64
+	snapshotting     chan struct{} // ocupado enquanto um snapshot e gravado
+	restoredEpoch    uint64
+	replaying        bool
 }
 
 func (a *AttorneyGeneral) IncorporateGrantPower(handle string, grant *attorney.GrantPowerOfAttorney) {
@@ -65,20 +74,59 @@ func (A *AttorneyGeneral) RegisterAxeDataBase(axe state.HandleProvider) {
 	A.state.Axe = axe
 }
 
+// RegisterHealth sets the connection to the chain whose health is reported
+// at /api/v1/health.
+func (a *AttorneyGeneral) RegisterHealth(health HealthReporter) {
+	a.health = health
+}
+
 func (a *AttorneyGeneral) IncorporateRevokePower(handle string) {
 	//TODO: implement interface for a user to revoke power of attorney
 }
 
 func (a *AttorneyGeneral) Incorporate(action []byte) {
-	if err := a.state.Action(action); err != nil {
-		//fmt.Println("Error incorporating action:", err, action)
+	if a.replaying {
+		return
 	}
-
+	err := a.state.Action(action)
+	a.Confirmed(action, err)
 }
 
 func (a *AttorneyGeneral) SetEpoch(epoch uint64) {
 	//a.epoch = uint64(epoch)
-	a.state.Epoch = epoch
+	if epoch < a.restoredEpoch {
+		// block already incorporated on the restored snapshot
+		a.replaying = true
+		return
+	}
+	a.replaying = false
+	a.state.SetEpoch(epoch)
+	for _, submission := range a.mempool.Expire(epoch, a.chainHealth().Connected) {
+		log.Printf("action %v not included by epoch %v, expired", submission.ID, submission.Deadline)
+	}
+	if a.snapshotPath != "" && a.snapshotInterval > 0 && epoch > a.restoredEpoch && epoch%a.snapshotInterval == 0 {
+		a.writeSnapshot()
+	}
+}
+
+// writeSnapshot serializes the state on the goroutine of the blocks and saves
+// the bytes on another one, so that the disk does not hold the incorporation
+// of the next blocks. A snapshot is skipped while the previous one is still
+// being saved.
+func (a *AttorneyGeneral) writeSnapshot() {
+	select {
+	case a.snapshotting <- struct{}{}:
+	default:
+		log.Printf("state snapshot at epoch %v skipped: previous snapshot still being written", a.state.Epoch)
+		return
+	}
+	data := a.state.Snapshot()
+	go func() {
+		if err := state.SaveSnapshot(a.snapshotPath, data); err != nil {
+			log.Printf("could not write state snapshot: %v", err)
+		}
+		<-a.snapshotting
+	}()
 }
 
 func (a *AttorneyGeneral) DestroySession(token crypto.Token, cookie string) {
@@ -117,15 +165,48 @@ func (a *AttorneyGeneral) Handle(r *http.Request) string {
 	return handle
 }
 
-func (a *AttorneyGeneral) Send(all []actions.Action, author crypto.Token) {
+// Send dresses and forwards the actions to the gateway, returning the hashes
+// by which the state will refer to each of them. The actions are not checked
+// against the state: it is used by the attorney itself, as for the signins
+// that may arrive before the member is known to the local state.
+func (a *AttorneyGeneral) Send(all []actions.Action, author crypto.Token) []crypto.Hash {
+	submissions := a.submit(all, author)
+	hashes := make([]crypto.Hash, 0, len(submissions))
+	for _, submission := range submissions {
+		if submission != nil {
+			hashes = append(hashes, submission.Hash)
+		}
+	}
+	return hashes
+}
+
+// Submit checks the actions of a member against the current state and, if
+// all of them are valid, dresses and forwards them to the gateway, returning
+// the receipts by which their inclusion is followed on /myactions. Nothing is
+// sent if any action is invalid. There is one receipt for each action, nil
+// for an action that could not be dressed and was not sent.
+func (a *AttorneyGeneral) Submit(all []actions.Action, author crypto.Token) ([]*Submission, error) {
+	if err := a.validate(all, author); err != nil {
+		return nil, err
+	}
+	return a.submit(all, author), nil
+}
+
+func (a *AttorneyGeneral) submit(all []actions.Action, author crypto.Token) []*Submission {
+	submissions := make([]*Submission, 0, len(all))
 	for _, action := range all {
 		dressed := a.DressAction(action, author)
-		//fmt.Println("Dressed action:", dressed)
+		if dressed == nil {
+			submissions = append(submissions, nil)
+			continue
+		}
+		submissions = append(submissions, a.mempool.Add(dressed, author, a.state.Epoch))
 		// gambiarra o certo esta emabixo
 		a.gateway <- dressed
 		// a.gateway <- append([]byte{messages.MsgAction}, dressed...)
 		//a.gateway.Action(dressed)
 	}
+	return submissions
 }
 
 // Dress a giving action with current epoch, attorney´s author
@@ -152,8 +233,10 @@ func (a *AttorneyGeneral) DressAction(action actions.Action, author crypto.Token
 	return bytes
 }
 
-func (a *AttorneyGeneral) Confirmed(hash crypto.Hash) {
-	delete(a.pending, hash)
+// Confirmed updates the receipt of an action found on a block with the result
+// of its incorporation.
+func (a *AttorneyGeneral) Confirmed(action []byte, err error) {
+	a.mempool.Incorporated(action, a.state.Epoch, err)
 }
 
 // Translate synergy byte array to the head of the corresponding breeze instruction
@@ -169,3 +252,40 @@ func SynergyToBreeze(action []byte, epoch uint64) []byte {
 	bytes = append(bytes, action[8:]...)                 //
 	return bytes
 }
+
+// Breeze Void + Axé Void Specification
+// version for breeze           (byte)           0
+// void breeze instruction      (byte)           1
+// Epoch                        (8 bytes)        2
+// Protocol Code                (4 bytes)        10
+// Axé Void instruction code    (byte)           14
+// Author                       (32 bytes)       15
+// Data ....                    (Variable)
+// Signer                       (32 bytes)
+// Signature                    (64 bytes)
+// Wallet                       (32 bytes)
+// Fee                          (8 bytes)
+// Signature                    (64 bytes)
+
+// DressedTailSize is the size of the tail put by DressAction after the data:
+// signer, signature, wallet, fee and wallet signature.
+const DressedTailSize = 2*crypto.TokenSize + 2*crypto.SignatureSize + 8
+
+// Translate breeze byte array into synergy byte array, the inverse of
+// DressAction. Returns nil if the instruction is not a synergy action.
+func BreezeToSynergy(action []byte) []byte {
+	if len(action) < 15+DressedTailSize {
+		return nil
+	}
+	// verifica se eh uma acao synergy/motiro
+	if action[0] != 0 || action[1] != breeze.IVoid || action[10] != 1 || action[11] != 1 || action[12] != 0 || action[13] != 0 || action[14] != attorney.VoidType {
+		return nil
+	}
+	// strip first 2 bytes, the 4 bytes of protocol, the byte for the axe void and
+	// the tail (signer ... wallet signayture)
+	// copia: um append sobre action[2:10] sobrescreveria a propria acao
+	synergy := make([]byte, 0, len(action)-7-DressedTailSize)
+	synergy = append(synergy, action[2:10]...)
+	synergy = append(synergy, action[15:len(action)-DressedTailSize]...)
+	return synergy
+}
@@ -0,0 +1,361 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/state"
+)
+
+var (
+	ErrCiteFormat   = errors.New("unknown citation format")
+	ErrCiteNotFound = errors.New("release not found")
+)
+
+// CiteRecord is a release described as a publication.
+type CiteRecord struct {
+	Key         string
+	Title       string
+	Authors     []string // handles dos autores ou o nome do coletivo
+	Collective  bool
+	Description string
+	Keywords    []string
+	Released    time.Time
+	Version     int      // posicao do esboco na cadeia de versoes
+	Stamps      []string // coletivos que imprimiram selo
+	URL         string
+	Hash        string
+}
+
+type citeFormat struct {
+	contentType string
+	extension   string
+	write       func([]CiteRecord) ([]byte, error)
+}
+
+var citeFormats = map[string]citeFormat{
+	"bibtex": {"application/x-bibtex; charset=utf-8", "bib", bibTeX},
+	"csl":    {"application/vnd.citationstyles.csl+json; charset=utf-8", "json", cslJSON},
+	"ris":    {"application/x-research-info-systems; charset=utf-8", "ris", risRecords},
+}
+
+// releaseOf finds a release by the hash of the released draft or by the hash
+// of the release instruction.
+func releaseOf(s *state.State, hash crypto.Hash) (*state.Release, bool) {
+	if release, ok := s.Releases[hash]; ok {
+		return release, true
+	}
+	for _, release := range s.Releases {
+		if release.Hash.Equal(hash) {
+			return release, true
+		}
+	}
+	return nil, false
+}
+
+// CiteRecordFromState describes a release. url is the stable prefix of the
+// links to drafts (host and server name).
+func CiteRecordFromState(s *state.State, release *state.Release, url string) CiteRecord {
+	draft := release.Draft
+	hash := crypto.EncodeHash(draft.DraftHash)
+	record := CiteRecord{
+		Title:       draft.Title,
+		Authors:     make([]string, 0),
+		Description: draft.Description,
+		Keywords:    draft.Keywords,
+		Released:    s.TimeOfEpoch(release.Epoch),
+		Version:     1,
+		Stamps:      make([]string, 0),
+		URL:         fmt.Sprintf("%s/draft/%s", url, hash),
+		Hash:        hash,
+	}
+	if name := draft.Authors.CollectiveName(); name != "" {
+		record.Authors = append(record.Authors, name)
+		record.Collective = true
+	} else {
+		for token := range draft.Authors.ListOfMembers() {
+			if handle, ok := s.Members[crypto.HashToken(token)]; ok {
+				record.Authors = append(record.Authors, handle)
+			}
+		}
+		sort.Strings(record.Authors)
+	}
+	for previous := draft.PreviousVersion; previous != nil; previous = previous.PreviousVersion {
+		record.Version++
+	}
+	for _, stamp := range release.Stamps {
+		if stamp.Imprinted && stamp.Reputation != nil {
+			record.Stamps = append(record.Stamps, stamp.Reputation.Name)
+		}
+	}
+	record.Key = citeKey(record)
+	return record
+}
+
+// chave no estilo autorANOpalavra, apenas com letras e digitos
+func citeKey(record CiteRecord) string {
+	clean := func(text string) string {
+		return strings.Map(func(r rune) rune {
+			if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, text)
+	}
+	author := "synergy"
+	if len(record.Authors) > 0 && clean(record.Authors[0]) != "" {
+		author = clean(record.Authors[0])
+	}
+	word := ""
+	for _, field := range strings.Fields(record.Title) {
+		if word = clean(field); len(word) > 3 {
+			break
+		}
+	}
+	return fmt.Sprintf("%s%d%s", author, record.Released.Year(), word)
+}
+
+// chaves repetidas ganham sufixo a, b, c, ..., z, aa, ab, ... sem repetir uma
+// chave ja existente
+func uniqueKeys(records []CiteRecord) {
+	count := make(map[string]int)
+	for _, record := range records {
+		count[record.Key]++
+	}
+	used := make(map[string]int)
+	for n, record := range records {
+		if count[record.Key] > 1 {
+			for {
+				key := record.Key + keySuffix(used[record.Key])
+				used[record.Key]++
+				if count[key] == 0 {
+					count[key] = 1
+					records[n].Key = key
+					break
+				}
+			}
+		}
+	}
+}
+
+// keySuffix is the n-th suffix (from 0) of a, b, ..., z, aa, ab, ...
+func keySuffix(n int) string {
+	suffix := ""
+	for n++; n > 0; n = (n - 1) / 26 {
+		suffix = string(rune('a'+(n-1)%26)) + suffix
+	}
+	return suffix
+}
+
+// ReleasesOnBoard describes the released drafts pinned to a board.
+func ReleasesOnBoard(s *state.State, name, url string) ([]CiteRecord, bool) {
+	board, ok := s.Board(name)
+	if !ok {
+		return nil, false
+	}
+	releases := make([]*state.Release, 0)
+	for _, draft := range board.Pinned {
+		if release, ok := s.Releases[draft.DraftHash]; ok {
+			releases = append(releases, release)
+		}
+	}
+	return citeRecords(s, releases, url), true
+}
+
+// ReleasesOfCollective describes the releases authored on behalf of the
+// collective or stamped by it.
+func ReleasesOfCollective(s *state.State, name, url string) ([]CiteRecord, bool) {
+	if _, ok := s.Collective(name); !ok {
+		return nil, false
+	}
+	releases := make([]*state.Release, 0)
+	for _, release := range s.Releases {
+		included := release.Draft.Authors.CollectiveName() == name
+		for _, stamp := range release.Stamps {
+			included = included || (stamp.Imprinted && stamp.Reputation != nil && stamp.Reputation.Name == name)
+		}
+		if included {
+			releases = append(releases, release)
+		}
+	}
+	return citeRecords(s, releases, url), true
+}
+
+func citeRecords(s *state.State, releases []*state.Release, url string) []CiteRecord {
+	sort.Slice(releases, func(n, m int) bool { return releases[n].Epoch < releases[m].Epoch })
+	records := make([]CiteRecord, 0, len(releases))
+	for _, release := range releases {
+		records = append(records, CiteRecordFromState(s, release, url))
+	}
+	uniqueKeys(records)
+	return records
+}
+
+// WriteCiteRecords writes the records in format "bibtex" (default), "csl"
+// (CSL-JSON) or "ris" as a file named name.
+func WriteCiteRecords(w http.ResponseWriter, records []CiteRecord, format, name string) error {
+	if format == "" {
+		format = "bibtex"
+	}
+	writer, ok := citeFormats[format]
+	if !ok {
+		return ErrCiteFormat
+	}
+	data, err := writer.write(records)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", writer.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+writer.extension))
+	_, err = w.Write(data)
+	return err
+}
+
+var bibTeXEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`, "{", `\{`, "}", `\}`, "&", `\&`, "%", `\%`,
+	"$", `\$`, "#", `\#`, "_", `\_`, "~", `\textasciitilde{}`, "^", `\textasciicircum{}`,
+)
+
+var bibTeXMonths = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+
+func bibTeX(records []CiteRecord) ([]byte, error) {
+	var text strings.Builder
+	for n, record := range records {
+		if n > 0 {
+			text.WriteString("\n")
+		}
+		authors := make([]string, 0, len(record.Authors))
+		for _, author := range record.Authors {
+			// entre chaves para nao ser lido como nome e sobrenome
+			authors = append(authors, "{"+bibTeXEscaper.Replace(author)+"}")
+		}
+		fields := [][2]string{
+			{"title", "{" + bibTeXEscaper.Replace(record.Title) + "}"},
+			{"author", strings.Join(authors, " and ")},
+			{"year", fmt.Sprintf("%d", record.Released.Year())},
+			{"month", bibTeXMonths[record.Released.Month()-1]},
+			{"date", record.Released.Format("2006-01-02")},
+			{"version", fmt.Sprintf("%d", record.Version)},
+			{"howpublished", "Synergy"},
+			{"url", record.URL},
+		}
+		if record.Description != "" {
+			fields = append(fields, [2]string{"abstract", bibTeXEscaper.Replace(record.Description)})
+		}
+		if len(record.Keywords) > 0 {
+			fields = append(fields, [2]string{"keywords", bibTeXEscaper.Replace(strings.Join(record.Keywords, ", "))})
+		}
+		if len(record.Stamps) > 0 {
+			fields = append(fields, [2]string{"note", "Selos: " + bibTeXEscaper.Replace(strings.Join(record.Stamps, ", "))})
+		}
+		fmt.Fprintf(&text, "@misc{%s,\n", record.Key)
+		for k, field := range fields {
+			separator := ","
+			if k == len(fields)-1 {
+				separator = ""
+			}
+			if field[0] == "month" {
+				fmt.Fprintf(&text, "  %s = %s%s\n", field[0], field[1], separator)
+			} else {
+				fmt.Fprintf(&text, "  %s = {%s}%s\n", field[0], field[1], separator)
+			}
+		}
+		text.WriteString("}\n")
+	}
+	return []byte(text.String()), nil
+}
+
+type cslName struct {
+	Literal string `json:"literal"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+type cslItem struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Title     string    `json:"title"`
+	Author    []cslName `json:"author"`
+	Issued    cslDate   `json:"issued"`
+	Abstract  string    `json:"abstract,omitempty"`
+	Keyword   string    `json:"keyword,omitempty"`
+	Version   string    `json:"version"`
+	Publisher string    `json:"publisher"`
+	URL       string    `json:"URL"`
+	Note      string    `json:"note,omitempty"`
+}
+
+func cslJSON(records []CiteRecord) ([]byte, error) {
+	items := make([]cslItem, 0, len(records))
+	for _, record := range records {
+		item := cslItem{
+			ID:        record.Key,
+			Type:      "manuscript",
+			Title:     record.Title,
+			Author:    make([]cslName, 0, len(record.Authors)),
+			Issued:    cslDate{DateParts: [][]int{{record.Released.Year(), int(record.Released.Month()), record.Released.Day()}}},
+			Abstract:  record.Description,
+			Keyword:   strings.Join(record.Keywords, ", "),
+			Version:   fmt.Sprintf("%d", record.Version),
+			Publisher: "Synergy",
+			URL:       record.URL,
+		}
+		for _, author := range record.Authors {
+			item.Author = append(item.Author, cslName{Literal: author})
+		}
+		if len(record.Stamps) > 0 {
+			item.Note = "Selos: " + strings.Join(record.Stamps, ", ")
+		}
+		items = append(items, item)
+	}
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(items); err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
+
+func risRecords(records []CiteRecord) ([]byte, error) {
+	var text strings.Builder
+	line := func(tag, value string) {
+		value = strings.ReplaceAll(strings.ReplaceAll(value, "\r", " "), "\n", " ")
+		fmt.Fprintf(&text, "%s  - %s\r\n", tag, value)
+	}
+	for _, record := range records {
+		line("TY", "GEN")
+		line("ID", record.Key)
+		line("TI", record.Title)
+		for _, author := range record.Authors {
+			line("AU", author)
+		}
+		line("PY", fmt.Sprintf("%d", record.Released.Year()))
+		line("DA", record.Released.Format("2006/01/02/"))
+		line("ET", fmt.Sprintf("%d", record.Version))
+		line("PB", "Synergy")
+		line("UR", record.URL)
+		if record.Description != "" {
+			line("AB", record.Description)
+		}
+		for _, keyword := range record.Keywords {
+			line("KW", keyword)
+		}
+		if len(record.Stamps) > 0 {
+			line("N1", "Selos: "+strings.Join(record.Stamps, ", "))
+		}
+		line("ER", "")
+	}
+	return []byte(text.String()), nil
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// citeTestRecords: a release with characters escaped by bibtex and a line
+// break on the description, and a release of a collective
+func citeTestRecords() []CiteRecord {
+	return []CiteRecord{
+		{
+			Key:         "ana2024custos",
+			Title:       "Custos & {lucros}_100%",
+			Authors:     []string{"ana", "bruno_b"},
+			Description: "linha 1\nlinha 2 $x^2$",
+			Keywords:    []string{"economia", "a~b"},
+			Released:    time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC),
+			Version:     2,
+			Stamps:      []string{"revista"},
+			URL:         "https://synergy/draft/h1",
+			Hash:        "h1",
+		},
+		{
+			Key:        "coletivo2025nota",
+			Title:      "Nota",
+			Authors:    []string{"coletivo"},
+			Collective: true,
+			Keywords:   []string{},
+			Released:   time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC),
+			Version:    1,
+			Stamps:     []string{},
+			URL:        "https://synergy/draft/h2",
+			Hash:       "h2",
+		},
+	}
+}
+
+func TestCiteFormats(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"bibtex", `@misc{ana2024custos,
+  title = {{Custos \& \{lucros\}\_100\%}},
+  author = {{ana} and {bruno\_b}},
+  year = {2024},
+  month = mar,
+  date = {2024-03-05},
+  version = {2},
+  howpublished = {Synergy},
+  url = {https://synergy/draft/h1},
+  abstract = {linha 1
+linha 2 \$x\textasciicircum{}2\$},
+  keywords = {economia, a\textasciitilde{}b},
+  note = {Selos: revista}
+}
+
+@misc{coletivo2025nota,
+  title = {{Nota}},
+  author = {{coletivo}},
+  year = {2025},
+  month = dec,
+  date = {2025-12-31},
+  version = {1},
+  howpublished = {Synergy},
+  url = {https://synergy/draft/h2}
+}
+`},
+		{"csl", `[
+  {
+    "id": "ana2024custos",
+    "type": "manuscript",
+    "title": "Custos & {lucros}_100%",
+    "author": [
+      {
+        "literal": "ana"
+      },
+      {
+        "literal": "bruno_b"
+      }
+    ],
+    "issued": {
+      "date-parts": [
+        [
+          2024,
+          3,
+          5
+        ]
+      ]
+    },
+    "abstract": "linha 1\nlinha 2 $x^2$",
+    "keyword": "economia, a~b",
+    "version": "2",
+    "publisher": "Synergy",
+    "URL": "https://synergy/draft/h1",
+    "note": "Selos: revista"
+  },
+  {
+    "id": "coletivo2025nota",
+    "type": "manuscript",
+    "title": "Nota",
+    "author": [
+      {
+        "literal": "coletivo"
+      }
+    ],
+    "issued": {
+      "date-parts": [
+        [
+          2025,
+          12,
+          31
+        ]
+      ]
+    },
+    "version": "1",
+    "publisher": "Synergy",
+    "URL": "https://synergy/draft/h2"
+  }
+]
+`},
+		{"ris", "TY  - GEN\r\n" +
+			"ID  - ana2024custos\r\n" +
+			"TI  - Custos & {lucros}_100%\r\n" +
+			"AU  - ana\r\n" +
+			"AU  - bruno_b\r\n" +
+			"PY  - 2024\r\n" +
+			"DA  - 2024/03/05/\r\n" +
+			"ET  - 2\r\n" +
+			"PB  - Synergy\r\n" +
+			"UR  - https://synergy/draft/h1\r\n" +
+			"AB  - linha 1 linha 2 $x^2$\r\n" +
+			"KW  - economia\r\n" +
+			"KW  - a~b\r\n" +
+			"N1  - Selos: revista\r\n" +
+			"ER  - \r\n" +
+			"TY  - GEN\r\n" +
+			"ID  - coletivo2025nota\r\n" +
+			"TI  - Nota\r\n" +
+			"AU  - coletivo\r\n" +
+			"PY  - 2025\r\n" +
+			"DA  - 2025/12/31/\r\n" +
+			"ET  - 1\r\n" +
+			"PB  - Synergy\r\n" +
+			"UR  - https://synergy/draft/h2\r\n" +
+			"ER  - \r\n"},
+	}
+	for _, test := range tests {
+		data, err := citeFormats[test.format].write(citeTestRecords())
+		if err != nil {
+			t.Fatalf("%v: %v", test.format, err)
+		}
+		if string(data) != test.expected {
+			t.Errorf("%v: got\n%q\nexpected\n%q", test.format, data, test.expected)
+		}
+	}
+}
+
+func TestCiteMonths(t *testing.T) {
+	for month := time.January; month <= time.December; month++ {
+		record := CiteRecord{Key: "chave", Released: time.Date(2024, month, 1, 0, 0, 0, 0, time.UTC)}
+		data, _ := bibTeX([]CiteRecord{record})
+		macro := fmt.Sprintf("  month = %s,\n", strings.ToLower(month.String()[:3]))
+		if !strings.Contains(string(data), macro) {
+			t.Errorf("%v: month macro missing on\n%s", month, data)
+		}
+	}
+}
+
+func TestUniqueKeys(t *testing.T) {
+	records := make([]CiteRecord, 0)
+	for n := 0; n < 28; n++ {
+		records = append(records, CiteRecord{Key: "ana2024custos"})
+	}
+	// a key that a suffix would produce is not repeated
+	records = append(records, CiteRecord{Key: "ana2024custosb"}, CiteRecord{Key: "unica"})
+	uniqueKeys(records)
+	expected := map[int]string{0: "ana2024custosa", 1: "ana2024custosc", 24: "ana2024custosz", 25: "ana2024custosaa", 26: "ana2024custosab", 27: "ana2024custosac", 28: "ana2024custosb", 29: "unica"}
+	for n, key := range expected {
+		if records[n].Key != key {
+			t.Errorf("record %v: key %v, expected %v", n, records[n].Key, key)
+		}
+	}
+	keys := make(map[string]bool)
+	for _, record := range records {
+		if keys[record.Key] {
+			t.Errorf("repeated key %v", record.Key)
+		}
+		keys[record.Key] = true
+	}
+}
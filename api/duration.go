@@ -33,6 +33,17 @@ func mdToHTML(md []byte) string {
 	return ""
 }
 
+// commentToHTML renders the markdown of a comment. Raw HTML on comments is
+// dropped.
+func commentToHTML(md []byte) string {
+	extensions := parser.CommonExtensions | parser.NoEmptyLineBeforeBlock
+	p := parser.NewWithExtensions(extensions)
+	doc := p.Parse(md)
+	htmlFlags := html.CommonFlags | html.HrefTargetBlank | html.SkipHTML
+	renderer := html.NewRenderer(html.RendererOptions{Flags: htmlFlags})
+	return string(markdown.Render(doc, renderer))
+}
+
 func PrettyDate(date time.Time) string {
 	return date.Format("02 Jan 06")
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/freehandle/breeze/crypto"
 	"github.com/freehandle/synergy/social/actions"
+	"github.com/freehandle/synergy/social/diff"
 	"github.com/freehandle/synergy/social/index"
 	"github.com/freehandle/synergy/social/state"
 )
@@ -57,6 +58,7 @@ type ReferenceDetail struct {
 	Title  string
 	Author string
 	Date   string
+	Link   string
 }
 
 // co-autor, stamp, pin, version, release
@@ -114,6 +116,10 @@ type DraftDetailView struct {
 	Edits        []DraftEditView
 	ServerName   string
 	IsPending    bool
+	Diffable     bool // versao anterior e esta em txt ou md
+	Mergeable    bool // possui edicoes em patch para combinar
+	CitedBy      []CitationView
+	Comments     CommentThread
 }
 
 type EditDetailedView struct {
@@ -126,6 +132,8 @@ type EditDetailedView struct {
 	Head       HeaderInfo
 	ServerName string
 	Authorship bool
+	Diffable   bool // edit e draft em txt ou md
+	Comments   CommentThread
 }
 
 func EditDetailFromState(s *state.State, i *index.Index, hash crypto.Hash, token crypto.Token) *EditDetailedView {
@@ -151,6 +159,8 @@ func EditDetailFromState(s *state.State, i *index.Index, hash crypto.Hash, token
 		Votes:      make([]DraftVoteAction, 0),
 		Head:       head,
 		Authorship: edit.Authors.IsMember(token),
+		Diffable:   (isTextType(edit.EditType) || edit.EditType == diff.PatchType) && isTextType(edit.Draft.DraftType),
+		Comments:   CommentsFromIndex(s, i, hash, "editview/"+crypto.EncodeHash(hash)),
 	}
 	pending := i.GetVotes(token)
 	if len(pending) > 0 {
@@ -223,6 +233,7 @@ func References(r []crypto.Hash, s *state.State, genesis time.Time) []ReferenceD
 				Title:  draft.Title,
 				Author: authorsEtAll(draft.Authors, s),
 				Date:   fmt.Sprintf("%v", date.Year()),
+				Link:   crypto.EncodeHash(hash),
 			}
 			references = append(references, reference)
 		}
@@ -321,6 +332,8 @@ func DraftDetailFromState(s *state.State, i *index.Index, hash crypto.Hash, toke
 		Authorship:  draft.Authors.IsMember(token),
 		Hash:        string(hashText),
 		IsPending:   ispending,
+		CitedBy:     CitedByFromIndex(s, i, hash, genesis),
+		Comments:    CommentsFromIndex(s, i, hash, "draft/"+string(hashText)),
 	}
 	if view.Authorship {
 		view.Head = HeaderInfo{
@@ -375,19 +388,21 @@ func DraftDetailFromState(s *state.State, i *index.Index, hash crypto.Hash, toke
 	}
 	if len(draft.Edits) > 0 {
 		view.Edited = true
+		view.Mergeable = isTextType(draft.DraftType) && len(patchEdits(draft)) > 0
 	}
 	if draft.PreviousVersion != nil {
 		text, _ := draft.PreviousVersion.DraftHash.MarshalText()
 		view.PreviousHash = string(text)
+		view.Diffable = isTextType(draft.DraftType) && isTextType(draft.PreviousVersion.DraftType)
 	}
 	view.Policy.Majority, view.Policy.SuperMajority = draft.Authors.GetPolicy()
 
 	if draft.DraftType == "txt" {
-		if media, ok := s.Media[hash]; ok {
+		if media, ok := s.GetMedia(hash); ok {
 			view.Content = string(media)
 		}
 	} else if draft.DraftType == "md" {
-		if media, ok := s.Media[hash]; ok {
+		if media, ok := s.GetMedia(hash); ok {
 			view.Content = mdToHTML(media)
 		}
 	}
@@ -465,6 +480,7 @@ type VotesView struct {
 	ComplementLink    string
 	ComplementCaption string
 	Reasons           string
+	Cast              string // voto ja dado pelo membro, que pode ser alterado
 	ServerName        string
 }
 
@@ -479,6 +495,19 @@ type VoteDetailView struct {
 	ServerName string
 }
 
+func voteChoice(vote actions.Vote) string {
+	if vote.Retract {
+		return "retirado"
+	}
+	if vote.Abstain {
+		return "abstenção"
+	}
+	if vote.Approve {
+		return "a favor"
+	}
+	return "contra"
+}
+
 func VotesFromState(s *state.State, i *index.Index, token crypto.Token) VotesListView {
 	head := HeaderInfo{
 		Active:  "Votes",
@@ -490,7 +519,14 @@ func VotesFromState(s *state.State, i *index.Index, token crypto.Token) VotesLis
 		Head:  head,
 		Votes: make([]VotesView, 0),
 	}
-	votes := i.GetVotes(token)
+	votes := make(map[crypto.Hash]struct{})
+	for hash := range i.GetVotes(token) {
+		votes[hash] = struct{}{}
+	}
+	cast := i.GetCastVotes(token)
+	for hash := range cast {
+		votes[hash] = struct{}{}
+	}
 	for hash := range votes {
 		hashText, _ := hash.MarshalText()
 		itemView := VotesView{
@@ -500,6 +536,9 @@ func VotesFromState(s *state.State, i *index.Index, token crypto.Token) VotesLis
 			Hash:      string(hashText),
 			Reasons:   i.Reason(hash),
 		}
+		if vote, ok := cast[hash]; ok {
+			itemView.Cast = voteChoice(vote)
+		}
 		switch s.Proposals.Kind(hash) {
 		case state.RequestMembershipProposal:
 			prop := s.Proposals.RequestMembership[hash]
@@ -564,6 +603,26 @@ func VotesFromState(s *state.State, i *index.Index, token crypto.Token) VotesLis
 			itemView.ComplementCaption = prop.Board.Name
 			itemView.ComplementType = "board"
 			itemView.ComplementLink = fmt.Sprintf("board/%v", url.QueryEscape(prop.Board.Name))
+		case state.CreateJournalProposal:
+			itemView.Handler = "votecreatejournal"
+		case state.JournalIssueProposal:
+			itemView.Handler = "votejournalissue"
+		case state.JournalEditorProposal:
+			prop := s.Proposals.JournalEditor[hash]
+			editor, ok := s.Members[crypto.HashToken(prop.Editor)]
+			if ok {
+				itemView.ObjectCaption = editor
+				itemView.ObjectLink = fmt.Sprintf("member/%v", url.QueryEscape(editor))
+				if prop.Insert {
+					itemView.ObjectType = "include"
+				} else {
+					itemView.ObjectType = "remove"
+				}
+			}
+			itemView.Scope = ""
+			itemView.ComplementCaption = prop.Journal.Name
+			itemView.ComplementType = "journal"
+			itemView.ComplementLink = fmt.Sprintf("journal/%v", url.QueryEscape(prop.Journal.Name))
 		case state.ReactProposal:
 		case state.CreateEventProposal:
 			itemView.Handler = "votecreateevent"
@@ -694,6 +753,8 @@ type CollectiveUpdateView struct {
 	Majority         int
 	OldSuperMajority int
 	SuperMajority    int
+	OldExpiry        int // dias
+	Expiry           int
 	Member           bool
 	Hash             string
 	Reasons          string
@@ -702,6 +763,54 @@ type CollectiveUpdateView struct {
 	ServerName       string
 }
 
+func expiryDays(collective *state.Collective) int {
+	return int(collective.ProposalExpiry() / (24 * 60 * 60))
+}
+
+type DelegationView struct {
+	To    CaptionLink
+	Kinds []string
+}
+
+type KindOption struct {
+	Kind byte
+	Name string
+}
+
+// tipos de proposta decididos pelo coletivo, que podem ter o voto delegado
+var delegableKinds = []KindOption{
+	{Kind: state.UpdateCollectiveProposal, Name: "atualização do coletivo"},
+	{Kind: state.RequestMembershipProposal, Name: "pedido de ingresso"},
+	{Kind: state.RemoveMemberProposal, Name: "remoção de membro"},
+	{Kind: state.DraftProposal, Name: "esboço"},
+	{Kind: state.EditProposal, Name: "edição"},
+	{Kind: state.CreateBoardProposal, Name: "criação de mural"},
+	{Kind: state.UpdateBoardProposal, Name: "atualização de mural"},
+	{Kind: state.BoardEditorProposal, Name: "editor de mural"},
+	{Kind: state.ReleaseDraftProposal, Name: "publicação de esboço"},
+	{Kind: state.ImprintStampProposal, Name: "selo"},
+	{Kind: state.CreateEventProposal, Name: "criação de evento"},
+	{Kind: state.CreateJournalProposal, Name: "criação de periódico"},
+	{Kind: state.JournalEditorProposal, Name: "editor de periódico"},
+	{Kind: state.JournalIssueProposal, Name: "número de periódico"},
+}
+
+func kindName(kind byte) string {
+	for _, option := range delegableKinds {
+		if option.Kind == kind {
+			return option.Name
+		}
+	}
+	return ""
+}
+
+func policyKindName(kind byte) string {
+	if kind == actions.QuorumPolicy {
+		return "quórum no prazo"
+	}
+	return "maioria"
+}
+
 func CollectiveToUpdateFromState(s *state.State, name string) *CollectiveUpdateView {
 	collectiveName, _ := url.QueryUnescape(name)
 	col, ok := s.Collective(collectiveName)
@@ -720,6 +829,7 @@ func CollectiveToUpdateFromState(s *state.State, name string) *CollectiveUpdateV
 		OldDescription:   col.Description,
 		OldMajority:      col.Policy.Majority,
 		OldSuperMajority: col.Policy.SuperMajority,
+		OldExpiry:        expiryDays(col),
 		Head:             head,
 	}
 	return update
@@ -743,6 +853,7 @@ func CollectiveUpdateFromState(s *state.State, hash crypto.Hash, token crypto.To
 		OldDescription:   live.Description,
 		OldMajority:      live.Policy.Majority,
 		OldSuperMajority: live.Policy.SuperMajority,
+		OldExpiry:        expiryDays(live),
 		Hash:             crypto.EncodeHash(hash),
 		Reasons:          pending.Update.Reasons,
 		Head:             head,
@@ -757,6 +868,9 @@ func CollectiveUpdateFromState(s *state.State, hash crypto.Hash, token crypto.To
 	if pending.Update.SuperMajority != nil {
 		update.SuperMajority = int(*pending.Update.SuperMajority)
 	}
+	if pending.Update.Expiry != nil {
+		update.Expiry = int(*pending.Update.Expiry / (24 * 60 * 60))
+	}
 	if live.IsMember(token) {
 		update.Member = true
 	}
@@ -1108,11 +1222,18 @@ type CollectiveDetailView struct {
 	Description   string
 	Majority      int
 	SuperMajority int
+	Expiry        int // prazo das propostas em dias
+	PolicyKind    string
+	Quorum        int
+	Veto          []string
+	Delegations   []DelegationView // delegacoes de voto do membro logado
+	Kinds         []KindOption
 	Members       []MemberDetailView
 	Membership    bool
 	Head          HeaderInfo
 	Stamps        []StampView
 	Boards        []BoardOnCollectiveView
+	Journals      []JournalOnCollectiveView
 	Events        []EventOnCollectiveView
 	ServerName    string
 }
@@ -1153,12 +1274,35 @@ func CollectiveDetailFromState(s *state.State, i *index.Index, name string, toke
 		Description:   collective.Description,
 		Majority:      collective.Policy.Majority,
 		SuperMajority: collective.Policy.SuperMajority,
+		Expiry:        expiryDays(collective),
+		PolicyKind:    policyKindName(collective.Policy.Kind),
+		Quorum:        collective.Policy.Quorum,
+		Veto:          make([]string, 0),
 		Members:       make([]MemberDetailView, 0),
 		Membership:    collective.IsMember(token),
 		Stamps:        make([]StampView, 0),
 		Boards:        make([]BoardOnCollectiveView, 0),
 		Events:        make([]EventOnCollectiveView, 0),
 	}
+	for _, token := range collective.Policy.Veto {
+		if handle, ok := s.Members[crypto.Hasher(token[:])]; ok {
+			view.Veto = append(view.Veto, handle)
+		}
+	}
+	if view.Membership {
+		view.Kinds = delegableKinds
+		for _, delegation := range collective.Delegations[token] {
+			handle := s.Members[crypto.HashToken(delegation.To)]
+			delegationView := DelegationView{
+				To:    CaptionLink{Caption: handle, Link: url.QueryEscape(handle)},
+				Kinds: make([]string, 0),
+			}
+			for _, kind := range delegation.Kinds {
+				delegationView.Kinds = append(delegationView.Kinds, kindName(kind))
+			}
+			view.Delegations = append(view.Delegations, delegationView)
+		}
+	}
 	if view.Membership {
 		view.Head = HeaderInfo{
 			Active:  "Connections",
@@ -1213,6 +1357,8 @@ func CollectiveDetailFromState(s *state.State, i *index.Index, name string, toke
 		view.Boards = append(view.Boards, boardView)
 	}
 
+	view.Journals = JournalsOnCollective(i, collective)
+
 	events := i.EventsOnCollective(collective)
 	for _, event := range events {
 		eventView := EventOnCollectiveView{
@@ -0,0 +1,344 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/index"
+	"github.com/freehandle/synergy/social/state"
+)
+
+type JournalsView struct {
+	Name           string
+	Description    string
+	Collective     string
+	CollectiveLink string
+	Link           string
+	Keywords       []string
+	Issues         int
+	ServerName     string
+}
+
+type JournalsListView struct {
+	Journals   []JournalsView
+	Head       HeaderInfo
+	ServerName string
+}
+
+func JournalsFromState(s *state.State) JournalsListView {
+	head := HeaderInfo{
+		Active:  "Journals",
+		Path:    "explore / ",
+		EndPath: "periódicos",
+		Section: "explore",
+	}
+	view := JournalsListView{
+		Head:     head,
+		Journals: make([]JournalsView, 0),
+	}
+	for _, journal := range s.Journals {
+		itemView := JournalsView{
+			Name:           journal.Name,
+			Description:    journal.Description,
+			Collective:     journal.Collective.Name,
+			CollectiveLink: url.QueryEscape(journal.Collective.Name),
+			Link:           url.QueryEscape(journal.Name),
+			Keywords:       journal.Keywords,
+			Issues:         len(journal.Issues),
+		}
+		view.Journals = append(view.Journals, itemView)
+	}
+	sort.Slice(view.Journals, func(n, m int) bool { return view.Journals[n].Name < view.Journals[m].Name })
+	return view
+}
+
+type IssuesView struct {
+	Number int
+	Title  string
+	Date   string
+	Hash   string
+	Drafts int
+}
+
+type JournalDetailView struct {
+	Name             string
+	Link             string
+	Description      string
+	Collective       string
+	CollectiveLink   string
+	Keywords         []string
+	Editors          []MemberDetailView
+	Issues           []IssuesView
+	Candidates       []DraftsView // lancamentos com selo ainda nao publicados no periodico
+	Editorship       bool
+	CollectiveMember bool
+	Reasons          string
+	Author           string
+	Hash             string
+	Head             HeaderInfo
+	Voting           DetailedVoteView
+	ServerName       string
+}
+
+func JournalDetailFromState(s *state.State, name string, token crypto.Token) *JournalDetailView {
+	journalName, _ := url.QueryUnescape(name)
+	journal, ok := s.Journal(journalName)
+	if !ok {
+		return nil
+	}
+	view := JournalDetailView{
+		Name:             journal.Name,
+		Link:             url.QueryEscape(journal.Name),
+		Description:      journal.Description,
+		Collective:       journal.Collective.Name,
+		CollectiveLink:   url.QueryEscape(journal.Collective.Name),
+		Keywords:         journal.Keywords,
+		Editors:          make([]MemberDetailView, 0),
+		Issues:           make([]IssuesView, 0),
+		Candidates:       make([]DraftsView, 0),
+		Editorship:       journal.Editors.IsMember(token),
+		CollectiveMember: journal.Collective.IsMember(token),
+		Hash:             crypto.EncodeHash(journal.Hash),
+	}
+	if view.Editorship {
+		view.Head = HeaderInfo{
+			Active:  "Connections",
+			Path:    "realize / conexões / periódicos / ",
+			EndPath: LimitStringSize(journal.Name, maxStringSize),
+			Section: "realize",
+		}
+	} else {
+		view.Head = HeaderInfo{
+			Active:  "Journals",
+			Path:    "explore / periódicos / ",
+			EndPath: LimitStringSize(journal.Name, maxStringSize),
+			Section: "explore",
+		}
+	}
+	for token := range journal.Editors.Members {
+		if handle, ok := s.Members[crypto.HashToken(token)]; ok {
+			view.Editors = append(view.Editors, MemberDetailView{Handle: handle, Link: url.QueryEscape(handle)})
+		}
+	}
+	// numeros mais recentes primeiro
+	for n := len(journal.Issues) - 1; n >= 0; n-- {
+		issue := journal.Issues[n]
+		view.Issues = append(view.Issues, IssuesView{
+			Number: issue.Number,
+			Title:  issue.Title,
+			Date:   PrettyDate(s.TimeOfEpoch(issue.Epoch)),
+			Hash:   crypto.EncodeHash(issue.Hash),
+			Drafts: len(issue.Drafts),
+		})
+	}
+	if view.Editorship {
+		view.Candidates = issueCandidates(s, journal)
+	}
+	return &view
+}
+
+// issueCandidates lists the released drafts with an imprinted stamp not yet
+// published by the journal, most recent releases first.
+func issueCandidates(s *state.State, journal *state.Journal) []DraftsView {
+	releases := make([]*state.Release, 0)
+	for _, release := range s.Releases {
+		if !release.Released || journal.Published(release.Draft) {
+			continue
+		}
+		for _, stamp := range release.Stamps {
+			if stamp.Imprinted {
+				releases = append(releases, release)
+				break
+			}
+		}
+	}
+	sort.Slice(releases, func(n, m int) bool { return releases[n].Epoch > releases[m].Epoch })
+	candidates := make([]DraftsView, 0, len(releases))
+	for _, release := range releases {
+		draft := release.Draft
+		candidates = append(candidates, DraftsView{
+			Title:       draft.Title,
+			Authors:     make([]AuthorDetail, 0),
+			Hash:        crypto.EncodeHash(draft.DraftHash),
+			Description: draft.Description,
+			Keywords:    draft.Keywords,
+		})
+	}
+	return candidates
+}
+
+func PendingJournalFromState(s *state.State, hash crypto.Hash) *JournalDetailView {
+	pending, ok := s.Proposals.CreateJournal[hash]
+	if !ok {
+		return nil
+	}
+	journal := pending.Journal
+	head := HeaderInfo{
+		Active:  "Connections",
+		Path:    "realize / conexões / coletivos / " + LimitStringSize(journal.Collective.Name, maxStringSize) + " / ",
+		EndPath: "criar periódico " + LimitStringSize(journal.Name, maxStringSize),
+		Section: "realize",
+	}
+	view := JournalDetailView{
+		Name:           journal.Name,
+		Link:           url.QueryEscape(journal.Name),
+		Description:    journal.Description,
+		Collective:     journal.Collective.Name,
+		CollectiveLink: url.QueryEscape(journal.Collective.Name),
+		Keywords:       journal.Keywords,
+		Editors:        make([]MemberDetailView, 0),
+		Issues:         make([]IssuesView, 0),
+		Candidates:     make([]DraftsView, 0),
+		Reasons:        pending.Origin.Reasons,
+		Author:         s.Members[crypto.HashToken(pending.Origin.Author)],
+		Hash:           crypto.EncodeHash(hash),
+		Head:           head,
+		Voting:         NewDetailedVoteView(pending.Votes, journal.Collective, s),
+	}
+	view.Editors = append(view.Editors, MemberDetailView{Handle: view.Author, Link: url.QueryEscape(view.Author)})
+	return &view
+}
+
+type IssueEntryView struct {
+	Title       string
+	Hash        string
+	Authors     string
+	Description string
+	Keywords    []string
+	Released    string
+}
+
+type IssueDetailView struct {
+	Journal        string
+	JournalLink    string
+	Collective     string
+	CollectiveLink string
+	Number         int
+	Title          string
+	Description    string
+	Date           string
+	Contents       []IssueEntryView // sumario
+	Previous       string           // hash do numero anterior
+	Next           string           // hash do numero seguinte
+	Reasons        string
+	Author         string
+	Hash           string
+	Head           HeaderInfo
+	Voting         DetailedVoteView
+	ServerName     string
+}
+
+func issueContents(s *state.State, issue *state.Issue) []IssueEntryView {
+	contents := make([]IssueEntryView, 0, len(issue.Drafts))
+	for _, draft := range issue.Drafts {
+		entry := IssueEntryView{
+			Title:       draft.Title,
+			Hash:        crypto.EncodeHash(draft.DraftHash),
+			Authors:     authorsEtAll(draft.Authors, s),
+			Description: draft.Description,
+			Keywords:    draft.Keywords,
+		}
+		if release, ok := s.Releases[draft.DraftHash]; ok {
+			entry.Released = PrettyDate(s.TimeOfEpoch(release.Epoch))
+		}
+		contents = append(contents, entry)
+	}
+	return contents
+}
+
+func IssueDetailFromState(s *state.State, hash crypto.Hash) *IssueDetailView {
+	issue, ok := s.Issues[hash]
+	if !ok {
+		return nil
+	}
+	journal := issue.Journal
+	view := IssueDetailView{
+		Journal:        journal.Name,
+		JournalLink:    url.QueryEscape(journal.Name),
+		Collective:     journal.Collective.Name,
+		CollectiveLink: url.QueryEscape(journal.Collective.Name),
+		Number:         issue.Number,
+		Title:          issue.Title,
+		Description:    issue.Description,
+		Date:           PrettyDate(s.TimeOfEpoch(issue.Epoch)),
+		Contents:       issueContents(s, issue),
+		Hash:           crypto.EncodeHash(issue.Hash),
+		Head: HeaderInfo{
+			Active:  "Journals",
+			Path:    "explore / periódicos / " + LimitStringSize(journal.Name, maxStringSize) + " / ",
+			EndPath: fmt.Sprintf("número %v", issue.Number),
+			Section: "explore",
+		},
+	}
+	if previous, ok := journal.Issue(issue.Number - 1); ok {
+		view.Previous = crypto.EncodeHash(previous.Hash)
+	}
+	if next, ok := journal.Issue(issue.Number + 1); ok {
+		view.Next = crypto.EncodeHash(next.Hash)
+	}
+	return &view
+}
+
+// IssueOfJournal finds the issue by journal name and number.
+func IssueOfJournal(s *state.State, name string, number int) *IssueDetailView {
+	journal, ok := s.Journal(name)
+	if !ok {
+		return nil
+	}
+	issue, ok := journal.Issue(number)
+	if !ok {
+		return nil
+	}
+	return IssueDetailFromState(s, issue.Hash)
+}
+
+func PendingIssueFromState(s *state.State, hash crypto.Hash) *IssueDetailView {
+	pending, ok := s.Proposals.JournalIssue[hash]
+	if !ok {
+		return nil
+	}
+	issue := pending.Issue
+	journal := issue.Journal
+	view := IssueDetailView{
+		Journal:        journal.Name,
+		JournalLink:    url.QueryEscape(journal.Name),
+		Collective:     journal.Collective.Name,
+		CollectiveLink: url.QueryEscape(journal.Collective.Name),
+		Number:         len(journal.Issues) + 1,
+		Title:          issue.Title,
+		Description:    issue.Description,
+		Contents:       issueContents(s, issue),
+		Reasons:        pending.Origin.Reasons,
+		Author:         s.Members[crypto.HashToken(pending.Origin.Author)],
+		Hash:           crypto.EncodeHash(hash),
+		Head: HeaderInfo{
+			Active:  "Connections",
+			Path:    "realize / conexões / periódicos / " + LimitStringSize(journal.Name, maxStringSize) + " / ",
+			EndPath: "novo número",
+			Section: "realize",
+		},
+		Voting: NewDetailedVoteView(pending.Votes, journal.Collective, s),
+	}
+	return &view
+}
+
+type JournalOnCollectiveView struct {
+	Journal     CaptionLink
+	Description string
+	Issues      int
+	ServerName  string
+}
+
+func JournalsOnCollective(i *index.Index, collective *state.Collective) []JournalOnCollectiveView {
+	views := make([]JournalOnCollectiveView, 0)
+	for _, journal := range i.JournalsOnCollective(collective) {
+		views = append(views, JournalOnCollectiveView{
+			Journal:     CaptionLink{Caption: journal.Name, Link: url.QueryEscape(journal.Name)},
+			Description: journal.Description,
+			Issues:      len(journal.Issues),
+		})
+	}
+	return views
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"time"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+	"github.com/freehandle/synergy/social/state"
+)
+
+var actionKindNames = map[byte]string{
+	actions.AVote:               "voto",
+	actions.ACreateCollective:   "criar coletivo",
+	actions.AUpdateCollective:   "atualizar coletivo",
+	actions.ARequestMembership:  "pedido de participação",
+	actions.ARemoveMember:       "remover membro",
+	actions.ADraft:              "esboço",
+	actions.AEdit:               "edição",
+	actions.AMultipartMedia:     "mídia",
+	actions.ACreateBoard:        "criar mural",
+	actions.AUpdateBoard:        "atualizar mural",
+	actions.APin:                "afixar",
+	actions.ABoardEditor:        "editor de mural",
+	actions.AReleaseDraft:       "publicar esboço",
+	actions.AImprintStamp:       "selo",
+	actions.AReact:              "reação",
+	actions.ASignIn:             "inscrição",
+	actions.ACreateEvent:        "criar evento",
+	actions.ACancelEvent:        "cancelar evento",
+	actions.AUpdateEvent:        "atualizar evento",
+	actions.ACheckinEvent:       "check-in",
+	actions.AGreetCheckinEvent:  "boas-vindas",
+	actions.ADelegate:           "delegação",
+	actions.AComment:            "comentário",
+	actions.ACreateJournal:      "criar periódico",
+	actions.AJournalEditor:      "editor de periódico",
+	actions.AJournalIssue:       "número de periódico",
+	actions.ACancelCheckinEvent: "cancelar check-in",
+}
+
+var submissionStatusNames = map[byte]string{
+	Submitted: "enviada",
+	Included:  "incluída",
+	Rejected:  "rejeitada",
+	Expired:   "expirada",
+}
+
+type SubmissionView struct {
+	ID          uint64
+	Kind        string
+	Hash        string
+	Status      string
+	Final       bool // incluida, rejeitada ou expirada
+	Included    bool
+	Expired     bool   // nao incluida no prazo, deve ser enviada de novo
+	Reason      string // motivo da rejeicao
+	SubmittedAt string
+	UpdatedAt   string
+}
+
+type MyActionsView struct {
+	Actions    []SubmissionView
+	Pending    int
+	Head       HeaderInfo
+	ServerName string
+}
+
+// MyActionsFromMempool lists the receipts of the actions of the member, most
+// recent first.
+func MyActionsFromMempool(s *state.State, m *Mempool, token crypto.Token) *MyActionsView {
+	head := HeaderInfo{
+		Active:  "MyActions",
+		Path:    "realize / ",
+		EndPath: "minhas ações",
+		Section: "realize",
+	}
+	view := &MyActionsView{
+		Actions: make([]SubmissionView, 0),
+		Head:    head,
+	}
+	receipts := m.Receipts(token)
+	for n := len(receipts) - 1; n >= 0; n-- {
+		receipt := receipts[n]
+		entry := SubmissionView{
+			ID:          receipt.ID,
+			Kind:        actionKindNames[receipt.Kind],
+			Hash:        crypto.EncodeHash(receipt.Hash),
+			Status:      submissionStatusNames[receipt.Status],
+			Final:       receipt.Status != Submitted,
+			Included:    receipt.Status == Included,
+			Reason:      receipt.Reason,
+			Expired:     receipt.Status == Expired,
+			SubmittedAt: PrettyDuration(time.Since(s.TimeOfEpoch(receipt.Epoch))),
+			UpdatedAt:   PrettyDuration(time.Since(s.TimeOfEpoch(receipt.Updated))),
+		}
+		if !entry.Final {
+			view.Pending++
+		}
+		view.Actions = append(view.Actions, entry)
+	}
+	return view
+}
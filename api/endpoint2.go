@@ -1,11 +1,14 @@
 package api
 
 import (
+	"fmt"
 	"net/url"
+	"sort"
 	"time"
 
 	"github.com/freehandle/breeze/crypto"
 	"github.com/freehandle/breeze/crypto/dh"
+	"github.com/freehandle/synergy/social/actions"
 	"github.com/freehandle/synergy/social/index"
 	"github.com/freehandle/synergy/social/state"
 )
@@ -18,6 +21,8 @@ type EventsView struct {
 	Collective  NameLink
 	Public      bool
 	ServerName  string
+	Occurrence  uint64 // numero da ocorrencia em eventos recorrentes (0 se unico)
+	Occurrences uint64
 }
 
 type EventVoteAction struct {
@@ -45,6 +50,7 @@ type VoteUpdateEventView struct {
 	OldOpen         string
 	Public          string
 	OldPublic       string
+	Occurrence      uint64 // 0 altera a serie
 	Hash            string
 	Reasons         string
 	Collective      string
@@ -102,10 +108,17 @@ func EventUpdateFromState(s *state.State, hash crypto.Hash, token crypto.Token)
 		vote.StartAt = update.StartAt.String()
 	}
 	if update.EstimatedEnd != nil {
-		vote.StartAt = update.StartAt.String()
+		vote.EstimatedEnd = update.EstimatedEnd.String()
 	}
 	if update.Venue != nil {
-		vote.StartAt = *update.Venue
+		vote.Venue = *update.Venue
+	}
+	if occurrence, ok := old.Occurrence(update.Occurrence); ok && update.Occurrence > 0 {
+		vote.Occurrence = update.Occurrence
+		vote.OldStartAt = occurrence.StartAt.String()
+		vote.OldEstimatedEnd = occurrence.EstimatedEnd.String()
+		vote.OldVenue = occurrence.Venue
+		vote.OldDescription = occurrence.Description
 	}
 	if old.Managers.IsMember(token) {
 		vote.Managing = true
@@ -148,6 +161,89 @@ type EventDetailView struct {
 	Head               HeaderInfo
 	EventReasons       string
 	ServerName         string
+	Comments           CommentThread
+	Capacity           uint64 // 0 sem limite
+	Vacancies          int
+	Full               bool
+	RSVPClose          string // data limite para check-in, vazio se nao houver
+	RSVPClosed         bool
+	CheckedIn          bool               // check-in do usuario aguardando boas-vindas
+	WaitlistPosition   int                // posicao do usuario na lista de espera (0 se nao esta)
+	Waitlist           []MemberDetailView // visivel aos organizadores
+	Recurrence         string             // descricao da recorrencia, vazio se unico
+	Occurrences        []OccurrenceView
+	Occurrence         uint64 // ocorrencia objeto do cancelamento (0 a serie)
+}
+
+type OccurrenceView struct {
+	Number       uint64
+	StartAt      string
+	EstimatedEnd string
+	Description  string
+	Venue        string
+	Cancelled    bool
+	Overridden   bool
+}
+
+var recurrenceNames = map[byte][2]string{
+	actions.DailyRecurrence:   {"diariamente", "dias"},
+	actions.WeeklyRecurrence:  {"semanalmente", "semanas"},
+	actions.MonthlyRecurrence: {"mensalmente", "meses"},
+}
+
+// RecurrenceCaption describes the recurrence rule in portuguese.
+func RecurrenceCaption(r actions.Recurrence) string {
+	if !r.Recurring() {
+		return ""
+	}
+	names := recurrenceNames[r.Frequency]
+	if r.Interval > 1 {
+		return fmt.Sprintf("a cada %v %v, %v vezes", r.Interval, names[1], r.Count)
+	}
+	return fmt.Sprintf("%v, %v vezes", names[0], r.Count)
+}
+
+// eventOccurrences lists the occurrences of a recurring event.
+func eventOccurrences(event *state.Event, view *EventDetailView) {
+	view.Recurrence = RecurrenceCaption(event.Recurrence)
+	view.Occurrences = make([]OccurrenceView, 0)
+	if !event.Recurrence.Recurring() {
+		return
+	}
+	for _, occurrence := range event.Occurrences() {
+		view.Occurrences = append(view.Occurrences, OccurrenceView{
+			Number:       occurrence.Number,
+			StartAt:      occurrence.StartAt.Format(time.RFC822),
+			EstimatedEnd: occurrence.EstimatedEnd.Format(time.RFC822),
+			Description:  occurrence.Description,
+			Venue:        occurrence.Venue,
+			Cancelled:    occurrence.Cancelled,
+			Overridden:   occurrence.Overridden,
+		})
+	}
+}
+
+// eventCapacity fills the capacity, waitlist and rsvp deadline of the view.
+func eventCapacity(s *state.State, event *state.Event, token crypto.Token, view *EventDetailView) {
+	view.Capacity = event.Capacity
+	view.Full = event.Full()
+	if event.Capacity > 0 && !view.Full {
+		view.Vacancies = int(event.Capacity) - len(event.Checkin)
+	}
+	if event.RSVPClose > 0 {
+		view.RSVPClose = s.TimeOfEpoch(event.RSVPClose).Format(time.RFC822)
+		view.RSVPClosed = event.RSVPClosed(s.Epoch)
+	}
+	if greeting, ok := event.Checkin[token]; ok && greeting.Action == nil {
+		view.CheckedIn = true
+	}
+	view.WaitlistPosition = event.WaitlistPosition(token)
+	view.Waitlist = make([]MemberDetailView, 0)
+	for _, waiting := range event.Waitlist {
+		if handle, ok := s.Members[crypto.HashToken(waiting.Token)]; ok {
+			view.Waitlist = append(view.Waitlist, MemberDetailView{Handle: handle, Link: url.QueryEscape(handle)})
+		}
+	}
 }
 
 func PendingEventFromState(s *state.State, i *index.Index, hash crypto.Hash) *EventDetailView {
@@ -179,6 +275,7 @@ func PendingEventFromState(s *state.State, i *index.Index, hash crypto.Hash) *Ev
 		Votes:           NewDetailedVoteView(event.Votes, event.Collective, s),
 		Hash:            crypto.EncodeHash(hash),
 		EventReasons:    event.EventReasons,
+		Recurrence:      RecurrenceCaption(event.Recurrence),
 	}
 	return &view
 }
@@ -211,6 +308,13 @@ func CancelEventFromState(s *state.State, i *index.Index, hash crypto.Hash) *Eve
 		Votes:           NewDetailedVoteView(cancel.Votes, event.Collective, s),
 		Hash:            crypto.EncodeHash(hash),
 		EventReasons:    cancel.Reasons,
+		Recurrence:      RecurrenceCaption(event.Recurrence),
+	}
+	if occurrence, ok := event.Occurrence(cancel.Occurrence); ok && cancel.Occurrence > 0 {
+		view.Occurrence = occurrence.Number
+		view.StartAt = occurrence.StartAt
+		view.EstimatedEnd = occurrence.EstimatedEnd
+		view.Venue = occurrence.Venue
 	}
 	return &view
 }
@@ -227,17 +331,35 @@ func EventsFromState(state *state.State) EventsListView {
 		Events: make([]EventsView, 0),
 	}
 	for _, event := range state.Events {
-		itemView := EventsView{
-			Hash: crypto.EncodeHash(event.Hash),
-			Live: event.Live,
-
-			Description: event.Description,
-			StartAt:     event.StartAt,
-			Collective:  NameLinker(event.Collective.Name),
-			Public:      event.Public,
+		if !event.Recurrence.Recurring() {
+			itemView := EventsView{
+				Hash: crypto.EncodeHash(event.Hash),
+				Live: event.Live,
+
+				Description: event.Description,
+				StartAt:     event.StartAt,
+				Collective:  NameLinker(event.Collective.Name),
+				Public:      event.Public,
+			}
+			view.Events = append(view.Events, itemView)
+			continue
+		}
+		// uma entrada por ocorrencia da serie
+		for _, occurrence := range event.Occurrences() {
+			itemView := EventsView{
+				Hash:        crypto.EncodeHash(event.Hash),
+				Live:        event.Live && !occurrence.Cancelled,
+				Description: occurrence.Description,
+				StartAt:     occurrence.StartAt,
+				Collective:  NameLinker(event.Collective.Name),
+				Public:      event.Public,
+				Occurrence:  occurrence.Number,
+				Occurrences: event.Recurrence.Occurrences(),
+			}
+			view.Events = append(view.Events, itemView)
 		}
-		view.Events = append(view.Events, itemView)
 	}
+	sort.Slice(view.Events, func(n, m int) bool { return view.Events[n].StartAt.Before(view.Events[m].StartAt) })
 	return view
 }
 
@@ -270,6 +392,7 @@ func EventDetailFromState(s *state.State, i *index.Index, hash crypto.Hash, toke
 		Managers:           make([]MemberDetailView, 0),
 		Managing:           event.Managers.IsMember(token),
 		Hash:               crypto.EncodeHash(hash),
+		Comments:           CommentsFromIndex(s, i, hash, "event/"+crypto.EncodeHash(hash)),
 	}
 	if view.Managing {
 		view.Head = HeaderInfo{
@@ -313,6 +436,8 @@ func EventDetailFromState(s *state.State, i *index.Index, hash crypto.Hash, toke
 			}
 		}
 	}
+	eventCapacity(s, event, token, &view)
+	eventOccurrences(event, &view)
 	return &view
 }
 
@@ -351,6 +476,8 @@ func EventUpdateDetailFromState(s *state.State, i *index.Index, hash crypto.Hash
 			view.Managers = append(view.Managers, MemberDetailView{Handle: handle, Link: url.QueryEscape(handle)})
 		}
 	}
+	eventCapacity(s, event, token, &view)
+	eventOccurrences(event, &view)
 	return &view
 }
 
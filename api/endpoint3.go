@@ -10,6 +10,8 @@ import (
 
 	"github.com/freehandle/breeze/crypto"
 	"github.com/freehandle/breeze/crypto/dh"
+	"github.com/freehandle/synergy/social/actions"
+	"github.com/freehandle/synergy/social/citation"
 	"github.com/freehandle/synergy/social/index"
 	"github.com/freehandle/synergy/social/state"
 )
@@ -211,6 +213,7 @@ type PendingActionDetailView struct {
 	VotesReject  int
 	VotesNeeded  int
 	VoteHash     string
+	ExpiresIn    string
 	ServerName   string
 }
 
@@ -218,6 +221,7 @@ type ConcludedActionDetailView struct {
 	Description string
 	ProposedAt  string
 	Approved    bool
+	Expired     bool
 	VoteHash    string
 	ServerName  string
 }
@@ -245,6 +249,10 @@ func PendingActionsFromState(s *state.State, i *index.Index, token crypto.Token,
 		if len(pending.Pool.Votes) > 0 {
 			item.VoteHash = crypto.EncodeHash(pending.Pool.Votes[0].Hash)
 		}
+		if pending.Deadline > 0 {
+			deadline := genesisTime.Add(time.Duration(pending.Deadline) * time.Second)
+			item.ExpiresIn = PrettyDuration(time.Until(deadline))
+		}
 		for _, vote := range pending.Pool.Votes {
 			if _, ok := pending.Pool.Voters[vote.Author]; ok {
 				if vote.Approve {
@@ -262,6 +270,9 @@ func PendingActionsFromState(s *state.State, i *index.Index, token crypto.Token,
 		var epoch uint64
 		if concluded.Approved {
 			description, epoch, _ = i.ActionToStringWithLinks(concluded.Action, state.Favorable)
+		} else if concluded.Expired {
+			// proposta expirada e descrita como foi proposta
+			description, epoch, _ = i.ActionToStringWithLinks(concluded.Action, state.Undecided)
 		} else {
 			description, epoch, _ = i.ActionToStringWithLinks(concluded.Action, state.Against)
 		}
@@ -274,6 +285,7 @@ func PendingActionsFromState(s *state.State, i *index.Index, token crypto.Token,
 			Description: description,
 			ProposedAt:  PrettyDuration(time.Since(proposed)),
 			Approved:    concluded.Approved,
+			Expired:     concluded.Expired,
 			VoteHash:    string(hashText),
 		}
 		view.Concluded = append(view.Concluded, item)
@@ -529,6 +541,9 @@ type MyEventView struct {
 	ServerName           string
 	Managing             bool
 	OriginalHash         crypto.Hash
+	Capacity             uint64
+	WaitlistPosition     int // posicao do membro na lista de espera
+	WaitlistCount        int
 }
 
 type MyEventsView struct {
@@ -537,6 +552,7 @@ type MyEventsView struct {
 	FurtherCount  int
 	Events        []MyEventView
 	Managed       []MyEventView
+	CalendarLink  string // assinatura do calendario pessoal
 	Head          HeaderInfo
 	ServerName    string
 }
@@ -562,9 +578,16 @@ func MyEventsFromState(s *state.State, i *index.Index, token crypto.Token, ephem
 		if _, ok := hashes[hash]; !ok {
 			if event, ok := s.Events[hash]; ok {
 				events = append(events, event)
+				hashes[hash] = struct{}{}
 			}
 		}
 	}
+	// eventos lotados em que o membro aguarda na lista de espera
+	for hash, event := range s.Events {
+		if _, ok := hashes[hash]; !ok && event.Live && event.WaitlistPosition(token) > 0 {
+			events = append(events, event)
+		}
+	}
 	for _, event := range events {
 		if time.Until(event.StartAt) < -12*time.Hour {
 			continue
@@ -586,7 +609,10 @@ func MyEventsFromState(s *state.State, i *index.Index, token crypto.Token, ephem
 			Hash:          crypto.EncodeHash(event.Hash),
 			OriginalHash:  event.Hash,
 			AttendeeCount: len(event.Checkin),
+			Capacity:      event.Capacity,
+			WaitlistCount: len(event.Waitlist),
 		}
+		eventView.WaitlistPosition = event.WaitlistPosition(token)
 		eventView.Managing = slices.Contains(managed, event.Hash)
 		if greeting, ok := event.Checkin[token]; ok {
 			if greeting.Action != nil {
@@ -630,16 +656,27 @@ func MyEventsFromState(s *state.State, i *index.Index, token crypto.Token, ephem
 
 type DetailedVote struct {
 	Author     CaptionLink
+	OnBehalfOf []CaptionLink // membros que delegaram o voto ao autor
 	Approve    bool
 	Reasons    string
 	ServerName string
 }
 
+type VoteHistoryView struct {
+	Author  CaptionLink
+	Choice  string
+	When    string
+	Reasons string
+}
+
 type DetailedPool struct {
 	Description string
 	Reasons     string
 	Approve     []DetailedVote
 	Reject      []DetailedVote
+	Abstain     []DetailedVote
+	History     []VoteHistoryView // todos os votos dados, inclusive alterados
+	Comments    CommentThread
 	Needed      int
 	NotVoted    []CaptionLink
 	Head        HeaderInfo
@@ -647,12 +684,15 @@ type DetailedPool struct {
 	ServerName  string
 	Concluded   bool
 	Approved    bool
+	Expired     bool
 }
 
 func DetailedVoteFromState(s *state.State, i *index.Index, hash crypto.Hash, genesisTime time.Time, urlpath string) *DetailedPool {
 	detailed := DetailedPool{
 		Approve:  make([]DetailedVote, 0),
 		Reject:   make([]DetailedVote, 0),
+		Abstain:  make([]DetailedVote, 0),
+		History:  make([]VoteHistoryView, 0),
 		NotVoted: make([]CaptionLink, 0),
 	}
 	detailed.Head = HeaderInfo{
@@ -670,6 +710,16 @@ func DetailedVoteFromState(s *state.State, i *index.Index, hash crypto.Hash, gen
 	detailed.Reasons = reasons
 	old := time.Since(genesisTime.Add(time.Duration(epoch) * time.Second))
 	detailed.ProposedAt = PrettyDuration(old)
+	detailed.Comments = CommentsFromIndex(s, i, hash, "detailedvote/"+crypto.EncodeHash(hash))
+	for _, vote := range i.VoteHistory(hash) {
+		author := s.Members[crypto.HashToken(vote.Author)]
+		detailed.History = append(detailed.History, VoteHistoryView{
+			Author:  CaptionLink{Caption: author, Link: url.QueryEscape(author)},
+			Choice:  voteChoice(vote),
+			When:    PrettyDuration(time.Since(genesisTime.Add(time.Duration(vote.Epoch) * time.Second))),
+			Reasons: vote.Reasons,
+		})
+	}
 
 	pool := s.Proposals.Pooling(hash)
 	if pool == nil {
@@ -680,6 +730,7 @@ func DetailedVoteFromState(s *state.State, i *index.Index, hash crypto.Hash, gen
 		}
 		detailed.Concluded = true
 		detailed.Approved = i.ActionStatusByHash(hash) == state.Favorable
+		detailed.Expired = i.ActionStatusByHash(hash) == state.Expired
 		for _, vote := range completedVotes {
 			author := s.Members[crypto.HashToken(vote.Author)]
 			voteDetailed := DetailedVote{
@@ -687,7 +738,9 @@ func DetailedVoteFromState(s *state.State, i *index.Index, hash crypto.Hash, gen
 				Approve: vote.Approve,
 				Reasons: vote.Reasons,
 			}
-			if vote.Approve {
+			if vote.Abstain {
+				detailed.Abstain = append(detailed.Abstain, voteDetailed)
+			} else if vote.Approve {
 				detailed.Approve = append(detailed.Approve, voteDetailed)
 			} else {
 				detailed.Reject = append(detailed.Reject, voteDetailed)
@@ -705,7 +758,16 @@ func DetailedVoteFromState(s *state.State, i *index.Index, hash crypto.Hash, gen
 				Approve: vote.Approve,
 				Reasons: vote.Reasons,
 			}
-			if vote.Approve {
+			for member, delegate := range pool.Delegated {
+				if delegate == vote.Author {
+					handle := s.Members[crypto.HashToken(member)]
+					voteDetailed.OnBehalfOf = append(voteDetailed.OnBehalfOf, CaptionLink{Caption: handle, Link: url.QueryEscape(handle)})
+					delete(pool.Voters, member)
+				}
+			}
+			if vote.Abstain {
+				detailed.Abstain = append(detailed.Abstain, voteDetailed)
+			} else if vote.Approve {
 				detailed.Approve = append(detailed.Approve, voteDetailed)
 			} else {
 				detailed.Reject = append(detailed.Reject, voteDetailed)
@@ -737,6 +799,8 @@ type MemberView struct {
 	Events      []CaptionLink
 	Drafts      []DraftFromMember
 	Edits       []CaptionLink
+	Reputation  ReputationView
+	Citations   citation.Metrics
 	ServerName  string
 }
 
@@ -754,6 +818,8 @@ func MemberViewFromState(s *state.State, i *index.Index, handle string) *MemberV
 	if !ok {
 		return &view
 	}
+	view.Reputation = MemberReputation(s, i, token, s.GenesisTime)
+	view.Citations = MemberCitations(i, token)
 	personal := i.Personal(token)
 	for _, collective := range personal.Collectives {
 		view.Collectives = append(view.Collectives, CaptionLink{Caption: collective, Link: url.QueryEscape(collective)})
@@ -802,3 +868,51 @@ func MemberViewFromState(s *state.State, i *index.Index, handle string) *MemberV
 	}
 	return &view
 }
+
+// CommentView is a comment on a thread. Replies are listed right after the
+// comment they answer, with Depth one level deeper.
+type CommentView struct {
+	Hash       string
+	Author     CaptionLink
+	OnBehalfOf string
+	Body       string
+	When       string
+	Depth      int
+}
+
+type CommentThread struct {
+	Target     string
+	Redirect   string // pagina para onde voltar depois de comentar
+	Comments   []CommentView
+	ServerName string
+}
+
+func CommentsFromIndex(s *state.State, i *index.Index, hash crypto.Hash, redirect string) CommentThread {
+	thread := CommentThread{
+		Target:   crypto.EncodeHash(hash),
+		Redirect: redirect,
+		Comments: make([]CommentView, 0),
+	}
+	replies := make(map[crypto.Hash][]*actions.Comment)
+	for _, comment := range i.CommentsOn(hash) {
+		replies[comment.ReplyTo] = append(replies[comment.ReplyTo], comment)
+	}
+	var walk func(parent crypto.Hash, depth int)
+	walk = func(parent crypto.Hash, depth int) {
+		for _, comment := range replies[parent] {
+			author := s.Members[crypto.HashToken(comment.Author)]
+			commentHash := comment.Hashed()
+			thread.Comments = append(thread.Comments, CommentView{
+				Hash:       crypto.EncodeHash(commentHash),
+				Author:     CaptionLink{Caption: author, Link: url.QueryEscape(author)},
+				OnBehalfOf: comment.OnBehalfOf,
+				Body:       commentToHTML([]byte(comment.Body)),
+				When:       PrettyDuration(time.Since(s.TimeOfEpoch(comment.Epoch))),
+				Depth:      depth,
+			})
+			walk(commentHash, depth+1)
+		}
+	}
+	walk(crypto.ZeroHash, 0)
+	return thread
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/index"
+	"github.com/freehandle/synergy/social/search"
+)
+
+// maximum number of results returned by a search
+const maxSearchResults = 100
+
+var searchKindNames = map[search.Kind]string{
+	search.DraftDocument:      "esboço",
+	search.EditDocument:       "edição",
+	search.BoardDocument:      "mural",
+	search.CollectiveDocument: "coletivo",
+	search.EventDocument:      "evento",
+}
+
+type SearchResultView struct {
+	Kind        string
+	KindName    string
+	Title       string
+	Link        string
+	Hash        string
+	Description string
+	Authors     []AuthorDetail
+	Collective  string
+	Date        string
+	Score       float64
+}
+
+type SearchView struct {
+	Query      string
+	Results    []SearchResultView
+	Total      int
+	Head       HeaderInfo
+	ServerName string
+}
+
+func searchLink(doc *search.Document) string {
+	hash := crypto.EncodeHash(doc.Hash)
+	switch doc.Kind {
+	case search.DraftDocument:
+		return fmt.Sprintf("/draft/%v", hash)
+	case search.EditDocument:
+		return fmt.Sprintf("/editview/%v", hash)
+	case search.BoardDocument:
+		return fmt.Sprintf("/board/%v", url.QueryEscape(doc.Title))
+	case search.CollectiveDocument:
+		return fmt.Sprintf("/collective/%v", url.QueryEscape(doc.Title))
+	case search.EventDocument:
+		return fmt.Sprintf("/event/%v", hash)
+	}
+	return ""
+}
+
+func SearchFromIndex(i *index.Index, query string, genesis time.Time) SearchView {
+	view := SearchView{
+		Query:   query,
+		Results: make([]SearchResultView, 0),
+		Head: HeaderInfo{
+			Active:  "Search",
+			Path:    "explore / ",
+			EndPath: "busca",
+			Section: "explore",
+		},
+	}
+	results := i.Search(query)
+	view.Total = len(results)
+	if len(results) > maxSearchResults {
+		results = results[:maxSearchResults]
+	}
+	for _, result := range results {
+		doc := result.Document
+		item := SearchResultView{
+			Kind:        doc.Kind.String(),
+			KindName:    searchKindNames[doc.Kind],
+			Title:       doc.Title,
+			Link:        searchLink(doc),
+			Hash:        crypto.EncodeHash(doc.Hash),
+			Description: LimitStringSize(doc.Body, 3*maxStringSize),
+			Authors:     make([]AuthorDetail, 0),
+			Collective:  doc.Collective,
+			Score:       result.Score,
+		}
+		if doc.Date > 0 {
+			item.Date = PrettyDate(genesis.Add(time.Duration(doc.Date) * time.Second))
+		}
+		if doc.Kind != search.CollectiveDocument {
+			for _, handle := range doc.Authors {
+				item.Authors = append(item.Authors, AuthorDetail{Name: handle, Link: url.QueryEscape(handle)})
+			}
+		}
+		view.Results = append(view.Results, item)
+	}
+	return view
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/diff"
+	"github.com/freehandle/synergy/social/state"
+)
+
+// lines of unchanged context around each hunk
+const diffContext = 3
+
+var (
+	ErrDiffVersionNotFound = errors.New("version not found")
+	ErrDiffNotText         = errors.New("only txt and md versions can be compared")
+	ErrDiffOtherChain      = errors.New("versions are not of the same draft")
+)
+
+type DiffVersionView struct {
+	Kind  string // draft ou edit
+	Title string
+	Hash  string
+	Link  string
+	Date  string
+}
+
+type DiffHunkView struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []diff.Line
+	Pairs    []diff.Pair `json:"-"` // lado a lado, apenas para o html
+}
+
+type DiffView struct {
+	From       DiffVersionView
+	To         DiffVersionView
+	Mode       string // inline ou split
+	Inserted   int
+	Deleted    int
+	Hunks      []DiffHunkView
+	Versions   []DiffVersionView // versoes da cadeia para escolher a comparacao
+	Head       HeaderInfo
+	ServerName string
+}
+
+// a version on a draft chain: a draft (released or pending) or an edit
+type diffVersion struct {
+	view     DiffVersionView
+	hash     crypto.Hash
+	date     uint64
+	root     *state.Draft
+	textType bool
+	patchOf  crypto.Hash // draft sobre o qual o patch se aplica (edits do tipo patch)
+}
+
+func isTextType(kind string) bool {
+	return kind == "txt" || kind == "md"
+}
+
+func draftChainRoot(draft *state.Draft) *state.Draft {
+	for draft.PreviousVersion != nil {
+		draft = draft.PreviousVersion
+	}
+	return draft
+}
+
+func draftVersion(draft *state.Draft, genesis time.Time) diffVersion {
+	hash := crypto.EncodeHash(draft.DraftHash)
+	return diffVersion{
+		view: DiffVersionView{
+			Kind:  "draft",
+			Title: draft.Title,
+			Hash:  hash,
+			Link:  fmt.Sprintf("/draft/%v", hash),
+			Date:  PrettyDate(genesis.Add(time.Duration(draft.Date) * time.Second)),
+		},
+		hash:     draft.DraftHash,
+		date:     draft.Date,
+		root:     draftChainRoot(draft),
+		textType: isTextType(draft.DraftType),
+	}
+}
+
+func editVersion(edit *state.Edit, genesis time.Time) diffVersion {
+	hash := crypto.EncodeHash(edit.Edit)
+	version := diffVersion{
+		view: DiffVersionView{
+			Kind:  "edit",
+			Title: "edição de " + edit.Draft.Title,
+			Hash:  hash,
+			Link:  fmt.Sprintf("/editview/%v", hash),
+			Date:  PrettyDate(genesis.Add(time.Duration(edit.Date) * time.Second)),
+		},
+		hash:     edit.Edit,
+		date:     edit.Date,
+		root:     draftChainRoot(edit.Draft),
+		textType: isTextType(edit.EditType) || edit.EditType == diff.PatchType,
+	}
+	if edit.EditType == diff.PatchType {
+		version.patchOf = edit.Draft.DraftHash
+	}
+	return version
+}
+
+// content of a version. Patch edits are shown as applied on their draft.
+func versionContent(s *state.State, version diffVersion) ([]byte, error) {
+	content, ok := s.GetMedia(version.hash)
+	if !ok {
+		return nil, ErrDiffVersionNotFound
+	}
+	if version.patchOf == crypto.ZeroHash {
+		return content, nil
+	}
+	base, ok := s.GetMedia(version.patchOf)
+	if !ok {
+		return nil, ErrDiffVersionNotFound
+	}
+	hunks, err := diff.Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+	applied, err := diff.Apply(diff.Split(string(base)), hunks)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(diff.Join(applied)), nil
+}
+
+func diffVersionFromState(s *state.State, hash crypto.Hash, genesis time.Time) (diffVersion, bool) {
+	if draft, ok := s.Drafts[hash]; ok {
+		return draftVersion(draft, genesis), true
+	}
+	if draft, ok := s.Proposals.Draft[hash]; ok {
+		return draftVersion(draft, genesis), true
+	}
+	if edit, ok := s.Edits[hash]; ok {
+		return editVersion(edit, genesis), true
+	}
+	if edit, ok := s.Proposals.Edit[hash]; ok {
+		return editVersion(edit, genesis), true
+	}
+	return diffVersion{}, false
+}
+
+// todas as versoes (drafts e edits) da cadeia que comeca em root
+func draftChainVersions(s *state.State, root *state.Draft, genesis time.Time) []DiffVersionView {
+	versions := make([]diffVersion, 0)
+	drafts := make([]*state.Draft, 0)
+	for _, draft := range s.Drafts {
+		drafts = append(drafts, draft)
+	}
+	for _, draft := range s.Proposals.Draft {
+		drafts = append(drafts, draft)
+	}
+	for _, draft := range drafts {
+		if draftChainRoot(draft) == root {
+			versions = append(versions, draftVersion(draft, genesis))
+		}
+	}
+	edits := make([]*state.Edit, 0)
+	for _, edit := range s.Edits {
+		edits = append(edits, edit)
+	}
+	for _, edit := range s.Proposals.Edit {
+		edits = append(edits, edit)
+	}
+	for _, edit := range edits {
+		if draftChainRoot(edit.Draft) == root {
+			versions = append(versions, editVersion(edit, genesis))
+		}
+	}
+	sort.Slice(versions, func(n, m int) bool { return versions[n].date < versions[m].date })
+	views := make([]DiffVersionView, 0, len(versions))
+	for _, version := range versions {
+		if version.textType {
+			views = append(views, version.view)
+		}
+	}
+	return views
+}
+
+// DiffFromState compares the content of two versions of the same draft chain
+// (any two drafts of the chain or a draft and an edit). Mode is either
+// "inline" or "split" (side by side).
+func DiffFromState(s *state.State, from, to crypto.Hash, mode string, genesis time.Time) (*DiffView, error) {
+	older, ok := diffVersionFromState(s, from, genesis)
+	if !ok {
+		return nil, ErrDiffVersionNotFound
+	}
+	newer, ok := diffVersionFromState(s, to, genesis)
+	if !ok {
+		return nil, ErrDiffVersionNotFound
+	}
+	if !older.textType || !newer.textType {
+		return nil, ErrDiffNotText
+	}
+	if older.root != newer.root {
+		return nil, ErrDiffOtherChain
+	}
+	oldContent, err := versionContent(s, older)
+	if err != nil {
+		return nil, err
+	}
+	newContent, err := versionContent(s, newer)
+	if err != nil {
+		return nil, err
+	}
+	if mode != "split" {
+		mode = "inline"
+	}
+	view := DiffView{
+		From:     older.view,
+		To:       newer.view,
+		Mode:     mode,
+		Versions: draftChainVersions(s, older.root, genesis),
+		Head: HeaderInfo{
+			Active:  "Drafts",
+			Path:    "explore / esboços / " + LimitStringSize(older.root.Title, maxStringSize) + " / ",
+			EndPath: "diferenças",
+			Section: "explore",
+		},
+	}
+	lines := diff.Text(string(oldContent), string(newContent))
+	view.Inserted, view.Deleted = diff.Stats(lines)
+	view.Hunks = diffHunks(lines, mode == "split")
+	return &view, nil
+}
+
+func diffHunks(lines []diff.Line, split bool) []DiffHunkView {
+	hunks := make([]DiffHunkView, 0)
+	for _, hunk := range diff.Hunks(lines, diffContext) {
+		hunkView := DiffHunkView{
+			OldStart: hunk.OldStart,
+			OldLines: hunk.OldLines,
+			NewStart: hunk.NewStart,
+			NewLines: hunk.NewLines,
+			Lines:    hunk.Lines,
+		}
+		if split {
+			hunkView.Pairs = diff.SideBySide(hunk.Lines)
+		}
+		hunks = append(hunks, hunkView)
+	}
+	return hunks
+}
@@ -0,0 +1,253 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/diff"
+	"github.com/freehandle/synergy/social/state"
+)
+
+var (
+	ErrMergeNoEdits     = errors.New("no patch edits to merge")
+	ErrMergeNotAuthor   = errors.New("only authors of the draft can publish a merged version")
+	ErrMergeDraftNotTxt = errors.New("only txt and md drafts can be merged")
+)
+
+type MergeEditView struct {
+	Hash     string
+	Authors  []AuthorDetail
+	Reasons  string
+	Date     string
+	Selected bool
+}
+
+type MergeConflictView struct {
+	Edit   string
+	With   string // edit aceito que alterou as mesmas linhas (vazio se nao aplica)
+	Lines  string
+	Reason string
+}
+
+type MergeView struct {
+	DraftTitle string
+	DraftHash  string
+	Edits      []MergeEditView
+	Conflicts  []MergeConflictView
+	Inserted   int
+	Deleted    int
+	Hunks      []DiffHunkView
+	Authorship bool
+	Head       HeaderInfo
+	ServerName string
+}
+
+// approved patch edits of a draft in the order they were proposed
+func patchEdits(draft *state.Draft) []*state.Edit {
+	edits := make([]*state.Edit, 0)
+	for _, edit := range draft.Edits {
+		if edit.EditType == diff.PatchType {
+			edits = append(edits, edit)
+		}
+	}
+	sort.SliceStable(edits, func(n, m int) bool { return edits[n].Date < edits[m].Date })
+	return edits
+}
+
+// selected patch edits (all of them if none is selected)
+func selectedEdits(edits []*state.Edit, selected []crypto.Hash) []*state.Edit {
+	if len(selected) == 0 {
+		return edits
+	}
+	chosen := make([]*state.Edit, 0)
+	for _, edit := range edits {
+		for _, hash := range selected {
+			if edit.Edit.Equal(hash) {
+				chosen = append(chosen, edit)
+				break
+			}
+		}
+	}
+	return chosen
+}
+
+// mergeEdits composes the patches of edits on the content of draft.
+func mergeEdits(s *state.State, draft *state.Draft, edits []*state.Edit) ([]string, []string, []diff.Conflict, error) {
+	if !isTextType(draft.DraftType) {
+		return nil, nil, nil, ErrMergeDraftNotTxt
+	}
+	content, ok := s.GetMedia(draft.DraftHash)
+	if !ok {
+		return nil, nil, nil, ErrDiffVersionNotFound
+	}
+	base := diff.Split(string(content))
+	patches := make([][]diff.Hunk, len(edits))
+	invalid := make([]diff.Conflict, 0)
+	for n, edit := range edits {
+		patch, ok := s.GetMedia(edit.Edit)
+		if !ok {
+			invalid = append(invalid, diff.Conflict{Patch: n, With: -1, Reason: "patch not available"})
+			continue
+		}
+		hunks, err := diff.Parse(string(patch))
+		if err != nil {
+			invalid = append(invalid, diff.Conflict{Patch: n, With: -1, Reason: err.Error()})
+			continue
+		}
+		patches[n] = hunks
+	}
+	merged, conflicts := diff.Merge(base, patches)
+	return base, merged, append(invalid, conflicts...), nil
+}
+
+func MergeFromState(s *state.State, hash crypto.Hash, selected []crypto.Hash, token crypto.Token, genesis time.Time) (*MergeView, error) {
+	draft, ok := s.Drafts[hash]
+	if !ok {
+		return nil, ErrDiffVersionNotFound
+	}
+	all := patchEdits(draft)
+	if len(all) == 0 {
+		return nil, ErrMergeNoEdits
+	}
+	edits := selectedEdits(all, selected)
+	view := MergeView{
+		DraftTitle: draft.Title,
+		DraftHash:  crypto.EncodeHash(hash),
+		Edits:      make([]MergeEditView, 0),
+		Conflicts:  make([]MergeConflictView, 0),
+		Hunks:      make([]DiffHunkView, 0),
+		Authorship: draft.Authors.IsMember(token),
+		Head: HeaderInfo{
+			Active:  "Drafts",
+			Path:    "explore / esboços / " + LimitStringSize(draft.Title, maxStringSize) + " / ",
+			EndPath: "combinar edições",
+			Section: "explore",
+		},
+	}
+	for _, edit := range all {
+		chosen := false
+		for _, other := range edits {
+			chosen = chosen || other == edit
+		}
+		view.Edits = append(view.Edits, MergeEditView{
+			Hash:     crypto.EncodeHash(edit.Edit),
+			Authors:  AuthorList(edit.Authors, s),
+			Reasons:  edit.Reasons,
+			Date:     PrettyDate(genesis.Add(time.Duration(edit.Date) * time.Second)),
+			Selected: chosen,
+		})
+	}
+	base, merged, conflicts, err := mergeEdits(s, draft, edits)
+	if err != nil {
+		return nil, err
+	}
+	for _, conflict := range conflicts {
+		conflictView := MergeConflictView{
+			Edit:   crypto.EncodeHash(edits[conflict.Patch].Edit),
+			Reason: conflict.Reason,
+		}
+		if conflict.With >= 0 {
+			conflictView.With = crypto.EncodeHash(edits[conflict.With].Edit)
+			conflictView.Lines = fmt.Sprintf("%d-%d", conflict.OldStart, conflict.OldStart+conflict.OldLines-1)
+		}
+		view.Conflicts = append(view.Conflicts, conflictView)
+	}
+	lines := diff.Lines(base, merged)
+	view.Inserted, view.Deleted = diff.Stats(lines)
+	view.Hunks = diffHunks(lines, false)
+	return &view, nil
+}
+
+// MergedDraft is the new version of the draft with the selected patch edits
+// applied. Authors of the merged edits are credited as co-authors; for drafts
+// on behalf of a collective (that cannot have co-authors) they are credited on
+// the reasons.
+func MergedDraft(s *state.State, hash crypto.Hash, selected []crypto.Hash, author crypto.Token) (Draft, error) {
+	draft, ok := s.Drafts[hash]
+	if !ok {
+		return Draft{}, ErrDiffVersionNotFound
+	}
+	if !draft.Authors.IsMember(author) {
+		return Draft{}, ErrMergeNotAuthor
+	}
+	edits := selectedEdits(patchEdits(draft), selected)
+	if len(edits) == 0 {
+		return Draft{}, ErrMergeNoEdits
+	}
+	_, merged, _, err := mergeEdits(s, draft, edits)
+	if err != nil {
+		return Draft{}, err
+	}
+	credited := make([]string, 0)
+	coauthors := make([]crypto.Token, 0)
+	seen := map[crypto.Token]struct{}{author: {}}
+	include := func(token crypto.Token) {
+		if _, ok := seen[token]; !ok {
+			seen[token] = struct{}{}
+			coauthors = append(coauthors, token)
+		}
+	}
+	collective := draft.Authors.CollectiveName()
+	if collective == "" {
+		for token := range draft.Authors.ListOfMembers() {
+			include(token)
+		}
+	}
+	for _, edit := range edits {
+		if name := edit.Authors.CollectiveName(); name != "" {
+			if !slices.Contains(credited, name) {
+				credited = append(credited, name)
+			}
+			continue
+		}
+		for token := range edit.Authors.ListOfMembers() {
+			if handle := s.Members[crypto.HashToken(token)]; !slices.Contains(credited, handle) {
+				credited = append(credited, handle)
+			}
+			if collective == "" {
+				include(token)
+			}
+		}
+	}
+	// as edicoes combinadas ficam registradas nas referencias da nova versao
+	references := append([]crypto.Hash{}, draft.References...)
+	for _, edit := range edits {
+		references = append(references, edit.Edit)
+	}
+	action := Draft{
+		Action:        "Draft",
+		Reasons:       "versão combinada das edições de " + strings.Join(credited, ", "),
+		Title:         draft.Title,
+		Keywords:      draft.Keywords,
+		Description:   draft.Description,
+		ContentType:   draft.DraftType,
+		File:          []byte(diff.Join(merged)),
+		PreviousDraft: draft.DraftHash,
+		References:    references,
+	}
+	if collective != "" {
+		action.OnBehalfOf = collective
+	} else if len(coauthors) > 0 {
+		action.CoAuthors = coauthors
+		majority, supermajority := draft.Authors.GetPolicy()
+		action.Policy = &Policy{Majority: majority, SuperMajority: supermajority}
+	}
+	return action, nil
+}
+
+// hashes of the edits checked on the merge form
+func formToEdits(r *http.Request) []crypto.Hash {
+	hashes := make([]crypto.Hash, 0)
+	for _, value := range r.Form["edits"] {
+		if hash := crypto.DecodeHash(value); hash != crypto.ZeroHash {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
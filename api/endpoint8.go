@@ -0,0 +1,155 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/index"
+	"github.com/freehandle/synergy/social/reputation"
+	"github.com/freehandle/synergy/social/state"
+)
+
+// number of entries on the leaderboards and of recent credits on /member/
+const (
+	leaderboardSize   = 50
+	reputationCredits = 20
+)
+
+var reputationSourceNames = []string{"selos", "lançamentos", "afixações em murais", "edições aceitas", "reações"}
+
+func reputationSourceName(source reputation.Source) string {
+	if int(source) >= len(reputationSourceNames) {
+		return "outros"
+	}
+	return reputationSourceNames[source]
+}
+
+func formatScore(score float64) string {
+	return fmt.Sprintf("%.1f", score)
+}
+
+type ReputationSourceView struct {
+	Source string
+	Count  int
+	Points string
+}
+
+type ReputationCreditView struct {
+	Source string
+	Title  string
+	Link   string // relativo ao servidor
+	Date   string
+	Points string
+}
+
+type ReputationView struct {
+	Score   string
+	Sources []ReputationSourceView
+	Credits []ReputationCreditView // mais recentes primeiro
+}
+
+type RankView struct {
+	Position int
+	Caption  string
+	Link     string
+	Score    string
+}
+
+type LeaderboardView struct {
+	Members     []RankView
+	Collectives []RankView
+	Weights     reputation.Weights
+	Head        HeaderInfo
+	ServerName  string
+}
+
+// titulo e link do objeto creditado (draft ou edit)
+func creditedObject(s *state.State, hash crypto.Hash) (string, string) {
+	if draft, ok := s.Drafts[hash]; ok {
+		return draft.Title, fmt.Sprintf("/draft/%v", crypto.EncodeHash(hash))
+	}
+	if draft, ok := s.Proposals.Draft[hash]; ok {
+		return draft.Title, fmt.Sprintf("/draft/%v", crypto.EncodeHash(hash))
+	}
+	if edit, ok := s.Edits[hash]; ok {
+		return "edição de " + edit.Draft.Title, fmt.Sprintf("/editview/%v", crypto.EncodeHash(hash))
+	}
+	if edit, ok := s.Proposals.Edit[hash]; ok {
+		return "edição de " + edit.Draft.Title, fmt.Sprintf("/editview/%v", crypto.EncodeHash(hash))
+	}
+	return crypto.EncodeHash(hash), ""
+}
+
+func reputationView(s *state.State, score reputation.Score, genesis time.Time) ReputationView {
+	view := ReputationView{
+		Score:   formatScore(score.Total),
+		Sources: make([]ReputationSourceView, 0, len(score.Breakdown)),
+		Credits: make([]ReputationCreditView, 0),
+	}
+	for _, breakdown := range score.Breakdown {
+		view.Sources = append(view.Sources, ReputationSourceView{
+			Source: reputationSourceName(breakdown.Source),
+			Count:  breakdown.Count,
+			Points: formatScore(breakdown.Points),
+		})
+	}
+	for _, credit := range score.Credits {
+		if len(view.Credits) == reputationCredits {
+			break
+		}
+		title, link := creditedObject(s, credit.Object)
+		view.Credits = append(view.Credits, ReputationCreditView{
+			Source: reputationSourceName(credit.Source),
+			Title:  title,
+			Link:   link,
+			Date:   PrettyDate(genesis.Add(time.Duration(credit.Epoch) * time.Second)),
+			Points: formatScore(credit.Points),
+		})
+	}
+	return view
+}
+
+// MemberReputation is the score of a member with its breakdown by source.
+func MemberReputation(s *state.State, i *index.Index, token crypto.Token, genesis time.Time) ReputationView {
+	return reputationView(s, i.Reputation().Member(token, i.ReputationEpoch()), genesis)
+}
+
+// LeaderboardFromIndex ranks members and collectives by reputation.
+func LeaderboardFromIndex(s *state.State, i *index.Index) LeaderboardView {
+	ledger := i.Reputation()
+	now := i.ReputationEpoch()
+	view := LeaderboardView{
+		Members:     make([]RankView, 0),
+		Collectives: make([]RankView, 0),
+		Weights:     ledger.Weights(),
+		Head: HeaderInfo{
+			Active:  "Members",
+			Path:    "explore / membros / ",
+			EndPath: "reputação",
+			Section: "explore",
+		},
+	}
+	for _, rank := range ledger.Members(now, leaderboardSize) {
+		handle, ok := s.Members[crypto.HashToken(rank.Token)]
+		if !ok {
+			continue
+		}
+		view.Members = append(view.Members, RankView{
+			Position: len(view.Members) + 1,
+			Caption:  handle,
+			Link:     fmt.Sprintf("/member/%v", url.QueryEscape(handle)),
+			Score:    formatScore(rank.Score),
+		})
+	}
+	for _, rank := range ledger.Collectives(now, leaderboardSize) {
+		view.Collectives = append(view.Collectives, RankView{
+			Position: len(view.Collectives) + 1,
+			Caption:  rank.Name,
+			Link:     fmt.Sprintf("/collective/%v", url.QueryEscape(rank.Name)),
+			Score:    formatScore(rank.Score),
+		})
+	}
+	return view
+}
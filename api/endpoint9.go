@@ -0,0 +1,76 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/citation"
+	"github.com/freehandle/synergy/social/index"
+	"github.com/freehandle/synergy/social/state"
+)
+
+var ErrCitationFormat = errors.New("unknown citation graph format")
+
+type CitationView struct {
+	Title   string
+	Link    string
+	Authors string
+	Date    string
+	Version string // versao citada quando nao e a versao exibida
+}
+
+// CitedByFromIndex lists the drafts citing any version of the draft.
+func CitedByFromIndex(s *state.State, i *index.Index, hash crypto.Hash, genesis time.Time) []CitationView {
+	graph := i.Citations()
+	views := make([]CitationView, 0)
+	for _, cite := range graph.CitedBy(hash) {
+		draft, ok := s.Drafts[cite.Citing]
+		if !ok {
+			continue
+		}
+		view := CitationView{
+			Title:   draft.Title,
+			Link:    fmt.Sprintf("/draft/%v", crypto.EncodeHash(cite.Citing)),
+			Authors: authorsEtAll(draft.Authors, s),
+			Date:    PrettyDate(genesis.Add(time.Duration(draft.Date) * time.Second)),
+		}
+		if cite.Cited != hash {
+			if cited, ok := graph.Work(cite.Cited); ok {
+				view.Version = "versão de " + PrettyDate(genesis.Add(time.Duration(cited.Date)*time.Second))
+			}
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+// WriteCitations exports the citation graph (or the graph around draft, if
+// not zero) in format "dot" or "graphml".
+func WriteCitations(w http.ResponseWriter, i *index.Index, draft crypto.Hash, format string) error {
+	graph := i.Citations()
+	name := "citations"
+	if draft != crypto.ZeroHash {
+		graph = graph.Subgraph(draft)
+		name = "citations-" + crypto.EncodeHash(draft)
+	}
+	switch format {
+	case "dot", "":
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".dot"))
+		return graph.WriteDOT(w)
+	case "graphml":
+		w.Header().Set("Content-Type", "application/graphml+xml; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".graphml"))
+		return graph.WriteGraphML(w)
+	}
+	return ErrCitationFormat
+}
+
+// MemberCitations are the citation metrics of the drafts (co)authored by the
+// member.
+func MemberCitations(i *index.Index, token crypto.Token) citation.Metrics {
+	return i.Citations().MemberMetrics(token)
+}
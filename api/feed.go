@@ -0,0 +1,406 @@
+package api
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/index"
+	"github.com/freehandle/synergy/social/state"
+)
+
+var ErrFeedFormat = errors.New("unknown feed format")
+
+// Feed is a list of entries published as RSS 2.0 or Atom.
+type Feed struct {
+	ID          string
+	Title       string
+	Link        string
+	Description string
+	Updated     time.Time
+	Items       []FeedItem
+}
+
+type FeedItem struct {
+	Title       string
+	Link        string
+	Description string
+	Content     string // html, opcional
+	Author      string
+	Published   time.Time
+	GUID        string
+}
+
+// feedGUID is the stable identifier of an entry derived from the hash of the
+// action or object.
+func feedGUID(hash crypto.Hash) string {
+	return "urn:synergy:" + crypto.EncodeHash(hash)
+}
+
+// o feed eh atualizado pela entrada mais recente
+func (f *Feed) touch(fallback time.Time) {
+	for _, item := range f.Items {
+		if item.Published.After(f.Updated) {
+			f.Updated = item.Published
+		}
+	}
+	if f.Updated.IsZero() {
+		f.Updated = fallback
+	}
+}
+
+type rssGUID struct {
+	PermaLink bool   `xml:"isPermaLink,attr"`
+	Value     string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description,omitempty"`
+	Author      string  `xml:"dc:creator,omitempty"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+func rssDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC1123Z)
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Link    atomLink    `xml:"link"`
+	Updated string      `xml:"updated"`
+	Author  *atomPerson `xml:"author,omitempty"`
+	Summary string      `xml:"summary,omitempty"`
+	Content *atomText   `xml:"content,omitempty"`
+}
+
+type atomDocument struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Links    []atomLink  `xml:"link"`
+	Updated  string      `xml:"updated"`
+	Author   atomPerson  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+func atomDate(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func rssFeed(feed Feed) any {
+	document := rssDocument{
+		Version: "2.0",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         feed.Title,
+			Link:          feed.Link,
+			Description:   feed.Description,
+			LastBuildDate: rssDate(feed.Updated),
+			Items:         make([]rssItem, 0, len(feed.Items)),
+		},
+	}
+	for _, item := range feed.Items {
+		description := item.Description
+		if item.Content != "" {
+			description = item.Content
+		}
+		document.Channel.Items = append(document.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: description,
+			Author:      item.Author,
+			PubDate:     rssDate(item.Published),
+			GUID:        rssGUID{PermaLink: false, Value: item.GUID},
+		})
+	}
+	return document
+}
+
+func atomFeed(feed Feed) any {
+	id := feed.ID
+	if id == "" {
+		id = feed.Link
+	}
+	document := atomDocument{
+		ID:       id,
+		Title:    feed.Title,
+		Subtitle: feed.Description,
+		Links:    []atomLink{{Href: feed.Link, Rel: "alternate"}},
+		Updated:  atomDate(feed.Updated),
+		Author:   atomPerson{Name: "synergy"},
+		Entries:  make([]atomEntry, 0, len(feed.Items)),
+	}
+	for _, item := range feed.Items {
+		entry := atomEntry{
+			ID:      item.GUID,
+			Title:   item.Title,
+			Link:    atomLink{Href: item.Link, Rel: "alternate"},
+			Updated: atomDate(item.Published),
+			Summary: item.Description,
+		}
+		if item.Author != "" {
+			entry.Author = &atomPerson{Name: item.Author}
+		}
+		if item.Content != "" {
+			entry.Content = &atomText{Type: "html", Value: item.Content}
+		}
+		document.Entries = append(document.Entries, entry)
+	}
+	return document
+}
+
+// WriteFeed writes the feed in format "rss" (RSS 2.0, default) or "atom".
+func WriteFeed(w http.ResponseWriter, feed Feed, format string) error {
+	var document any
+	switch format {
+	case "rss", "":
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		document = rssFeed(feed)
+	case "atom":
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		document = atomFeed(feed)
+	default:
+		return ErrFeedFormat
+	}
+	data, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// feedFormat splits the suffix /rss or /atom of a path.
+func feedFormat(path string) (string, string, bool) {
+	for _, format := range []string{"rss", "atom"} {
+		if rest, ok := strings.CutSuffix(path, "/"+format); ok {
+			return rest, format, true
+		}
+	}
+	return path, "", false
+}
+
+// IssueFeed lists the table of contents of an issue. base is the stable prefix
+// of the links (host and server name).
+func IssueFeed(s *state.State, issue *state.Issue, base string) Feed {
+	journal := issue.Journal
+	published := s.TimeOfEpoch(issue.Epoch)
+	feed := Feed{
+		ID:          feedGUID(issue.Hash),
+		Title:       fmt.Sprintf("%s, número %d: %s", journal.Name, issue.Number, issue.Title),
+		Link:        fmt.Sprintf("%s/issue/%s", base, crypto.EncodeHash(issue.Hash)),
+		Description: issue.Description,
+		Updated:     published,
+		Items:       make([]FeedItem, 0, len(issue.Drafts)),
+	}
+	for _, draft := range issue.Drafts {
+		feed.Items = append(feed.Items, FeedItem{
+			Title:       draft.Title,
+			Link:        fmt.Sprintf("%s/draft/%s", base, crypto.EncodeHash(draft.DraftHash)),
+			Description: draft.Description,
+			Author:      authorsEtAll(draft.Authors, s),
+			Published:   published,
+			GUID:        feedGUID(draft.DraftHash),
+		})
+	}
+	return feed
+}
+
+// JournalFeed lists the issues of a journal, most recent first.
+func JournalFeed(s *state.State, journal *state.Journal, base string) Feed {
+	feed := Feed{
+		ID:          feedGUID(journal.Hash),
+		Title:       journal.Name,
+		Link:        fmt.Sprintf("%s/journal/%s", base, url.PathEscape(journal.Name)),
+		Description: journal.Description,
+		Items:       make([]FeedItem, 0, len(journal.Issues)),
+	}
+	for n := len(journal.Issues) - 1; n >= 0; n-- {
+		issue := journal.Issues[n]
+		feed.Items = append(feed.Items, FeedItem{
+			Title:       fmt.Sprintf("número %d: %s", issue.Number, issue.Title),
+			Link:        fmt.Sprintf("%s/issue/%s", base, crypto.EncodeHash(issue.Hash)),
+			Description: issue.Description,
+			Author:      journal.Collective.Name,
+			Published:   s.TimeOfEpoch(issue.Epoch),
+			GUID:        feedGUID(issue.Hash),
+		})
+	}
+	feed.touch(s.TimeOfEpoch(s.Epoch))
+	return feed
+}
+
+// BoardFeed lists the drafts pinned to a board, dated by their release (or
+// by the draft itself if not released).
+func BoardFeed(s *state.State, name, base string) (Feed, bool) {
+	board, ok := s.Board(name)
+	if !ok {
+		return Feed{}, false
+	}
+	feed := Feed{
+		ID:          feedGUID(crypto.Hasher([]byte(board.Name))),
+		Title:       board.Name,
+		Link:        fmt.Sprintf("%s/board/%s", base, url.PathEscape(board.Name)),
+		Description: board.Description,
+		Items:       make([]FeedItem, 0, len(board.Pinned)),
+	}
+	for n := len(board.Pinned) - 1; n >= 0; n-- {
+		draft := board.Pinned[n]
+		published := s.TimeOfEpoch(draft.Date)
+		if release, ok := s.Releases[draft.DraftHash]; ok && release.Released {
+			published = s.TimeOfEpoch(release.Epoch)
+		}
+		feed.Items = append(feed.Items, FeedItem{
+			Title:       draft.Title,
+			Link:        fmt.Sprintf("%s/draft/%s", base, crypto.EncodeHash(draft.DraftHash)),
+			Description: draft.Description,
+			Author:      authorsEtAll(draft.Authors, s),
+			Published:   published,
+			GUID:        feedGUID(draft.DraftHash),
+		})
+	}
+	feed.touch(s.TimeOfEpoch(s.Epoch))
+	return feed, true
+}
+
+// CollectiveFeed lists the recent actions on a collective.
+func CollectiveFeed(s *state.State, i *index.Index, name, base string) (Feed, bool) {
+	collective, ok := s.Collective(name)
+	if !ok {
+		return Feed{}, false
+	}
+	hash := crypto.Hasher([]byte(collective.Name))
+	link := fmt.Sprintf("%s/collective/%s", base, url.PathEscape(collective.Name))
+	feed := Feed{
+		ID:          feedGUID(hash),
+		Title:       collective.Name,
+		Link:        link,
+		Description: collective.Description,
+		Items:       make([]FeedItem, 0),
+	}
+	recent := i.GetRecentActions(hash)
+	for n := len(recent) - 1; n >= 0; n-- {
+		if recent[n].Description == "" {
+			continue
+		}
+		feed.Items = append(feed.Items, FeedItem{
+			Title:     recent[n].Description,
+			Link:      link,
+			Published: s.TimeOfEpoch(recent[n].Epoch),
+			GUID:      feedGUID(recent[n].Hash),
+		})
+	}
+	feed.touch(s.TimeOfEpoch(s.Epoch))
+	return feed, true
+}
+
+// actionItem describes an action as a feed entry, with html content linking
+// to the objects involved.
+func actionItem(s *state.State, i *index.Index, indexed *index.IndexedAction, link, base string) (FeedItem, bool) {
+	title, _, author, epoch, _ := i.ActionToString(indexed.Action, indexed.Status)
+	if title == "" {
+		return FeedItem{}, false
+	}
+	content, _, _ := i.ActionToStringWithPrefix(indexed.Action, indexed.Status, base)
+	return FeedItem{
+		Title:     title,
+		Link:      link,
+		Content:   content,
+		Author:    s.Members[crypto.HashToken(author)],
+		Published: s.TimeOfEpoch(epoch),
+		GUID:      feedGUID(indexed.Hash),
+	}, true
+}
+
+// MemberFeed lists the approved actions of a member.
+func MemberFeed(s *state.State, i *index.Index, handle, base string) (Feed, bool) {
+	token, ok := s.MembersIndex[handle]
+	if !ok {
+		return Feed{}, false
+	}
+	link := fmt.Sprintf("%s/member/%s", base, url.PathEscape(handle))
+	feed := Feed{
+		ID:          feedGUID(crypto.HashToken(token)),
+		Title:       handle,
+		Link:        link,
+		Description: fmt.Sprintf("instruções de %s", handle),
+		Items:       make([]FeedItem, 0),
+	}
+	for _, indexed := range i.ApprovedActionsOfMember(token) {
+		if item, ok := actionItem(s, i, indexed, link, base); ok {
+			feed.Items = append(feed.Items, item)
+		}
+	}
+	feed.touch(s.TimeOfEpoch(s.Epoch))
+	return feed, true
+}
+
+// NewsFeed lists the approved actions of the news stream (/news).
+func NewsFeed(s *state.State, i *index.Index, base string) Feed {
+	link := fmt.Sprintf("%s/news", base)
+	feed := Feed{
+		ID:          link,
+		Title:       "novidades",
+		Link:        link,
+		Description: "novidades da rede",
+		Items:       make([]FeedItem, 0),
+	}
+	for _, indexed := range i.RecentActions {
+		if indexed.Status != state.Favorable {
+			continue
+		}
+		if news, _, _ := i.ActionToFormatedString(indexed.Action); news == "" {
+			continue
+		}
+		if item, ok := actionItem(s, i, indexed, link, base); ok {
+			feed.Items = append(feed.Items, item)
+		}
+	}
+	feed.touch(s.TimeOfEpoch(s.Epoch))
+	return feed
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+func testFeed() Feed {
+	published := time.Date(2024, 3, 5, 12, 30, 0, 0, time.FixedZone("BRT", -3*3600))
+	return Feed{
+		ID:          feedGUID(crypto.Hasher([]byte("mural"))),
+		Title:       "mural",
+		Link:        "https://synergy/board/mural",
+		Description: "esbocos & notas",
+		Items: []FeedItem{
+			{
+				Title:       "primeiro <esboco>",
+				Link:        "https://synergy/draft/h1",
+				Description: "resumo",
+				Author:      "ana",
+				Published:   published,
+				GUID:        feedGUID(crypto.Hasher([]byte("h1"))),
+			},
+			{
+				Title:       "segundo",
+				Link:        "https://synergy/draft/h2",
+				Description: "resumo",
+				Content:     `<a href="https://synergy/draft/h1">primeiro</a>`,
+				Published:   published.Add(time.Hour),
+				GUID:        feedGUID(crypto.Hasher([]byte("h2"))),
+			},
+		},
+	}
+}
+
+// the shape read back by a feed reader: title, link, date, identifier and the
+// html content of each entry
+type feedEntry struct {
+	Title, Link, Date, GUID, Content, Author string
+}
+
+func TestWriteFeed(t *testing.T) {
+	feed := testFeed()
+	feed.touch(time.Time{})
+	h1, h2 := feed.Items[0].GUID, feed.Items[1].GUID
+	tests := []struct {
+		format      string
+		contentType string
+		root        string
+		updated     string
+		entries     []feedEntry
+	}{
+		{
+			"rss",
+			"application/rss+xml; charset=utf-8",
+			"rss",
+			"Tue, 05 Mar 2024 16:30:00 +0000",
+			[]feedEntry{
+				{"primeiro <esboco>", "https://synergy/draft/h1", "Tue, 05 Mar 2024 15:30:00 +0000", h1, "resumo", "ana"},
+				{"segundo", "https://synergy/draft/h2", "Tue, 05 Mar 2024 16:30:00 +0000", h2, `<a href="https://synergy/draft/h1">primeiro</a>`, ""},
+			},
+		},
+		{
+			"atom",
+			"application/atom+xml; charset=utf-8",
+			"feed",
+			"2024-03-05T16:30:00Z",
+			[]feedEntry{
+				{"primeiro <esboco>", "https://synergy/draft/h1", "2024-03-05T15:30:00Z", h1, "", "ana"},
+				{"segundo", "https://synergy/draft/h2", "2024-03-05T16:30:00Z", h2, `<a href="https://synergy/draft/h1">primeiro</a>`, ""},
+			},
+		},
+	}
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		if err := WriteFeed(recorder, feed, test.format); err != nil {
+			t.Fatalf("%v: %v", test.format, err)
+		}
+		if content := recorder.Header().Get("Content-Type"); content != test.contentType {
+			t.Errorf("%v: content type %v", test.format, content)
+		}
+		body := recorder.Body.String()
+		if !strings.HasPrefix(body, xml.Header) {
+			t.Errorf("%v: missing xml header", test.format)
+		}
+		var root struct{ XMLName xml.Name }
+		xml.Unmarshal([]byte(body), &root)
+		if root.XMLName.Local != test.root {
+			t.Errorf("%v: root element %v", test.format, root.XMLName.Local)
+		}
+		var updated string
+		entries := make([]feedEntry, 0)
+		if test.format == "rss" {
+			var document rssDocument
+			if err := xml.Unmarshal([]byte(body), &document); err != nil {
+				t.Fatalf("rss: %v", err)
+			}
+			if document.Version != "2.0" || document.Channel.Title != "mural" || document.Channel.Link != feed.Link || document.Channel.Description != feed.Description {
+				t.Errorf("rss channel %+v", document.Channel)
+			}
+			if !strings.Contains(body, `<guid isPermaLink="false">`) || !strings.Contains(body, `xmlns:dc="http://purl.org/dc/elements/1.1/"`) {
+				t.Errorf("rss: guid or dublin core namespace missing on\n%s", body)
+			}
+			updated = document.Channel.LastBuildDate
+			for _, item := range document.Channel.Items {
+				entries = append(entries, feedEntry{item.Title, item.Link, item.PubDate, item.GUID.Value, item.Description, ""})
+			}
+			// dc:creator is not read back by the prefixed field name
+			for n, item := range feed.Items {
+				if item.Author != "" && !strings.Contains(body, "<dc:creator>"+item.Author+"</dc:creator>") {
+					t.Errorf("rss: creator of item %v missing", n)
+				}
+				entries[n].Author = item.Author
+			}
+		} else {
+			var document atomDocument
+			if err := xml.Unmarshal([]byte(body), &document); err != nil {
+				t.Fatalf("atom: %v", err)
+			}
+			if document.XMLName.Space != "http://www.w3.org/2005/Atom" || document.ID != feed.ID || document.Title != "mural" || document.Subtitle != feed.Description {
+				t.Errorf("atom feed %+v", document)
+			}
+			if len(document.Links) != 1 || document.Links[0] != (atomLink{Href: feed.Link, Rel: "alternate"}) || document.Author.Name == "" {
+				t.Errorf("atom links %+v, author %+v", document.Links, document.Author)
+			}
+			updated = document.Updated
+			for _, entry := range document.Entries {
+				read := feedEntry{Title: entry.Title, Link: entry.Link.Href, Date: entry.Updated, GUID: entry.ID}
+				if entry.Content != nil {
+					if entry.Content.Type != "html" {
+						t.Errorf("atom: content of type %v", entry.Content.Type)
+					}
+					read.Content = entry.Content.Value
+				}
+				if entry.Author != nil {
+					read.Author = entry.Author.Name
+				}
+				entries = append(entries, read)
+			}
+		}
+		if updated != test.updated {
+			t.Errorf("%v: updated %v, expected %v", test.format, updated, test.updated)
+		}
+		if len(entries) != len(test.entries) {
+			t.Fatalf("%v: %v entries", test.format, len(entries))
+		}
+		for n, entry := range entries {
+			if entry != test.entries[n] {
+				t.Errorf("%v entry %v:\n%+v\nexpected\n%+v", test.format, n, entry, test.entries[n])
+			}
+		}
+	}
+	if err := WriteFeed(httptest.NewRecorder(), feed, "json"); !errors.Is(err, ErrFeedFormat) {
+		t.Errorf("unknown format: %v", err)
+	}
+}
+
+func TestFeedFormat(t *testing.T) {
+	tests := []struct {
+		path, rest, format string
+		ok                 bool
+	}{
+		{"/board/mural/rss", "/board/mural", "rss", true},
+		{"/board/mural/atom", "/board/mural", "atom", true},
+		{"/board/mural", "/board/mural", "", false},
+		{"/board/rss", "/board", "rss", true},
+	}
+	for _, test := range tests {
+		rest, format, ok := feedFormat(test.path)
+		if rest != test.rest || format != test.format || ok != test.ok {
+			t.Errorf("%v: %v, %v, %v", test.path, rest, format, ok)
+		}
+	}
+}
+
+// the identifier of an entry is the hash of its object: it does not change as
+// the feed is generated again, on later epochs or once the draft is released
+func TestFeedGUID(t *testing.T) {
+	_, s, members := notifyState(1)
+	s.SetEpoch(1)
+	incorporate := func(action actions.Action) {
+		t.Helper()
+		if err := s.Action(action.Serialize()); err != nil {
+			t.Fatalf("could not incorporate %T: %v", action, err)
+		}
+	}
+	incorporate(&actions.CreateCollective{Epoch: 1, Author: members[0], Name: "coletivo", Policy: actions.Policy{Majority: 50, SuperMajority: 50}})
+	incorporate(&actions.CreateBoard{Epoch: 1, Author: members[0], OnBehalfOf: "coletivo", Name: "mural", Keywords: []string{"mural"}, PinMajority: 1})
+	draft := notifyDraft(members[0], 0)
+	incorporate(draft)
+	incorporate(&actions.Pin{Epoch: 1, Author: members[0], Board: "mural", Draft: draft.ContentHash, Pin: true})
+	first, ok := BoardFeed(s, "mural", "https://synergy")
+	if !ok || len(first.Items) != 1 {
+		t.Fatalf("board feed %+v", first)
+	}
+	s.SetEpoch(10)
+	incorporate(&actions.ReleaseDraft{Epoch: 10, Author: members[0], ContentHash: draft.ContentHash})
+	second, _ := BoardFeed(s, "mural", "https://synergy")
+	if first.ID != second.ID || first.ID != feedGUID(crypto.Hasher([]byte("mural"))) {
+		t.Errorf("feed id changed: %v, %v", first.ID, second.ID)
+	}
+	if first.Items[0].GUID != second.Items[0].GUID || second.Items[0].GUID != "urn:synergy:"+crypto.EncodeHash(draft.ContentHash) {
+		t.Errorf("entry guid changed: %v, %v", first.Items[0].GUID, second.Items[0].GUID)
+	}
+	// the release dates the entry
+	if !second.Items[0].Published.Equal(s.TimeOfEpoch(10)) || !second.Items[0].Published.After(first.Items[0].Published) {
+		t.Errorf("entry published at %v, then %v", first.Items[0].Published, second.Items[0].Published)
+	}
+	if _, ok := BoardFeed(s, "desconhecido", "https://synergy"); ok {
+		t.Error("feed of an unknown board")
+	}
+}
@@ -1,16 +1,18 @@
 package api
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/freehandle/breeze/crypto"
 	"github.com/freehandle/synergy/social/actions"
+	"github.com/freehandle/synergy/social/state"
 )
 
 type TemplateInfo struct {
@@ -343,16 +345,28 @@ func (a *AttorneyGeneral) ApiHandler(w http.ResponseWriter, r *http.Request) {
 		actionArray, err = CancelEventForm(r).ToAction()
 	case "CheckinEvent":
 		actionArray, err = CheckinEventForm(r, a.ephemeralpub).ToAction()
+	case "CancelCheckinEvent":
+		actionArray, err = CancelCheckinEventForm(r).ToAction()
+	case "Comment":
+		actionArray, err = CommentForm(r).ToAction()
 	case "CreateBoard":
 		actionArray, err = CreateBoardForm(r).ToAction()
 	case "CreateCollective":
-		actionArray, err = CreateCollectiveForm(r).ToAction()
+		actionArray, err = CreateCollectiveForm(r, a.state.MembersIndex).ToAction()
 	case "CreateEvent":
-		actionArray, err = CreateEventForm(r, a.state.MembersIndex, author).ToAction()
+		actionArray, err = CreateEventForm(r, a.state.MembersIndex, author, a.genesisTime).ToAction()
+	case "CreateJournal":
+		actionArray, err = CreateJournalForm(r).ToAction()
+	case "Delegate":
+		actionArray, err = DelegateForm(r, a.state.MembersIndex).ToAction()
 	case "GreetCheckinEvent":
 		actionArray, err = GreetCheckinEventForm(r, a.state.MembersIndex).ToAction()
 	case "ImprintStamp":
 		actionArray, err = ImprintStampForm(r).ToAction()
+	case "JournalEditor":
+		actionArray, err = JournalEditorForm(r, a.state.MembersIndex).ToAction()
+	case "JournalIssue":
+		actionArray, err = JournalIssueForm(r).ToAction()
 	case "Pin":
 		actionArray, err = PinForm(r).ToAction()
 	case "React":
@@ -368,17 +382,40 @@ func (a *AttorneyGeneral) ApiHandler(w http.ResponseWriter, r *http.Request) {
 	case "UpdateCollective":
 		actionArray, err = UpdateCollectiveForm(r).ToAction()
 	case "UpdateEvent":
-		actionArray, err = UpdateEventForm(r, a.state.MembersIndex).ToAction()
+		actionArray, err = UpdateEventForm(r, a.state.MembersIndex, a.genesisTime).ToAction()
 	case "Vote":
 		actionArray, err = VoteForm(r).ToAction()
+	case "MergeEdits":
+		var merged Draft
+		if merged, err = MergedDraft(a.state, FormToHash(r, "draft"), formToEdits(r), author); err == nil {
+			actionArray, err = merged.ToAction()
+		}
 	}
 	if err == nil && len(actionArray) > 0 {
-		a.Send(actionArray, author)
+		if _, err := a.Submit(actionArray, author); err != nil {
+			a.RejectedHandler(w, r, err)
+			return
+		}
 	}
 	redirect := fmt.Sprintf("%v/%v", a.serverName, r.FormValue("redirect"))
 	http.Redirect(w, r, redirect, http.StatusSeeOther)
 }
 
+// RejectedHandler tells the member why an action was not sent.
+func (a *AttorneyGeneral) RejectedHandler(w http.ResponseWriter, r *http.Request, err error) {
+	view := ServerName{
+		Head: HeaderInfo{
+			Error:      fmt.Sprintf("ação não enviada: %v", RejectionMessage(err)),
+			UserHandle: a.Handle(r),
+			ServerName: a.serverName,
+		},
+		ServerName: a.serverName,
+	}
+	if err := a.templates.ExecuteTemplate(w, "main.html", view); err != nil {
+		log.Println(err)
+	}
+}
+
 func (a *AttorneyGeneral) MainHandler(w http.ResponseWriter, r *http.Request) {
 	view := ServerName{
 		Head: HeaderInfo{
@@ -535,6 +572,7 @@ func (a *AttorneyGeneral) EditViewHandler(w http.ResponseWriter, r *http.Request
 		view.Head.UserHandle = a.Handle(r)
 		view.Head.ServerName = a.serverName
 		view.ServerName = a.serverName
+		view.Comments.ServerName = a.serverName
 		if err := a.templates.ExecuteTemplate(w, "editview.html", view); err != nil {
 			log.Println(err)
 		} else {
@@ -554,17 +592,46 @@ func (a *AttorneyGeneral) EditViewHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// CitationsHandler exports the citation graph: /citations?format=dot|graphml
+// for the entire graph or with &draft=hash for the graph around a draft.
+func (a *AttorneyGeneral) CitationsHandler(w http.ResponseWriter, r *http.Request) {
+	draft := crypto.ZeroHash
+	if hashtext := r.URL.Query().Get("draft"); hashtext != "" {
+		draft = crypto.DecodeHash(hashtext)
+		if _, ok := a.indexer.Citations().Work(draft); !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("draft not found"))
+			return
+		}
+	}
+	if err := WriteCitations(w, a.indexer, draft, r.URL.Query().Get("format")); err != nil {
+		if errors.Is(err, ErrCitationFormat) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		log.Println(err)
+	}
+}
+
 func (a *AttorneyGeneral) MediaHandler(w http.ResponseWriter, r *http.Request) {
 	hashtext := r.URL.Path
 	hashtext = strings.Replace(hashtext, "/media/", "", 1)
 	hash := crypto.DecodeHash(hashtext)
 
-	file, ok := a.state.Media[hash]
-	if !ok {
+	if _, ok := a.state.Media[hash]; !ok {
 		w.WriteHeader(http.StatusNotFound)
 		w.Write([]byte("file not found"))
 		return
 	}
+	// le do MediaStore apenas os trechos pedidos (range requests)
+	file, err := state.NewMediaReader(a.state.MediaStore(), hash)
+	if err != nil {
+		log.Printf("PANIC BUG: media %v incorporated but not on the store: %v", hashtext, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("file not available"))
+		return
+	}
 	title := hashtext
 	var ext string
 	if edit, ok := a.state.Edits[hash]; ok {
@@ -580,7 +647,7 @@ func (a *AttorneyGeneral) MediaHandler(w http.ResponseWriter, r *http.Request) {
 	//cd := mime.FormatMediaType("attachment", map[string]string{"filename": name})
 	//w.Header().Set("Content-Disposition", cd)
 	//w.Header().Set("Content-Type", "application/octet-stream")
-	http.ServeContent(w, r, name, time.Now(), bytes.NewReader(file))
+	http.ServeContent(w, r, name, time.Now(), file)
 }
 
 func (a *AttorneyGeneral) NewEditHandler(w http.ResponseWriter, r *http.Request) {
@@ -629,6 +696,16 @@ func (a *AttorneyGeneral) BoardHandler(w http.ResponseWriter, r *http.Request) {
 	boardName := r.URL.Path
 	boardName = strings.Replace(boardName, "/board/", "", 1)
 	boardName, _ = url.QueryUnescape(boardName)
+	if name, ok := strings.CutSuffix(boardName, "/cite"); ok {
+		records, found := ReleasesOnBoard(a.state, name, a.citeURL())
+		a.writeCite(w, r, records, found, "board-"+name)
+		return
+	}
+	if name, format, ok := feedFormat(boardName); ok {
+		feed, found := BoardFeed(a.state, name, a.citeURL())
+		a.writeFeed(w, feed, found, format)
+		return
+	}
 	view := BoardDetailFromState(a.state, boardName, author)
 	if view != nil {
 		view.Head.ServerName = a.serverName
@@ -654,6 +731,50 @@ func (a *AttorneyGeneral) BoardHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// prefixo estavel dos links citados
+func (a *AttorneyGeneral) citeURL() string {
+	return fmt.Sprintf("%s%s", a.hostname, a.serverName)
+}
+
+func (a *AttorneyGeneral) writeCite(w http.ResponseWriter, r *http.Request, records []CiteRecord, found bool, name string) {
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(ErrCiteNotFound.Error()))
+		return
+	}
+	if err := WriteCiteRecords(w, records, r.URL.Query().Get("format"), name); err != nil {
+		if errors.Is(err, ErrCiteFormat) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		log.Println(err)
+	}
+}
+
+// ReleaseHandler exports a release as a citation on /release/{hash}/cite
+// (hash of the draft or of the release) and redirects /release/{hash} to the
+// released draft.
+func (a *AttorneyGeneral) ReleaseHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Replace(r.URL.Path, "/release/", "", 1)
+	hashtext, cite := strings.CutSuffix(path, "/cite")
+	release, ok := releaseOf(a.state, crypto.DecodeHash(hashtext))
+	if !cite {
+		if ok {
+			http.Redirect(w, r, fmt.Sprintf("%s/draft/%s", a.serverName, crypto.EncodeHash(release.Draft.DraftHash)), http.StatusSeeOther)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(ErrCiteNotFound.Error()))
+		return
+	}
+	records := make([]CiteRecord, 0)
+	if ok {
+		records = append(records, CiteRecordFromState(a.state, release, a.citeURL()))
+	}
+	a.writeCite(w, r, records, ok, "release-"+hashtext)
+}
+
 func (a *AttorneyGeneral) CollectivesHandler(w http.ResponseWriter, r *http.Request) {
 	view := CollectivesFromState(a.state)
 	view.Head.UserHandle = a.Handle(r)
@@ -668,6 +789,21 @@ func (a *AttorneyGeneral) CollectiveHandler(w http.ResponseWriter, r *http.Reque
 	name := r.URL.Path
 	name = strings.Replace(name, "/collective/", "", 1)
 	name, _ = url.QueryUnescape(name)
+	if collective, ok := strings.CutSuffix(name, "/cite"); ok {
+		records, found := ReleasesOfCollective(a.state, collective, a.citeURL())
+		a.writeCite(w, r, records, found, "collective-"+collective)
+		return
+	}
+	if collective, format, ok := feedFormat(name); ok {
+		feed, found := CollectiveFeed(a.state, a.indexer, collective, a.citeURL())
+		a.writeFeed(w, feed, found, format)
+		return
+	}
+	if collective, ok := strings.CutSuffix(name, "/ics"); ok {
+		calendar, found := CollectiveCalendar(a.state, collective, a.citeURL())
+		a.writeCalendar(w, calendar, found, "collective-"+collective)
+		return
+	}
 	author := a.Author(r)
 	view := CollectiveDetailFromState(a.state, a.indexer, name, author)
 	if view != nil {
@@ -713,6 +849,7 @@ func (a *AttorneyGeneral) DraftHandler(w http.ResponseWriter, r *http.Request) {
 	if view != nil {
 		view.Head.ServerName = a.serverName
 		view.ServerName = a.serverName
+		view.Comments.ServerName = a.serverName
 		view.Head.UserHandle = a.Handle(r)
 		if err := a.templates.ExecuteTemplate(w, "draft.html", view); err != nil {
 			log.Println(err)
@@ -754,15 +891,33 @@ func (a *AttorneyGeneral) EventsHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+func (a *AttorneyGeneral) writeCalendar(w http.ResponseWriter, calendar Calendar, found bool, name string) {
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("calendar not found"))
+		return
+	}
+	if err := WriteCalendar(w, calendar, name); err != nil {
+		log.Println(err)
+	}
+}
+
+// EventHandler serves /event/{hash} and its iCalendar export /event/{hash}/ics.
 func (a *AttorneyGeneral) EventHandler(w http.ResponseWriter, r *http.Request) {
 	hashEncoded := r.URL.Path
 	hashEncoded = strings.Replace(hashEncoded, "/event/", "", 1)
+	if hashText, ok := strings.CutSuffix(hashEncoded, "/ics"); ok {
+		calendar, found := EventCalendar(a.state, crypto.DecodeHash(hashText), a.citeURL())
+		a.writeCalendar(w, calendar, found, "event-"+hashText)
+		return
+	}
 	hash := crypto.DecodeHash(hashEncoded)
 	author := a.Author(r)
 	view := EventDetailFromState(a.state, a.indexer, hash, author, a.ephemeralprv)
 	if view != nil {
 		view.Head.ServerName = a.serverName
 		view.ServerName = a.serverName
+		view.Comments.ServerName = a.serverName
 		view.Head.UserHandle = a.Handle(r)
 		if err := a.templates.ExecuteTemplate(w, "event.html", view); err != nil {
 			log.Println(err)
@@ -795,6 +950,76 @@ func (a *AttorneyGeneral) VotesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// DiffHandler serves /diff/{from}/{to}. The version selector on the page
+// submits /diff?from=&to= instead.
+func (a *AttorneyGeneral) DiffHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, to := crypto.DecodeHash(query.Get("from")), crypto.DecodeHash(query.Get("to"))
+	if path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/diff"), "/"); path != "" {
+		first, second, _ := strings.Cut(path, "/")
+		from, to = crypto.DecodeHash(first), crypto.DecodeHash(second)
+	}
+	view, err := DiffFromState(a.state, from, to, query.Get("mode"), a.genesisTime)
+	if err == nil {
+		view.Head.UserHandle = a.Handle(r)
+		view.Head.ServerName = a.serverName
+		view.ServerName = a.serverName
+		if err := a.templates.ExecuteTemplate(w, "diff.html", view); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+	mainview := ServerName{
+		Head: HeaderInfo{
+			Error:      err.Error(),
+			UserHandle: a.Handle(r),
+			ServerName: a.serverName,
+		},
+		ServerName: a.serverName,
+	}
+	if err := a.templates.ExecuteTemplate(w, "main.html", mainview); err != nil {
+		log.Println(err)
+	}
+}
+
+// MergeHandler serves /merge/{draft}: the candidate version with the checked
+// patch edits (all of them when none is checked) applied on the draft.
+func (a *AttorneyGeneral) MergeHandler(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	hash := getHash(r.URL.Path, "/merge/")
+	view, err := MergeFromState(a.state, hash, formToEdits(r), a.Author(r), a.genesisTime)
+	if err == nil {
+		view.Head.UserHandle = a.Handle(r)
+		view.Head.ServerName = a.serverName
+		view.ServerName = a.serverName
+		if err := a.templates.ExecuteTemplate(w, "merge.html", view); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+	mainview := ServerName{
+		Head: HeaderInfo{
+			Error:      err.Error(),
+			UserHandle: a.Handle(r),
+			ServerName: a.serverName,
+		},
+		ServerName: a.serverName,
+	}
+	if err := a.templates.ExecuteTemplate(w, "main.html", mainview); err != nil {
+		log.Println(err)
+	}
+}
+
+func (a *AttorneyGeneral) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	view := SearchFromIndex(a.indexer, r.URL.Query().Get("q"), a.genesisTime)
+	view.Head.UserHandle = a.Handle(r)
+	view.Head.ServerName = a.serverName
+	view.ServerName = a.serverName
+	if err := a.templates.ExecuteTemplate(w, "search.html", view); err != nil {
+		log.Println(err)
+	}
+}
+
 func (a *AttorneyGeneral) MembersHandler(w http.ResponseWriter, r *http.Request) {
 	view := MembersFromState(a.state)
 	view.Head.UserHandle = a.Handle(r)
@@ -805,10 +1030,25 @@ func (a *AttorneyGeneral) MembersHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+func (a *AttorneyGeneral) ReputationHandler(w http.ResponseWriter, r *http.Request) {
+	view := LeaderboardFromIndex(a.state, a.indexer)
+	view.Head.UserHandle = a.Handle(r)
+	view.Head.ServerName = a.serverName
+	view.ServerName = a.serverName
+	if err := a.templates.ExecuteTemplate(w, "reputation.html", view); err != nil {
+		log.Println(err)
+	}
+}
+
 func (a *AttorneyGeneral) MemberHandler(w http.ResponseWriter, r *http.Request) {
 	name := r.URL.Path
 	name = strings.Replace(name, "/member/", "", 1)
 	name, _ = url.QueryUnescape(name)
+	if handle, format, ok := feedFormat(name); ok {
+		feed, found := MemberFeed(a.state, a.indexer, handle, a.citeURL())
+		a.writeFeed(w, feed, found, format)
+		return
+	}
 	view := MemberViewFromState(a.state, a.indexer, name)
 	if view != nil {
 		view.Head.ServerName = a.serverName
@@ -885,6 +1125,191 @@ func (a *AttorneyGeneral) VoteCreateBoardHandler(w http.ResponseWriter, r *http.
 	}
 }
 
+func (a *AttorneyGeneral) JournalsHandler(w http.ResponseWriter, r *http.Request) {
+	view := JournalsFromState(a.state)
+	view.Head.UserHandle = a.Handle(r)
+	view.Head.ServerName = a.serverName
+	view.ServerName = a.serverName
+	if err := a.templates.ExecuteTemplate(w, "journals.html", view); err != nil {
+		log.Println(err)
+	}
+}
+
+func (a *AttorneyGeneral) writeFeed(w http.ResponseWriter, feed Feed, found bool, format string) {
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("feed not found"))
+		return
+	}
+	if err := WriteFeed(w, feed, format); err != nil {
+		log.Println(err)
+	}
+}
+
+// JournalHandler serves /journal/{name}, the issue /journal/{name}/{number}
+// and the feed of issues /journal/{name}/rss (or /atom).
+func (a *AttorneyGeneral) JournalHandler(w http.ResponseWriter, r *http.Request) {
+	author := a.Author(r)
+	path := strings.Replace(r.URL.Path, "/journal/", "", 1)
+	path = strings.TrimSuffix(path, "/")
+	journalName, sub, _ := strings.Cut(path, "/")
+	journalName, _ = url.PathUnescape(journalName)
+	if sub == "rss" || sub == "atom" {
+		journal, ok := a.state.Journal(journalName)
+		if ok {
+			a.writeFeed(w, JournalFeed(a.state, journal, a.citeURL()), true, sub)
+		} else {
+			a.writeFeed(w, Feed{}, false, sub)
+		}
+		return
+	}
+	if sub != "" {
+		if number, err := strconv.Atoi(sub); err == nil {
+			if view := IssueOfJournal(a.state, journalName, number); view != nil {
+				a.writeIssue(w, r, view)
+				return
+			}
+		}
+	} else if view := JournalDetailFromState(a.state, journalName, author); view != nil {
+		view.Head.ServerName = a.serverName
+		view.ServerName = a.serverName
+		view.Head.UserHandle = a.Handle(r)
+		if err := a.templates.ExecuteTemplate(w, "journal.html", view); err != nil {
+			log.Println(err)
+		} else {
+			return
+		}
+	}
+	mainview := ServerName{
+		Head: HeaderInfo{
+			Error:      "journal not found",
+			UserHandle: a.Handle(r),
+			ServerName: a.serverName,
+		},
+		ServerName: a.serverName,
+	}
+	if err := a.templates.ExecuteTemplate(w, "main.html", mainview); err != nil {
+		log.Println(err)
+	}
+}
+
+func (a *AttorneyGeneral) writeIssue(w http.ResponseWriter, r *http.Request, view *IssueDetailView) {
+	view.Head.ServerName = a.serverName
+	view.ServerName = a.serverName
+	view.Head.UserHandle = a.Handle(r)
+	if err := a.templates.ExecuteTemplate(w, "issue.html", view); err != nil {
+		log.Println(err)
+	}
+}
+
+// IssueHandler serves the table of contents /issue/{hash} and its feed
+// /issue/{hash}/rss (or /atom).
+func (a *AttorneyGeneral) IssueHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Replace(r.URL.Path, "/issue/", "", 1)
+	hashtext, format, isFeed := feedFormat(path)
+	hash := crypto.DecodeHash(hashtext)
+	if isFeed {
+		issue, ok := a.state.Issues[hash]
+		if ok {
+			a.writeFeed(w, IssueFeed(a.state, issue, a.citeURL()), true, format)
+		} else {
+			a.writeFeed(w, Feed{}, false, format)
+		}
+		return
+	}
+	if view := IssueDetailFromState(a.state, hash); view != nil {
+		a.writeIssue(w, r, view)
+		return
+	}
+	mainview := ServerName{
+		Head: HeaderInfo{
+			Error:      "issue not found",
+			UserHandle: a.Handle(r),
+			ServerName: a.serverName,
+		},
+		ServerName: a.serverName,
+	}
+	if err := a.templates.ExecuteTemplate(w, "main.html", mainview); err != nil {
+		log.Println(err)
+	}
+}
+
+func (a *AttorneyGeneral) CreateJournalHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		fmt.Fprintf(w, "ParseForm() err: %v", err)
+		return
+	}
+	collective := r.FormValue("collective")
+	head := HeaderInfo{
+		Active:     "Connections",
+		Path:       "realize / conexões / coletivos / " + collective + " / ",
+		EndPath:    "criar periódico",
+		Section:    "realize",
+		UserHandle: a.Handle(r),
+		ServerName: a.serverName,
+	}
+	info := TemplateInfo{
+		Head:           head,
+		CollectiveName: collective,
+		ServerName:     a.serverName,
+	}
+	if err := a.templates.ExecuteTemplate(w, "createjournal.html", info); err != nil {
+		log.Println(err)
+	}
+}
+
+func (a *AttorneyGeneral) VoteCreateJournalHandler(w http.ResponseWriter, r *http.Request) {
+	hash := getHash(r.URL.Path, "/votecreatejournal/")
+	view := PendingJournalFromState(a.state, hash)
+	if view != nil {
+		view.Head.UserHandle = a.Handle(r)
+		view.Head.ServerName = a.serverName
+		view.ServerName = a.serverName
+		if err := a.templates.ExecuteTemplate(w, "votecreatejournal.html", view); err != nil {
+			log.Println(err)
+		} else {
+			return
+		}
+	}
+	mainview := ServerName{
+		Head: HeaderInfo{
+			Error:      "pending journal not found",
+			UserHandle: a.Handle(r),
+			ServerName: a.serverName,
+		},
+		ServerName: a.serverName,
+	}
+	if err := a.templates.ExecuteTemplate(w, "main.html", mainview); err != nil {
+		log.Println(err)
+	}
+}
+
+func (a *AttorneyGeneral) VoteJournalIssueHandler(w http.ResponseWriter, r *http.Request) {
+	hash := getHash(r.URL.Path, "/votejournalissue/")
+	view := PendingIssueFromState(a.state, hash)
+	if view != nil {
+		view.Head.UserHandle = a.Handle(r)
+		view.Head.ServerName = a.serverName
+		view.ServerName = a.serverName
+		if err := a.templates.ExecuteTemplate(w, "votejournalissue.html", view); err != nil {
+			log.Println(err)
+		} else {
+			return
+		}
+	}
+	mainview := ServerName{
+		Head: HeaderInfo{
+			Error:      "pending issue not found",
+			UserHandle: a.Handle(r),
+			ServerName: a.serverName,
+		},
+		ServerName: a.serverName,
+	}
+	if err := a.templates.ExecuteTemplate(w, "main.html", mainview); err != nil {
+		log.Println(err)
+	}
+}
+
 func (a *AttorneyGeneral) VoteCreateEventHandler(w http.ResponseWriter, r *http.Request) {
 	hash := getHash(r.URL.Path, "/votecreateevent/")
 	view := PendingEventFromState(a.state, a.indexer, hash)
@@ -1218,6 +1643,9 @@ func (a *AttorneyGeneral) MyEventsHandler(w http.ResponseWriter, r *http.Request
 	author := a.Author(r)
 	view := MyEventsFromState(a.state, a.indexer, author, a.ephemeralprv)
 	if view != nil {
+		if handle := a.Handle(r); handle != "" {
+			view.CalendarLink = fmt.Sprintf("%s/calendar/%s/%s", a.citeURL(), url.QueryEscape(handle), CalendarKey(a.pk, author))
+		}
 		view.Head.UserHandle = a.Handle(r)
 		view.Head.ServerName = a.serverName
 		view.ServerName = a.serverName
@@ -1240,7 +1668,56 @@ func (a *AttorneyGeneral) MyEventsHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// MyActionsHandler lists the receipts of the actions the member sent through
+// this attorney: submitted, included or rejected with the reason given by the
+// state.
+func (a *AttorneyGeneral) MyActionsHandler(w http.ResponseWriter, r *http.Request) {
+	author := a.Author(r)
+	if author != crypto.ZeroToken {
+		view := MyActionsFromMempool(a.state, a.mempool, author)
+		view.Head.UserHandle = a.Handle(r)
+		view.Head.ServerName = a.serverName
+		view.ServerName = a.serverName
+		if err := a.templates.ExecuteTemplate(w, "myactions.html", view); err != nil {
+			log.Println(err)
+		} else {
+			return
+		}
+	}
+	mainview := ServerName{
+		Head: HeaderInfo{
+			Error:      "could not load my actions",
+			UserHandle: a.Handle(r),
+			ServerName: a.serverName,
+		},
+		ServerName: a.serverName,
+	}
+	if err := a.templates.ExecuteTemplate(w, "main.html", mainview); err != nil {
+		log.Println(err)
+	}
+}
+
+// CalendarHandler serves the personal calendar /calendar/{handle}/{key} with
+// the events the member is checked into. The key is given on /myevents.
+func (a *AttorneyGeneral) CalendarHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Replace(r.URL.Path, "/calendar/", "", 1)
+	handle, key, _ := strings.Cut(path, "/")
+	handle, _ = url.QueryUnescape(handle)
+	token, ok := a.state.MembersIndex[handle]
+	if !ok || !ValidCalendarKey(a.pk, token, key) {
+		a.writeCalendar(w, Calendar{}, false, "")
+		return
+	}
+	calendar, found := MemberCalendar(a.state, a.indexer, handle, a.citeURL())
+	a.writeCalendar(w, calendar, found, "member-"+handle)
+}
+
+// NewsHandler serves /news and its feeds /news/rss and /news/atom.
 func (a *AttorneyGeneral) NewsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, format, ok := feedFormat(r.URL.Path); ok {
+		a.writeFeed(w, NewsFeed(a.state, a.indexer, a.citeURL()), true, format)
+		return
+	}
 	view := NewActionsFromState(a.state, a.indexer, a.genesisTime)
 	if view != nil {
 		view.Head.UserHandle = a.Handle(r)
@@ -1273,6 +1750,7 @@ func (a *AttorneyGeneral) DetailedVoteHandler(w http.ResponseWriter, r *http.Req
 		view.Head.UserHandle = a.Handle(r)
 		view.Head.ServerName = a.serverName
 		view.ServerName = a.serverName
+		view.Comments.ServerName = a.serverName
 		if err := a.templates.ExecuteTemplate(w, "detailedvote.html", view); err != nil {
 			log.Println(err)
 		} else {
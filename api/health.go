@@ -0,0 +1,29 @@
+package api
+
+import "time"
+
+// ChainHealth describes the connection of the node to the chain, shown to
+// the members when the node is out of sync or disconnected.
+type ChainHealth struct {
+	Connected bool      `json:"connected"`
+	Live      bool      `json:"live"`      // em dia com o gateway
+	Epoch     uint64    `json:"epoch"`     // ultimo bloco incorporado
+	Attempts  int       `json:"attempts"`  // tentativas de reconexao sem sucesso
+	Pending   int       `json:"pending"`   // acoes aguardando reconexao
+	LastError string    `json:"lastError"` // causa da ultima desconexao
+	Since     time.Time `json:"since"`     // inicio do estado atual
+}
+
+// HealthReporter is implemented by the connection of the node to the chain.
+type HealthReporter interface {
+	Health() ChainHealth
+}
+
+// chainHealth of a node without a supervised connection is reported as
+// connected and live at the current epoch.
+func (a *AttorneyGeneral) chainHealth() ChainHealth {
+	if a.health == nil {
+		return ChainHealth{Connected: true, Live: true, Epoch: a.state.Epoch}
+	}
+	return a.health.Health()
+}
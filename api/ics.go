@@ -0,0 +1,263 @@
+package api
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+	"github.com/freehandle/synergy/social/index"
+	"github.com/freehandle/synergy/social/state"
+)
+
+// Calendar is a list of events published as iCalendar (RFC 5545).
+type Calendar struct {
+	Name   string
+	Method string // PUBLISH ou CANCEL
+	Events []CalendarEvent
+}
+
+// CalendarEvent is an event as a VEVENT. Sequence is incremented by every
+// accepted update or cancellation so that calendar clients replace their copy.
+type CalendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	Modified    time.Time
+	Sequence    int
+	Cancelled   bool
+	RRule       string      // regra de recorrencia da serie
+	ExDates     []time.Time // ocorrencias canceladas
+	Recurrence  time.Time   // RECURRENCE-ID de uma ocorrencia alterada
+}
+
+const icsDate = "20060102T150405Z"
+
+// CalendarEventFromState describes an event. base is the stable prefix of the
+// links (host and server name).
+func CalendarEventFromState(s *state.State, event *state.Event, base string) CalendarEvent {
+	hash := crypto.EncodeHash(event.Hash)
+	summary, _, _ := strings.Cut(event.Description, "\n")
+	entry := CalendarEvent{
+		UID:         hash + "@synergy",
+		Summary:     fmt.Sprintf("%s: %s", event.Collective.Name, LimitStringSize(strings.TrimSpace(summary), maxStringSize)),
+		Description: event.Description,
+		Location:    event.Venue,
+		URL:         fmt.Sprintf("%s/event/%s", base, hash),
+		Start:       event.StartAt,
+		End:         event.EstimatedEnd,
+		Modified:    s.TimeOfEpoch(event.Modified),
+		Sequence:    event.Sequence,
+	}
+	// cancelado: continua em s.Events mas deixa de estar ativo
+	if _, ok := s.Events[event.Hash]; ok && !event.Live {
+		entry.Cancelled = true
+	}
+	return entry
+}
+
+var icsFrequency = map[byte]string{
+	actions.DailyRecurrence:   "DAILY",
+	actions.WeeklyRecurrence:  "WEEKLY",
+	actions.MonthlyRecurrence: "MONTHLY",
+}
+
+// calendarSeries describes a recurring event as the master VEVENT with its
+// rule and cancelled dates, followed by one VEVENT per altered occurrence.
+func calendarSeries(s *state.State, event *state.Event, base string) []CalendarEvent {
+	master := CalendarEventFromState(s, event, base)
+	if !event.Recurrence.Recurring() {
+		return []CalendarEvent{master}
+	}
+	interval := event.Recurrence.Interval
+	if interval == 0 {
+		interval = 1
+	}
+	master.RRule = fmt.Sprintf("FREQ=%s;INTERVAL=%d;COUNT=%d", icsFrequency[event.Recurrence.Frequency], interval, event.Recurrence.Count)
+	entries := []CalendarEvent{master}
+	for _, occurrence := range event.Occurrences() {
+		if occurrence.Cancelled {
+			master.ExDates = append(master.ExDates, event.ScheduledStart(occurrence.Number))
+		} else if occurrence.Overridden {
+			override := master
+			override.RRule = ""
+			override.ExDates = nil
+			override.Description = occurrence.Description
+			override.Location = occurrence.Venue
+			override.Start = occurrence.StartAt
+			override.End = occurrence.EstimatedEnd
+			override.Recurrence = event.ScheduledStart(occurrence.Number)
+			entries = append(entries, override)
+		}
+	}
+	entries[0] = master
+	return entries
+}
+
+func sortCalendar(events []CalendarEvent) {
+	sort.Slice(events, func(n, m int) bool { return events[n].Start.Before(events[m].Start) })
+}
+
+// EventCalendar exports a single event. A cancelled event is sent with method
+// CANCEL.
+func EventCalendar(s *state.State, hash crypto.Hash, base string) (Calendar, bool) {
+	event, ok := s.Events[hash]
+	if !ok {
+		return Calendar{}, false
+	}
+	entry := CalendarEventFromState(s, event, base)
+	calendar := Calendar{
+		Name:   entry.Summary,
+		Method: "PUBLISH",
+		Events: calendarSeries(s, event, base),
+	}
+	if entry.Cancelled {
+		calendar.Method = "CANCEL"
+	}
+	return calendar, true
+}
+
+// CollectiveCalendar lists the public events of a collective, cancelled ones
+// included so that subscribers learn about the cancellation.
+func CollectiveCalendar(s *state.State, name, base string) (Calendar, bool) {
+	collective, ok := s.Collective(name)
+	if !ok {
+		return Calendar{}, false
+	}
+	calendar := Calendar{
+		Name:   "eventos de " + collective.Name,
+		Method: "PUBLISH",
+		Events: make([]CalendarEvent, 0),
+	}
+	for _, event := range s.Events {
+		if event.Public && event.Collective == collective {
+			calendar.Events = append(calendar.Events, calendarSeries(s, event, base)...)
+		}
+	}
+	sortCalendar(calendar.Events)
+	return calendar, true
+}
+
+// MemberCalendar lists the events the member is checked into.
+func MemberCalendar(s *state.State, i *index.Index, handle, base string) (Calendar, bool) {
+	token, ok := s.MembersIndex[handle]
+	if !ok {
+		return Calendar{}, false
+	}
+	calendar := Calendar{
+		Name:   "eventos de " + handle,
+		Method: "PUBLISH",
+		Events: make([]CalendarEvent, 0),
+	}
+	for _, event := range i.MemberToCheckin[token] {
+		calendar.Events = append(calendar.Events, calendarSeries(s, event, base)...)
+	}
+	sortCalendar(calendar.Events)
+	return calendar, true
+}
+
+// CalendarKey is the secret of the personal calendar subscription of a member.
+// It is derived from the key of the attorney so that no state is kept and the
+// link cannot be guessed from the handle.
+func CalendarKey(pk crypto.PrivateKey, token crypto.Token) string {
+	data := append(pk[:], token[:]...)
+	return crypto.EncodeHash(crypto.Hasher(data))
+}
+
+// ValidCalendarKey checks the secret of a personal calendar subscription.
+func ValidCalendarKey(pk crypto.PrivateKey, token crypto.Token, key string) bool {
+	return subtle.ConstantTimeCompare([]byte(CalendarKey(pk, token)), []byte(key)) == 1
+}
+
+// icsEscape escapes a TEXT value.
+func icsEscape(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", `\n`).Replace(text)
+	return text
+}
+
+// icsLine writes a content line folded at 75 octets without splitting a
+// multibyte character.
+func icsLine(buffer *bytes.Buffer, name, value string) {
+	line := name + ":" + value
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		buffer.WriteString(line[:cut])
+		buffer.WriteString("\r\n ")
+		line = line[cut:]
+		// a linha de continuacao comeca com espaco
+		limit = 74
+	}
+	buffer.WriteString(line)
+	buffer.WriteString("\r\n")
+}
+
+// ICalendar formats the calendar as an iCalendar object.
+func ICalendar(calendar Calendar) []byte {
+	var buffer bytes.Buffer
+	icsLine(&buffer, "BEGIN", "VCALENDAR")
+	icsLine(&buffer, "VERSION", "2.0")
+	icsLine(&buffer, "PRODID", "-//freehandle//synergy//PT")
+	icsLine(&buffer, "CALSCALE", "GREGORIAN")
+	icsLine(&buffer, "METHOD", calendar.Method)
+	icsLine(&buffer, "X-WR-CALNAME", icsEscape(calendar.Name))
+	for _, event := range calendar.Events {
+		icsLine(&buffer, "BEGIN", "VEVENT")
+		icsLine(&buffer, "UID", event.UID)
+		icsLine(&buffer, "DTSTAMP", event.Modified.UTC().Format(icsDate))
+		icsLine(&buffer, "LAST-MODIFIED", event.Modified.UTC().Format(icsDate))
+		if !event.Recurrence.IsZero() {
+			icsLine(&buffer, "RECURRENCE-ID", event.Recurrence.UTC().Format(icsDate))
+		}
+		icsLine(&buffer, "DTSTART", event.Start.UTC().Format(icsDate))
+		if event.End.After(event.Start) {
+			icsLine(&buffer, "DTEND", event.End.UTC().Format(icsDate))
+		}
+		if event.RRule != "" {
+			icsLine(&buffer, "RRULE", event.RRule)
+		}
+		for _, date := range event.ExDates {
+			icsLine(&buffer, "EXDATE", date.UTC().Format(icsDate))
+		}
+		icsLine(&buffer, "SEQUENCE", fmt.Sprintf("%d", event.Sequence))
+		icsLine(&buffer, "SUMMARY", icsEscape(event.Summary))
+		if event.Description != "" {
+			icsLine(&buffer, "DESCRIPTION", icsEscape(event.Description))
+		}
+		if event.Location != "" {
+			icsLine(&buffer, "LOCATION", icsEscape(event.Location))
+		}
+		icsLine(&buffer, "URL", event.URL)
+		if event.Cancelled {
+			icsLine(&buffer, "STATUS", "CANCELLED")
+		} else {
+			icsLine(&buffer, "STATUS", "CONFIRMED")
+		}
+		icsLine(&buffer, "END", "VEVENT")
+	}
+	icsLine(&buffer, "END", "VCALENDAR")
+	return buffer.Bytes()
+}
+
+// WriteCalendar writes the calendar as text/calendar. name is the suggested
+// file name without extension.
+func WriteCalendar(w http.ResponseWriter, calendar Calendar, name string) error {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", name+".ics"))
+	_, err := w.Write(ICalendar(calendar))
+	return err
+}
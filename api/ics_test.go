@@ -0,0 +1,200 @@
+package api
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+func TestICSEscape(t *testing.T) {
+	tests := []struct {
+		text, expected string
+	}{
+		{"sem escape", "sem escape"},
+		{"a;b,c", `a\;b\,c`},
+		{`barra \ invertida`, `barra \\ invertida`},
+		{"linha 1\nlinha 2", `linha 1\nlinha 2`},
+		{"linha 1\r\nlinha 2\rlinha 3", `linha 1\nlinha 2\nlinha 3`},
+		{`\n literal`, `\\n literal`},
+		{"dois pontos: nao escapam", "dois pontos: nao escapam"},
+	}
+	for _, test := range tests {
+		if escaped := icsEscape(test.text); escaped != test.expected {
+			t.Errorf("%q: got %q, expected %q", test.text, escaped, test.expected)
+		}
+	}
+}
+
+// unfold joins the continuation lines of an iCalendar object
+func unfold(data []byte) []string {
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n ", ""), "\r\n"), "\r\n")
+}
+
+func TestICSLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		lines int
+	}{
+		{"short", "reuniao", 1},
+		{"exactly 75 octets", strings.Repeat("a", 75-len("SUMMARY:")), 1},
+		{"76 octets", strings.Repeat("a", 76-len("SUMMARY:")), 2},
+		{"long ascii", strings.Repeat("abcdefghij", 30), 5},
+		{"two octet characters", strings.Repeat("ção ", 40), 0},
+		{"three octet characters", strings.Repeat("€", 100), 0},
+		{"four octet characters", "x" + strings.Repeat("🎉", 50), 0},
+	}
+	for _, test := range tests {
+		var buffer bytes.Buffer
+		icsLine(&buffer, "SUMMARY", test.value)
+		data := buffer.Bytes()
+		if !bytes.HasSuffix(data, []byte("\r\n")) {
+			t.Errorf("%v: line not terminated by CRLF", test.name)
+		}
+		physical := strings.Split(strings.TrimSuffix(string(data), "\r\n"), "\r\n")
+		if test.lines > 0 && len(physical) != test.lines {
+			t.Errorf("%v: %v lines, expected %v", test.name, len(physical), test.lines)
+		}
+		for n, line := range physical {
+			if len(line) > 75 {
+				t.Errorf("%v: line %v with %v octets", test.name, n, len(line))
+			}
+			if !utf8.ValidString(line) {
+				t.Errorf("%v: line %v splits a character: %q", test.name, n, line)
+			}
+			if n > 0 && !strings.HasPrefix(line, " ") {
+				t.Errorf("%v: continuation line %v without leading space", test.name, n)
+			}
+			// the fold uses the whole line but for the last one
+			if n < len(physical)-1 && len(line) < 72 {
+				t.Errorf("%v: line %v folded at %v octets", test.name, n, len(line))
+			}
+		}
+		if unfolded := unfold(data); len(unfolded) != 1 || unfolded[0] != "SUMMARY:"+test.value {
+			t.Errorf("%v: unfolded to %q", test.name, unfolded)
+		}
+	}
+}
+
+// vevents splits the unfolded calendar on its VEVENT components, each one a
+// map of property to values
+func vevents(data []byte) (map[string][]string, []map[string][]string) {
+	calendar := make(map[string][]string)
+	events := make([]map[string][]string, 0)
+	var current map[string][]string
+	for _, line := range unfold(data) {
+		name, value, _ := strings.Cut(line, ":")
+		switch {
+		case line == "BEGIN:VEVENT":
+			current = make(map[string][]string)
+		case line == "END:VEVENT":
+			events = append(events, current)
+			current = nil
+		case current != nil:
+			current[name] = append(current[name], value)
+		default:
+			calendar[name] = append(calendar[name], value)
+		}
+	}
+	return calendar, events
+}
+
+func property(component map[string][]string, name string) string {
+	return strings.Join(component[name], "|")
+}
+
+// a weekly event every two weeks with four occurrences: the second one is moved
+// to another venue, the third one is cancelled and then the whole series
+func TestEventCalendar(t *testing.T) {
+	_, s, members := notifyState(1)
+	s.SetEpoch(1)
+	incorporate := func(action actions.Action) {
+		t.Helper()
+		if err := s.Action(action.Serialize()); err != nil {
+			t.Fatalf("could not incorporate %T: %v", action, err)
+		}
+	}
+	incorporate(&actions.CreateCollective{Epoch: 1, Author: members[0], Name: "coletivo", Policy: actions.Policy{Majority: 50, SuperMajority: 50}})
+	brt := time.FixedZone("BRT", -3*3600)
+	create := &actions.CreateEvent{
+		Epoch:           1,
+		Author:          members[0],
+		OnBehalfOf:      "coletivo",
+		StartAt:         time.Date(2024, 6, 3, 19, 0, 0, 0, brt),
+		EstimatedEnd:    time.Date(2024, 6, 3, 21, 0, 0, 0, brt),
+		Description:     "leitura; debate, cafe\nsegunda linha",
+		Venue:           "sede",
+		Public:          true,
+		ManagerMajority: 50,
+		Managers:        []crypto.Token{members[0]},
+		Recurrence:      actions.Recurrence{Frequency: actions.WeeklyRecurrence, Interval: 2, Count: 4},
+	}
+	incorporate(create)
+	hash := create.Hashed()
+	venue := "biblioteca"
+	incorporate(&actions.UpdateEvent{Epoch: 1, Author: members[0], EventHash: hash, Venue: &venue, Occurrence: 2})
+	incorporate(&actions.CancelEvent{Epoch: 1, Author: members[0], Hash: hash, Occurrence: 3})
+
+	calendar, ok := EventCalendar(s, hash, "https://synergy")
+	if !ok || calendar.Method != "PUBLISH" {
+		t.Fatalf("calendar %+v", calendar)
+	}
+	head, events := vevents(ICalendar(calendar))
+	if property(head, "VERSION") != "2.0" || property(head, "METHOD") != "PUBLISH" || property(head, "X-WR-CALNAME") != `coletivo: leitura\; debate\, cafe` {
+		t.Errorf("calendar properties %v", head)
+	}
+	if len(events) != 2 {
+		t.Fatalf("%v VEVENTs, expected the series and the moved occurrence", len(events))
+	}
+	uid := crypto.EncodeHash(hash) + "@synergy"
+	tests := []struct {
+		property string
+		master   string
+		moved    string
+	}{
+		{"UID", uid, uid},
+		{"DTSTART", "20240603T220000Z", "20240617T220000Z"},
+		{"DTEND", "20240604T000000Z", "20240618T000000Z"},
+		{"RRULE", "FREQ=WEEKLY;INTERVAL=2;COUNT=4", ""},
+		{"EXDATE", "20240701T220000Z", ""},
+		{"RECURRENCE-ID", "", "20240617T220000Z"},
+		{"SEQUENCE", "2", "2"},
+		{"DESCRIPTION", `leitura\; debate\, cafe\nsegunda linha`, `leitura\; debate\, cafe\nsegunda linha`},
+		{"LOCATION", "sede", "biblioteca"},
+		{"STATUS", "CONFIRMED", "CONFIRMED"},
+	}
+	for _, test := range tests {
+		if value := property(events[0], test.property); value != test.master {
+			t.Errorf("series %v: %q, expected %q", test.property, value, test.master)
+		}
+		if value := property(events[1], test.property); value != test.moved {
+			t.Errorf("moved occurrence %v: %q, expected %q", test.property, value, test.moved)
+		}
+	}
+
+	// cancelling the series is a new revision sent with method CANCEL
+	incorporate(&actions.CancelEvent{Epoch: 1, Author: members[0], Hash: hash})
+	calendar, _ = EventCalendar(s, hash, "https://synergy")
+	head, events = vevents(ICalendar(calendar))
+	if calendar.Method != "CANCEL" || property(head, "METHOD") != "CANCEL" {
+		t.Errorf("cancelled event sent with method %v", calendar.Method)
+	}
+	for n, event := range events {
+		if property(event, "SEQUENCE") != "3" || property(event, "STATUS") != "CANCELLED" || property(event, "UID") != uid {
+			t.Errorf("VEVENT %v of the cancelled event: %v", n, event)
+		}
+	}
+	// subscribers of the collective learn about the cancellation
+	collective, _ := CollectiveCalendar(s, "coletivo", "https://synergy")
+	if collective.Method != "PUBLISH" || len(collective.Events) != 2 || !collective.Events[0].Cancelled {
+		t.Errorf("collective calendar %+v", collective)
+	}
+	if _, ok := EventCalendar(s, crypto.Hasher([]byte("desconhecido")), "https://synergy"); ok {
+		t.Error("calendar of an unknown event")
+	}
+}
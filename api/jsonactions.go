@@ -13,14 +13,20 @@ import (
 	actions
 		BoardEditor
 		CancelEvent
+		CancelCheckinEvent
 		CheckinEvent
+		Comment
 		CreateBoard
 		CreateCollective
 		CreateEvent
+		CreateJournal
+		Delegate
 		Draft
 		Edit
 		GreetCheckinEvent
 		ImprintStamp
+		JournalEditor
+		JournalIssue
 		Pin
 		React
 		ReleaseDraft
@@ -33,8 +39,11 @@ import (
 */
 
 type Policy struct {
-	Majority      int `json:"majority"`
-	SuperMajority int `json:"superMajority"`
+	Majority      int            `json:"majority"`
+	SuperMajority int            `json:"superMajority"`
+	Kind          byte           `json:"kind,omitempty"`
+	Quorum        int            `json:"quorum,omitempty"`
+	Veto          []crypto.Token `json:"veto,omitempty"`
 }
 
 type Action struct {
@@ -48,6 +57,12 @@ func JSONType(data []byte) string {
 	return a.Action
 }
 
+func JSONID(data []byte) int {
+	var a Action
+	json.Unmarshal(data, &a)
+	return a.ID
+}
+
 type MultiGreetCheckinEvent struct {
 	Action         string
 	ID             int
@@ -125,16 +140,33 @@ func (a BoardEditor) ToAction() ([]actions.Action, error) {
 }
 
 type CancelEvent struct {
-	Action  string      `json:"action"`
-	ID      int         `json:"id"`
-	Reasons string      `json:"reasons"`
-	Hash    crypto.Hash `json:"hash"`
+	Action     string      `json:"action"`
+	ID         int         `json:"id"`
+	Reasons    string      `json:"reasons"`
+	Hash       crypto.Hash `json:"hash"`
+	Occurrence uint64      `json:"occurrence,omitempty"`
 }
 
 func (a CancelEvent) ToAction() ([]actions.Action, error) {
 	action := actions.CancelEvent{
-		Reasons: a.Reasons,
-		Hash:    a.Hash,
+		Reasons:    a.Reasons,
+		Hash:       a.Hash,
+		Occurrence: a.Occurrence,
+	}
+	return []actions.Action{&action}, nil
+}
+
+type CancelCheckinEvent struct {
+	Action    string      `json:"action"`
+	ID        int         `json:"id"`
+	Reasons   string      `json:"reasons"`
+	EventHash crypto.Hash `json:"eventHash"`
+}
+
+func (a CancelCheckinEvent) ToAction() ([]actions.Action, error) {
+	action := actions.CancelCheckinEvent{
+		Reasons:   a.Reasons,
+		EventHash: a.EventHash,
 	}
 	return []actions.Action{&action}, nil
 }
@@ -156,6 +188,27 @@ func (a CheckinEvent) ToAction() ([]actions.Action, error) {
 	return []actions.Action{&action}, nil
 }
 
+type Comment struct {
+	Action     string      `json:"action"`
+	ID         int         `json:"id"`
+	Reasons    string      `json:"reasons"`
+	OnBehalfOf string      `json:"onBehalfOf,omitempty"`
+	Target     crypto.Hash `json:"target"`
+	ReplyTo    crypto.Hash `json:"replyTo,omitempty"`
+	Body       string      `json:"body"`
+}
+
+func (a Comment) ToAction() ([]actions.Action, error) {
+	action := actions.Comment{
+		Reasons:    a.Reasons,
+		OnBehalfOf: a.OnBehalfOf,
+		Target:     a.Target,
+		ReplyTo:    a.ReplyTo,
+		Body:       a.Body,
+	}
+	return []actions.Action{&action}, nil
+}
+
 type CreateBoard struct {
 	Action      string   `json:"action"`
 	ID          int      `json:"id"`
@@ -179,6 +232,27 @@ func (a CreateBoard) ToAction() ([]actions.Action, error) {
 	return []actions.Action{&action}, nil
 }
 
+type CreateJournal struct {
+	Action      string   `json:"action"`
+	ID          int      `json:"id"`
+	Reasons     string   `json:"reasons"`
+	OnBehalfOf  string   `json:"onBehalfOf"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Keywords    []string `json:"keywords"`
+}
+
+func (a CreateJournal) ToAction() ([]actions.Action, error) {
+	action := actions.CreateJournal{
+		Reasons:     a.Reasons,
+		OnBehalfOf:  a.OnBehalfOf,
+		Name:        a.Name,
+		Description: a.Description,
+		Keywords:    a.Keywords,
+	}
+	return []actions.Action{&action}, nil
+}
+
 type CreateCollective struct {
 	Action      string `json:"action"`
 	ID          int    `json:"id"`
@@ -186,6 +260,7 @@ type CreateCollective struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	Policy      Policy `json:"policy"`
+	Expiry      uint64 `json:"expiry,omitempty"`
 }
 
 func (a CreateCollective) ToAction() ([]actions.Action, error) {
@@ -194,6 +269,7 @@ func (a CreateCollective) ToAction() ([]actions.Action, error) {
 		Name:        a.Name,
 		Description: a.Description,
 		Policy:      actions.Policy(a.Policy),
+		Expiry:      a.Expiry,
 	}
 	return []actions.Action{&action}, nil
 }
@@ -211,9 +287,48 @@ type CreateEvent struct {
 	Public          bool           `json:"public"`
 	ManagerMajority int            `json:"managerMajority"`
 	Managers        []crypto.Token `json:"managers,omitempty"`
+	Capacity        uint64         `json:"capacity,omitempty"`
+	RSVPClose       uint64         `json:"rsvpClose,omitempty"` // epoch
+	Recurrence      *Recurrence    `json:"recurrence,omitempty"`
+}
+
+// Recurrence of an event: frequency "daily", "weekly" or "monthly", every
+// interval days, weeks or months, count times.
+type Recurrence struct {
+	Frequency string `json:"frequency"`
+	Interval  int    `json:"interval,omitempty"`
+	Count     int    `json:"count"`
+}
+
+var recurrenceFrequency = map[string]byte{
+	"":        actions.NoRecurrence,
+	"none":    actions.NoRecurrence,
+	"daily":   actions.DailyRecurrence,
+	"weekly":  actions.WeeklyRecurrence,
+	"monthly": actions.MonthlyRecurrence,
+}
+
+func (r *Recurrence) toRecurrence() (actions.Recurrence, error) {
+	if r == nil {
+		return actions.Recurrence{}, nil
+	}
+	frequency, ok := recurrenceFrequency[r.Frequency]
+	if !ok || r.Interval < 0 || r.Interval > 255 || r.Count < 0 {
+		return actions.Recurrence{}, actions.ErrInvalidRecurrence
+	}
+	recurrence := actions.Recurrence{
+		Frequency: frequency,
+		Interval:  byte(r.Interval),
+		Count:     uint64(r.Count),
+	}
+	return recurrence, recurrence.Validate()
 }
 
 func (a CreateEvent) ToAction() ([]actions.Action, error) {
+	recurrence, err := a.Recurrence.toRecurrence()
+	if err != nil {
+		return nil, err
+	}
 	action := actions.CreateEvent{
 		Reasons:         a.Reasons,
 		OnBehalfOf:      a.OnBehalfOf,
@@ -225,6 +340,9 @@ func (a CreateEvent) ToAction() ([]actions.Action, error) {
 		Public:          a.Public,
 		ManagerMajority: byte(a.ManagerMajority),
 		Managers:        a.Managers,
+		Capacity:        a.Capacity,
+		RSVPClose:       a.RSVPClose,
+		Recurrence:      recurrence,
 	}
 	return []actions.Action{&action}, nil
 }
@@ -233,7 +351,7 @@ type Draft struct {
 	Action        string         `json:"action"`
 	ID            int            `json:"id"`
 	Reasons       string         `json:"reasons"`
-	OnBehalfOf    string         `json:"onBeahlfOf,omitempty"`
+	OnBehalfOf    string         `json:"onBehalfOf,omitempty"`
 	CoAuthors     []crypto.Token `json:"coAuthors,omitempty"`
 	Policy        *Policy        `json:"policy"`
 	Title         string         `json:"title"`
@@ -278,7 +396,7 @@ type Edit struct {
 	Action      string         `json:"action"`
 	ID          int            `json:"id"`
 	Reasons     string         `json:"reasons"`
-	OnBehalfOf  string         `json:"onBeahlfOf,omitempty"`
+	OnBehalfOf  string         `json:"onBehalfOf,omitempty"`
 	CoAuthors   []crypto.Token `json:"coAuthors,omitempty"`
 	EditedDraft crypto.Hash    `json:"editedDraft"`
 	ContentType string         `json:"contentType"`
@@ -313,7 +431,7 @@ type ImprintStamp struct {
 	Action     string      `json:"action"`
 	ID         int         `json:"id"`
 	Reasons    string      `json:"reasons"`
-	OnBehalfOf string      `json:"onBeahlfOf,omitempty"`
+	OnBehalfOf string      `json:"onBehalfOf,omitempty"`
 	Hash       crypto.Hash `json:"hash"`
 }
 
@@ -326,6 +444,46 @@ func (a ImprintStamp) ToAction() ([]actions.Action, error) {
 	return []actions.Action{&action}, nil
 }
 
+type JournalEditor struct {
+	Action  string       `json:"action"`
+	ID      int          `json:"id"`
+	Reasons string       `json:"reasons"`
+	Journal string       `json:"journal"`
+	Editor  crypto.Token `json:"editor"`
+	Insert  bool         `json:"insert"`
+}
+
+func (a JournalEditor) ToAction() ([]actions.Action, error) {
+	action := actions.JournalEditor{
+		Reasons: a.Reasons,
+		Journal: a.Journal,
+		Editor:  a.Editor,
+		Insert:  a.Insert,
+	}
+	return []actions.Action{&action}, nil
+}
+
+type JournalIssue struct {
+	Action      string        `json:"action"`
+	ID          int           `json:"id"`
+	Reasons     string        `json:"reasons"`
+	Journal     string        `json:"journal"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	Drafts      []crypto.Hash `json:"drafts"`
+}
+
+func (a JournalIssue) ToAction() ([]actions.Action, error) {
+	action := actions.JournalIssue{
+		Reasons:     a.Reasons,
+		Journal:     a.Journal,
+		Title:       a.Title,
+		Description: a.Description,
+		Drafts:      a.Drafts,
+	}
+	return []actions.Action{&action}, nil
+}
+
 type Pin struct {
 	Action  string      `json:"action"`
 	ID      int         `json:"id"`
@@ -349,7 +507,7 @@ type React struct {
 	Action     string      `json:"action"`
 	ID         int         `json:"id"`
 	Reasons    string      `json:"reasons"`
-	OnBehalfOf string      `json:"onBeahlfOf,omitempty"`
+	OnBehalfOf string      `json:"onBehalfOf,omitempty"`
 	Hash       crypto.Hash `json:"hash"`
 	Reaction   byte        `json:"reaction"`
 }
@@ -383,7 +541,7 @@ type RemoveMember struct {
 	Action     string       `json:"action"`
 	ID         int          `json:"id"`
 	Reasons    string       `json:"reasons"`
-	OnBehalfOf string       `json:"onBeahlfOf,omitempty"`
+	OnBehalfOf string       `json:"onBehalfOf,omitempty"`
 	Member     crypto.Token `json:"member"`
 }
 
@@ -396,6 +554,27 @@ func (a RemoveMember) ToAction() ([]actions.Action, error) {
 	return []actions.Action{&action}, nil
 }
 
+type Delegate struct {
+	Action     string       `json:"action"`
+	ID         int          `json:"id"`
+	Reasons    string       `json:"reasons"`
+	Collective string       `json:"collective"`
+	Delegate   crypto.Token `json:"delegate"`
+	Kinds      []byte       `json:"kinds,omitempty"`
+	Revoke     bool         `json:"revoke,omitempty"`
+}
+
+func (a Delegate) ToAction() ([]actions.Action, error) {
+	action := actions.Delegate{
+		Reasons:    a.Reasons,
+		Collective: a.Collective,
+		Delegate:   a.Delegate,
+		Kinds:      a.Kinds,
+		Revoke:     a.Revoke,
+	}
+	return []actions.Action{&action}, nil
+}
+
 type RequestMembership struct {
 	Action     string `json:"action"`
 	ID         int    `json:"id"`
@@ -442,6 +621,7 @@ type UpdateCollective struct {
 	Description   *string `json:"description,omitempty"`
 	Majority      *byte   `json:"majority,omitempty"`
 	SuperMajority *byte   `json:"superMajority,omitempty"`
+	Expiry        *uint64 `json:"expiry,omitempty"`
 }
 
 func (a UpdateCollective) ToAction() ([]actions.Action, error) {
@@ -451,6 +631,7 @@ func (a UpdateCollective) ToAction() ([]actions.Action, error) {
 		Description:   a.Description,
 		Majority:      a.Majority,
 		SuperMajority: a.SuperMajority,
+		Expiry:        a.Expiry,
 	}
 	return []actions.Action{&action}, nil
 }
@@ -460,12 +641,17 @@ type UpdateEvent struct {
 	ID              int             `json:"id"`
 	Reasons         string          `json:"reasons"`
 	EventHash       crypto.Hash     `json:"eventHash"`
+	StartAt         *time.Time      `json:"startAt,omitempty"`
+	EstimatedEnd    *time.Time      `json:"estimatedEnd,omitempty"`
 	Description     *string         `json:"description,omitempty"`
 	Venue           *string         `json:"venue,omitempty"`
 	Open            *bool           `json:"open,omitempty"`
 	Public          *bool           `json:"public,omitempty"`
 	ManagerMajority *byte           `json:"managerMajority,omitempty"`
 	Managers        *[]crypto.Token `json:"managers,omitempty"`
+	Capacity        *uint64         `json:"capacity,omitempty"`
+	RSVPClose       *uint64         `json:"rsvpClose,omitempty"`  // epoch
+	Occurrence      uint64          `json:"occurrence,omitempty"` // apenas uma ocorrencia
 }
 
 func (a UpdateEvent) ToAction() ([]actions.Action, error) {
@@ -474,15 +660,20 @@ func (a UpdateEvent) ToAction() ([]actions.Action, error) {
 	// 	*byteMajority = byte(*a.ManagerMajority)
 	// }
 	action := actions.UpdateEvent{
-		Reasons:     a.Reasons,
-		EventHash:   a.EventHash,
-		Description: a.Description,
-		Venue:       a.Venue,
-		Open:        a.Open,
-		Public:      a.Public,
+		Reasons:      a.Reasons,
+		EventHash:    a.EventHash,
+		StartAt:      a.StartAt,
+		EstimatedEnd: a.EstimatedEnd,
+		Description:  a.Description,
+		Venue:        a.Venue,
+		Open:         a.Open,
+		Public:       a.Public,
 		// ManagerMajority: byteMajority,
 		ManagerMajority: a.ManagerMajority,
 		Managers:        a.Managers,
+		Capacity:        a.Capacity,
+		RSVPClose:       a.RSVPClose,
+		Occurrence:      a.Occurrence,
 	}
 	return []actions.Action{&action}, nil
 }
@@ -493,13 +684,17 @@ type Vote struct {
 	Reasons string      `json:"reasons,omitempty"`
 	Hash    crypto.Hash `json:"hash"`
 	Approve bool        `json:"approve"`
+	Abstain bool        `json:"abstain,omitempty"`
+	Retract bool        `json:"retract,omitempty"`
 }
 
 func (a Vote) ToAction() ([]actions.Action, error) {
 	action := actions.Vote{
 		Reasons: a.Reasons,
 		Hash:    a.Hash,
-		Approve: a.Approve,
+		Approve: a.Approve && !a.Abstain && !a.Retract,
+		Abstain: a.Abstain && !a.Retract,
+		Retract: a.Retract,
 	}
 	return []actions.Action{&action}, nil
 }
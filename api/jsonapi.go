@@ -0,0 +1,391 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+// JSON interface versionada em /api/v1/. As acoes aceitam os mesmos structs de
+// jsonactions.go (o campo "action" define o tipo) e as consultas devolvem as
+// mesmas views usadas nos templates html.
+
+const apiV1Path = "/api/v1/"
+
+// maximum size of a JSON request body (drafts and edits carry the file)
+const maxJSONBodySize = 8 << 20
+
+// JSONAction is implemented by every struct on jsonactions.go
+type JSONAction interface {
+	ToAction() ([]actions.Action, error)
+}
+
+type APIError struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type APIErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+type SubmittedActions struct {
+	Action   string            `json:"action"`
+	ID       int               `json:"id"`
+	Epoch    uint64            `json:"epoch"`
+	Hashes   []crypto.Hash     `json:"hashes"`
+	Receipts []uint64          `json:"receipts"` // acompanhados em myactions
+	Results  []SubmittedAction `json:"results"`  // um por acao, na ordem
+}
+
+// SubmittedAction is the result of each action of a request: either sent with
+// its hash and receipt or not sent with the error.
+type SubmittedAction struct {
+	Hash    crypto.Hash `json:"hash"`
+	Receipt uint64      `json:"receipt"`
+	Error   string      `json:"error,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println(err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, APIErrorResponse{Error: APIError{Status: status, Code: code, Message: message}})
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed", fmt.Sprintf("only %v is allowed", allowed))
+}
+
+// Author for the json api: the session cookie or the same session value as a
+// bearer token (for clients without a cookie jar)
+func (a *AttorneyGeneral) apiAuthor(r *http.Request) crypto.Token {
+	if author := a.Author(r); author != crypto.ZeroToken {
+		return author
+	}
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return crypto.ZeroToken
+	}
+	bearer := strings.TrimPrefix(header, "Bearer ")
+	if token, ok := a.session.Get(bearer); ok {
+		return token
+	}
+	return crypto.ZeroToken
+}
+
+// ParseJSONAction decodes a JSON body into the struct of jsonactions.go given
+// by its "action" field
+func ParseJSONAction(data []byte) (string, JSONAction, error) {
+	kind := JSONType(data)
+	var action JSONAction
+	switch kind {
+	case "BoardEditor":
+		action = &BoardEditor{}
+	case "CancelEvent":
+		action = &CancelEvent{}
+	case "CheckinEvent":
+		action = &CheckinEvent{}
+	case "CancelCheckinEvent":
+		action = &CancelCheckinEvent{}
+	case "Comment":
+		action = &Comment{}
+	case "CreateBoard":
+		action = &CreateBoard{}
+	case "CreateCollective":
+		action = &CreateCollective{}
+	case "CreateEvent":
+		action = &CreateEvent{}
+	case "CreateJournal":
+		action = &CreateJournal{}
+	case "Delegate":
+		action = &Delegate{}
+	case "Draft":
+		action = &Draft{}
+	case "Edit":
+		action = &Edit{}
+	case "GreetCheckinEvent":
+		action = &GreetCheckinEvent{}
+	case "MultiGreetCheckinEvent":
+		action = &MultiGreetCheckinEvent{}
+	case "ImprintStamp":
+		action = &ImprintStamp{}
+	case "JournalEditor":
+		action = &JournalEditor{}
+	case "JournalIssue":
+		action = &JournalIssue{}
+	case "Pin":
+		action = &Pin{}
+	case "React":
+		action = &React{}
+	case "ReleaseDraft", "Release":
+		action = &ReleaseDraft{}
+	case "RemoveMember":
+		action = &RemoveMember{}
+	case "RequestMembership":
+		action = &RequestMembership{}
+	case "UpdateBoard":
+		action = &UpdateBoard{}
+	case "UpdateCollective":
+		action = &UpdateCollective{}
+	case "UpdateEvent":
+		action = &UpdateEvent{}
+	case "Vote":
+		action = &Vote{}
+	case "":
+		return kind, nil, errors.New("missing action field")
+	default:
+		return kind, nil, fmt.Errorf("unknown action %v", kind)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(action); err != nil {
+		return kind, nil, fmt.Errorf("invalid %v: %v", kind, err)
+	}
+	return kind, action, nil
+}
+
+// hash of the synergy action carried by a dressed breeze instruction, that is
+// the hash the state uses to refer to the action (see DressAction)
+func dressedHash(dressed []byte) crypto.Hash {
+	synergy := BreezeToSynergy(dressed)
+	if synergy == nil {
+		return crypto.ZeroHash
+	}
+	if action := actions.ParseAction(synergy); action != nil {
+		return action.Hashed()
+	}
+	return crypto.ZeroHash
+}
+
+func (a *AttorneyGeneral) APIv1Handler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, apiV1Path)
+	path = strings.TrimSuffix(path, "/")
+	resource, item, _ := strings.Cut(path, "/")
+	if resource == "actions" && item == "" {
+		a.apiSubmitAction(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	item, _ = url.PathUnescape(item)
+	author := a.apiAuthor(r)
+	var view any
+	switch resource {
+	case "boards":
+		if item == "" {
+			view = BoardsFromState(a.state)
+		} else if detail := BoardDetailFromState(a.state, item, author); detail != nil {
+			view = detail
+		}
+	case "journals":
+		journal, sub, _ := strings.Cut(item, "/")
+		if item == "" {
+			view = JournalsFromState(a.state)
+		} else if sub == "" {
+			if detail := JournalDetailFromState(a.state, journal, author); detail != nil {
+				view = detail
+			}
+		} else if number, err := strconv.Atoi(sub); err == nil {
+			if detail := IssueOfJournal(a.state, journal, number); detail != nil {
+				view = detail
+			}
+		}
+	case "issues":
+		if item != "" {
+			if detail := IssueDetailFromState(a.state, crypto.DecodeHash(item)); detail != nil {
+				view = detail
+			}
+		}
+	case "collectives":
+		if item == "" {
+			view = CollectivesFromState(a.state)
+		} else if detail := CollectiveDetailFromState(a.state, a.indexer, item, author); detail != nil {
+			view = detail
+		}
+	case "drafts":
+		draft, sub, _ := strings.Cut(item, "/")
+		if item == "" {
+			view = DraftsFromState(a.state)
+		} else if sub == "edits" {
+			view = EditsFromState(a.state, crypto.DecodeHash(draft))
+		} else if sub == "" {
+			if detail := DraftDetailFromState(a.state, a.indexer, crypto.DecodeHash(draft), author, a.genesisTime); detail != nil {
+				view = detail
+			}
+		}
+	case "edits":
+		if item != "" {
+			if detail := EditDetailFromState(a.state, a.indexer, crypto.DecodeHash(item), author); detail != nil {
+				view = detail
+			}
+		}
+	case "events":
+		if item == "" {
+			view = EventsFromState(a.state)
+		} else if detail := EventDetailFromState(a.state, a.indexer, crypto.DecodeHash(item), author, a.ephemeralprv); detail != nil {
+			view = detail
+		}
+	case "members":
+		if item == "" {
+			view = MembersFromState(a.state)
+		} else if detail := MemberViewFromState(a.state, a.indexer, item); detail != nil {
+			view = detail
+		}
+	case "votes":
+		if item == "" {
+			if author == crypto.ZeroToken {
+				writeJSONError(w, http.StatusUnauthorized, "unauthorized", "missing or expired session")
+				return
+			}
+			view = VotesFromState(a.state, a.indexer, author)
+		} else if detail := DetailedVoteFromState(a.state, a.indexer, crypto.DecodeHash(item), a.genesisTime, r.URL.Path); detail != nil {
+			view = detail
+		}
+	case "diff":
+		from, to, _ := strings.Cut(item, "/")
+		detail, err := DiffFromState(a.state, crypto.DecodeHash(from), crypto.DecodeHash(to), r.URL.Query().Get("mode"), a.genesisTime)
+		if errors.Is(err, ErrDiffVersionNotFound) {
+			writeJSONError(w, http.StatusNotFound, "not_found", err.Error())
+			return
+		} else if err != nil {
+			writeJSONError(w, http.StatusUnprocessableEntity, "not_diffable", err.Error())
+			return
+		}
+		view = detail
+	case "merge":
+		r.ParseForm()
+		detail, err := MergeFromState(a.state, crypto.DecodeHash(item), formToEdits(r), author, a.genesisTime)
+		if errors.Is(err, ErrDiffVersionNotFound) {
+			writeJSONError(w, http.StatusNotFound, "not_found", err.Error())
+			return
+		} else if err != nil {
+			writeJSONError(w, http.StatusUnprocessableEntity, "not_mergeable", err.Error())
+			return
+		}
+		view = detail
+	case "reputation":
+		view = LeaderboardFromIndex(a.state, a.indexer)
+	case "news":
+		view = NewActionsFromState(a.state, a.indexer, a.genesisTime)
+	case "health":
+		view = a.chainHealth()
+	case "search":
+		view = SearchFromIndex(a.indexer, r.URL.Query().Get("q"), a.genesisTime)
+	case "pending", "updates", "connections", "mymedia", "myevents", "myactions":
+		if author == crypto.ZeroToken {
+			writeJSONError(w, http.StatusUnauthorized, "unauthorized", "missing or expired session")
+			return
+		}
+		switch resource {
+		case "pending":
+			view = PendingActionsFromState(a.state, a.indexer, author, a.genesisTime)
+		case "updates":
+			view = UpdatesViewFromState(a.state, a.indexer, author, a.genesisTime)
+		case "connections":
+			view = ConnectionsFromState(a.state, a.indexer, author, a.genesisTime)
+		case "mymedia":
+			view = MyMediaFromState(a.state, a.indexer, author)
+		case "myevents":
+			view = MyEventsFromState(a.state, a.indexer, author, a.ephemeralprv)
+		case "myactions":
+			view = MyActionsFromMempool(a.state, a.mempool, author)
+		}
+	}
+	if view == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", fmt.Sprintf("%v not found", r.URL.Path))
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+// POST /api/v1/actions: dress and send the actions of a JSON body, answering
+// with the hashes by which they will be known to the state once incorporated
+func (a *AttorneyGeneral) apiSubmitAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	author := a.apiAuthor(r)
+	if author == crypto.ZeroToken {
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized", "missing or expired session")
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxJSONBodySize+1))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+	if len(data) > maxJSONBodySize {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, "body_too_large", fmt.Sprintf("body larger than %v bytes", maxJSONBodySize))
+		return
+	}
+	kind, action, err := ParseJSONAction(data)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_action", err.Error())
+		return
+	}
+	// o checkin eh sempre cifrado para a chave efemera do servidor
+	if checkin, ok := action.(*CheckinEvent); ok && checkin.EphemeralToken == crypto.ZeroToken {
+		checkin.EphemeralToken = a.ephemeralpub
+	}
+	actionArray, err := action.ToAction()
+	if err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, "invalid_action", err.Error())
+		return
+	}
+	if len(actionArray) == 0 {
+		writeJSONError(w, http.StatusUnprocessableEntity, "empty_action", fmt.Sprintf("%v produced no actions", kind))
+		return
+	}
+	epoch := a.state.Epoch
+	submissions, err := a.Submit(actionArray, author)
+	if err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, "rejected_action", err.Error())
+		return
+	}
+	response := SubmittedActions{
+		Action:   kind,
+		ID:       JSONID(data),
+		Epoch:    epoch,
+		Hashes:   make([]crypto.Hash, len(submissions)),
+		Receipts: make([]uint64, len(submissions)),
+		Results:  make([]SubmittedAction, len(submissions)),
+	}
+	sent := 0
+	for n, submission := range submissions {
+		if submission == nil {
+			response.Results[n].Error = "could not dress action"
+			continue
+		}
+		sent++
+		response.Hashes[n] = submission.Hash
+		response.Receipts[n] = submission.ID
+		response.Results[n] = SubmittedAction{Hash: submission.Hash, Receipt: submission.ID}
+	}
+	// o que foi enviado nao volta atras: o erro de cada acao vai nos resultados
+	if sent == 0 {
+		writeJSONError(w, http.StatusInternalServerError, "dress_failed", "could not dress action")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, response)
+}
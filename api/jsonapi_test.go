@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+// jsonAPI returns a test server for the json api of an attorney with the
+// member on the session "sessao"
+func jsonAPI(t *testing.T) (*httptest.Server, *AttorneyGeneral) {
+	t.Helper()
+	attorney, member := testAttorney()
+	attorney.session = &CookieStore{session: map[string]crypto.Token{"sessao": member}}
+	server := httptest.NewServer(http.HandlerFunc(attorney.APIv1Handler))
+	t.Cleanup(server.Close)
+	return server, attorney
+}
+
+func apiRequest(t *testing.T, server *httptest.Server, method, path, session, body string) *http.Response {
+	t.Helper()
+	request, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if session != "" {
+		request.Header.Set("Authorization", "Bearer "+session)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("%v %v: %v", method, path, err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+	return response
+}
+
+func TestAPISubmitAction(t *testing.T) {
+	server, attorney := jsonAPI(t)
+	create := `{"action":"CreateCollective","id":7,"name":"coletivo","description":"teste","policy":{"majority":50,"superMajority":50}}`
+	tests := []struct {
+		name    string
+		method  string
+		session string
+		body    string
+		status  int
+		code    string
+	}{
+		{"without session", http.MethodPost, "", create, http.StatusUnauthorized, "unauthorized"},
+		{"expired session", http.MethodPost, "outra", create, http.StatusUnauthorized, "unauthorized"},
+		{"get", http.MethodGet, "sessao", "", http.StatusMethodNotAllowed, ""},
+		{"invalid json", http.MethodPost, "sessao", `{"action":`, http.StatusBadRequest, "invalid_action"},
+		{"unknown action", http.MethodPost, "sessao", `{"action":"Desconhecida"}`, http.StatusBadRequest, "invalid_action"},
+		{"unknown field", http.MethodPost, "sessao", `{"action":"CreateCollective","campo":1}`, http.StatusBadRequest, "invalid_action"},
+		{"rejected action", http.MethodPost, "sessao", `{"action":"Vote","hash":"` + crypto.ZeroHash.String() + `","approve":true}`, http.StatusUnprocessableEntity, "rejected_action"},
+	}
+	for _, test := range tests {
+		response := apiRequest(t, server, test.method, "/api/v1/actions", test.session, test.body)
+		if response.StatusCode != test.status {
+			t.Errorf("%v: status %v, expected %v", test.name, response.StatusCode, test.status)
+			continue
+		}
+		if test.code != "" {
+			var apiError APIErrorResponse
+			json.NewDecoder(response.Body).Decode(&apiError)
+			if apiError.Error.Code != test.code || apiError.Error.Status != test.status {
+				t.Errorf("%v: error %v, expected %v", test.name, apiError, test.code)
+			}
+		}
+	}
+	if len(attorney.gateway) != 0 {
+		t.Fatal("rejected request sent")
+	}
+	response := apiRequest(t, server, http.MethodPost, "/api/v1/actions", "sessao", create)
+	if response.StatusCode != http.StatusAccepted {
+		t.Fatalf("valid action: status %v", response.StatusCode)
+	}
+	var submitted SubmittedActions
+	if err := json.NewDecoder(response.Body).Decode(&submitted); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if submitted.Action != "CreateCollective" || submitted.ID != 7 || len(submitted.Results) != 1 || len(attorney.gateway) != 1 {
+		t.Fatalf("unexpected response %+v", submitted)
+	}
+	result := submitted.Results[0]
+	if result.Error != "" || result.Hash != submitted.Hashes[0] || result.Receipt != submitted.Receipts[0] {
+		t.Errorf("result %+v does not match the hashes and receipts", result)
+	}
+	// the hash is the one the state will know the dressed action by
+	if dressed := <-attorney.gateway; result.Hash == crypto.ZeroHash || dressedHash(dressed) != result.Hash {
+		t.Errorf("hash %v is not the hash of the sent action", result.Hash)
+	}
+}
+
+func TestAPIViews(t *testing.T) {
+	server, _ := jsonAPI(t)
+	tests := []struct {
+		name    string
+		path    string
+		session string
+		status  int
+	}{
+		{"members", "/api/v1/members", "", http.StatusOK},
+		{"member", "/api/v1/members/membro", "", http.StatusOK},
+		{"unknown board", "/api/v1/boards/desconhecido", "", http.StatusNotFound},
+		{"collectives", "/api/v1/collectives", "", http.StatusOK},
+		{"health", "/api/v1/health", "", http.StatusOK},
+		{"unknown resource", "/api/v1/desconhecido", "", http.StatusNotFound},
+		{"votes without session", "/api/v1/votes", "", http.StatusUnauthorized},
+		{"actions without session", "/api/v1/myactions", "", http.StatusUnauthorized},
+		{"actions of the session", "/api/v1/myactions", "sessao", http.StatusOK},
+	}
+	for _, test := range tests {
+		response := apiRequest(t, server, http.MethodGet, test.path, test.session, "")
+		if response.StatusCode != test.status {
+			t.Errorf("%v: status %v, expected %v", test.name, response.StatusCode, test.status)
+		}
+		if content := response.Header.Get("Content-Type"); !strings.HasPrefix(content, "application/json") {
+			t.Errorf("%v: content type %v", test.name, content)
+		}
+	}
+	response := apiRequest(t, server, http.MethodGet, "/api/v1/health", "", "")
+	var health ChainHealth
+	if err := json.NewDecoder(response.Body).Decode(&health); err != nil || !health.Live || health.Epoch != 5 {
+		t.Errorf("health %+v, %v", health, err)
+	}
+	if response := apiRequest(t, server, http.MethodPost, "/api/v1/members", "", ""); response.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("post to a view: status %v", response.StatusCode)
+	}
+}
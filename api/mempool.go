@@ -0,0 +1,153 @@
+package api
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+// Status of an action submitted to the gateway.
+const (
+	Submitted byte = iota // enviada, aguardando inclusao
+	Included              // incluida na chain e aceita pelo estado
+	Rejected              // incluida na chain mas recusada pelo estado
+	Expired               // nao incluida no prazo, deve ser enviada de novo
+)
+
+// An action not included within SubmissionTimeout epochs is reported as
+// expired. It is not sent again: the epoch it was signed for is stale by then
+// and the member resubmits it.
+const SubmissionTimeout = 30
+
+// MaxReceipts is the number of submissions kept for each member.
+const MaxReceipts = 100
+
+// Submission is the receipt of a dressed action sent to the gateway.
+type Submission struct {
+	ID       uint64
+	Author   crypto.Token
+	Kind     byte
+	Hash     crypto.Hash // hash do objeto, como devolvido pelo Send
+	Epoch    uint64      // epoca do envio
+	Deadline uint64      // epoca limite para a inclusao
+	Status   byte
+	Reason   string // erro do State.Action quando rejeitada
+	Updated  uint64
+	key      crypto.Hash
+}
+
+// Mempool keeps the actions sent to the gateway until they are included in a
+// block. Actions are matched by the hash of their synergy bytes, the same on
+// the dressed action and on the block.
+type Mempool struct {
+	mu       sync.Mutex
+	next     uint64
+	inflight map[crypto.Hash]*Submission
+	byAuthor map[crypto.Token][]*Submission
+}
+
+func NewMempool() *Mempool {
+	return &Mempool{
+		next:     1,
+		inflight: make(map[crypto.Hash]*Submission),
+		byAuthor: make(map[crypto.Token][]*Submission),
+	}
+}
+
+// submissionKey of a synergy action as found on the blocks.
+func submissionKey(synergy []byte) crypto.Hash {
+	return crypto.Hasher(synergy)
+}
+
+// Add registers a dressed action sent at epoch.
+func (m *Mempool) Add(dressed []byte, author crypto.Token, epoch uint64) *Submission {
+	synergy := BreezeToSynergy(dressed)
+	submission := &Submission{
+		Author:   author,
+		Kind:     actions.ActionKind(synergy),
+		Hash:     dressedHash(dressed),
+		Epoch:    epoch,
+		Deadline: epoch + SubmissionTimeout,
+		Status:   Submitted,
+		Updated:  epoch,
+		key:      submissionKey(synergy),
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if pending, ok := m.inflight[submission.key]; ok {
+		// mesma acao enviada de novo na mesma epoca: um unico recibo
+		return pending
+	}
+	submission.ID = m.next
+	m.next++
+	m.inflight[submission.key] = submission
+	receipts := append(m.byAuthor[author], submission)
+	if len(receipts) > MaxReceipts {
+		// so recibos concluidos saem: os enviados seguem aguardando o bloco
+		for n, old := range receipts {
+			if old.Status != Submitted {
+				receipts = append(receipts[:n], receipts[n+1:]...)
+				break
+			}
+		}
+	}
+	m.byAuthor[author] = receipts
+	return submission
+}
+
+// Incorporated matches an action of a block against the submissions. err is
+// the result of State.Action for the action.
+func (m *Mempool) Incorporated(synergy []byte, epoch uint64, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := submissionKey(synergy)
+	submission, ok := m.inflight[key]
+	if !ok {
+		return
+	}
+	delete(m.inflight, key)
+	submission.Updated = epoch
+	if err != nil {
+		submission.Status = Rejected
+		submission.Reason = err.Error()
+	} else {
+		submission.Status = Included
+	}
+}
+
+// Expire marks as expired the submissions past their deadline at epoch and
+// returns them. If the gateway is not connected the deadlines are postponed
+// instead: the actions are still waiting for the connection.
+func (m *Mempool) Expire(epoch uint64, connected bool) []*Submission {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expired := make([]*Submission, 0)
+	for key, submission := range m.inflight {
+		if epoch <= submission.Deadline {
+			continue
+		}
+		if !connected {
+			submission.Deadline = epoch + SubmissionTimeout
+			continue
+		}
+		submission.Updated = epoch
+		submission.Status = Expired
+		delete(m.inflight, key)
+		expired = append(expired, submission)
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].ID < expired[j].ID })
+	return expired
+}
+
+// Receipts returns a copy of the submissions of the author, oldest first.
+func (m *Mempool) Receipts(author crypto.Token) []Submission {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	receipts := make([]Submission, len(m.byAuthor[author]))
+	for n, submission := range m.byAuthor[author] {
+		receipts[n] = *submission
+	}
+	return receipts
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+var (
+	testAuthor = crypto.Token{1}
+	testOther  = crypto.Token{2}
+)
+
+// testAction returns the synergy bytes of a distinct action and the same
+// action dressed for the gateway.
+func testAction(n int, epoch uint64) ([]byte, []byte) {
+	synergy := (&actions.Signin{Epoch: epoch, Author: testAuthor, Reasons: fmt.Sprintf("acao %v", n)}).Serialize()
+	dressed := SynergyToBreeze(synergy, epoch)
+	return synergy, append(dressed, make([]byte, DressedTailSize)...)
+}
+
+func TestMempoolIncorporated(t *testing.T) {
+	m := NewMempool()
+	synergy, dressed := testAction(1, 5)
+	submission := m.Add(dressed, testAuthor, 5)
+	if submission.ID != 1 || submission.Status != Submitted || submission.Kind != actions.ASignIn || submission.Deadline != 5+SubmissionTimeout {
+		t.Errorf("unexpected receipt %+v", submission)
+	}
+	if again := m.Add(dressed, testAuthor, 5); again != submission {
+		t.Error("action sent twice with two receipts")
+	}
+	// unknown action
+	unknown, _ := testAction(2, 5)
+	m.Incorporated(unknown, 6, nil)
+	m.Incorporated(synergy, 6, nil)
+	receipts := m.Receipts(testAuthor)
+	if len(receipts) != 1 || receipts[0].Status != Included || receipts[0].Updated != 6 {
+		t.Errorf("receipts %+v", receipts)
+	}
+
+	synergy, dressed = testAction(3, 6)
+	m.Add(dressed, testAuthor, 6)
+	m.Incorporated(synergy, 7, errors.New("invalid"))
+	receipts = m.Receipts(testAuthor)
+	if len(receipts) != 2 || receipts[1].Status != Rejected || receipts[1].Reason != "invalid" {
+		t.Errorf("rejection not recorded: %+v", receipts)
+	}
+	// the same bytes again are not a submission anymore
+	m.Incorporated(synergy, 8, nil)
+	if m.Receipts(testAuthor)[1].Status != Rejected {
+		t.Error("concluded receipt changed")
+	}
+}
+
+func TestMempoolExpire(t *testing.T) {
+	m := NewMempool()
+	late, dressed := testAction(1, 10)
+	m.Add(dressed, testAuthor, 10)
+	_, dressed = testAction(2, 20)
+	m.Add(dressed, testOther, 20)
+
+	if expired := m.Expire(10+SubmissionTimeout, true); len(expired) != 0 {
+		t.Errorf("expired at the deadline: %+v", expired)
+	}
+	// while disconnected the actions wait for the connection
+	if expired := m.Expire(11+SubmissionTimeout, false); len(expired) != 0 {
+		t.Errorf("expired while disconnected: %+v", expired)
+	}
+	if deadline := m.Receipts(testAuthor)[0].Deadline; deadline != 11+2*SubmissionTimeout {
+		t.Errorf("deadline %v not postponed", deadline)
+	}
+	expired := m.Expire(21+SubmissionTimeout, true)
+	if len(expired) != 1 || expired[0].Author != testOther || expired[0].Status != Expired {
+		t.Errorf("expired %+v", expired)
+	}
+	expired = m.Expire(12+2*SubmissionTimeout, true)
+	if len(expired) != 1 || expired[0].Author != testAuthor {
+		t.Errorf("expired %+v", expired)
+	}
+	// included only after expiring: the member was already told to resubmit
+	m.Incorporated(late, 13+2*SubmissionTimeout, nil)
+	if receipt := m.Receipts(testAuthor)[0]; receipt.Status != Expired || receipt.Updated != 12+2*SubmissionTimeout {
+		t.Errorf("expired receipt changed: %+v", receipt)
+	}
+}
+
+func TestMempoolReceiptsTrim(t *testing.T) {
+	m := NewMempool()
+	first, dressed := testAction(0, 1)
+	m.Add(dressed, testAuthor, 1)
+	pending, dressed := testAction(1, 1)
+	m.Add(dressed, testAuthor, 1)
+	m.Incorporated(first, 2, nil)
+	for n := 2; n <= MaxReceipts; n++ {
+		_, dressed := testAction(n, 2)
+		m.Add(dressed, testAuthor, 2)
+	}
+	receipts := m.Receipts(testAuthor)
+	if len(receipts) != MaxReceipts || receipts[0].ID != 2 {
+		t.Fatalf("%v receipts starting at %v, expected %v starting at 2", len(receipts), receipts[0].ID, MaxReceipts)
+	}
+	// no concluded receipt left to drop
+	_, dressed = testAction(MaxReceipts+1, 2)
+	m.Add(dressed, testAuthor, 2)
+	if receipts := m.Receipts(testAuthor); len(receipts) != MaxReceipts+1 {
+		t.Errorf("submitted receipt dropped: %v receipts", len(receipts))
+	}
+	// the oldest submission is still waiting for its block
+	m.Incorporated(pending, 3, nil)
+	if receipt := m.Receipts(testAuthor)[0]; receipt.ID != 2 || receipt.Status != Included {
+		t.Errorf("oldest submission not tracked: %+v", receipt)
+	}
+}
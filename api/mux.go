@@ -10,7 +10,6 @@ import (
 	"github.com/freehandle/breeze/crypto"
 
 	"github.com/freehandle/synergy/config"
-	"github.com/freehandle/synergy/social/actions"
 	"github.com/freehandle/synergy/social/index"
 	"github.com/freehandle/synergy/social/state"
 )
@@ -18,12 +17,13 @@ import (
 var templateFiles []string = []string{
 	"main",
 	"boards", "board", "collectives", "collective", "draft", "drafts", "edits", "events",
-	"event", "member", "members", "votes", "newdraft2", "edit",
+	"event", "member", "members", "reputation", "votes", "newdraft2", "edit",
 	"createboard", "votecreateboard", "updateboard", "voteupdateboard", "updateevent",
 	"updatecollective", "voteupdatecollective", "createevent", "voteupdateevent", "editview",
-	"createcollective", "connections", "updates", "news", "pending", "mymedia", "myevents",
+	"createcollective", "connections", "updates", "news", "pending", "mymedia", "myevents", "myactions",
 	"detailedvote", "concludedvote", "votecreateevent", "votecancelevent", "login", "signin", "totalsignin",
-	"forgot", "reset", "resetpassword", "invite",
+	"forgot", "reset", "resetpassword", "invite", "search", "diff", "merge",
+	"journals", "journal", "issue", "createjournal", "votecreatejournal", "votejournalissue",
 }
 
 type ServerConfig struct {
@@ -43,6 +43,9 @@ type ServerConfig struct {
 	ServerName  string
 	Hostname    string
 	Safe        int // optional link to safe for direct onbboarding
+	// state snapshot written every SnapshotInterval epochs (disabled if empty)
+	SnapshotPath     string
+	SnapshotInterval uint64
 }
 
 //type AuthorAction struct {
@@ -69,21 +72,27 @@ func NewGeneralAttorneyServer(cfg ServerConfig) (*AttorneyGeneral, chan error) {
 		pk:      attorneySecret,
 		Token:   cfg.Attorney,
 		wallet:  attorneySecret,
-		pending: make(map[crypto.Hash]actions.Action),
+		mempool: NewMempool(),
 		gateway: cfg.Gateway,
 		state:   cfg.State,
 		indexer: cfg.Indexer,
 		session: cfg.CookieStore,
 		//Mail:    cfg.Mail,
 		//sessionend:   make(map[uint64][]string),
-		genesisTime:  cfg.GenesisTime,
-		ephemeralpub: cfg.Ephemeral,
-		ephemeralprv: ephemeralSecret,
-		serverName:   cfg.ServerName,
-		hostname:     cfg.Hostname,
-		safe:         cfg.Safe,
-		inviteUser:   make(map[crypto.Hash]struct{}),
+		genesisTime:      cfg.GenesisTime,
+		ephemeralpub:     cfg.Ephemeral,
+		ephemeralprv:     ephemeralSecret,
+		serverName:       cfg.ServerName,
+		hostname:         cfg.Hostname,
+		safe:             cfg.Safe,
+		inviteUser:       make(map[crypto.Hash]struct{}),
+		snapshotPath:     cfg.SnapshotPath,
+		snapshotInterval: cfg.SnapshotInterval,
+		snapshotting:     make(chan struct{}, 1),
+		restoredEpoch:    cfg.State.Epoch,
 	}
+	attorney.notifications = NewNotificationHub(cfg.Indexer)
+	cfg.State.SetNotifier(attorney.notifications.Notifier())
 	if cfg.Path == "" {
 		cfg.Path = "./"
 	}
@@ -130,9 +139,13 @@ func NewServer(attorney *AttorneyGeneral, port int, staticPath string, finalize
 	fs := http.FileServer(http.Dir(staticPath))
 	mux.Handle("/static/", http.StripPrefix("/static/", fs)) //
 	mux.HandleFunc("/api", attorney.ApiHandler)
+	mux.HandleFunc(apiV1Path, attorney.APIv1Handler)
 	mux.HandleFunc("/", attorney.MainHandler)
 	mux.HandleFunc("/boards", attorney.BoardsHandler)
 	mux.HandleFunc("/board/", attorney.BoardHandler)
+	mux.HandleFunc("/journals", attorney.JournalsHandler)
+	mux.HandleFunc("/journal/", attorney.JournalHandler)
+	mux.HandleFunc("/issue/", attorney.IssueHandler)
 	mux.HandleFunc("/collectives", attorney.CollectivesHandler)
 	mux.HandleFunc("/collective/", attorney.CollectiveHandler)
 	mux.HandleFunc("/drafts", attorney.DraftsHandler)
@@ -141,18 +154,28 @@ func NewServer(attorney *AttorneyGeneral, port int, staticPath string, finalize
 	mux.HandleFunc("/events", attorney.EventsHandler)
 	mux.HandleFunc("/event/", attorney.EventHandler)
 	mux.HandleFunc("/members", attorney.MembersHandler)
+	mux.HandleFunc("/search", attorney.SearchHandler)
 	mux.HandleFunc("/member/", attorney.MemberHandler)
+	mux.HandleFunc("/reputation", attorney.ReputationHandler)
+	mux.HandleFunc("/citations", attorney.CitationsHandler)
+	mux.HandleFunc("/release/", attorney.ReleaseHandler)
 	// mux.HandleFunc("/votes/", attorney.VotesHandler)
 	mux.HandleFunc("/votes", attorney.VotesHandler)
 	mux.HandleFunc("/newdraft", attorney.NewDraft2Handler)
 	mux.HandleFunc("/edit", attorney.NewEditHandler)
 	mux.HandleFunc("/editview/", attorney.EditViewHandler)
+	mux.HandleFunc("/diff", attorney.DiffHandler)
+	mux.HandleFunc("/diff/", attorney.DiffHandler)
+	mux.HandleFunc("/merge/", attorney.MergeHandler)
 	mux.HandleFunc("/media/", attorney.MediaHandler)
 	mux.HandleFunc("/uploadfile", attorney.UploadHandler)
 	mux.HandleFunc("/createboard", attorney.CreateBoardHandler)
 	mux.HandleFunc("/votecreateboard/", attorney.VoteCreateBoardHandler)
 	mux.HandleFunc("/updateboard/", attorney.UpdateBoardHandler)
 	mux.HandleFunc("/voteupdateboard/", attorney.VoteUpdateBoardHandler)
+	mux.HandleFunc("/createjournal", attorney.CreateJournalHandler)
+	mux.HandleFunc("/votecreatejournal/", attorney.VoteCreateJournalHandler)
+	mux.HandleFunc("/votejournalissue/", attorney.VoteJournalIssueHandler)
 	mux.HandleFunc("/updatecollective/", attorney.UpdateCollectiveHandler)
 	mux.HandleFunc("/voteupdatecollective/", attorney.VoteUpdateCollectiveHandler)
 	mux.HandleFunc("/updateevent/", attorney.UpdateEventHandler)
@@ -161,12 +184,15 @@ func NewServer(attorney *AttorneyGeneral, port int, staticPath string, finalize
 	mux.HandleFunc("/createevent", attorney.CreateEventHandler)
 	mux.HandleFunc("/voteupdateevent/", attorney.VoteUpdateEventHandler)
 	mux.HandleFunc("/news", attorney.NewsHandler)
+	mux.HandleFunc("/news/", attorney.NewsHandler)
 	mux.HandleFunc("/connections/", attorney.ConnectionsHandler)
 	mux.HandleFunc("/updates", attorney.UpdatesHandler)
 	mux.HandleFunc("/pending", attorney.PendingActionsHandler)
 	mux.HandleFunc("/createcollective/", attorney.CreateCollectiveHandler)
 	mux.HandleFunc("/mymedia", attorney.MyMediaHandler)
 	mux.HandleFunc("/myevents", attorney.MyEventsHandler)
+	mux.HandleFunc("/myactions", attorney.MyActionsHandler)
+	mux.HandleFunc("/calendar/", attorney.CalendarHandler)
 	mux.HandleFunc("/detailedvote/", attorney.DetailedVoteHandler)
 	mux.HandleFunc("/concludedvote/", attorney.ConcludedVoteHandler)
 	mux.HandleFunc("/login", attorney.LoginHandler)
@@ -184,6 +210,7 @@ func NewServer(attorney *AttorneyGeneral, port int, staticPath string, finalize
 	mux.HandleFunc("/resetpassword", attorney.ResetPasswordHandler)
 	mux.HandleFunc("/credentialsreset", attorney.CredentialsResetHandler)
 	mux.HandleFunc("/invitenewuser", attorney.InviteNewUserHandler)
+	mux.HandleFunc("/notifications", attorney.NotificationsHandler)
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%v", port),
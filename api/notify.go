@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/index"
+	"github.com/freehandle/synergy/social/state"
+)
+
+// Notificacoes em tempo real: o estado publica no notifier do hub cada objeto
+// afetado por uma acao incorporada e o hub repassa para as sessoes abertas em
+// /notifications (server-sent events) apenas o que interessa ao membro.
+
+const (
+	notifierBufferSize   = 256
+	subscriberBufferSize = 32
+	keepAliveInterval    = 30 * time.Second
+)
+
+type Subscriber struct {
+	token   crypto.Token
+	updates chan state.Updated
+}
+
+type NotificationHub struct {
+	mu          sync.Mutex
+	notifier    state.Notifier
+	indexer     *index.Index
+	subscribers map[*Subscriber]struct{}
+}
+
+func NewNotificationHub(indexer *index.Index) *NotificationHub {
+	hub := &NotificationHub{
+		notifier:    make(state.Notifier, notifierBufferSize),
+		indexer:     indexer,
+		subscribers: make(map[*Subscriber]struct{}),
+	}
+	go hub.run()
+	return hub
+}
+
+// Notifier is the channel to be set on the state with SetNotifier
+func (h *NotificationHub) Notifier() state.Notifier {
+	return h.notifier
+}
+
+func (h *NotificationHub) Subscribe(token crypto.Token) *Subscriber {
+	subscriber := &Subscriber{
+		token:   token,
+		updates: make(chan state.Updated, subscriberBufferSize),
+	}
+	h.mu.Lock()
+	h.subscribers[subscriber] = struct{}{}
+	h.mu.Unlock()
+	return subscriber
+}
+
+func (h *NotificationHub) Unsubscribe(subscriber *Subscriber) {
+	h.mu.Lock()
+	delete(h.subscribers, subscriber)
+	h.mu.Unlock()
+}
+
+func (h *NotificationHub) run() {
+	for update := range h.notifier {
+		h.mu.Lock()
+		interests := make(map[crypto.Token]map[crypto.Hash]struct{})
+		for subscriber := range h.subscribers {
+			// varias sessoes do mesmo membro compartilham os interesses
+			hashes, ok := interests[subscriber.token]
+			if !ok {
+				hashes = h.indexer.Interests(subscriber.token)
+				interests[subscriber.token] = hashes
+			}
+			if _, ok := hashes[update.Hash]; !ok {
+				continue
+			}
+			// sessao lenta perde a notificacao mas nao trava o estado
+			select {
+			case subscriber.updates <- update:
+			default:
+			}
+		}
+		h.mu.Unlock()
+	}
+}
+
+type UpdatedView struct {
+	Action string      `json:"action"`
+	Object string      `json:"object"`
+	Hash   crypto.Hash `json:"hash"`
+}
+
+var notifyActionNames = map[state.Action]string{
+	state.ReactAction:      "react",
+	state.PinAction:        "pin",
+	state.PublishAction:    "publish",
+	state.DraftAction:      "draft",
+	state.EditAction:       "edit",
+	state.BoardAction:      "board",
+	state.JournalAction:    "journal",
+	state.CollectiveAction: "collective",
+	state.MediaAction:      "media",
+	state.VoteAction:       "vote",
+	state.ExpireProposal:   "expire",
+	state.AcceptProposal:   "accept",
+	state.SigninAction:     "signin",
+	state.MediaUpload:      "upload",
+	state.EventAction:      "event",
+	state.CommentAction:    "comment",
+}
+
+var notifyObjectNames = map[state.Object]string{
+	state.NoObject:         "",
+	state.AuthorObject:     "author",
+	state.DraftObject:      "draft",
+	state.EditObject:       "edit",
+	state.BoardObject:      "board",
+	state.JournalObject:    "journal",
+	state.EventObject:      "event",
+	state.CollectiveObject: "collective",
+	state.MemberObject:     "member",
+	state.MediaObject:      "media",
+}
+
+func NewUpdatedView(update state.Updated) UpdatedView {
+	return UpdatedView{
+		Action: notifyActionNames[update.Action],
+		Object: notifyObjectNames[update.Object],
+		Hash:   update.Hash,
+	}
+}
+
+func (a *AttorneyGeneral) NotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	author := a.Author(r)
+	if author == crypto.ZeroToken {
+		http.Error(w, "sessão não encontrada", http.StatusUnauthorized)
+		return
+	}
+	if a.notifications == nil {
+		http.Error(w, "notificações desabilitadas", http.StatusServiceUnavailable)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming não suportado", http.StatusInternalServerError)
+		return
+	}
+	// o servidor tem write timeout curto, a conexao de eventos nao
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("could not clear write deadline for notifications: %v", err)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	subscriber := a.notifications.Subscribe(author)
+	defer a.notifications.Unsubscribe(subscriber)
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case update := <-subscriber.updates:
+			data, err := json.Marshal(NewUpdatedView(update))
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: update\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
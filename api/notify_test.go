@@ -0,0 +1,99 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+	"github.com/freehandle/synergy/social/index"
+	"github.com/freehandle/synergy/social/state"
+)
+
+// notifyState returns an indexed state with n members notifying the hub
+func notifyState(n int) (*NotificationHub, *state.State, []crypto.Token) {
+	indexer := index.NewIndex()
+	s := state.GenesisState(indexer)
+	indexer.SetState(s)
+	members := make([]crypto.Token, n)
+	for m := range members {
+		members[m], _ = crypto.RandomAsymetricKey()
+		handle := fmt.Sprintf("member%v", m)
+		s.Members[crypto.HashToken(members[m])] = handle
+		s.MembersIndex[handle] = members[m]
+		indexer.AddMemberToIndex(members[m], handle)
+	}
+	hub := NewNotificationHub(indexer)
+	s.SetNotifier(hub.Notifier())
+	return hub, s, members
+}
+
+func notifyDraft(author crypto.Token, n int) *actions.Draft {
+	content := []byte(fmt.Sprintf("esboco %v", n))
+	return &actions.Draft{
+		Epoch:         1,
+		Author:        author,
+		Title:         fmt.Sprintf("esboco %v", n),
+		Keywords:      []string{"notificacao"},
+		ContentType:   "md",
+		ContentHash:   crypto.Hasher(content),
+		NumberOfParts: 1,
+		Content:       content,
+	}
+}
+
+// updates waits for count updates on the session
+func updates(t *testing.T, subscriber *Subscriber, count int) []state.Updated {
+	t.Helper()
+	received := make([]state.Updated, 0, count)
+	for len(received) < count {
+		select {
+		case update := <-subscriber.updates:
+			received = append(received, update)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%v updates received, expected %v", len(received), count)
+		}
+	}
+	return received
+}
+
+func TestNotificationHub(t *testing.T) {
+	hub, s, members := notifyState(2)
+	first := hub.Subscribe(members[0])
+	second := hub.Subscribe(members[0])
+	other := hub.Subscribe(members[1])
+
+	draft := notifyDraft(members[0], 0)
+	if err := s.Action(draft.Serialize()); err != nil {
+		t.Fatalf("could not incorporate draft: %v", err)
+	}
+	// every session of the author gets the author and the draft
+	for _, session := range []*Subscriber{first, second} {
+		received := updates(t, session, 2)
+		if received[0].Hash != crypto.HashToken(members[0]) || received[1].Hash != draft.ContentHash || received[1].Action != state.DraftAction {
+			t.Errorf("unexpected updates %+v", received)
+		}
+	}
+	// the hub goes through the updates in order: nothing is left for members[1]
+	select {
+	case update := <-other.updates:
+		t.Errorf("update %+v of no interest sent", update)
+	default:
+	}
+
+	hub.Unsubscribe(second)
+	// the hub reads the index while the blocks go on
+	for n := 1; n <= 10; n++ {
+		if err := s.Action(notifyDraft(members[0], n).Serialize()); err != nil {
+			t.Fatalf("could not incorporate draft: %v", err)
+		}
+		s.SetEpoch(uint64(n + 1))
+	}
+	updates(t, first, 20)
+	select {
+	case update := <-second.updates:
+		t.Errorf("update %+v sent after unsubscribe", update)
+	default:
+	}
+}
@@ -12,6 +12,53 @@ window.onload = function () {
       el.addEventListener("focusout", hideinfo(id+"info"));
     }
   }
+
+  // real time updates
+  liveupdates();
+  chainhealth();
+}
+
+function liveupdates() {
+  let el = document.getElementById("liveupdates");
+  if (!el || !window.EventSource) {
+    return;
+  }
+  let source = new EventSource(el.getAttribute("data-stream"));
+  source.addEventListener("update", () => {
+    el.classList.remove("liveupdateshide");
+    el.classList.add("liveupdatesshow");
+  });
+}
+
+function chainhealth() {
+  let el = document.getElementById("chainhealth");
+  if (!el || !window.fetch) {
+    return;
+  }
+  let check = () => {
+    fetch(el.getAttribute("data-health"))
+      .then((response) => response.json())
+      .then((health) => {
+        if (health.connected && health.live) {
+          el.classList.remove("chainhealthshow");
+          el.classList.add("chainhealthhide");
+          return;
+        }
+        if (!health.connected) {
+          el.textContent = "sem conexão com a rede: tentando reconectar";
+          if (health.pending > 0) {
+            el.textContent += " (" + health.pending + " ações aguardando envio)";
+          }
+        } else {
+          el.textContent = "sincronizando com a rede (época " + health.epoch + ")";
+        }
+        el.classList.remove("chainhealthhide");
+        el.classList.add("chainhealthshow");
+      })
+      .catch(() => {});
+  };
+  check();
+  setInterval(check, 10000);
 }
 
 function selectFile() {
@@ -82,6 +129,20 @@ function dialogleavecollective() {
   leavepar.innerHTML = "sair do coletivo "+ pagename;
 }
 
+// delegate vote on collective
+
+function dialogdelegate() {
+  // shows dialog element
+  let el = document.getElementById("dialogdelegateel");
+  el.showModal();
+
+  // gets outline paragraph to be shown in modal
+  let delegatepar = document.getElementById("delegateoutline");
+  let pagename = document.getElementById("modaloutlinename").innerHTML;
+
+  delegatepar.innerHTML = "delegar voto no coletivo "+ pagename;
+}
+
 // join collective
 
 function dialogjoincollective() {
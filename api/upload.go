@@ -55,10 +55,19 @@ func (a *AttorneyGeneral) UploadHandler(w http.ResponseWriter, r *http.Request)
 	case "Draft":
 		actionArray, err = DraftForm(r, a.state.MembersIndex, fileBytes, ext).ToAction()
 	case "Edit":
-		actionArray, err = EditForm(r, a.state.MembersIndex, fileBytes, ext).ToAction()
+		edit := EditForm(r, a.state.MembersIndex, fileBytes, ext)
+		if FormToBool(r, "asPatch") {
+			edit, err = PatchEdit(a.state, edit)
+		}
+		if err == nil {
+			actionArray, err = edit.ToAction()
+		}
 	}
 	if err == nil && len(actionArray) > 0 {
-		a.Send(actionArray, author)
+		if _, err := a.Submit(actionArray, author); err != nil {
+			a.RejectedHandler(w, r, err)
+			return
+		}
 	}
 	http.Redirect(w, r, fmt.Sprintf("%v/", a.serverName), http.StatusSeeOther)
 }
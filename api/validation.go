@@ -0,0 +1,110 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
+	"github.com/freehandle/synergy/social/actions"
+	"github.com/freehandle/synergy/social/state"
+)
+
+// rejections translates the validation errors of the state to the message
+// shown to the member.
+var rejections = []struct {
+	err     error
+	message string
+}{
+	{state.ErrInvalidAction, "ação mal formada"},
+	{state.ErrUnknownAction, "ação desconhecida"},
+	{state.ErrNotMember, "é preciso ser membro do synergy"},
+	{state.ErrAlreadyMember, "já é membro"},
+	{state.ErrUnknownHandle, "perfil não reconhecido"},
+	{state.ErrNotCollectiveMember, "é preciso ser membro do coletivo"},
+	{state.ErrNotAuthor, "é preciso ser autor"},
+	{state.ErrNotEditor, "é preciso ser editor"},
+	{state.ErrNotManager, "é preciso ser organizador do evento"},
+	{state.ErrSelfDelegation, "não é possível delegar para si mesmo"},
+	{state.ErrUnknownCollective, "coletivo não encontrado"},
+	{state.ErrUnknownBoard, "mural não encontrado"},
+	{state.ErrUnknownJournal, "periódico não encontrado"},
+	{state.ErrUnknownDraft, "esboço não encontrado"},
+	{state.ErrUnknownRelease, "publicação não encontrada"},
+	{state.ErrUnknownEvent, "evento não encontrado"},
+	{state.ErrUnknownOccurrence, "ocorrência não encontrada"},
+	{state.ErrUnknownCheckin, "check-in não encontrado"},
+	{state.ErrUnknownMedia, "arquivo não encontrado"},
+	{state.ErrUnknownComment, "comentário não encontrado"},
+	{state.ErrCollectiveExists, "já existe um coletivo com esse nome"},
+	{state.ErrNameTaken, "já existe um mural ou periódico com esse nome"},
+	{state.ErrHashClaimed, "arquivo já enviado"},
+	{state.ErrHashMismatch, "conteúdo não confere com o hash"},
+	{state.ErrDuplicateProposal, "proposta já existente"},
+	{state.ErrProposalNotFound, "proposta não encontrada"},
+	{state.ErrProposalConcluded, "proposta já concluída"},
+	{state.ErrInvalidProposalKind, "tipo de proposta inválido"},
+	{state.ErrInvalidReaction, "reação inválida"},
+	{state.ErrInvalidParts, "número de partes incompatível"},
+	{state.ErrInvalidPatch, "edição como patch apenas para esboços txt ou md"},
+	{state.ErrDraftUnavailable, "conteúdo do esboço indisponível"},
+	{state.ErrEmptyIssue, "número sem esboços"},
+	{state.ErrDuplicateDraft, "esboço incluído duas vezes"},
+	{state.ErrDraftNotReleased, "esboço não publicado"},
+	{state.ErrReleaseNotStamped, "publicação sem selo"},
+	{state.ErrEmptyComment, "comentário vazio"},
+	{state.ErrInvalidTarget, "não é possível comentar esse objeto"},
+	{state.ErrReplyOtherTarget, "resposta a comentário sobre outro objeto"},
+	{state.ErrDuplicateComment, "comentário já enviado"},
+	{state.ErrEventCancelled, "evento cancelado"},
+	{state.ErrAlreadyCheckedIn, "check-in já realizado"},
+	{state.ErrRSVPClosed, "inscrições encerradas"},
+	{actions.ErrInvalidPolicy, "política inválida"},
+}
+
+// RejectionMessage of an action refused by State.Validate.
+func RejectionMessage(err error) string {
+	for _, rejection := range rejections {
+		if errors.Is(err, rejection.err) {
+			return rejection.message
+		}
+	}
+	return err.Error()
+}
+
+// validate checks the actions of a submission against the current state, as
+// the state will see them once dressed for author. The parts of a media file
+// after the first are sent together with the draft or edit that opens it and
+// cannot be checked before it is incorporated.
+func (a *AttorneyGeneral) validate(all []actions.Action, author crypto.Token) error {
+	opened := make(map[crypto.Hash]struct{})
+	for _, action := range all {
+		switch v := action.(type) {
+		case *actions.Draft:
+			opened[v.ContentHash] = struct{}{}
+		case *actions.Edit:
+			opened[v.ContentHash] = struct{}{}
+		case *actions.MultipartMedia:
+			if _, ok := opened[v.Hash]; ok {
+				continue
+			}
+		}
+		if err := a.state.Validate(authored(action, author, a.state.Epoch)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// authored serializes the action with the epoch and the author that
+// DressAction puts on the breeze instruction.
+func authored(action actions.Action, author crypto.Token, epoch uint64) []byte {
+	data := action.Serialize()
+	if len(data) < 8+crypto.TokenSize {
+		return data
+	}
+	head := make([]byte, 0, 8+crypto.TokenSize)
+	util.PutUint64(epoch, &head)
+	util.PutToken(author, &head)
+	copy(data, head)
+	return data
+}
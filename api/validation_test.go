@@ -0,0 +1,60 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+	"github.com/freehandle/synergy/social/index"
+	"github.com/freehandle/synergy/social/state"
+)
+
+// testAttorney returns an attorney on an indexed state with one member that
+// keeps on gateway what it sends.
+func testAttorney() (*AttorneyGeneral, crypto.Token) {
+	indexer := index.NewIndex()
+	s := state.GenesisState(indexer)
+	indexer.SetState(s)
+	s.SetEpoch(5)
+	member, _ := crypto.RandomAsymetricKey()
+	s.Members[crypto.HashToken(member)] = "membro"
+	s.MembersIndex["membro"] = member
+	indexer.AddMemberToIndex(member, "membro")
+	_, pk := crypto.RandomAsymetricKey()
+	attorney := &AttorneyGeneral{
+		pk:      pk,
+		mempool: NewMempool(),
+		gateway: make(chan []byte, 100),
+		state:   s,
+		indexer: indexer,
+	}
+	return attorney, member
+}
+
+// actions come without author and epoch from the forms and the JSON body: they
+// are checked as dressed for the member that submits them
+func TestSubmitValidatesAuthor(t *testing.T) {
+	attorney, member := testAttorney()
+	outsider, _ := crypto.RandomAsymetricKey()
+	create := func(name string) []actions.Action {
+		return []actions.Action{&actions.CreateCollective{Name: name, Policy: actions.Policy{Majority: 50, SuperMajority: 50}}}
+	}
+	if _, err := attorney.Submit(create("coletivo"), outsider); !errors.Is(err, state.ErrNotMember) {
+		t.Errorf("action by an outsider: expected %v, got %v", state.ErrNotMember, err)
+	}
+	if len(attorney.gateway) != 0 {
+		t.Fatal("rejected action sent")
+	}
+	submissions, err := attorney.Submit(create("coletivo"), member)
+	if err != nil {
+		t.Fatalf("action by a member rejected: %v", err)
+	}
+	if len(submissions) != 1 || submissions[0].Author != member || len(attorney.gateway) != 1 {
+		t.Errorf("submissions %+v", submissions)
+	}
+	data := authored(create("coletivo")[0], member, 5)
+	if parsed := actions.ParseCreateCollective(data); parsed == nil || parsed.Author != member || parsed.Epoch != 5 {
+		t.Errorf("authored action %+v", parsed)
+	}
+}
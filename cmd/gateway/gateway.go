@@ -18,14 +18,15 @@ func GetState(chain *blockchain) (*state.State, error) {
 	if genesis == nil {
 		return nil, errors.New("could not create genesis state")
 	}
-	for _, block := range chain.blocks {
-		for _, action := range block.data {
-			if !IsAxeNonVoid(action) {
-				if err := genesis.Action(action); err != nil {
-					log.Printf("blockchain has invalid action: %v", err)
-				}
+	err := chain.Replay(func(action []byte) {
+		if !IsAxeNonVoid(action) {
+			if err := genesis.Action(action); err != nil {
+				log.Printf("blockchain has invalid action: %v", err)
 			}
 		}
+	})
+	if err != nil {
+		return nil, err
 	}
 	return genesis, nil
 }
@@ -53,6 +54,8 @@ func NewActionsGateway(port int, credentials crypto.PrivateKey, chain *blockchai
 	}
 
 	pool := make(ConnectionPool)
+	// connections waiting for their sync request, out of the pool
+	syncing := make(ConnectionPool)
 	incorporate := make(chan *CachedConnection)
 
 	shutDown := make(chan crypto.Token) // receive connection shutdown
@@ -83,12 +86,30 @@ func NewActionsGateway(port int, credentials crypto.PrivateKey, chain *blockchai
 				// next block and broadcast
 				chain.NewBlock(pool)
 			case cached := <-incorporate:
-				pool.Connect(cached)
-				// start sync node
-				go chain.Sync(cached, len(chain.blocks)-1, len(chain.current.data))
+				syncing.Connect(cached)
 			case token := <-shutDown:
+				syncing.Drop(token)
 				pool.Drop(token)
 			case msg := <-messages:
+				if from, skip, ok := network.ParseSyncRequest(msg.Data); ok {
+					cached, waiting := syncing[msg.Token]
+					if !waiting {
+						log.Printf("unexpected sync request")
+						continue
+					}
+					delete(syncing, msg.Token)
+					epoch, actionCount := chain.Head()
+					if from > epoch {
+						log.Printf("sync request beyond epoch %v", epoch)
+						cached.conn.Shutdown()
+						continue
+					}
+					// live messages from now on are held by the connection
+					// until the sync is done
+					pool.Connect(cached)
+					go chain.Sync(cached, from, skip, epoch, actionCount)
+					continue
+				}
 				axe := IsAxeNonVoid(msg.Data)
 				if axe {
 					chain.NewAction(msg.Data, pool)
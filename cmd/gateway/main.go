@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/freehandle/breeze/crypto"
 	"github.com/freehandle/breeze/socket"
 	"github.com/freehandle/synergy/social/actions"
@@ -18,8 +20,20 @@ var pks []crypto.PrivateKey = []crypto.PrivateKey{
 var gatewayPK = crypto.PrivateKey{121, 98, 124, 72, 181, 150, 37, 34, 195, 97, 127, 65, 198, 38, 114, 116, 94, 244, 191, 249, 171, 114, 54, 232, 84, 87, 151, 146, 40, 249, 220, 89, 52, 170, 195, 171,
 	223, 79, 238, 175, 43, 29, 241, 31, 238, 42, 141, 254, 202, 212, 102, 132, 0, 53, 249, 84, 179, 102, 229, 5, 205, 10, 145, 246}
 
+// chainPath is the default directory of the chain segments. It can be changed
+// with the environment variable SYNERGY_CHAIN_PATH.
+const chainPath = "../../chain"
+
+// legacyChainFile is the single file chain of earlier versions of the gateway,
+// imported into the segments on the first start.
+const legacyChainFile = "../../chain.dat"
+
 func main() {
-	chain, exists := OpenBlockchain()
+	path := chainPath
+	if env := os.Getenv("SYNERGY_CHAIN_PATH"); env != "" {
+		path = env
+	}
+	chain, exists := OpenBlockchain(path, legacyChainFile)
 	message, _ := NewActionsGateway(4100, gatewayPK, chain)
 	if !exists {
 		for _, pk := range pks {
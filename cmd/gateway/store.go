@@ -1,58 +1,99 @@
 package main
 
 import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
 	"log"
+	"math"
 	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/freehandle/breeze/util"
+	"github.com/freehandle/synergy/network"
 )
 
+// SegmentBlocks is the number of blocks on each segment file of the chain. The
+// segment of a block is given by its epoch.
+const SegmentBlocks = 1 << 16
+
+// maxRecordSize bounds the payload of a record so that a damaged length does
+// not trigger a huge allocation.
+const maxRecordSize = 1 << 24
+
+var errCorruptedRecord = errors.New("corrupted record")
+
 type block struct {
 	data [][]byte
 }
 
-func multiblock(startEpoch uint64, blocks []*block) []byte {
-	bytes := []byte{2}
-	for n, block := range blocks {
-		util.PutUint64(startEpoch+uint64(n), &bytes)
-		util.PutActionsArray(block.data, &bytes)
-	}
-	return bytes
+// position of a block record on the store
+type position struct {
+	segment int
+	offset  int64
 }
 
+// blockchain is an append-only store of blocks split in segment files. Every
+// record carries its own checksum and a finished segment is sealed with the
+// checksum of its contents. Only the actions of the current block are kept in
+// memory, older blocks are read from disk through the epoch index.
 type blockchain struct {
-	mu      sync.Mutex
-	io      *os.File
-	blocks  []*block
-	current *block
+	mu       sync.Mutex
+	dir      string
+	tail     *os.File // segment being written
+	segment  int      // numero do segmento sendo escrito
+	size     int64
+	checksum hash.Hash32
+	index    []position // posicao do registro de cada bloco por epoch
+	current  *block
 }
 
-// sync a new connection
-func (b *blockchain) Sync(conn *CachedConnection, epoch, actionCount int) {
-	b.mu.Lock()
-	currentBlockCache := make([][]byte, actionCount)
-	for n := 0; n < actionCount; n++ {
-		currentBlockCache[n] = b.blocks[epoch].data[n]
-	}
-	b.mu.Unlock()
-	for n := 0; n <= (epoch-1)/1000; n++ {
-		count := 1000
-		if (n+1)*1000 > (epoch - 1) {
-			count = (epoch - 1) % 1000
-		}
-		blocks := make([]*block, count)
-		for c := 0; c < count; c++ {
-			blocks[c] = &block{
-				data: make([][]byte, 0),
+// Sync answers the sync request of a connection that has the blocks before
+// from and the first skip actions of block from. It streams the stored blocks
+// up to the head (epoch, actionCount) taken when the connection joined the
+// pool, then signals the handover to the live messages held by the connection
+// since then.
+func (b *blockchain) Sync(conn *CachedConnection, from uint64, skip int, epoch uint64, actionCount int) {
+	batch := []byte{network.MultiBlockMessage}
+	err := b.ReadBlocks(from, epoch, func(n uint64, data [][]byte) error {
+		if n == epoch {
+			if len(data) < actionCount {
+				return errors.New("current block out of sync")
+			}
+			data = data[:actionCount]
+		}
+		if n == from {
+			if len(data) < skip {
+				return fmt.Errorf("block %v has less than %v actions", from, skip)
 			}
-			blocks[c].data = append(blocks[c].data, b.blocks[n*1000+c].data...)
+			data = data[skip:]
 		}
-		conn.SendDirect(multiblock(uint64(n*1000), blocks))
+		entry := make([]byte, 0)
+		network.PutSyncBlock(n, data, &entry)
+		if len(batch) > 1 && len(batch)+len(entry) > network.SyncBatchSize {
+			if err := conn.SendDirect(batch); err != nil {
+				return err
+			}
+			batch = []byte{network.MultiBlockMessage}
+		}
+		batch = append(batch, entry...)
+		return nil
+	})
+	if err == nil {
+		err = conn.SendDirect(batch)
 	}
-	conn.SendDirect(newBlockBytes(uint64(epoch)))
-	for _, action := range currentBlockCache {
-		conn.SendDirect(append([]byte{actionsignal}, action...))
+	if err == nil {
+		err = conn.SendDirect(network.Live(epoch, actionCount))
+	}
+	if err != nil {
+		log.Printf("could not sync connection: %v", err)
+		conn.conn.Shutdown()
+		return
 	}
 	conn.Ready()
 }
@@ -60,6 +101,7 @@ func (b *blockchain) Sync(conn *CachedConnection, epoch, actionCount int) {
 const (
 	blocksignal  byte = 0
 	actionsignal byte = 1
+	sealsignal   byte = 2 // fecha o segmento com o checksum do conteudo
 )
 
 func newBlockBytes(epoch uint64) []byte {
@@ -68,91 +110,448 @@ func newBlockBytes(epoch uint64) []byte {
 	return data
 }
 
+// Record on disk:
+// kind                         (byte)
+// payload length               (4 bytes)
+// payload                      (variable)
+// crc32 of the preceding bytes (4 bytes)
+func newRecord(kind byte, payload []byte) []byte {
+	record := make([]byte, 5, len(payload)+9)
+	record[0] = kind
+	binary.LittleEndian.PutUint32(record[1:], uint32(len(payload)))
+	record = append(record, payload...)
+	return binary.LittleEndian.AppendUint32(record, crc32.ChecksumIEEE(record))
+}
+
+func recordPayload(record []byte) []byte {
+	return record[5 : len(record)-4]
+}
+
+// readRecord returns io.EOF at a clean end of the data, io.ErrUnexpectedEOF
+// for a partial record and errCorruptedRecord if the checksum does not match.
+func readRecord(r io.Reader) ([]byte, error) {
+	header := make([]byte, 5)
+	if n, err := io.ReadFull(r, header); err != nil {
+		if n == 0 && err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, io.ErrUnexpectedEOF
+	}
+	size := binary.LittleEndian.Uint32(header[1:])
+	if size > maxRecordSize {
+		return nil, errCorruptedRecord
+	}
+	record := make([]byte, 5+int(size)+4)
+	copy(record, header)
+	if _, err := io.ReadFull(r, record[5:]); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	body := record[:len(record)-4]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(record[len(record)-4:]) {
+		return nil, errCorruptedRecord
+	}
+	return record, nil
+}
+
+func (b *blockchain) segmentPath(n int) string {
+	return filepath.Join(b.dir, fmt.Sprintf("chain-%06d.dat", n))
+}
+
+// write appends a record to the tail segment and returns its offset.
+func (b *blockchain) write(kind byte, payload []byte) int64 {
+	record := newRecord(kind, payload)
+	offset := b.size
+	if n, err := b.tail.WriteAt(record, offset); n != len(record) || err != nil {
+		log.Fatalf("could not write to blockchain: %v", err)
+	}
+	b.size += int64(len(record))
+	b.checksum.Write(record)
+	return offset
+}
+
+func (b *blockchain) openTail(n int) {
+	file, err := os.OpenFile(b.segmentPath(n), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		log.Fatalf("could not create blockchain segment: %v", err)
+	}
+	b.tail = file
+	b.segment = n
+	b.size = 0
+	b.checksum = crc32.NewIEEE()
+}
+
+// roll seals the tail segment with its checksum and starts the next one.
+func (b *blockchain) roll() {
+	seal := make([]byte, 4)
+	binary.LittleEndian.PutUint32(seal, b.checksum.Sum32())
+	b.write(sealsignal, seal)
+	if err := b.tail.Sync(); err != nil {
+		log.Fatalf("could not seal blockchain segment: %v", err)
+	}
+	b.tail.Close()
+	b.openTail(b.segment + 1)
+}
+
+// appendBlock writes the record of the next block, rolling the segment when
+// it is full. Must be called with the lock held.
+func (b *blockchain) appendBlock() []byte {
+	epoch := uint64(len(b.index))
+	if int(epoch/SegmentBlocks) != b.segment {
+		b.roll()
+	}
+	data := newBlockBytes(epoch)
+	offset := b.write(blocksignal, data[1:])
+	b.index = append(b.index, position{segment: b.segment, offset: offset})
+	b.current = &block{data: make([][]byte, 0)}
+	return data
+}
+
 func (b *blockchain) NewBlock(pool ConnectionPool) {
-	newBlock := &block{data: make([][]byte, 0)}
-	b.current = newBlock
-	b.blocks = append(b.blocks, b.current)
-	// pool = nil when reading data from file at initialization
-	if pool != nil {
-		epoch := uint64(len(b.blocks) - 1)
-		data := newBlockBytes(epoch)
-		if n, err := b.io.Write(data); n != len(data) || err != nil {
-			log.Fatalf("could not write block: %v", err)
+	b.mu.Lock()
+	// o bloco anterior vai para o disco antes de comecar o proximo (roll
+	// tambem sincroniza o segmento que fecha)
+	if int(uint64(len(b.index))/SegmentBlocks) == b.segment {
+		if err := b.tail.Sync(); err != nil {
+			log.Fatalf("could not sync blockchain: %v", err)
 		}
+	}
+	data := b.appendBlock()
+	b.mu.Unlock()
+	// pool = nil when writing the genesis block at initialization
+	if pool != nil {
 		pool.Broadcast(data)
 	}
 }
 
 func (b *blockchain) NewAction(action []byte, pool ConnectionPool) {
 	b.mu.Lock()
+	b.write(actionsignal, action)
 	b.current.data = append(b.current.data, action)
-	b.mu.Unlock() // pool = nil when reading data from file at initialization
+	b.mu.Unlock()
 	if pool != nil {
-		data := []byte{actionsignal}
-		util.PutUint64(uint64(len(action)), &data)
-		data = append(data, action...)
-		if n, err := b.io.Write(data); n != len(data) || err != nil {
-			log.Fatalf("could not write action: %v", err)
-		}
 		pool.Broadcast(append([]byte{actionsignal}, action...))
 	}
 }
 
-func (b *blockchain) Close() {
-	b.io.Close()
+// Head returns the epoch of the current block and its number of actions.
+func (b *blockchain) Head() (uint64, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return uint64(len(b.index) - 1), len(b.current.data)
 }
 
-func OpenBlockchain() (*blockchain, bool) {
-	exists := true
-	if stat, err := os.Stat("../../chain.dat"); err != nil || stat.Size() == 0 {
-		exists = false
+// readBlock reads the actions of the block whose record starts at offset.
+func readBlock(file *os.File, offset int64) ([][]byte, error) {
+	reader := bufio.NewReader(io.NewSectionReader(file, offset, math.MaxInt64-offset))
+	record, err := readRecord(reader)
+	if err != nil {
+		return nil, err
+	}
+	if record[0] != blocksignal {
+		return nil, errors.New("blockchain index out of sync")
 	}
-	file, err := os.OpenFile("../../chain.dat", os.O_CREATE|os.O_RDWR, 0666)
+	data := make([][]byte, 0)
+	for {
+		record, err := readRecord(reader)
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if record[0] != actionsignal {
+			return data, nil
+		}
+		data = append(data, recordPayload(record))
+	}
+}
+
+// ReadBlocks calls read for every block from epoch first to last, inclusive.
+// Stored blocks are located by the index and read from disk, the current block
+// is copied from memory.
+func (b *blockchain) ReadBlocks(first, last uint64, read func(epoch uint64, data [][]byte) error) error {
+	b.mu.Lock()
+	if first > last || last >= uint64(len(b.index)) {
+		b.mu.Unlock()
+		return fmt.Errorf("blocks %v to %v not in blockchain", first, last)
+	}
+	positions := make([]position, last-first+1)
+	copy(positions, b.index[first:last+1])
+	var current [][]byte
+	if last == uint64(len(b.index)-1) {
+		// bloco corrente ainda nao fechado esta em memoria
+		current = make([][]byte, len(b.current.data))
+		copy(current, b.current.data)
+		positions = positions[:len(positions)-1]
+	}
+	b.mu.Unlock()
+	var file *os.File
+	segment := -1
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+	for n, pos := range positions {
+		if pos.segment != segment {
+			if file != nil {
+				file.Close()
+			}
+			var err error
+			if file, err = os.Open(b.segmentPath(pos.segment)); err != nil {
+				return err
+			}
+			segment = pos.segment
+		}
+		data, err := readBlock(file, pos.offset)
+		if err != nil {
+			return err
+		}
+		if err := read(first+uint64(n), data); err != nil {
+			return err
+		}
+	}
+	if current != nil {
+		return read(last, current)
+	}
+	return nil
+}
+
+// Blocks returns count consecutive blocks starting at epoch first.
+func (b *blockchain) Blocks(first uint64, count int) ([]*block, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("invalid number of blocks: %v", count)
+	}
+	blocks := make([]*block, 0, count)
+	err := b.ReadBlocks(first, first+uint64(count)-1, func(epoch uint64, data [][]byte) error {
+		blocks = append(blocks, &block{data: data})
+		return nil
+	})
 	if err != nil {
-		log.Fatalf("could not access chain file: %v\n", err)
+		return nil, err
 	}
-	b := &blockchain{
-		mu:     sync.Mutex{},
-		blocks: make([]*block, 0),
-		io:     file,
-	}
-	newBlock := &block{data: make([][]byte, 0)}
-	b.current = newBlock
-	b.blocks = append(b.blocks, b.current)
-	if !exists {
-		data := make([]byte, 9)
-		data[0] = blocksignal
-		// write the creation of genesis block
-		if n, err := file.Write(data); n != len(data) || err != nil {
-			log.Fatalf("could not write block: %v", err)
+	return blocks, nil
+}
+
+// Replay calls incorporate for every action of the chain, in order.
+func (b *blockchain) Replay(incorporate func(action []byte)) error {
+	epoch, _ := b.Head()
+	return b.ReadBlocks(0, epoch, func(_ uint64, data [][]byte) error {
+		for _, action := range data {
+			incorporate(action)
+		}
+		return nil
+	})
+}
+
+func (b *blockchain) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tail.Sync()
+	b.tail.Close()
+}
+
+// loadSegment indexes the blocks of the n-th segment. A record cut short at
+// the end of the last segment is the trace of an interrupted write and is
+// truncated. Any other damaged record (wrong checksum, impossible length, data
+// cut short anywhere else) is corruption and the chain is not opened. The seal
+// of the last segment is also removed (the gateway stopped before starting the
+// next segment) and is written again on roll.
+func (b *blockchain) loadSegment(n int, last bool) error {
+	file, err := os.OpenFile(b.segmentPath(n), os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("could not access blockchain segment: %w", err)
+	}
+	reader := bufio.NewReader(file)
+	checksum := crc32.NewIEEE()
+	offset := int64(0)
+	sealed := false
+	sealOffset := int64(0)
+	for {
+		record, err := readRecord(reader)
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF && last {
+			log.Printf("blockchain segment %v: discarding incomplete record at offset %v", n, offset)
+			if err := file.Truncate(offset); err != nil {
+				file.Close()
+				return fmt.Errorf("could not truncate blockchain segment: %w", err)
+			}
+			break
+		}
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("blockchain segment %v corrupted at offset %v: %w", n, offset, err)
+		}
+		if sealed {
+			file.Close()
+			return fmt.Errorf("blockchain segment %v corrupted: data after seal", n)
+		}
+		payload := recordPayload(record)
+		switch record[0] {
+		case blocksignal:
+			epoch, _ := util.ParseUint64(payload, 0)
+			if len(payload) != 8 || epoch != uint64(len(b.index)) || int(epoch/SegmentBlocks) != n {
+				file.Close()
+				return fmt.Errorf("blockchain segment %v corrupted: block out of order", n)
+			}
+			b.index = append(b.index, position{segment: n, offset: offset})
+			b.current = &block{data: make([][]byte, 0)}
+		case actionsignal:
+			if len(b.index) == 0 {
+				file.Close()
+				return errors.New("blockchain file corrupted: action before genesis block")
+			}
+			b.current.data = append(b.current.data, payload)
+		case sealsignal:
+			if len(payload) != 4 || binary.LittleEndian.Uint32(payload) != checksum.Sum32() {
+				file.Close()
+				return fmt.Errorf("blockchain segment %v corrupted: checksum mismatch", n)
+			}
+			sealed = true
+			sealOffset = offset
+		default:
+			file.Close()
+			return errors.New("blockchain file corrupted: invalid data type")
+		}
+		if !sealed {
+			checksum.Write(record)
+		}
+		offset += int64(len(record))
+	}
+	if !last {
+		file.Close()
+		if !sealed {
+			return fmt.Errorf("blockchain segment %v corrupted: segment not sealed", n)
 		}
-		return b, false
+		return nil
 	}
+	if sealed {
+		offset = sealOffset
+		if err := file.Truncate(offset); err != nil {
+			file.Close()
+			return fmt.Errorf("could not truncate blockchain segment: %w", err)
+		}
+	}
+	b.tail = file
+	b.segment = n
+	b.size = offset
+	b.checksum = checksum
+	return nil
+}
+
+// importLegacy appends to the empty blockchain the blocks of the single file
+// written by earlier versions of the gateway: a 9 byte signal (kind and epoch
+// of a block or kind and length of an action) followed, for actions, by the
+// action. As on the old loader, an incomplete signal or action at the end of
+// the file is ignored.
+func (b *blockchain) importLegacy(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
 	signal := make([]byte, 9)
 	for {
-		if n, err := b.io.Read(signal); n != 9 || err != nil {
+		if _, err := io.ReadFull(reader, signal); err == io.EOF {
+			break
+		} else if err == io.ErrUnexpectedEOF {
+			log.Printf("legacy chain %v: discarding incomplete tail", path)
 			break
+		} else if err != nil {
+			return err
 		}
 		number, _ := util.ParseUint64(signal, 1)
-		if signal[0] == blocksignal {
-			if number == 0 {
-				if len(b.blocks) != 1 {
-					log.Fatal("blockchain file corrupted: genesis block elsewhere")
-				}
-			} else if number != uint64(len(b.blocks)) {
-				log.Fatal("blockchain file corrupted: block out of order")
-			} else {
-				b.NewBlock(nil)
+		switch signal[0] {
+		case blocksignal:
+			if number != uint64(len(b.index)) {
+				return fmt.Errorf("legacy chain %v corrupted: block %v out of order", path, number)
+			}
+			b.appendBlock()
+		case actionsignal:
+			if len(b.index) == 0 {
+				return fmt.Errorf("legacy chain %v corrupted: action before genesis block", path)
+			}
+			if number > maxRecordSize {
+				return fmt.Errorf("legacy chain %v corrupted: action of %v bytes", path, number)
 			}
-		} else if signal[0] == actionsignal {
-			data := make([]byte, int(number))
-			if n, _ := b.io.Read(data); n != len(data) {
-				log.Fatal("blockchain file corrupted: incomplete action")
+			action := make([]byte, int(number))
+			if _, err := io.ReadFull(reader, action); err != nil {
+				log.Printf("legacy chain %v: discarding incomplete action", path)
+				return b.tail.Sync()
 			}
-			b.NewAction(data, nil)
-		} else {
-			log.Fatal("blockchain file corrupted: invalid data type")
+			b.write(actionsignal, action)
+			b.current.data = append(b.current.data, action)
+		default:
+			return fmt.Errorf("legacy chain %v corrupted: invalid data type", path)
 		}
 	}
-	return b, true
+	return b.tail.Sync()
+}
+
+// OpenBlockchain opens (or creates) the blockchain on the directory dir. It
+// returns false if the blockchain did not exist. The chain file legacy of
+// earlier versions of the gateway, if found, is imported into a new blockchain
+// and renamed with the suffix .imported. The gateway refuses to start if both
+// exist: either the file was restored after the import or the import was
+// interrupted (remove the segments to import it again).
+func OpenBlockchain(dir, legacy string) (*blockchain, bool) {
+	b, exists, err := openBlockchain(dir, legacy)
+	if err != nil {
+		log.Fatalf("could not open blockchain: %v", err)
+	}
+	return b, exists
+}
+
+func openBlockchain(dir, legacy string) (*blockchain, bool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, false, fmt.Errorf("could not access chain directory: %w", err)
+	}
+	b := &blockchain{
+		mu:      sync.Mutex{},
+		dir:     dir,
+		index:   make([]position, 0),
+		current: &block{data: make([][]byte, 0)},
+	}
+	segments := 0
+	for {
+		if _, err := os.Stat(b.segmentPath(segments)); err != nil {
+			break
+		}
+		segments++
+	}
+	importing := false
+	if legacy != "" {
+		if _, err := os.Stat(legacy); err == nil {
+			if segments > 0 {
+				return nil, false, fmt.Errorf("legacy chain file %v found along the segments on %v (if its import was interrupted remove the segments)", legacy, dir)
+			}
+			importing = true
+		}
+	}
+	for n := 0; n < segments; n++ {
+		if err := b.loadSegment(n, n == segments-1); err != nil {
+			return nil, false, err
+		}
+	}
+	if segments == 0 {
+		b.openTail(0)
+	}
+	if importing {
+		if err := b.importLegacy(legacy); err != nil {
+			return nil, false, err
+		}
+		if err := os.Rename(legacy, legacy+".imported"); err != nil {
+			return nil, false, err
+		}
+		log.Printf("legacy chain file %v imported: %v blocks", legacy, len(b.index))
+	}
+	if len(b.index) == 0 {
+		// write the creation of genesis block
+		b.NewBlock(nil)
+		return b, false, nil
+	}
+	return b, true, nil
 }
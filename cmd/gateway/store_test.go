@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/freehandle/breeze/util"
+)
+
+// testBlocks are the actions of the blocks after genesis
+var testBlocks = [][][]byte{
+	{[]byte("primeira"), []byte("segunda")},
+	{},
+	{[]byte("terceira")},
+}
+
+func openTestChain(t *testing.T, dir string) *blockchain {
+	t.Helper()
+	b, _, err := openBlockchain(dir, "")
+	if err != nil {
+		t.Fatalf("could not open blockchain: %v", err)
+	}
+	return b
+}
+
+func fillChain(b *blockchain) {
+	for _, actions := range testBlocks {
+		b.NewBlock(nil)
+		for _, action := range actions {
+			b.NewAction(action, nil)
+		}
+	}
+}
+
+func checkChain(t *testing.T, b *blockchain, expected [][][]byte) {
+	t.Helper()
+	epoch, count := b.Head()
+	if epoch != uint64(len(expected)) || count != len(expected[len(expected)-1]) {
+		t.Fatalf("head at epoch %v with %v actions, expected %v with %v", epoch, count, len(expected), len(expected[len(expected)-1]))
+	}
+	blocks, err := b.Blocks(1, len(expected))
+	if err != nil {
+		t.Fatalf("could not read blocks: %v", err)
+	}
+	for n, block := range blocks {
+		if len(block.data) != len(expected[n]) || (len(block.data) > 0 && !reflect.DeepEqual(block.data, expected[n])) {
+			t.Errorf("block %v has actions %q, expected %q", n+1, block.data, expected[n])
+		}
+	}
+}
+
+func segmentSize(t *testing.T, b *blockchain) int64 {
+	t.Helper()
+	stat, err := os.Stat(b.segmentPath(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stat.Size()
+}
+
+func TestBlockchainReopen(t *testing.T) {
+	dir := t.TempDir()
+	b, exists, err := openBlockchain(dir, "")
+	if err != nil || exists {
+		t.Fatalf("new blockchain: exists %v, error %v", exists, err)
+	}
+	fillChain(b)
+	b.Close()
+	b, exists, err = openBlockchain(dir, "")
+	if err != nil || !exists {
+		t.Fatalf("reopened blockchain: exists %v, error %v", exists, err)
+	}
+	checkChain(t, b, testBlocks)
+	// the last block goes on receiving actions
+	b.NewAction([]byte("quarta"), nil)
+	b.Close()
+	checkChain(t, openTestChain(t, dir), [][][]byte{testBlocks[0], testBlocks[1], {[]byte("terceira"), []byte("quarta")}})
+}
+
+func TestBlockchainTornTail(t *testing.T) {
+	dir := t.TempDir()
+	b := openTestChain(t, dir)
+	fillChain(b)
+	b.Close()
+	size := segmentSize(t, b)
+	record := newRecord(actionsignal, []byte("interrompida"))
+	for _, torn := range [][]byte{record[:3], record[:len(record)-1]} {
+		file, err := os.OpenFile(b.segmentPath(0), os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Write(torn)
+		file.Close()
+		b = openTestChain(t, dir)
+		checkChain(t, b, testBlocks)
+		if segmentSize(t, b) != size {
+			t.Errorf("incomplete record not truncated: %v bytes, expected %v", segmentSize(t, b), size)
+		}
+		b.Close()
+	}
+}
+
+func TestBlockchainCorruption(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(data []byte) []byte
+	}{
+		{"checksum followed by data", func(data []byte) []byte {
+			// payload of the genesis block record
+			data[7] ^= 0xff
+			return data
+		}},
+		{"checksum of the last record", func(data []byte) []byte {
+			data[len(data)-1] ^= 0xff
+			return data
+		}},
+		{"impossible length", func(data []byte) []byte {
+			return append(data, newRecord(actionsignal, make([]byte, maxRecordSize+1))[:9]...)
+		}},
+		{"block out of order", func(data []byte) []byte {
+			payload := make([]byte, 0)
+			util.PutUint64(10, &payload)
+			return append(data, newRecord(blocksignal, payload)...)
+		}},
+	}
+	for _, test := range tests {
+		dir := t.TempDir()
+		b := openTestChain(t, dir)
+		fillChain(b)
+		b.Close()
+		path := b.segmentPath(0)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = test.corrupt(data)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := openBlockchain(dir, ""); err == nil {
+			t.Errorf("%v: corrupted blockchain opened", test.name)
+		}
+		if stat, _ := os.Stat(path); stat.Size() != int64(len(data)) {
+			t.Errorf("%v: corrupted segment modified", test.name)
+		}
+	}
+}
+
+func legacyChain(blocks [][][]byte) []byte {
+	data := make([]byte, 9) // genesis
+	for n, actions := range blocks {
+		if n > 0 {
+			data = append(data, blocksignal)
+			util.PutUint64(uint64(n), &data)
+		}
+		for _, action := range actions {
+			data = append(data, actionsignal)
+			util.PutUint64(uint64(len(action)), &data)
+			data = append(data, action...)
+		}
+	}
+	return data
+}
+
+func TestLegacyImport(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "chain.dat")
+	chain := filepath.Join(dir, "chain")
+	blocks := [][][]byte{{[]byte("signin")}, testBlocks[0], testBlocks[1], testBlocks[2]}
+	data := legacyChain(blocks)
+	// interrupted write of an action
+	data = append(data, actionsignal, 50, 0, 0)
+	if err := os.WriteFile(legacy, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, exists, err := openBlockchain(chain, legacy)
+	if err != nil || !exists {
+		t.Fatalf("legacy chain not imported: exists %v, error %v", exists, err)
+	}
+	checkChain(t, b, testBlocks)
+	genesis, err := b.Blocks(0, 1)
+	if err != nil || !reflect.DeepEqual(genesis[0].data, blocks[0]) {
+		t.Errorf("genesis block imported as %q", genesis[0].data)
+	}
+	b.Close()
+	if _, err := os.Stat(legacy + ".imported"); err != nil {
+		t.Errorf("legacy chain not renamed: %v", err)
+	}
+	checkChain(t, openTestChain(t, chain), testBlocks)
+	// the legacy file is back along the segments
+	if err := os.WriteFile(legacy, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := openBlockchain(chain, legacy); err == nil {
+		t.Error("blockchain opened with a legacy chain file along the segments")
+	}
+}
+
+func TestLegacyImportCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "chain.dat")
+	data := legacyChain([][][]byte{{}, {}})
+	data = append(data, blocksignal)
+	util.PutUint64(5, &data)
+	if err := os.WriteFile(legacy, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := openBlockchain(filepath.Join(dir, "chain"), legacy); err == nil {
+		t.Error("corrupted legacy chain imported")
+	}
+	if _, err := os.Stat(legacy); errors.Is(err, os.ErrNotExist) {
+		t.Error("corrupted legacy chain renamed")
+	}
+}
@@ -2,16 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/freehandle/breeze/crypto"
 	"github.com/freehandle/breeze/middleware/simple"
 	"github.com/freehandle/breeze/middleware/social"
+	"github.com/freehandle/breeze/socket"
 	"github.com/freehandle/breeze/util"
 	"github.com/freehandle/handles/attorney"
 	"github.com/freehandle/synergy/api"
@@ -27,6 +30,8 @@ const (
 	notarypath          = ""
 	blocksPath          = ""
 	blocksName          = "chain"
+	snapshotPath        = "state.snapshot"
+	mediaPath           = "media"
 )
 
 type ByArraySender chan []byte
@@ -59,10 +64,32 @@ func launchLocalChain(ctx context.Context, listeners []chan []byte, receiver cha
 	return <-chain.Start(ctx)
 }
 
-func launchSynergyServer(gateway chan []byte, receive chan []byte, synergyPass, emailPass string, vault *config.SecretsVault) {
+// gatewayConfig points to a synergy gateway (cmd/gateway). When set the node
+// keeps a supervised connection to it instead of the local breeze chain.
+type gatewayConfig struct {
+	address string
+	token   crypto.Token
+}
+
+func launchSynergyServer(ctx context.Context, gateway chan []byte, receive chan []byte, remote *gatewayConfig, snapshotInterval uint64, synergyPass, emailPass string, vault *config.SecretsVault) {
+	media, err := state.NewDiskMediaStore(mediaPath)
+	if err != nil {
+		log.Fatalf("could not open media store: %v", err)
+	}
 	indexer := index.NewIndex()
-	genesis := state.GenesisState(indexer)
-	indexer.SetState(genesis)
+	genesis, err := state.LoadSnapshot(snapshotPath, indexer)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("could not restore state snapshot, replaying entire chain: %v", err)
+		}
+		genesis = state.GenesisState(indexer)
+		genesis.SetMediaStore(media)
+		indexer.SetState(genesis)
+	} else {
+		log.Printf("state restored from snapshot at epoch %v", genesis.Epoch)
+		genesis.SetMediaStore(media)
+		indexer.RebuildFromState(genesis)
+	}
 
 	attorneySecret := vault.PK
 	cookieStore := api.OpenCokieStore("cookies.dat", genesis)
@@ -82,6 +109,8 @@ func launchSynergyServer(gateway chan []byte, receive chan []byte, synergyPass,
 		Port:        3000,
 		Safe:        8090,
 		//ServerName:    "/synergy",
+		SnapshotPath:     snapshotPath,
+		SnapshotInterval: snapshotInterval,
 	}
 	attorney, finalize := api.NewGeneralAttorneyServer(config)
 	if attorney == nil {
@@ -96,8 +125,18 @@ func launchSynergyServer(gateway chan []byte, receive chan []byte, synergyPass,
 		SynergyApp:    attorneySecret.PublicKey(),
 	}
 	genesis.Axe = handles
-	signal := network.ByteArrayToSignal(receive)
-	network.NewSynergyNode(handles, attorney, signal)
+	if remote == nil {
+		network.NewSynergyNode(handles, attorney, network.ByteArrayToSignal(receive))
+		return
+	}
+	dial := func() (network.Connection, error) {
+		return socket.Dial("", remote.address, attorneySecret, remote.token)
+	}
+	signal := make(chan *network.Signal)
+	supervisor := network.NewSupervisor(dial, genesis.Epoch, signal, gateway)
+	attorney.RegisterHealth(supervisor)
+	supervisor.Start(ctx)
+	network.NewGatewayNode(handles, attorney, signal)
 }
 
 func main() {
@@ -105,11 +144,25 @@ func main() {
 	envs := os.Environ()
 	var emailPassword string
 	var synergyPassword string
+	var gatewayAddress, gatewayToken string
+	var snapshotInterval uint64 = state.SnapshotInterval
 	for _, env := range envs {
 		if strings.HasPrefix(env, "FREEHANDLE_SECRET=") {
 			emailPassword, _ = strings.CutPrefix(env, "FREEHANDLE_SECRET=")
 		} else if strings.HasPrefix(env, "SYNERGY_SECRET=") {
 			synergyPassword, _ = strings.CutPrefix(env, "SYNERGY_SECRET=")
+		} else if strings.HasPrefix(env, "SYNERGY_GATEWAY=") {
+			gatewayAddress, _ = strings.CutPrefix(env, "SYNERGY_GATEWAY=")
+		} else if strings.HasPrefix(env, "SYNERGY_GATEWAY_TOKEN=") {
+			gatewayToken, _ = strings.CutPrefix(env, "SYNERGY_GATEWAY_TOKEN=")
+		} else if strings.HasPrefix(env, "SYNERGY_SNAPSHOT_INTERVAL=") {
+			// epocas entre snapshots do estado (0 desliga os snapshots)
+			interval, _ := strings.CutPrefix(env, "SYNERGY_SNAPSHOT_INTERVAL=")
+			epochs, err := strconv.ParseUint(interval, 10, 64)
+			if err != nil {
+				log.Fatalf("invalid SYNERGY_SNAPSHOT_INTERVAL: %v", err)
+			}
+			snapshotInterval = epochs
 		}
 	}
 
@@ -126,16 +179,28 @@ func main() {
 	ctxBack := context.Background()
 	ctx, cancel := context.WithCancel(ctxBack)
 
-	// HARD CODED ENDERECO DA CHAIN
-	synergyListener := simple.DissociateActions(ctx, simple.NewBlockReader(ctx, "/home/lienko/setembro/handles/cmd/proxy-handles", "blocos", time.Second))
-	//safeListener := simple.DissociateActions(ctx, simple.NewBlockReader(ctx, "", "blocos", time.Second))
-
-	//breezeToken, _ := crypto.RandomAsymetricKey()
-	breezeToken := crypto.TokenFromString("91ad274d06c4be307a332a0e59449ad25ae2c65e4ad5a8f0af87067ac2fc3a54")
-	fmt.Println("Using breeze token:", breezeToken.String())
-	sender, err := simple.Gateway(ctx, 7000, breezeToken, vault.PK)
-	if err != nil {
-		log.Fatalf("error creating gateway: %v", err)
+	var sender, synergyListener chan []byte
+	var remote *gatewayConfig
+	if gatewayAddress != "" {
+		// gateway synergy: conexao supervisionada, com reconexao
+		remote = &gatewayConfig{address: gatewayAddress, token: crypto.TokenFromString(gatewayToken)}
+		sender = make(chan []byte)
+		fmt.Println("Using synergy gateway:", gatewayAddress)
+	} else {
+		// HARD CODED ENDERECO DA CHAIN
+		// a chain local e sempre lida desde o inicio: depois de restaurar um
+		// snapshot os blocos anteriores sao lidos e descartados pelo attorney,
+		// o reinicio rapido (sem ler a chain inteira) so existe com o gateway
+		synergyListener = simple.DissociateActions(ctx, simple.NewBlockReader(ctx, "/home/lienko/setembro/handles/cmd/proxy-handles", "blocos", time.Second))
+		//safeListener := simple.DissociateActions(ctx, simple.NewBlockReader(ctx, "", "blocos", time.Second))
+
+		//breezeToken, _ := crypto.RandomAsymetricKey()
+		breezeToken := crypto.TokenFromString("91ad274d06c4be307a332a0e59449ad25ae2c65e4ad5a8f0af87067ac2fc3a54")
+		fmt.Println("Using breeze token:", breezeToken.String())
+		sender, err = simple.Gateway(ctx, 7000, breezeToken, vault.PK)
+		if err != nil {
+			log.Fatalf("error creating gateway: %v", err)
+		}
 	}
 
 	//sender := make(chan []byte)
@@ -161,7 +226,7 @@ func main() {
 
 	/* Initilize Synergy Server */
 
-	go launchSynergyServer(sender, synergyListener, synergyPassword, emailPassword, vault)
+	go launchSynergyServer(ctx, sender, synergyListener, remote, snapshotInterval, synergyPassword, emailPassword, vault)
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
@@ -52,34 +52,7 @@ func NewSynergyNode(axe *HandlesDB, attorneyGeneral *api.AttorneyGeneral, signal
 			epoch, _ := util.ParseUint64(signal.Data, 0)
 			attorneyGeneral.SetEpoch(epoch)
 		} else if signal.Signal == 1 {
-			if attorney.IsAxeNonVoid(signal.Data) {
-				if attorney.Kind(signal.Data) == attorney.GrantPowerOfAttorneyType {
-					grant := attorney.ParseGrantPowerOfAttorney(signal.Data)
-					if grant != nil {
-						if attorneyGeneral.Token.Equal(grant.Attorney) {
-							if user, ok := axe.TokenToHandle[grant.Author]; ok {
-								attorneyGeneral.IncorporateGrantPower(user.Handle, grant)
-							}
-						}
-					}
-				} else if attorney.Kind(signal.Data) == attorney.RevokePowerOfAttorneyType {
-					revoke := attorney.ParseGrantPowerOfAttorney(signal.Data)
-					if revoke != nil {
-						if attorneyGeneral.Token.Equal(revoke.Attorney) {
-							if user, ok := axe.TokenToHandle[revoke.Author]; ok {
-								attorneyGeneral.IncorporateRevokePower(user.Handle)
-							}
-						}
-					}
-				} else if attorney.Kind(signal.Data) == attorney.JoinNetworkType {
-					join := attorney.ParseJoinNetwork(signal.Data)
-					if join != nil {
-						axe.IncorporateJoin(signal.Data)
-					}
-				} else if attorney.Kind(signal.Data) == attorney.UpdateInfoType {
-					axe.IncorporateUpdate(signal.Data)
-				}
-			}
+			incorporateAxe(axe, attorneyGeneral, signal.Data)
 			synergyAction := axe.Incorporate(signal.Data)
 			if synergyAction != nil {
 				action := BreezeToSynergy(signal.Data)
@@ -92,3 +65,76 @@ func NewSynergyNode(axe *HandlesDB, attorneyGeneral *api.AttorneyGeneral, signal
 		}
 	}
 }
+
+// incorporateAxe keeps the handles and the powers of attorney of the axé
+// actions.
+func incorporateAxe(axe *HandlesDB, attorneyGeneral *api.AttorneyGeneral, data []byte) {
+	if !attorney.IsAxeNonVoid(data) {
+		return
+	}
+	if attorney.Kind(data) == attorney.GrantPowerOfAttorneyType {
+		grant := attorney.ParseGrantPowerOfAttorney(data)
+		if grant != nil {
+			if attorneyGeneral.Token.Equal(grant.Attorney) {
+				if user, ok := axe.TokenToHandle[grant.Author]; ok {
+					attorneyGeneral.IncorporateGrantPower(user.Handle, grant)
+				}
+			}
+		}
+	} else if attorney.Kind(data) == attorney.RevokePowerOfAttorneyType {
+		revoke := attorney.ParseGrantPowerOfAttorney(data)
+		if revoke != nil {
+			if attorneyGeneral.Token.Equal(revoke.Attorney) {
+				if user, ok := axe.TokenToHandle[revoke.Author]; ok {
+					attorneyGeneral.IncorporateRevokePower(user.Handle)
+				}
+			}
+		}
+	} else if attorney.Kind(data) == attorney.JoinNetworkType {
+		join := attorney.ParseJoinNetwork(data)
+		if join != nil {
+			axe.IncorporateJoin(data)
+		}
+	} else if attorney.Kind(data) == attorney.UpdateInfoType {
+		axe.IncorporateUpdate(data)
+	}
+}
+
+// chainNode incorporates the blocks of the chain (an *api.AttorneyGeneral).
+type chainNode interface {
+	SetEpoch(epoch uint64)
+	Incorporate(action []byte)
+}
+
+// NewGatewayNode is NewSynergyNode for the chain of a synergy gateway
+// (cmd/gateway). Actions are sent to the gateway dressed for breeze, as on the
+// local chain, but the gateway stores the synergy actions already undressed
+// (see BreezeToSynergy) and only the axé actions as received.
+func NewGatewayNode(axe *HandlesDB, attorneyGeneral *api.AttorneyGeneral, signals chan *Signal) {
+	gatewayNode(signals, attorneyGeneral, func(data []byte) bool {
+		if !attorney.IsAxeNonVoid(data) {
+			return false
+		}
+		incorporateAxe(axe, attorneyGeneral, data)
+		axe.Incorporate(data)
+		return true
+	})
+}
+
+// gatewayNode forwards the synergy actions of the gateway to node and the axé
+// ones to axe, which tells them apart.
+func gatewayNode(signals chan *Signal, node chainNode, axe func(data []byte) bool) {
+	for signal := range signals {
+		switch signal.Signal {
+		case BlockMessage:
+			epoch, _ := util.ParseUint64(signal.Data, 0)
+			node.SetEpoch(epoch)
+		case ActionMessage:
+			if !axe(signal.Data) {
+				node.Incorporate(signal.Data)
+			}
+		default:
+			log.Printf("invalid signal: %v", signal.Signal)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package network
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/freehandle/synergy/api"
+)
+
+// Backoff between reconnection attempts: it starts at MinBackoff, doubles on
+// every failure up to MaxBackoff and is reset by a connection that lasts at
+// least MaxBackoff.
+const (
+	MinBackoff = time.Second
+	MaxBackoff = time.Minute
+)
+
+// Backoff spaces the reconnection attempts of a connection.
+type Backoff struct {
+	delay time.Duration
+}
+
+// Reset is called after a successful connection.
+func (b *Backoff) Reset() {
+	b.delay = 0
+}
+
+// Wait sleeps until the next attempt, returning false if ctx is done first.
+// Half of the delay is random so that nodes do not reconnect all at once.
+func (b *Backoff) Wait(ctx context.Context) bool {
+	b.delay *= 2
+	if b.delay == 0 {
+		b.delay = MinBackoff
+	} else if b.delay > MaxBackoff {
+		b.delay = MaxBackoff
+	}
+	wait := b.delay/2 + time.Duration(rand.Int63n(int64(b.delay/2)+1))
+	select {
+	case <-time.After(wait):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// MaxPendingActions bounds the actions buffered while disconnected. Beyond it
+// the oldest ones are dropped.
+const MaxPendingActions = 1 << 12
+
+// Dialer opens a new connection to the gateway.
+type Dialer func() (Connection, error)
+
+// Supervisor keeps the connection of the node to the gateway. A failed
+// connection is dialed again with exponential backoff and the sync resumes
+// from the last incorporated action. Outgoing actions are buffered while
+// disconnected and sent on reconnection. Actions sent just before a failure
+// may still be lost in transit.
+//
+// Outgoing actions are forwarded as they are, dressed for breeze: the gateway
+// undresses the synergy ones before storing them, so the signals carry the
+// format of the gateway (see NewGatewayNode).
+type Supervisor struct {
+	mu          sync.Mutex
+	dial        Dialer
+	client      *SyncClient
+	outgoing    chan []byte
+	conn        Connection
+	pending     [][]byte
+	reconnected chan struct{}
+	attempts    int
+	lastError   string
+	since       time.Time
+}
+
+// NewSupervisor syncs from epoch into signals and forwards the actions of
+// outgoing to the gateway. Nothing happens until Start.
+func NewSupervisor(dial Dialer, epoch uint64, signals chan *Signal, outgoing chan []byte) *Supervisor {
+	return &Supervisor{
+		dial:        dial,
+		client:      NewSyncClient(epoch, signals),
+		outgoing:    outgoing,
+		pending:     make([][]byte, 0),
+		reconnected: make(chan struct{}, 1),
+		since:       time.Now(),
+	}
+}
+
+// Start runs the supervisor until ctx is done.
+func (s *Supervisor) Start(ctx context.Context) {
+	go s.forward(ctx)
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		if s.conn != nil {
+			s.conn.Shutdown()
+		}
+		s.mu.Unlock()
+	}()
+	go s.run(ctx)
+}
+
+func (s *Supervisor) run(ctx context.Context) {
+	backoff := &Backoff{}
+	for {
+		conn, err := s.dial()
+		if err != nil {
+			s.mu.Lock()
+			s.attempts++
+			attempts := s.attempts
+			s.lastError = err.Error()
+			s.mu.Unlock()
+			log.Printf("could not connect to gateway (attempt %v): %v", attempts, err)
+			if !backoff.Wait(ctx) {
+				return
+			}
+			continue
+		}
+		s.mu.Lock()
+		if ctx.Err() != nil {
+			s.mu.Unlock()
+			conn.Shutdown()
+			return
+		}
+		s.conn = conn
+		s.attempts = 0
+		s.since = time.Now()
+		s.mu.Unlock()
+		select {
+		case s.reconnected <- struct{}{}:
+		default:
+		}
+		epoch, _ := s.client.Position()
+		log.Printf("connected to gateway, syncing from epoch %v", epoch)
+
+		err = s.client.Run(conn)
+
+		s.mu.Lock()
+		s.conn = nil
+		s.lastError = err.Error()
+		connected := time.Since(s.since)
+		s.since = time.Now()
+		s.mu.Unlock()
+		conn.Shutdown()
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("connection to gateway lost: %v", err)
+		// um gateway que derruba a conexao logo apos aceitar nao e reconectado
+		// sem espera
+		if connected >= MaxBackoff {
+			backoff.Reset()
+		} else if !backoff.Wait(ctx) {
+			return
+		}
+	}
+}
+
+// forward sends the outgoing actions in order, buffering them while there is
+// no connection.
+func (s *Supervisor) forward(ctx context.Context) {
+	for {
+		select {
+		case action := <-s.outgoing:
+			s.mu.Lock()
+			s.pending = append(s.pending, action)
+			if len(s.pending) > MaxPendingActions {
+				log.Printf("gateway unavailable: dropping buffered action")
+				s.pending = s.pending[1:]
+			}
+			s.mu.Unlock()
+			s.flush()
+		case <-s.reconnected:
+			s.flush()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Supervisor) flush() {
+	for {
+		s.mu.Lock()
+		conn := s.conn
+		if conn == nil || len(s.pending) == 0 {
+			s.mu.Unlock()
+			return
+		}
+		action := s.pending[0]
+		s.mu.Unlock()
+		if err := conn.Send(action); err != nil {
+			// mantida no buffer: a leitura tambem falha e o run reconecta
+			log.Printf("error sending action, kept for reconnection: %v", err)
+			return
+		}
+		s.mu.Lock()
+		s.pending = s.pending[1:]
+		s.mu.Unlock()
+	}
+}
+
+// Health reports the state of the connection to the UI.
+func (s *Supervisor) Health() api.ChainHealth {
+	epoch, _ := s.client.Position()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return api.ChainHealth{
+		Connected: s.conn != nil,
+		Live:      s.conn != nil && s.client.Live(),
+		Epoch:     epoch,
+		Attempts:  s.attempts,
+		Pending:   len(s.pending),
+		LastError: s.lastError,
+		Since:     s.since,
+	}
+}
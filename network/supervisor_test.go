@@ -0,0 +1,108 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/freehandle/breeze/crypto"
+	breeze "github.com/freehandle/breeze/protocol/actions"
+	"github.com/freehandle/breeze/util"
+	"github.com/freehandle/handles/attorney"
+	"github.com/freehandle/synergy/api"
+)
+
+// testNode records what the gateway node incorporates.
+type testNode struct {
+	epochs  chan uint64
+	actions chan []byte
+}
+
+func (n *testNode) SetEpoch(epoch uint64) {
+	n.epochs <- epoch
+}
+
+func (n *testNode) Incorporate(action []byte) {
+	n.actions <- action
+}
+
+// dressedAction dresses a synergy payload for breeze as the attorney does.
+func dressedAction(epoch uint64, payload string) []byte {
+	data := []byte{0, breeze.IVoid}
+	util.PutUint64(epoch, &data)
+	data = append(data, 1, 1, 0, 0, attorney.VoidType)
+	data = append(data, bytes.Repeat([]byte{0xff}, crypto.TokenSize)...)
+	data = append(data, payload...)
+	return append(data, make([]byte, api.DressedTailSize)...)
+}
+
+func TestSupervisorGatewayFormats(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dressed := dressedAction(257, "acao")
+	// what the gateway stores and sends back
+	stored := BreezeToSynergy(dressed)
+	if stored == nil {
+		t.Fatal("synergy action not undressed")
+	}
+	if !bytes.Equal(dressed, dressedAction(257, "acao")) {
+		t.Fatal("dressed action modified when undressed")
+	}
+	conn := &testConn{
+		messages: [][]byte{multiBlock(SyncBlock{257, [][]byte{stored}}), Live(257, 1)},
+		done:     ctx.Done(),
+	}
+	dialed := false
+	dial := func() (Connection, error) {
+		if dialed {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		dialed = true
+		return conn, nil
+	}
+	signals := make(chan *Signal)
+	outgoing := make(chan []byte)
+	node := &testNode{epochs: make(chan uint64, 10), actions: make(chan []byte, 10)}
+	supervisor := NewSupervisor(dial, 257, signals, outgoing)
+	supervisor.Start(ctx)
+	go gatewayNode(signals, node, func(data []byte) bool { return attorney.IsAxeNonVoid(data) })
+
+	select {
+	case epoch := <-node.epochs:
+		if epoch != 257 {
+			t.Errorf("node at epoch %v", epoch)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("block not incorporated")
+	}
+	select {
+	case action := <-node.actions:
+		if !bytes.Equal(action, stored) {
+			t.Errorf("node incorporated %v, expected %v", action, stored)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("gateway action not incorporated")
+	}
+
+	// actions go to the gateway dressed, as it expects them
+	outgoing <- dressed
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		sent := conn.Sent()
+		if len(sent) == 2 {
+			if !bytes.Equal(sent[1], dressed) {
+				t.Errorf("sent %v, expected %v", sent[1], dressed)
+			}
+			if !bytes.Equal(BreezeToSynergy(sent[1]), stored) {
+				t.Error("sent action not accepted by the gateway")
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("action not sent: %v", sent)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,244 @@
+package network
+
+import (
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/freehandle/breeze/util"
+)
+
+// Sync protocol between a synergy node and the gateway.
+//
+// Upon connection the node asks for what it is missing:
+// sync request                 (byte)           3
+// Epoch                        (8 bytes)        1
+// Actions already received     (8 bytes)        9
+//
+// Epoch is the first block the node does not have in full and Actions the
+// number of actions of that block it has already received (0 for a node
+// starting from genesis or from a snapshot taken at Epoch). The gateway
+// answers with multiblock messages, each at most SyncBatchSize bytes (a larger
+// block goes alone):
+// multiblock                   (byte)           2
+// Epoch                        (8 bytes)
+// Actions                      (actions array)
+// ... repeated
+//
+// The first block of the answer skips the actions already received. After the
+// last stored block the gateway sends
+// live                         (byte)           4
+// Epoch                        (8 bytes)        1
+// Actions                      (8 bytes)        9
+// and goes on with the live new block (0) and action (1) messages from that
+// exact point, so that the node neither misses nor repeats an action. A node
+// that loses the connection resumes with a new request from where it stopped.
+const (
+	BlockMessage       byte = 0
+	ActionMessage      byte = 1
+	MultiBlockMessage  byte = 2
+	SyncRequestMessage byte = 3
+	LiveMessage        byte = 4
+)
+
+// SyncBatchSize bounds the size of the multiblock messages of the sync.
+const SyncBatchSize = 1 << 20
+
+var ErrInvalidSyncMessage = errors.New("invalid sync message")
+
+// SyncRequest asks for the blocks starting at epoch, skipping the first
+// actions of that block.
+func SyncRequest(epoch uint64, actions int) []byte {
+	data := []byte{SyncRequestMessage}
+	util.PutUint64(epoch, &data)
+	util.PutUint64(uint64(actions), &data)
+	return data
+}
+
+func ParseSyncRequest(data []byte) (uint64, int, bool) {
+	return parsePosition(data, SyncRequestMessage)
+}
+
+// Live marks the end of the stored blocks: the following messages are live.
+func Live(epoch uint64, actions int) []byte {
+	data := []byte{LiveMessage}
+	util.PutUint64(epoch, &data)
+	util.PutUint64(uint64(actions), &data)
+	return data
+}
+
+func ParseLive(data []byte) (uint64, int, bool) {
+	return parsePosition(data, LiveMessage)
+}
+
+func parsePosition(data []byte, kind byte) (uint64, int, bool) {
+	if len(data) != 17 || data[0] != kind {
+		return 0, 0, false
+	}
+	epoch, position := util.ParseUint64(data, 1)
+	actions, _ := util.ParseUint64(data, position)
+	return epoch, int(actions), true
+}
+
+// PutSyncBlock appends a block to a multiblock message.
+func PutSyncBlock(epoch uint64, actions [][]byte, data *[]byte) {
+	util.PutUint64(epoch, data)
+	util.PutActionsArray(actions, data)
+}
+
+type SyncBlock struct {
+	Epoch   uint64
+	Actions [][]byte
+}
+
+func ParseMultiBlocks(data []byte) ([]SyncBlock, error) {
+	if len(data) < 9 || data[0] != MultiBlockMessage {
+		return nil, ErrInvalidSyncMessage
+	}
+	blocks := make([]SyncBlock, 0)
+	position := 1
+	for position < len(data) {
+		block := SyncBlock{}
+		block.Epoch, position = util.ParseUint64(data, position)
+		block.Actions, position = util.ParseActionsArray(data, position)
+		if position > len(data) {
+			return nil, ErrInvalidSyncMessage
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// Connection is the connection of a node to the gateway, a
+// *socket.SignedConnection outside of the tests.
+type Connection interface {
+	Send(data []byte) error
+	Read() ([]byte, error)
+	Shutdown()
+}
+
+// SyncClient keeps the position of a node on the chain of the gateway and
+// translates the sync protocol into signals.
+type SyncClient struct {
+	mu      sync.Mutex // protege a posicao lida por outras goroutines
+	epoch   uint64     // bloco corrente
+	actions int        // acoes do bloco corrente ja recebidas
+	started bool       // sinal do bloco corrente ja emitido
+	live    bool
+	signals chan *Signal
+}
+
+// NewSyncClient starts the sync at epoch, usually the epoch of the restored
+// snapshot (0 for genesis).
+func NewSyncClient(epoch uint64, signals chan *Signal) *SyncClient {
+	return &SyncClient{
+		epoch:   epoch,
+		signals: signals,
+	}
+}
+
+// Position returns the current epoch and the number of its actions received.
+func (c *SyncClient) Position() (uint64, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.epoch, c.actions
+}
+
+// Live tells if the client has caught up with the gateway.
+func (c *SyncClient) Live() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.live
+}
+
+func (c *SyncClient) setLive(live bool) {
+	c.mu.Lock()
+	c.live = live
+	c.mu.Unlock()
+}
+
+func (c *SyncClient) block(epoch uint64) error {
+	if c.started && epoch == c.epoch {
+		// bloco retomado apos reconexao
+		return nil
+	}
+	if c.started && epoch != c.epoch+1 || !c.started && epoch != c.epoch {
+		return ErrInvalidSyncMessage
+	}
+	c.mu.Lock()
+	c.epoch = epoch
+	c.actions = 0
+	c.started = true
+	c.mu.Unlock()
+	data := make([]byte, 0, 8)
+	util.PutUint64(epoch, &data)
+	c.signals <- &Signal{Signal: BlockMessage, Data: data}
+	return nil
+}
+
+func (c *SyncClient) action(action []byte) {
+	c.mu.Lock()
+	c.actions++
+	c.mu.Unlock()
+	c.signals <- &Signal{Signal: ActionMessage, Data: action}
+}
+
+// Run asks the gateway for the missing blocks and forwards them and the live
+// stream as signals. It returns when the connection fails or the gateway
+// sends something out of order; calling Run with a new connection resumes the
+// transfer from where it stopped.
+func (c *SyncClient) Run(conn Connection) error {
+	c.setLive(false)
+	epoch, actions := c.Position()
+	if err := conn.Send(SyncRequest(epoch, actions)); err != nil {
+		return err
+	}
+	for {
+		data, err := conn.Read()
+		if err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			continue
+		}
+		switch data[0] {
+		case BlockMessage:
+			if len(data) != 9 {
+				return ErrInvalidSyncMessage
+			}
+			epoch, _ := util.ParseUint64(data, 1)
+			if err := c.block(epoch); err != nil {
+				return err
+			}
+		case ActionMessage:
+			if !c.started {
+				return ErrInvalidSyncMessage
+			}
+			if len(data) > 1 {
+				c.action(data[1:])
+			}
+		case MultiBlockMessage:
+			blocks, err := ParseMultiBlocks(data)
+			if err != nil {
+				return err
+			}
+			for _, block := range blocks {
+				if err := c.block(block.Epoch); err != nil {
+					return err
+				}
+				for _, action := range block.Actions {
+					c.action(action)
+				}
+			}
+		case LiveMessage:
+			epoch, actions, ok := ParseLive(data)
+			if !ok || epoch != c.epoch || actions != c.actions {
+				return ErrInvalidSyncMessage
+			}
+			c.setLive(true)
+			log.Printf("synced with gateway at epoch %v", epoch)
+		default:
+			log.Printf("invalid message type: %v", data[0])
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package network
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/freehandle/breeze/util"
+)
+
+// testConn plays the messages of a gateway and fails once they are over, or
+// once done is closed if there is one.
+type testConn struct {
+	mu       sync.Mutex
+	messages [][]byte
+	sent     [][]byte
+	done     <-chan struct{}
+}
+
+func (c *testConn) Send(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, data)
+	return nil
+}
+
+func (c *testConn) Sent() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([][]byte{}, c.sent...)
+}
+
+func (c *testConn) Read() ([]byte, error) {
+	if len(c.messages) == 0 {
+		if c.done != nil {
+			<-c.done
+		}
+		return nil, io.EOF
+	}
+	data := c.messages[0]
+	c.messages = c.messages[1:]
+	return data, nil
+}
+
+func (c *testConn) Shutdown() {}
+
+func multiBlock(blocks ...SyncBlock) []byte {
+	data := []byte{MultiBlockMessage}
+	for _, block := range blocks {
+		PutSyncBlock(block.Epoch, block.Actions, &data)
+	}
+	return data
+}
+
+func blockMessage(epoch uint64) []byte {
+	data := []byte{BlockMessage}
+	util.PutUint64(epoch, &data)
+	return data
+}
+
+func actionMessage(action string) []byte {
+	return append([]byte{ActionMessage}, action...)
+}
+
+// received renders the signals as "#epoch" for blocks and the action itself
+func received(signals chan *Signal) []string {
+	out := make([]string, 0)
+	for {
+		select {
+		case signal := <-signals:
+			if signal.Signal == BlockMessage {
+				epoch, _ := util.ParseUint64(signal.Data, 0)
+				out = append(out, "#"+string(rune('0'+epoch)))
+			} else {
+				out = append(out, string(signal.Data))
+			}
+		default:
+			return out
+		}
+	}
+}
+
+func TestSyncClientResume(t *testing.T) {
+	signals := make(chan *Signal, 100)
+	client := NewSyncClient(5, signals)
+
+	// the connection fails in the middle of block 6
+	conn := &testConn{messages: [][]byte{
+		multiBlock(SyncBlock{5, [][]byte{[]byte("a"), []byte("b")}}, SyncBlock{6, [][]byte{[]byte("c")}}),
+	}}
+	if err := client.Run(conn); !errors.Is(err, io.EOF) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(conn.Sent(), [][]byte{SyncRequest(5, 0)}) {
+		t.Errorf("first request %v", conn.Sent())
+	}
+	if epoch, actions := client.Position(); epoch != 6 || actions != 1 || client.Live() {
+		t.Errorf("stopped at epoch %v with %v actions, live %v", epoch, actions, client.Live())
+	}
+
+	// the gateway resumes block 6 skipping the action already received, then
+	// hands over to the live stream
+	conn = &testConn{messages: [][]byte{
+		multiBlock(SyncBlock{6, [][]byte{[]byte("d")}}, SyncBlock{7, [][]byte{}}),
+		Live(7, 0),
+		blockMessage(8),
+		actionMessage("e"),
+	}}
+	if err := client.Run(conn); !errors.Is(err, io.EOF) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(conn.Sent(), [][]byte{SyncRequest(6, 1)}) {
+		t.Errorf("resume request %v", conn.Sent())
+	}
+	if epoch, actions := client.Position(); epoch != 8 || actions != 1 || !client.Live() {
+		t.Errorf("stopped at epoch %v with %v actions, live %v", epoch, actions, client.Live())
+	}
+	expected := []string{"#5", "a", "b", "#6", "c", "d", "#7", "#8", "e"}
+	if signals := received(signals); !reflect.DeepEqual(signals, expected) {
+		t.Errorf("signals %v, expected %v", signals, expected)
+	}
+}
+
+func TestSyncClientInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages [][]byte
+	}{
+		{"action before any block", [][]byte{actionMessage("a")}},
+		{"first block after the epoch", [][]byte{multiBlock(SyncBlock{6, nil})}},
+		{"first block before the epoch", [][]byte{blockMessage(4)}},
+		{"skipped block", [][]byte{blockMessage(5), blockMessage(7)}},
+		{"live before the last action", [][]byte{multiBlock(SyncBlock{5, [][]byte{[]byte("a")}}), Live(5, 2)}},
+		{"live at another epoch", [][]byte{multiBlock(SyncBlock{5, nil}, SyncBlock{6, nil}), Live(5, 0)}},
+		{"malformed block", [][]byte{{BlockMessage, 5}}},
+		{"malformed multiblock", [][]byte{append(multiBlock(SyncBlock{5, nil}), 1)}},
+	}
+	for _, test := range tests {
+		client := NewSyncClient(5, make(chan *Signal, 100))
+		if err := client.Run(&testConn{messages: test.messages}); !errors.Is(err, ErrInvalidSyncMessage) {
+			t.Errorf("%v: expected %v, got %v", test.name, ErrInvalidSyncMessage, err)
+		}
+		if client.Live() {
+			t.Errorf("%v: client live", test.name)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/freehandle/breeze/socket"
 	"github.com/freehandle/breeze/util"
 	"github.com/freehandle/handles/attorney"
+	"github.com/freehandle/synergy/network"
 )
 
 const HandlePort = 8000
@@ -93,33 +94,62 @@ func GenesisHandlesServer(ctx context.Context, pk crypto.PrivateKey) error {
 	return <-chain.Start(ctxCancel)
 }
 
-func NewHandleConnector(address string, pk crypto.PrivateKey, token crypto.Token) (chan []byte, error) {
-	conn, err := socket.Dial("", fmt.Sprintf("localhost:%d", HandlePort), pk, token)
-	if err != nil {
-		return nil, err
+// NewHandleConnector keeps a connection to the handles server at address
+// (localhost:HandlePort if empty). Data written to send is forwarded to the
+// server and data from the server is delivered on receive. A failed
+// connection is dialed again with backoff; an action not yet sent is kept and
+// the others wait on send until the connection is restored.
+func NewHandleConnector(ctx context.Context, address string, pk crypto.PrivateKey, token crypto.Token) (send chan []byte, receive chan []byte) {
+	if address == "" {
+		address = fmt.Sprintf("localhost:%d", HandlePort)
 	}
-	sender := make(chan []byte, 2)
+	send = make(chan []byte, 2)
+	receive = make(chan []byte, 2)
 	go func() {
+		backoff := &network.Backoff{}
+		var pending []byte
 		for {
-			data, err := conn.Read()
+			conn, err := socket.Dial("", address, pk, token)
 			if err != nil {
-				fmt.Println("Error reading from handle server:", err)
-				return
+				fmt.Println("Error connecting to handle server:", err)
+				if !backoff.Wait(ctx) {
+					return
+				}
+				continue
 			}
-			sender <- data
-		}
-	}()
-
-	go func() {
-		for {
-			data := <-sender
-			if err := conn.Send(data); err != nil {
-				fmt.Println("Error writing to handle server:", err)
-				return
+			backoff.Reset()
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+				for {
+					data, err := conn.Read()
+					if err != nil {
+						fmt.Println("Error reading from handle server:", err)
+						return
+					}
+					receive <- data
+				}
+			}()
+		connected:
+			for {
+				if pending == nil {
+					select {
+					case pending = <-send:
+					case <-closed:
+						break connected
+					case <-ctx.Done():
+						conn.Shutdown()
+						return
+					}
+				}
+				if err := conn.Send(pending); err != nil {
+					fmt.Println("Error writing to handle server:", err)
+					break connected
+				}
+				pending = nil
 			}
+			conn.Shutdown()
 		}
 	}()
-
-	return sender, nil
-
+	return send, receive
 }
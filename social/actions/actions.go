@@ -24,6 +24,12 @@ const (
 	AUpdateEvent
 	ACheckinEvent
 	AGreetCheckinEvent
+	ADelegate
+	AComment
+	ACreateJournal
+	AJournalEditor
+	AJournalIssue
+	ACancelCheckinEvent
 	AUnknown
 )
 
@@ -47,3 +53,119 @@ func ActionKind(data []byte) byte {
 	}
 	return actionByte
 }
+
+// ParseAction interpreta qualquer acao synergy segundo o byte de tipo. Retorna
+// nil se o tipo for desconhecido ou se a acao nao puder ser interpretada.
+func ParseAction(data []byte) Action {
+	switch ActionKind(data) {
+	case AVote:
+		if action := ParseVote(data); action != nil {
+			return action
+		}
+	case ACreateCollective:
+		if action := ParseCreateCollective(data); action != nil {
+			return action
+		}
+	case AUpdateCollective:
+		if action := ParseUpdateCollective(data); action != nil {
+			return action
+		}
+	case ARequestMembership:
+		if action := ParseRequestMembership(data); action != nil {
+			return action
+		}
+	case ARemoveMember:
+		if action := ParseRemoveMember(data); action != nil {
+			return action
+		}
+	case ADraft:
+		if action := ParseDraft(data); action != nil {
+			return action
+		}
+	case AEdit:
+		if action := ParseEdit(data); action != nil {
+			return action
+		}
+	case AMultipartMedia:
+		if action := ParseMultipartMedia(data); action != nil {
+			return action
+		}
+	case ACreateBoard:
+		if action := ParseCreateBoard(data); action != nil {
+			return action
+		}
+	case AUpdateBoard:
+		if action := ParseUpdateBoard(data); action != nil {
+			return action
+		}
+	case APin:
+		if action := ParsePin(data); action != nil {
+			return action
+		}
+	case ABoardEditor:
+		if action := ParseBoardEditor(data); action != nil {
+			return action
+		}
+	case AReleaseDraft:
+		if action := ParseReleaseDraft(data); action != nil {
+			return action
+		}
+	case AImprintStamp:
+		if action := ParseImprintStamp(data); action != nil {
+			return action
+		}
+	case AReact:
+		if action := ParseReact(data); action != nil {
+			return action
+		}
+	case ASignIn:
+		if action := ParseSignIn(data); action != nil {
+			return action
+		}
+	case ACreateEvent:
+		if action := ParseCreateEvent(data); action != nil {
+			return action
+		}
+	case ACancelEvent:
+		if action := ParseCancelEvent(data); action != nil {
+			return action
+		}
+	case AUpdateEvent:
+		if action := ParseUpdateEvent(data); action != nil {
+			return action
+		}
+	case ACheckinEvent:
+		if action := ParseCheckinEvent(data); action != nil {
+			return action
+		}
+	case AGreetCheckinEvent:
+		if action := ParseGreetCheckinEvent(data); action != nil {
+			return action
+		}
+	case ADelegate:
+		if action := ParseDelegate(data); action != nil {
+			return action
+		}
+	case AComment:
+		if action := ParseComment(data); action != nil {
+			return action
+		}
+	case ACreateJournal:
+		if action := ParseCreateJournal(data); action != nil {
+			return action
+		}
+	case AJournalEditor:
+		if action := ParseJournalEditor(data); action != nil {
+			return action
+		}
+	case AJournalIssue:
+		if action := ParseJournalIssue(data); action != nil {
+			return action
+		}
+	case ACancelCheckinEvent:
+		if action := ParseCancelCheckinEvent(data); action != nil {
+			return action
+		}
+	}
+	return nil
+}
@@ -12,8 +12,14 @@ type CreateCollective struct {
 	Name        string
 	Description string
 	Policy      Policy
+	Expiry      uint64 // epochs until a pending proposal expires (0 for default)
 }
 
+// Expiry was added after the first CreateCollective and UpdateCollective
+// actions were incorporated into the chain. It is serialized as an optional
+// tail, absent when not set, so that the actions already on the chain keep
+// parsing (and hashing) as before.
+
 func (c *CreateCollective) Reasoning() string {
 	return c.Reasons
 }
@@ -40,6 +46,9 @@ func (c *CreateCollective) Serialize() []byte {
 	util.PutString(c.Name, &bytes)
 	util.PutString(c.Description, &bytes)
 	PutPolicy(c.Policy, &bytes)
+	if c.Expiry != 0 {
+		util.PutUint64(c.Expiry, &bytes)
+	}
 	return bytes
 }
 
@@ -56,6 +65,9 @@ func ParseCreateCollective(create []byte) *CreateCollective {
 	action.Name, position = util.ParseString(create, position)
 	action.Description, position = util.ParseString(create, position)
 	action.Policy, position = ParsePolicy(create, position)
+	if position < len(create) {
+		action.Expiry, position = util.ParseUint64(create, position)
+	}
 	if position != len(create) {
 		return nil
 	}
@@ -70,6 +82,7 @@ type UpdateCollective struct {
 	Description   *string
 	Majority      *byte
 	SuperMajority *byte
+	Expiry        *uint64
 }
 
 func (c *UpdateCollective) Reasoning() string {
@@ -114,6 +127,10 @@ func (c *UpdateCollective) Serialize() []byte {
 	} else {
 		util.PutByte(0, &bytes) // there is no policy
 	}
+	if c.Expiry != nil {
+		util.PutByte(1, &bytes)
+		util.PutUint64(*c.Expiry, &bytes)
+	}
 	return bytes
 }
 
@@ -159,6 +176,18 @@ func ParseUpdateCollective(update []byte) *UpdateCollective {
 	} else {
 		position += 1
 	}
+	if position == len(update) {
+		return &action
+	}
+	// sem prazo a acao termina na supermaioria: um 0 explicito seria uma
+	// segunda serializacao (e um segundo hash) da mesma acao
+	if update[position] != 1 {
+		return nil
+	}
+	var expiry uint64
+	position += 1
+	expiry, position = util.ParseUint64(update, position)
+	action.Expiry = &expiry
 	if position != len(update) {
 		return nil
 	}
@@ -272,3 +301,71 @@ func ParseRemoveMember(update []byte) *RemoveMember {
 	}
 	return &action
 }
+
+// Delegate entrusts the vote of Author on proposals of the collective to
+// another member (liquid democracy). Kinds restricts the delegation to those
+// kinds of proposal (as enumerated by the state); empty means every kind.
+// Revoke undoes the delegation for Kinds, or every delegation of Author on the
+// collective if Kinds is empty. Delegate is ignored on revocations.
+type Delegate struct {
+	Epoch      uint64
+	Author     crypto.Token
+	Reasons    string
+	Collective string
+	Delegate   crypto.Token
+	Kinds      []byte
+	Revoke     bool
+}
+
+func (c *Delegate) Reasoning() string {
+	return c.Reasons
+}
+
+func (c *Delegate) Hashed() crypto.Hash {
+	return crypto.Hasher(c.Serialize())
+}
+
+// Afeta o coletivo
+func (c *Delegate) Affected() []crypto.Hash {
+	return []crypto.Hash{crypto.Hasher([]byte(c.Collective))}
+}
+
+func (c *Delegate) Authored() crypto.Token {
+	return c.Author
+}
+
+func (c *Delegate) Serialize() []byte {
+	bytes := make([]byte, 0)
+	util.PutUint64(c.Epoch, &bytes)
+	util.PutToken(c.Author, &bytes)
+	util.PutByte(ADelegate, &bytes)
+	util.PutString(c.Reasons, &bytes)
+	util.PutString(c.Collective, &bytes)
+	util.PutToken(c.Delegate, &bytes)
+	util.PutByteArray(c.Kinds, &bytes)
+	util.PutBool(c.Revoke, &bytes)
+	return bytes
+}
+
+func ParseDelegate(data []byte) *Delegate {
+	action := Delegate{}
+	position := 0
+	action.Epoch, position = util.ParseUint64(data, position)
+	action.Author, position = util.ParseToken(data, position)
+	if position >= len(data) || data[position] != ADelegate {
+		return nil
+	}
+	position += 1
+	action.Reasons, position = util.ParseString(data, position)
+	action.Collective, position = util.ParseString(data, position)
+	action.Delegate, position = util.ParseToken(data, position)
+	action.Kinds, position = util.ParseByteArray(data, position)
+	if len(action.Kinds) == 0 {
+		action.Kinds = nil
+	}
+	action.Revoke, position = util.ParseBool(data, position)
+	if position != len(data) {
+		return nil
+	}
+	return &action
+}
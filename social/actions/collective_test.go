@@ -5,8 +5,11 @@ import (
 	"testing"
 
 	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
 )
 
+var expiry uint64 = 15 * 24 * 60 * 60
+
 var (
 	policy = &Policy{
 		Majority:      10,
@@ -20,6 +23,46 @@ var (
 		Name:        "first_collective",
 		Description: "create collective test",
 		Policy:      *policy,
+		Expiry:      7 * 24 * 60 * 60,
+	}
+
+	quorumCollective = &CreateCollective{
+		Epoch:       14,
+		Author:      crypto.Token{},
+		Reasons:     "create quorum collective test",
+		Name:        "second_collective",
+		Description: "create quorum collective test",
+		Policy: Policy{
+			Majority:      50,
+			SuperMajority: 75,
+			Kind:          QuorumPolicy,
+			Quorum:        30,
+			Veto:          []crypto.Token{{1}, {2}},
+		},
+	}
+
+	approve = &Vote{
+		Epoch:   16,
+		Author:  crypto.Token{},
+		Reasons: "vote test",
+		Hash:    crypto.Hash{},
+		Approve: true,
+	}
+
+	abstain = &Vote{
+		Epoch:   16,
+		Author:  crypto.Token{},
+		Reasons: "abstention test",
+		Hash:    crypto.Hash{},
+		Abstain: true,
+	}
+
+	retract = &Vote{
+		Epoch:   17,
+		Author:  crypto.Token{},
+		Reasons: "retraction test",
+		Hash:    crypto.Hash{},
+		Retract: true,
 	}
 
 	uCollective = &UpdateCollective{
@@ -29,6 +72,7 @@ var (
 		Description:   nil,
 		Majority:      nil,
 		SuperMajority: nil,
+		Expiry:        &expiry,
 	}
 
 	request = &RequestMembership{
@@ -46,6 +90,22 @@ var (
 		Reasons:    "remove member test",
 		Member:     crypto.Token{},
 	}
+
+	delegate = &Delegate{
+		Epoch:      18,
+		Author:     crypto.Token{},
+		Reasons:    "delegate test",
+		Collective: "first_collective",
+		Delegate:   crypto.Token{1},
+		Kinds:      []byte{3, 4},
+	}
+
+	revoke = &Delegate{
+		Epoch:      19,
+		Author:     crypto.Token{},
+		Collective: "first_collective",
+		Revoke:     true,
+	}
 )
 
 func TestCreateCollective(t *testing.T) {
@@ -59,6 +119,103 @@ func TestCreateCollective(t *testing.T) {
 	}
 }
 
+func TestCreateQuorumCollective(t *testing.T) {
+	c := ParseCreateCollective(quorumCollective.Serialize())
+	if c == nil {
+		t.Error("Could not parse actions CreateCollective with quorum policy")
+		return
+	}
+	if !reflect.DeepEqual(c, quorumCollective) {
+		t.Error("Parse and Serialize not working for actions CreateCollective with quorum policy")
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	if err := quorumCollective.Policy.Validate(); err != nil {
+		t.Errorf("valid quorum policy rejected: %v", err)
+	}
+	invalid := []Policy{
+		{Majority: 101},
+		{Majority: 50, SuperMajority: 50, Kind: UnkownPolicy},
+		{Majority: 50, SuperMajority: 50, Kind: QuorumPolicy},
+		{Majority: 50, SuperMajority: 50, Kind: QuorumPolicy, Quorum: 101},
+	}
+	for _, p := range invalid {
+		if p.Validate() == nil {
+			t.Errorf("invalid policy accepted: %+v", p)
+		}
+		if p.legacy() {
+			// politicas no formato legado sao recusadas pelo estado, nao
+			// pelo parse, como antes
+			continue
+		}
+		bytes := make([]byte, 0)
+		PutPolicy(p, &bytes)
+		if _, position := ParsePolicy(bytes, 0); position == len(bytes) {
+			t.Errorf("invalid policy parsed: %+v", p)
+		}
+	}
+}
+
+// CreateCollective as serialized before quorum policies and Expiry
+func TestLegacyCreateCollective(t *testing.T) {
+	bytes := make([]byte, 0)
+	util.PutUint64(14, &bytes)
+	util.PutToken(crypto.Token{}, &bytes)
+	util.PutByte(ACreateCollective, &bytes)
+	util.PutString("legacy collective", &bytes)
+	util.PutString("legacy", &bytes)
+	util.PutString("legacy description", &bytes)
+	bytes = append(bytes, 60, 80)
+	c := ParseCreateCollective(bytes)
+	if c == nil {
+		t.Fatal("Could not parse legacy CreateCollective")
+	}
+	expected := Policy{Majority: 60, SuperMajority: 80}
+	if !reflect.DeepEqual(c.Policy, expected) || c.Expiry != 0 || c.Name != "legacy" {
+		t.Errorf("legacy CreateCollective parsed as %+v", c)
+	}
+	if !reflect.DeepEqual(c.Serialize(), bytes) {
+		t.Error("legacy CreateCollective does not serialize to the same bytes")
+	}
+}
+
+// Vote as serialized before abstentions and retractions
+func TestLegacyVote(t *testing.T) {
+	for _, approve := range []bool{true, false} {
+		bytes := make([]byte, 0)
+		util.PutUint64(16, &bytes)
+		util.PutToken(crypto.Token{}, &bytes)
+		util.PutByte(AVote, &bytes)
+		util.PutString("legacy vote", &bytes)
+		util.PutHash(crypto.Hash{}, &bytes)
+		util.PutBool(approve, &bytes)
+		v := ParseVote(bytes)
+		if v == nil {
+			t.Fatal("Could not parse legacy Vote")
+		}
+		if v.Approve != approve || v.Abstain || v.Retract {
+			t.Errorf("legacy Vote parsed as %+v", v)
+		}
+		if !reflect.DeepEqual(v.Serialize(), bytes) {
+			t.Error("legacy Vote does not serialize to the same bytes (and hash)")
+		}
+	}
+}
+
+func TestVote(t *testing.T) {
+	for _, vote := range []*Vote{approve, abstain, retract} {
+		v := ParseVote(vote.Serialize())
+		if v == nil {
+			t.Error("Could not parse actions Vote")
+			return
+		}
+		if !reflect.DeepEqual(v, vote) {
+			t.Error("Parse and Serialize not working for actions Vote")
+		}
+	}
+}
+
 func TestUpdateCollective(t *testing.T) {
 	u := ParseUpdateCollective(uCollective.Serialize())
 	if u == nil {
@@ -70,6 +227,96 @@ func TestUpdateCollective(t *testing.T) {
 	}
 }
 
+func TestUpdateCollectiveWithoutExpiry(t *testing.T) {
+	description := "no expiry"
+	fixture := &UpdateCollective{
+		Epoch:       15,
+		Author:      crypto.Token{},
+		Reasons:     "update collective test",
+		OnBehalfOf:  "first_collective",
+		Description: &description,
+	}
+	u := ParseUpdateCollective(fixture.Serialize())
+	if u == nil {
+		t.Error("Could not parse actions UpdateCollective without expiry")
+		return
+	}
+	if !reflect.DeepEqual(u, fixture) {
+		t.Error("Parse and Serialize not working for actions UpdateCollective without expiry")
+	}
+}
+
+// UpdateCollective as serialized before Expiry was added
+func TestLegacyUpdateCollective(t *testing.T) {
+	var majority byte = 60
+	bytes := make([]byte, 0)
+	util.PutUint64(15, &bytes)
+	util.PutToken(crypto.Token{}, &bytes)
+	util.PutByte(AUpdateCollective, &bytes)
+	util.PutString("legacy update", &bytes)
+	util.PutString("first_collective", &bytes)
+	util.PutByte(0, &bytes)
+	util.PutByte(1, &bytes)
+	util.PutByte(majority, &bytes)
+	util.PutByte(0, &bytes)
+	u := ParseUpdateCollective(bytes)
+	if u == nil {
+		t.Fatal("Could not parse legacy UpdateCollective")
+	}
+	if u.Expiry != nil || u.Majority == nil || *u.Majority != majority || u.Description != nil {
+		t.Errorf("legacy UpdateCollective parsed as %+v", u)
+	}
+	if !reflect.DeepEqual(u.Serialize(), bytes) {
+		t.Error("legacy UpdateCollective does not serialize to the same bytes (and hash)")
+	}
+}
+
+func TestParseOptionalPolicy(t *testing.T) {
+	extended := make([]byte, 0)
+	PutOptionalPolicy(&Policy{Majority: 150, SuperMajority: 50, Kind: QuorumPolicy, Quorum: 50}, &extended)
+	tests := []struct {
+		name     string
+		data     []byte
+		policy   *Policy
+		position int
+	}{
+		{"no policy", []byte{0}, nil, 1},
+		{"legacy policy", []byte{1, 50, 60}, &Policy{Majority: 50, SuperMajority: 60}, 3},
+		// as before quorum policies: no policy, the action is not rejected
+		{"legacy majority above 100", []byte{1, 101, 50}, nil, 3},
+		{"legacy super majority above 100", []byte{1, 50, 200}, nil, 3},
+		{"invalid extended policy", extended, nil, len(extended) + 1},
+		{"invalid flag", []byte{2, 50, 60}, nil, 4},
+		{"truncated", []byte{1, 50}, nil, 3},
+	}
+	for _, test := range tests {
+		policy, position := ParseOptionalPolicy(test.data, 0)
+		if position != test.position {
+			t.Errorf("%v: position %v, expected %v", test.name, position, test.position)
+		}
+		if position == len(test.data) && !reflect.DeepEqual(policy, test.policy) {
+			t.Errorf("%v: parsed %+v, expected %+v", test.name, policy, test.policy)
+		}
+	}
+}
+
+// an update without expiry has a single serialization: a trailing 0 byte
+// would give the same action a second hash
+func TestUpdateCollectiveSingleEncoding(t *testing.T) {
+	description := "no expiry"
+	fixture := &UpdateCollective{Epoch: 15, OnBehalfOf: "first_collective", Description: &description}
+	bytes := fixture.Serialize()
+	if u := ParseUpdateCollective(append(bytes, 0)); u != nil {
+		t.Errorf("UpdateCollective with explicit no expiry parsed as %+v", u)
+	}
+	if u := ParseUpdateCollective(append(bytes, 2)); u != nil {
+		t.Errorf("UpdateCollective with invalid expiry flag parsed as %+v", u)
+	}
+	if u := ParseUpdateCollective(append(bytes, 1)); u != nil {
+		t.Errorf("UpdateCollective with truncated expiry parsed as %+v", u)
+	}
+}
+
 func TestRequestMembership(t *testing.T) {
 	r := ParseRequestMembership(request.Serialize())
 	if r == nil {
@@ -91,3 +338,16 @@ func TestRemoveMember(t *testing.T) {
 		t.Error("Parse and Serialize not working for actions RemoveMember")
 	}
 }
+
+func TestDelegate(t *testing.T) {
+	for _, fixture := range []*Delegate{delegate, revoke} {
+		d := ParseDelegate(fixture.Serialize())
+		if d == nil {
+			t.Error("Could not parse actions Delegate")
+			return
+		}
+		if !reflect.DeepEqual(d, fixture) {
+			t.Error("Parse and Serialize not working for actions Delegate")
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package actions
+
+import (
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
+)
+
+// Comment on a draft, edit, event or pending proposal identified by Target.
+// ReplyTo is the hash of the comment being answered (zero hash for a comment
+// that starts a thread). Body is markdown.
+type Comment struct {
+	Epoch      uint64
+	Author     crypto.Token
+	Reasons    string
+	OnBehalfOf string
+	Target     crypto.Hash
+	ReplyTo    crypto.Hash
+	Body       string
+}
+
+func (c *Comment) Reasoning() string {
+	return c.Reasons
+}
+
+func (c *Comment) Hashed() crypto.Hash {
+	return crypto.Hasher(c.Serialize())
+}
+
+// Afeta o objeto comentado
+func (c *Comment) Affected() []crypto.Hash {
+	return []crypto.Hash{c.Target}
+}
+
+func (c *Comment) Authored() crypto.Token {
+	return c.Author
+}
+
+func (c *Comment) Serialize() []byte {
+	bytes := make([]byte, 0)
+	util.PutUint64(c.Epoch, &bytes)
+	util.PutToken(c.Author, &bytes)
+	util.PutByte(AComment, &bytes)
+	util.PutString(c.Reasons, &bytes)
+	util.PutString(c.OnBehalfOf, &bytes)
+	util.PutHash(c.Target, &bytes)
+	util.PutHash(c.ReplyTo, &bytes)
+	util.PutString(c.Body, &bytes)
+	return bytes
+}
+
+func ParseComment(data []byte) *Comment {
+	action := Comment{}
+	position := 0
+	action.Epoch, position = util.ParseUint64(data, position)
+	action.Author, position = util.ParseToken(data, position)
+	if position >= len(data) || data[position] != AComment {
+		return nil
+	}
+	position += 1
+	action.Reasons, position = util.ParseString(data, position)
+	action.OnBehalfOf, position = util.ParseString(data, position)
+	action.Target, position = util.ParseHash(data, position)
+	action.ReplyTo, position = util.ParseHash(data, position)
+	action.Body, position = util.ParseString(data, position)
+	if position != len(data) {
+		return nil
+	}
+	return &action
+}
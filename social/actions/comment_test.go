@@ -0,0 +1,40 @@
+package actions
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+var (
+	comment = &Comment{
+		Epoch:      26,
+		Author:     crypto.Token{},
+		Reasons:    "comment test",
+		OnBehalfOf: "first_collective",
+		Target:     crypto.Hasher([]byte("draft")),
+		Body:       "um *comentário* em markdown",
+	}
+
+	reply = &Comment{
+		Epoch:   27,
+		Author:  crypto.Token{1},
+		Target:  crypto.Hasher([]byte("draft")),
+		ReplyTo: crypto.Hasher([]byte("comment")),
+		Body:    "uma resposta",
+	}
+)
+
+func TestComment(t *testing.T) {
+	for _, fixture := range []*Comment{comment, reply} {
+		c := ParseComment(fixture.Serialize())
+		if c == nil {
+			t.Error("Could not parse actions Comment")
+			return
+		}
+		if !reflect.DeepEqual(c, fixture) {
+			t.Error("Parse and Serialize not working for actions Comment")
+		}
+	}
+}
@@ -20,6 +20,9 @@ type CreateEvent struct {
 	Public          bool
 	ManagerMajority byte
 	Managers        []crypto.Token // default é qualquer um do coletivo
+	Capacity        uint64         // numero maximo de participantes (0 sem limite)
+	RSVPClose       uint64         // epoch a partir do qual nao se aceita check-in (0 sem prazo)
+	Recurrence      Recurrence     // serie de ocorrencias aprovada numa unica votacao
 }
 
 func (c *CreateEvent) Reasoning() string {
@@ -54,6 +57,16 @@ func (c *CreateEvent) Serialize() []byte {
 	util.PutBool(c.Public, &bytes)
 	util.PutByte(c.ManagerMajority, &bytes)
 	PutTokenArray(c.Managers, &bytes)
+	// capacidade, prazo de check-in e recorrencia sao uma cauda opcional:
+	// eventos sem eles mantem o formato (e o hash) anterior
+	recurring := c.Recurrence != (Recurrence{})
+	if c.Capacity != 0 || c.RSVPClose != 0 || recurring {
+		util.PutUint64(c.Capacity, &bytes)
+		util.PutUint64(c.RSVPClose, &bytes)
+	}
+	if recurring {
+		PutRecurrence(c.Recurrence, &bytes)
+	}
 	return bytes
 }
 
@@ -76,7 +89,17 @@ func ParseCreateEvent(create []byte) *CreateEvent {
 	action.Public, position = util.ParseBool(create, position)
 	action.ManagerMajority, position = util.ParseByte(create, position)
 	action.Managers, position = ParseTokenArray(create, position)
-
+	if position < len(create) {
+		action.Capacity, position = util.ParseUint64(create, position)
+		action.RSVPClose, position = util.ParseUint64(create, position)
+	}
+	if position < len(create) {
+		action.Recurrence, position = ParseRecurrence(create, position)
+		if action.Recurrence == (Recurrence{}) {
+			// uma recorrencia vazia e sempre omitida
+			return nil
+		}
+	}
 	if position != len(create) {
 		return nil
 	}
@@ -84,10 +107,11 @@ func ParseCreateEvent(create []byte) *CreateEvent {
 }
 
 type CancelEvent struct {
-	Epoch   uint64
-	Author  crypto.Token
-	Reasons string
-	Hash    crypto.Hash
+	Epoch      uint64
+	Author     crypto.Token
+	Reasons    string
+	Hash       crypto.Hash
+	Occurrence uint64 // ocorrencia de um evento recorrente (0 cancela a serie)
 }
 
 func (c *CancelEvent) Reasoning() string {
@@ -114,6 +138,10 @@ func (c *CancelEvent) Serialize() []byte {
 	util.PutByte(ACancelEvent, &bytes)
 	util.PutString(c.Reasons, &bytes)
 	util.PutHash(c.Hash, &bytes)
+	// a ocorrencia e omitida ao cancelar todo o evento
+	if c.Occurrence != 0 {
+		util.PutUint64(c.Occurrence, &bytes)
+	}
 	return bytes
 }
 
@@ -128,6 +156,12 @@ func ParseCancelEvent(create []byte) *CancelEvent {
 	position += 1
 	action.Reasons, position = util.ParseString(create, position)
 	action.Hash, position = util.ParseHash(create, position)
+	if position < len(create) {
+		action.Occurrence, position = util.ParseUint64(create, position)
+		if action.Occurrence == 0 {
+			return nil
+		}
+	}
 	if position != len(create) {
 		return nil
 	}
@@ -147,6 +181,9 @@ type UpdateEvent struct {
 	Public          *bool
 	ManagerMajority *byte
 	Managers        *[]crypto.Token
+	Capacity        *uint64
+	RSVPClose       *uint64
+	Occurrence      uint64 // altera apenas uma ocorrencia de um evento recorrente (0 altera a serie)
 }
 
 func (c *UpdateEvent) Reasoning() string {
@@ -221,6 +258,26 @@ func (c *UpdateEvent) Serialize() []byte {
 	} else {
 		util.PutByte(0, &bytes)
 	}
+	// caudas opcionais, omitidas quando nao alteram capacidade, prazo de
+	// check-in ou uma ocorrencia especifica
+	if c.Capacity == nil && c.RSVPClose == nil && c.Occurrence == 0 {
+		return bytes
+	}
+	if c.Capacity != nil {
+		util.PutByte(1, &bytes)
+		util.PutUint64(*c.Capacity, &bytes)
+	} else {
+		util.PutByte(0, &bytes)
+	}
+	if c.RSVPClose != nil {
+		util.PutByte(1, &bytes)
+		util.PutUint64(*c.RSVPClose, &bytes)
+	} else {
+		util.PutByte(0, &bytes)
+	}
+	if c.Occurrence != 0 {
+		util.PutUint64(c.Occurrence, &bytes)
+	}
 	return bytes
 }
 
@@ -300,6 +357,34 @@ func ParseUpdateEvent(create []byte) *UpdateEvent {
 		tokens, position = ParseTokenArray(create, position)
 		action.Managers = &tokens
 	}
+	if position == len(create) {
+		return &action
+	}
+	if create[position] == 0 {
+		position += 1
+	} else {
+		var capacity uint64
+		position += 1
+		capacity, position = util.ParseUint64(create, position)
+		action.Capacity = &capacity
+	}
+	if position >= len(create) {
+		return nil
+	}
+	if create[position] == 0 {
+		position += 1
+	} else {
+		var deadline uint64
+		position += 1
+		deadline, position = util.ParseUint64(create, position)
+		action.RSVPClose = &deadline
+	}
+	if position < len(create) {
+		action.Occurrence, position = util.ParseUint64(create, position)
+		if action.Occurrence == 0 {
+			return nil
+		}
+	}
 	if position != len(create) {
 		return nil
 	}
@@ -422,3 +507,56 @@ func ParseGreetCheckinEvent(create []byte) *GreetCheckinEvent {
 	}
 	return &action
 }
+
+// CancelCheckinEvent withdraws the check-in (or the place on the waitlist) of
+// the author. The first member on the waitlist takes the vacant place.
+type CancelCheckinEvent struct {
+	Epoch     uint64
+	Author    crypto.Token
+	Reasons   string
+	EventHash crypto.Hash
+}
+
+func (c *CancelCheckinEvent) Reasoning() string {
+	return c.Reasons
+}
+
+func (c *CancelCheckinEvent) Hashed() crypto.Hash {
+	return crypto.Hasher(c.Serialize())
+}
+
+// afeta apenas o proprio evento
+func (c *CancelCheckinEvent) Affected() []crypto.Hash {
+	return []crypto.Hash{c.EventHash}
+}
+
+func (c *CancelCheckinEvent) Authored() crypto.Token {
+	return c.Author
+}
+
+func (c *CancelCheckinEvent) Serialize() []byte {
+	bytes := make([]byte, 0)
+	util.PutUint64(c.Epoch, &bytes)
+	util.PutToken(c.Author, &bytes)
+	util.PutByte(ACancelCheckinEvent, &bytes)
+	util.PutString(c.Reasons, &bytes)
+	util.PutHash(c.EventHash, &bytes)
+	return bytes
+}
+
+func ParseCancelCheckinEvent(create []byte) *CancelCheckinEvent {
+	action := CancelCheckinEvent{}
+	position := 0
+	action.Epoch, position = util.ParseUint64(create, position)
+	action.Author, position = util.ParseToken(create, position)
+	if create[position] != ACancelCheckinEvent {
+		return nil
+	}
+	position += 1
+	action.Reasons, position = util.ParseString(create, position)
+	action.EventHash, position = util.ParseHash(create, position)
+	if position != len(create) {
+		return nil
+	}
+	return &action
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
 )
 
 var (
@@ -23,6 +24,35 @@ var (
 		Managers:     []crypto.Token{},
 	}
 
+	cappedEvent = &CreateEvent{
+		Epoch:        21,
+		Author:       crypto.Token{},
+		Reasons:      "create capped event test",
+		OnBehalfOf:   "first_collective",
+		StartAt:      time.Date(2021, 8, 15, 14, 30, 45, 100, time.Local),
+		EstimatedEnd: time.Date(2022, 8, 15, 14, 30, 45, 100, time.Local),
+		Description:  "create capped event test",
+		Venue:        "first_venue",
+		Open:         true,
+		Public:       true,
+		Managers:     []crypto.Token{},
+		Capacity:     20,
+		RSVPClose:    120,
+	}
+
+	recurringEvent = &CreateEvent{
+		Epoch:        21,
+		Author:       crypto.Token{},
+		Reasons:      "create recurring event test",
+		OnBehalfOf:   "first_collective",
+		StartAt:      time.Date(2021, 8, 15, 14, 30, 45, 100, time.Local),
+		EstimatedEnd: time.Date(2021, 8, 15, 16, 30, 45, 100, time.Local),
+		Description:  "create recurring event test",
+		Venue:        "first_venue",
+		Managers:     []crypto.Token{},
+		Recurrence:   Recurrence{Frequency: WeeklyRecurrence, Interval: 1, Count: 12},
+	}
+
 	cancel = &CancelEvent{
 		Epoch:   22,
 		Author:  crypto.Token{},
@@ -30,8 +60,18 @@ var (
 		Hash:    crypto.Hash{},
 	}
 
+	cancelOccurrence = &CancelEvent{
+		Epoch:      22,
+		Author:     crypto.Token{},
+		Reasons:    "test cancel occurrence",
+		Hash:       crypto.Hash{},
+		Occurrence: 3,
+	}
+
 	updtSTr = "test update event"
 
+	updtCapacity uint64 = 30
+
 	uEvent = &UpdateEvent{
 		Epoch:       23,
 		Author:      crypto.Token{},
@@ -43,37 +83,153 @@ var (
 		Public:      nil,
 		Managers:    nil,
 	}
+
+	cappedUpdate = &UpdateEvent{
+		Epoch:     23,
+		Author:    crypto.Token{},
+		Reasons:   "test update capacity",
+		EventHash: crypto.Hash{},
+		Capacity:  &updtCapacity,
+	}
+
+	updateOccurrence = &UpdateEvent{
+		Epoch:      23,
+		Author:     crypto.Token{},
+		Reasons:    "test update occurrence",
+		EventHash:  crypto.Hash{},
+		Venue:      &updtSTr,
+		Occurrence: 2,
+	}
+
+	cancelCheckin = &CancelCheckinEvent{
+		Epoch:     24,
+		Author:    crypto.Token{},
+		Reasons:   "test cancel checkin",
+		EventHash: crypto.Hash{},
+	}
 )
 
 func TestCreateEvent(t *testing.T) {
-	e := ParseCreateEvent(event.Serialize())
+	for _, create := range []*CreateEvent{event, cappedEvent, recurringEvent} {
+		e := ParseCreateEvent(create.Serialize())
+		if e == nil {
+			t.Error("Could not parse actions CreateEvent")
+			return
+		}
+		if !reflect.DeepEqual(e, create) {
+			t.Error("Parse and Serialize not working for actions CreateEvent")
+		}
+	}
+}
+
+// CreateEvent as serialized before capacity, check-in deadline and recurrence
+func TestLegacyCreateEvent(t *testing.T) {
+	bytes := make([]byte, 0)
+	util.PutUint64(21, &bytes)
+	util.PutToken(crypto.Token{}, &bytes)
+	util.PutByte(ACreateEvent, &bytes)
+	util.PutString("legacy event", &bytes)
+	util.PutString("first_collective", &bytes)
+	util.PutTime(time.Date(2021, 8, 15, 14, 30, 45, 100, time.Local), &bytes)
+	util.PutTime(time.Date(2022, 8, 15, 14, 30, 45, 100, time.Local), &bytes)
+	util.PutString("legacy description", &bytes)
+	util.PutString("legacy venue", &bytes)
+	util.PutBool(true, &bytes)
+	util.PutBool(false, &bytes)
+	util.PutByte(50, &bytes)
+	PutTokenArray([]crypto.Token{}, &bytes)
+	e := ParseCreateEvent(bytes)
 	if e == nil {
-		t.Error("Could not parse actions CreateEvent")
-		return
+		t.Fatal("Could not parse legacy CreateEvent")
+	}
+	if e.Venue != "legacy venue" || e.ManagerMajority != 50 || e.Capacity != 0 || e.RSVPClose != 0 || e.Recurrence.Recurring() {
+		t.Errorf("legacy CreateEvent parsed as %+v", e)
 	}
-	if !reflect.DeepEqual(e, event) {
-		t.Error("Parse and Serialize not working for actions CreateEvent")
+	if !reflect.DeepEqual(e.Serialize(), bytes) {
+		t.Error("legacy CreateEvent does not serialize to the same bytes (and hash)")
 	}
 }
 
 func TestCancelEvent(t *testing.T) {
-	c := ParseCancelEvent(cancel.Serialize())
+	for _, cancellation := range []*CancelEvent{cancel, cancelOccurrence} {
+		c := ParseCancelEvent(cancellation.Serialize())
+		if c == nil {
+			t.Error("Could not parse actions CancelEvent")
+			return
+		}
+		if !reflect.DeepEqual(c, cancellation) {
+			t.Error("Parse and Serialize not working for actions CancelEvent")
+		}
+	}
+}
+
+// CancelEvent as serialized before occurrences of recurring events
+func TestLegacyCancelEvent(t *testing.T) {
+	bytes := make([]byte, 0)
+	util.PutUint64(22, &bytes)
+	util.PutToken(crypto.Token{}, &bytes)
+	util.PutByte(ACancelEvent, &bytes)
+	util.PutString("legacy cancel", &bytes)
+	util.PutHash(crypto.Hasher([]byte("legacy event")), &bytes)
+	c := ParseCancelEvent(bytes)
 	if c == nil {
-		t.Error("Could not parse actions CancelEvent")
-		return
+		t.Fatal("Could not parse legacy CancelEvent")
+	}
+	if c.Occurrence != 0 || c.Hash != crypto.Hasher([]byte("legacy event")) {
+		t.Errorf("legacy CancelEvent parsed as %+v", c)
 	}
-	if !reflect.DeepEqual(c, cancel) {
-		t.Error("Parse and Serialize not working for actions CancelEvent")
+	if !reflect.DeepEqual(c.Serialize(), bytes) {
+		t.Error("legacy CancelEvent does not serialize to the same bytes (and hash)")
 	}
 }
 
 func TestUpdateEvent(t *testing.T) {
-	u := ParseUpdateEvent(uEvent.Serialize())
+	for _, update := range []*UpdateEvent{uEvent, cappedUpdate, updateOccurrence} {
+		u := ParseUpdateEvent(update.Serialize())
+		if u == nil {
+			t.Error("Could not parse actions UpdateEvent")
+			return
+		}
+		if !reflect.DeepEqual(u, update) {
+			t.Error("Parse and Serialize not working for actions UpdateEvent")
+		}
+	}
+}
+
+// UpdateEvent as serialized before capacity, check-in deadline and occurrences
+func TestLegacyUpdateEvent(t *testing.T) {
+	bytes := make([]byte, 0)
+	util.PutUint64(23, &bytes)
+	util.PutToken(crypto.Token{}, &bytes)
+	util.PutByte(AUpdateEvent, &bytes)
+	util.PutString("legacy update", &bytes)
+	util.PutHash(crypto.Hash{}, &bytes)
+	util.PutByte(0, &bytes) // StartAt
+	util.PutByte(0, &bytes) // EstimatedEnd
+	util.PutByte(1, &bytes) // Description
+	util.PutString("legacy description", &bytes)
+	for n := 0; n < 5; n++ { // Venue, Open, Public, ManagerMajority, Managers
+		util.PutByte(0, &bytes)
+	}
+	u := ParseUpdateEvent(bytes)
 	if u == nil {
-		t.Error("Could not parse actions UpdateEvent")
+		t.Fatal("Could not parse legacy UpdateEvent")
+	}
+	if u.Description == nil || *u.Description != "legacy description" || u.Capacity != nil || u.RSVPClose != nil || u.Occurrence != 0 {
+		t.Errorf("legacy UpdateEvent parsed as %+v", u)
+	}
+	if !reflect.DeepEqual(u.Serialize(), bytes) {
+		t.Error("legacy UpdateEvent does not serialize to the same bytes (and hash)")
+	}
+}
+
+func TestCancelCheckinEvent(t *testing.T) {
+	c := ParseCancelCheckinEvent(cancelCheckin.Serialize())
+	if c == nil {
+		t.Error("Could not parse actions CancelCheckinEvent")
 		return
 	}
-	if !reflect.DeepEqual(u, uEvent) {
-		t.Error("Parse and Serialize not working for actions UpdateEvent")
+	if !reflect.DeepEqual(c, cancelCheckin) {
+		t.Error("Parse and Serialize not working for actions CancelCheckinEvent")
 	}
 }
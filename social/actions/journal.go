@@ -0,0 +1,188 @@
+package actions
+
+import (
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
+)
+
+type CreateJournal struct {
+	Epoch       uint64
+	Author      crypto.Token
+	Reasons     string
+	OnBehalfOf  string
+	Name        string
+	Description string
+	Keywords    []string
+}
+
+func (c *CreateJournal) Reasoning() string {
+	return c.Reasons
+}
+
+// o hash do periodico eh o hash do nome, como no board
+func (c *CreateJournal) Hashed() crypto.Hash {
+	return crypto.Hasher([]byte(c.Name))
+}
+
+// afeta apenas o coletivo em nome do qual ta sendo criado o periodico
+func (c *CreateJournal) Affected() []crypto.Hash {
+	return []crypto.Hash{crypto.Hasher([]byte(c.OnBehalfOf))}
+}
+
+func (c *CreateJournal) Authored() crypto.Token {
+	return c.Author
+}
+
+func (c *CreateJournal) Serialize() []byte {
+	bytes := make([]byte, 0)
+	util.PutUint64(c.Epoch, &bytes)
+	util.PutToken(c.Author, &bytes)
+	util.PutByte(ACreateJournal, &bytes)
+	util.PutString(c.Reasons, &bytes)
+	util.PutString(c.OnBehalfOf, &bytes)
+	util.PutString(c.Name, &bytes)
+	util.PutString(c.Description, &bytes)
+	PutKeywords(c.Keywords, &bytes)
+	return bytes
+}
+
+func ParseCreateJournal(create []byte) *CreateJournal {
+	action := CreateJournal{}
+	position := 0
+	action.Epoch, position = util.ParseUint64(create, position)
+	action.Author, position = util.ParseToken(create, position)
+	if create[position] != ACreateJournal {
+		return nil
+	}
+	position += 1
+	action.Reasons, position = util.ParseString(create, position)
+	action.OnBehalfOf, position = util.ParseString(create, position)
+	action.Name, position = util.ParseString(create, position)
+	action.Description, position = util.ParseString(create, position)
+	action.Keywords, position = ParseKeywords(create, position)
+	if position != len(create) {
+		return nil
+	}
+	return &action
+}
+
+type JournalEditor struct {
+	Epoch   uint64
+	Author  crypto.Token
+	Reasons string
+	Journal string
+	Editor  crypto.Token
+	Insert  bool
+}
+
+func (c *JournalEditor) Reasoning() string {
+	return c.Reasons
+}
+
+func (c *JournalEditor) Hashed() crypto.Hash {
+	return crypto.Hasher(c.Serialize())
+}
+
+// afeta o periodico
+func (c *JournalEditor) Affected() []crypto.Hash {
+	return []crypto.Hash{crypto.Hasher([]byte(c.Journal))}
+}
+
+func (c *JournalEditor) Authored() crypto.Token {
+	return c.Author
+}
+
+func (c *JournalEditor) Serialize() []byte {
+	bytes := make([]byte, 0)
+	util.PutUint64(c.Epoch, &bytes)
+	util.PutToken(c.Author, &bytes)
+	util.PutByte(AJournalEditor, &bytes)
+	util.PutString(c.Reasons, &bytes)
+	util.PutString(c.Journal, &bytes)
+	util.PutToken(c.Editor, &bytes)
+	util.PutBool(c.Insert, &bytes)
+	return bytes
+}
+
+func ParseJournalEditor(create []byte) *JournalEditor {
+	action := JournalEditor{}
+	position := 0
+	action.Epoch, position = util.ParseUint64(create, position)
+	action.Author, position = util.ParseToken(create, position)
+	if create[position] != AJournalEditor {
+		return nil
+	}
+	position += 1
+	action.Reasons, position = util.ParseString(create, position)
+	action.Journal, position = util.ParseString(create, position)
+	action.Editor, position = util.ParseToken(create, position)
+	action.Insert, position = util.ParseBool(create, position)
+	if position != len(create) {
+		return nil
+	}
+	return &action
+}
+
+// JournalIssue proposes a new issue of a journal with the released drafts in
+// Drafts, in the order of the table of contents. The issue number is assigned
+// when the collective approves it.
+type JournalIssue struct {
+	Epoch       uint64
+	Author      crypto.Token
+	Reasons     string
+	Journal     string
+	Title       string
+	Description string
+	Drafts      []crypto.Hash
+}
+
+func (c *JournalIssue) Reasoning() string {
+	return c.Reasons
+}
+
+// o hash do numero eh o hash da acao que o propos
+func (c *JournalIssue) Hashed() crypto.Hash {
+	return crypto.Hasher(c.Serialize())
+}
+
+// afeta o periodico e os drafts incluidos
+func (c *JournalIssue) Affected() []crypto.Hash {
+	return append([]crypto.Hash{crypto.Hasher([]byte(c.Journal))}, c.Drafts...)
+}
+
+func (c *JournalIssue) Authored() crypto.Token {
+	return c.Author
+}
+
+func (c *JournalIssue) Serialize() []byte {
+	bytes := make([]byte, 0)
+	util.PutUint64(c.Epoch, &bytes)
+	util.PutToken(c.Author, &bytes)
+	util.PutByte(AJournalIssue, &bytes)
+	util.PutString(c.Reasons, &bytes)
+	util.PutString(c.Journal, &bytes)
+	util.PutString(c.Title, &bytes)
+	util.PutString(c.Description, &bytes)
+	PutHashArray(c.Drafts, &bytes)
+	return bytes
+}
+
+func ParseJournalIssue(create []byte) *JournalIssue {
+	action := JournalIssue{}
+	position := 0
+	action.Epoch, position = util.ParseUint64(create, position)
+	action.Author, position = util.ParseToken(create, position)
+	if create[position] != AJournalIssue {
+		return nil
+	}
+	position += 1
+	action.Reasons, position = util.ParseString(create, position)
+	action.Journal, position = util.ParseString(create, position)
+	action.Title, position = util.ParseString(create, position)
+	action.Description, position = util.ParseString(create, position)
+	action.Drafts, position = ParseHashArray(create, position)
+	if position != len(create) {
+		return nil
+	}
+	return &action
+}
@@ -0,0 +1,72 @@
+package actions
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+var (
+	journal = &CreateJournal{
+		Epoch:       30,
+		Author:      crypto.Token{},
+		Reasons:     "create journal test",
+		OnBehalfOf:  "coletivo_teste",
+		Name:        "first_journal",
+		Description: "create journal test",
+		Keywords:    []string{"test", "journal"},
+	}
+
+	journalEditor = &JournalEditor{
+		Epoch:   31,
+		Author:  crypto.Token{},
+		Reasons: "include journal editor test",
+		Journal: "first_journal",
+		Editor:  crypto.Token{1},
+		Insert:  true,
+	}
+
+	issue = &JournalIssue{
+		Epoch:       32,
+		Author:      crypto.Token{1},
+		Reasons:     "journal issue test",
+		Journal:     "first_journal",
+		Title:       "primeiro número",
+		Description: "journal issue test",
+		Drafts:      []crypto.Hash{crypto.Hasher([]byte("first")), crypto.Hasher([]byte("second"))},
+	}
+)
+
+func TestCreateJournal(t *testing.T) {
+	j := ParseCreateJournal(journal.Serialize())
+	if j == nil {
+		t.Error("Could not parse actions CreateJournal")
+		return
+	}
+	if !reflect.DeepEqual(j, journal) {
+		t.Error("Parse and Serialize not working for actions CreateJournal")
+	}
+}
+
+func TestJournalEditor(t *testing.T) {
+	e := ParseJournalEditor(journalEditor.Serialize())
+	if e == nil {
+		t.Error("Could not parse actions JournalEditor")
+		return
+	}
+	if !reflect.DeepEqual(e, journalEditor) {
+		t.Error("Parse and Serialize not working for actions JournalEditor")
+	}
+}
+
+func TestJournalIssue(t *testing.T) {
+	i := ParseJournalIssue(issue.Serialize())
+	if i == nil {
+		t.Error("Could not parse actions JournalIssue")
+		return
+	}
+	if !reflect.DeepEqual(i, issue) {
+		t.Error("Parse and Serialize not working for actions JournalIssue")
+	}
+}
@@ -1,36 +1,130 @@
 package actions
 
 import (
+	"errors"
+
 	"github.com/freehandle/breeze/crypto"
 	"github.com/freehandle/breeze/util"
 )
 
+// Kinds of policy. MajorityPolicy decides as soon as the votes in favor (or
+// against) among all members make the outcome certain. QuorumPolicy is time
+// boxed: the vote stays open until the proposal deadline and is then decided
+// by the participating members, provided at least Quorum percent of the
+// members took part (abstentions included).
+const (
+	MajorityPolicy byte = iota
+	QuorumPolicy
+	UnkownPolicy
+)
+
+var ErrInvalidPolicy = errors.New("invalid policy")
+
 // % + 1 Vote...or 100%
 // Supermahority to change policy rule
 // Majority for anything else
+// Veto members can block any proposal by voting against it.
 type Policy struct {
 	Majority      int
 	SuperMajority int
+	Kind          byte
+	Quorum        int // percentage of members that must take part (QuorumPolicy)
+	Veto          []crypto.Token
+}
+
+// Validate checks percentages and kind of the policy.
+func (p Policy) Validate() error {
+	if p.Majority < 0 || p.Majority > 100 || p.SuperMajority < 0 || p.SuperMajority > 100 {
+		return ErrInvalidPolicy
+	}
+	if p.Kind >= UnkownPolicy {
+		return ErrInvalidPolicy
+	}
+	if p.Kind == QuorumPolicy && (p.Quorum <= 0 || p.Quorum > 100) {
+		return ErrInvalidPolicy
+	}
+	return nil
+}
+
+// policyExtended marks a policy serialized with kind, quorum and veto. The
+// first policies on the chain have only the two percentages, both at most
+// 100, so their first byte is never policyExtended. A plain majority policy
+// is still serialized in that legacy layout.
+const policyExtended byte = 0xff
+
+func (p Policy) legacy() bool {
+	return p.Kind == MajorityPolicy && p.Quorum == 0 && len(p.Veto) == 0
 }
 
 func PutPolicy(policy Policy, bytes *[]byte) {
-	*bytes = append(*bytes, byte(policy.Majority), byte(policy.SuperMajority))
+	if policy.legacy() {
+		*bytes = append(*bytes, byte(policy.Majority), byte(policy.SuperMajority))
+		return
+	}
+	*bytes = append(*bytes, policyExtended, byte(policy.Majority), byte(policy.SuperMajority), policy.Kind)
+	if policy.Kind == QuorumPolicy {
+		*bytes = append(*bytes, byte(policy.Quorum))
+	}
+	PutTokenArray(policy.Veto, bytes)
 }
 
+// ParsePolicy returns position beyond the end of data if the policy could not
+// be parsed, so that callers checking position != len(data) reject it. Legacy
+// policies are parsed as they were, without validation: the verdict on the
+// actions already on the chain must not change.
 func ParsePolicy(data []byte, position int) (Policy, int) {
-	policy := Policy{
-		int(data[position]),
-		int(data[position+1]),
+	policy := Policy{}
+	if position+2 > len(data) {
+		return policy, len(data) + 1
+	}
+	if data[position] != policyExtended {
+		policy.Majority = int(data[position])
+		policy.SuperMajority = int(data[position+1])
+		return policy, position + 2
 	}
-	return policy, position + 2
+	position += 1
+	if position+3 > len(data) {
+		return policy, len(data) + 1
+	}
+	policy.Majority = int(data[position])
+	policy.SuperMajority = int(data[position+1])
+	policy.Kind = data[position+2]
+	position += 3
+	if policy.Kind == QuorumPolicy {
+		if position >= len(data) {
+			return policy, len(data) + 1
+		}
+		policy.Quorum = int(data[position])
+		position += 1
+	}
+	policy.Veto, position = ParseTokenArray(data, position)
+	if len(policy.Veto) == 0 {
+		policy.Veto = nil
+	}
+	if policy.legacy() || policy.Validate() != nil {
+		// a policy of the legacy layout has a single serialization
+		return policy, len(data) + 1
+	}
+	return policy, position
 }
 
+// a vote is in favor, against, an abstention or the retraction of a previous
+// vote by the same author
+const (
+	voteAgainst byte = iota
+	voteApprove
+	voteAbstain
+	voteRetract
+)
+
 type Vote struct {
 	Epoch   uint64
 	Author  crypto.Token
 	Reasons string
 	Hash    crypto.Hash
 	Approve bool
+	Abstain bool // counts for quorum but neither in favor nor against
+	Retract bool // withdraws the vote previously cast by the author
 }
 
 func (c *Vote) Reasoning() string {
@@ -52,7 +146,15 @@ func (v *Vote) Serialize() []byte {
 	util.PutByte(AVote, &bytes)
 	util.PutString(v.Reasons, &bytes)
 	util.PutHash(v.Hash, &bytes)
-	util.PutBool(v.Approve, &bytes)
+	if v.Retract {
+		util.PutByte(voteRetract, &bytes)
+	} else if v.Abstain {
+		util.PutByte(voteAbstain, &bytes)
+	} else if v.Approve {
+		util.PutByte(voteApprove, &bytes)
+	} else {
+		util.PutByte(voteAgainst, &bytes)
+	}
 	return bytes
 }
 
@@ -61,20 +163,27 @@ func PutOptionalPolicy(policy *Policy, bytes *[]byte) {
 		*bytes = append(*bytes, 0)
 		return
 	}
-	*bytes = append(*bytes, 1, byte(policy.Majority), byte(policy.SuperMajority))
+	*bytes = append(*bytes, 1)
+	PutPolicy(*policy, bytes)
 }
 
 func ParseOptionalPolicy(data []byte, position int) (*Policy, int) {
+	if position >= len(data) {
+		return nil, len(data) + 1
+	}
 	if data[position] == 0 {
 		return nil, position + 1
 	}
-	if data[position] != 1 || data[position+1] > 100 || data[position+2] > 100 {
+	if data[position] != 1 {
+		return nil, len(data) + 1
+	}
+	// an optional policy of the legacy layout with a percentage above 100 was
+	// always parsed as no policy: the verdict on the chain must not change
+	if position+2 < len(data) && data[position+1] != policyExtended && (data[position+1] > 100 || data[position+2] > 100) {
 		return nil, position + 3
 	}
-	return &Policy{
-		Majority:      int(data[position+1]),
-		SuperMajority: int(data[position+2]),
-	}, position + 3
+	policy, position := ParsePolicy(data, position+1)
+	return &policy, position
 }
 
 func ParseVote(vote []byte) *Vote {
@@ -88,7 +197,21 @@ func ParseVote(vote []byte) *Vote {
 	position += 1
 	action.Reasons, position = util.ParseString(vote, position)
 	action.Hash, position = util.ParseHash(vote, position)
-	action.Approve, position = util.ParseBool(vote, position)
+	if position >= len(vote) {
+		return nil
+	}
+	switch vote[position] {
+	case voteAgainst:
+	case voteApprove:
+		action.Approve = true
+	case voteAbstain:
+		action.Abstain = true
+	case voteRetract:
+		action.Retract = true
+	default:
+		return nil
+	}
+	position += 1
 	if position != len(vote) {
 		return nil
 	}
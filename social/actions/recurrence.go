@@ -0,0 +1,96 @@
+package actions
+
+import (
+	"errors"
+	"time"
+
+	"github.com/freehandle/breeze/util"
+)
+
+// Frequencies of a recurrence. An event with NoRecurrence happens only once.
+const (
+	NoRecurrence byte = iota
+	DailyRecurrence
+	WeeklyRecurrence
+	MonthlyRecurrence
+	UnknownRecurrence
+)
+
+// MaxOccurrences limits the number of occurrences of a recurring event.
+const MaxOccurrences = 520
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence")
+
+// Recurrence repeats an event as a series of occurrences, a simplified
+// iCalendar RRULE: every Interval days, weeks or months, Count times. The
+// whole series is approved by a single vote of the collective.
+type Recurrence struct {
+	Frequency byte
+	Interval  byte   // a cada Interval dias, semanas ou meses (0 equivale a 1)
+	Count     uint64 // numero de ocorrencias, incluindo a primeira
+}
+
+// Validate checks frequency and number of occurrences of the recurrence.
+func (r Recurrence) Validate() error {
+	if r.Frequency >= UnknownRecurrence {
+		return ErrInvalidRecurrence
+	}
+	if r.Frequency != NoRecurrence && (r.Count == 0 || r.Count > MaxOccurrences) {
+		return ErrInvalidRecurrence
+	}
+	return nil
+}
+
+// Recurring tells if the event has more than one occurrence.
+func (r Recurrence) Recurring() bool {
+	return r.Frequency != NoRecurrence && r.Count > 1
+}
+
+// Occurrences is the number of occurrences of the series (1 if not recurring).
+func (r Recurrence) Occurrences() uint64 {
+	if !r.Recurring() {
+		return 1
+	}
+	return r.Count
+}
+
+// Start returns the start of the n-th occurrence (starting at 1) of a series
+// that begins at first.
+func (r Recurrence) Start(first time.Time, n uint64) time.Time {
+	interval := int(r.Interval)
+	if interval == 0 {
+		interval = 1
+	}
+	steps := int(n-1) * interval
+	switch r.Frequency {
+	case DailyRecurrence:
+		return first.AddDate(0, 0, steps)
+	case WeeklyRecurrence:
+		return first.AddDate(0, 0, 7*steps)
+	case MonthlyRecurrence:
+		return first.AddDate(0, steps, 0)
+	}
+	return first
+}
+
+func PutRecurrence(r Recurrence, bytes *[]byte) {
+	*bytes = append(*bytes, r.Frequency, r.Interval)
+	util.PutUint64(r.Count, bytes)
+}
+
+// ParseRecurrence returns position beyond the end of data if the recurrence
+// could not be parsed.
+func ParseRecurrence(data []byte, position int) (Recurrence, int) {
+	r := Recurrence{}
+	if position+2 > len(data) {
+		return r, len(data) + 1
+	}
+	r.Frequency = data[position]
+	r.Interval = data[position+1]
+	position += 2
+	r.Count, position = util.ParseUint64(data, position)
+	if r.Validate() != nil {
+		return r, len(data) + 1
+	}
+	return r, position
+}
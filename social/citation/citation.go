@@ -0,0 +1,225 @@
+// Package citation keeps the graph of references between drafts.
+//
+// Every approved version of a draft is a work. A work cites the hashes listed
+// on its References and continues the chain of versions of its previous
+// version. Citations are counted by chain: a citation to any version of a
+// draft counts for the draft, every citing chain is counted once and a chain
+// citing itself (a new version referencing an older one) is not counted.
+//
+// Like social/search and social/reputation, the graph knows nothing about the
+// state and is fed by social/index.
+package citation
+
+import (
+	"sort"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+// a work with at least this number of citations counts for the i10-index
+const i10Citations = 10
+
+type Work struct {
+	Hash       crypto.Hash
+	Previous   crypto.Hash // zero for the first version
+	Title      string
+	Authors    []string // handles (or the collective name)
+	Members    []crypto.Token
+	Collective string
+	Date       uint64
+}
+
+// Citation is an edge from the citing work to the cited one.
+type Citation struct {
+	Citing crypto.Hash
+	Cited  crypto.Hash
+}
+
+// Metrics of the works of an author (member or collective). Works are chains
+// of versions.
+type Metrics struct {
+	Works     int
+	Citations int
+	HIndex    int
+	I10Index  int
+}
+
+type Graph struct {
+	works   map[crypto.Hash]*Work
+	order   []crypto.Hash                 // works in the order they were added
+	root    map[crypto.Hash]crypto.Hash   // version -> primeira versao da cadeia
+	chains  map[crypto.Hash][]crypto.Hash // primeira versao -> versoes
+	cites   map[crypto.Hash][]crypto.Hash // obra -> hashes citados
+	citedBy map[crypto.Hash][]crypto.Hash // hash citado -> obras que citam
+}
+
+func NewGraph() *Graph {
+	return &Graph{
+		works:   make(map[crypto.Hash]*Work),
+		order:   make([]crypto.Hash, 0),
+		root:    make(map[crypto.Hash]crypto.Hash),
+		chains:  make(map[crypto.Hash][]crypto.Hash),
+		cites:   make(map[crypto.Hash][]crypto.Hash),
+		citedBy: make(map[crypto.Hash][]crypto.Hash),
+	}
+}
+
+// Add includes a work and its references. References to hashes that are not
+// (yet) works are kept and count once the cited work is added.
+func (g *Graph) Add(work Work, references []crypto.Hash) {
+	if _, ok := g.works[work.Hash]; ok {
+		return
+	}
+	stored := work
+	g.works[work.Hash] = &stored
+	g.order = append(g.order, work.Hash)
+	root := work.Hash
+	if previous, ok := g.root[work.Previous]; ok && work.Previous != crypto.ZeroHash {
+		root = previous
+	}
+	g.root[work.Hash] = root
+	g.chains[root] = append(g.chains[root], work.Hash)
+	seen := make(map[crypto.Hash]struct{})
+	for _, cited := range references {
+		if _, ok := seen[cited]; ok || cited == work.Hash {
+			continue
+		}
+		seen[cited] = struct{}{}
+		g.cites[work.Hash] = append(g.cites[work.Hash], cited)
+		g.citedBy[cited] = append(g.citedBy[cited], work.Hash)
+	}
+}
+
+func (g *Graph) Work(hash crypto.Hash) (*Work, bool) {
+	work, ok := g.works[hash]
+	return work, ok
+}
+
+// Len is the number of works.
+func (g *Graph) Len() int {
+	return len(g.order)
+}
+
+// Root is the first version of the chain of hash.
+func (g *Graph) Root(hash crypto.Hash) crypto.Hash {
+	if root, ok := g.root[hash]; ok {
+		return root
+	}
+	return hash
+}
+
+// Versions are the works of the chain of hash, oldest first.
+func (g *Graph) Versions(hash crypto.Hash) []crypto.Hash {
+	return g.chains[g.Root(hash)]
+}
+
+// References are the works cited by hash.
+func (g *Graph) References(hash crypto.Hash) []crypto.Hash {
+	references := make([]crypto.Hash, 0)
+	for _, cited := range g.cites[hash] {
+		if _, ok := g.works[cited]; ok {
+			references = append(references, cited)
+		}
+	}
+	return references
+}
+
+// CitedBy lists the citations to any version of the chain of hash made by
+// works of other chains, most recent first.
+func (g *Graph) CitedBy(hash crypto.Hash) []Citation {
+	root := g.Root(hash)
+	citations := make([]Citation, 0)
+	for _, version := range g.chains[root] {
+		for _, citing := range g.citedBy[version] {
+			if g.root[citing] != root {
+				citations = append(citations, Citation{Citing: citing, Cited: version})
+			}
+		}
+	}
+	sort.SliceStable(citations, func(n, m int) bool {
+		return g.works[citations[n].Citing].Date > g.works[citations[m].Citing].Date
+	})
+	return citations
+}
+
+// Count is the number of chains citing the chain of hash.
+func (g *Graph) Count(hash crypto.Hash) int {
+	citing := make(map[crypto.Hash]struct{})
+	for _, citation := range g.CitedBy(hash) {
+		citing[g.root[citation.Citing]] = struct{}{}
+	}
+	return len(citing)
+}
+
+// Citations are all the edges between works, in the order the citing works
+// were added.
+func (g *Graph) Citations() []Citation {
+	citations := make([]Citation, 0)
+	for _, hash := range g.order {
+		for _, cited := range g.References(hash) {
+			citations = append(citations, Citation{Citing: hash, Cited: cited})
+		}
+	}
+	return citations
+}
+
+// Works are all the works in the order they were added.
+func (g *Graph) Works() []*Work {
+	works := make([]*Work, 0, len(g.order))
+	for _, hash := range g.order {
+		works = append(works, g.works[hash])
+	}
+	return works
+}
+
+func (g *Graph) metrics(authored func(*Work) bool) Metrics {
+	counts := make([]int, 0)
+	for root, versions := range g.chains {
+		for _, version := range versions {
+			if authored(g.works[version]) {
+				counts = append(counts, g.Count(root))
+				break
+			}
+		}
+	}
+	metrics := Metrics{Works: len(counts), HIndex: HIndex(counts)}
+	for _, count := range counts {
+		metrics.Citations += count
+		if count >= i10Citations {
+			metrics.I10Index++
+		}
+	}
+	return metrics
+}
+
+// MemberMetrics are the metrics of the chains with a version (co)authored by
+// the member.
+func (g *Graph) MemberMetrics(token crypto.Token) Metrics {
+	return g.metrics(func(work *Work) bool {
+		for _, member := range work.Members {
+			if member == token {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// CollectiveMetrics are the metrics of the chains with a version on behalf of
+// the collective.
+func (g *Graph) CollectiveMetrics(name string) Metrics {
+	return g.metrics(func(work *Work) bool {
+		return work.Collective == name
+	})
+}
+
+// HIndex is the largest h such that h of the counts are at least h.
+func HIndex(counts []int) int {
+	sorted := append([]int{}, counts...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+	h := 0
+	for h < len(sorted) && sorted[h] >= h+1 {
+		h++
+	}
+	return h
+}
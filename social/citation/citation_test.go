@@ -0,0 +1,194 @@
+package citation
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+func hash(name string) crypto.Hash {
+	return crypto.Hasher([]byte(name))
+}
+
+func member(n byte) crypto.Token {
+	var token crypto.Token
+	token[0] = n
+	return token
+}
+
+// testGraph: a1 <- a2 are versions of the same draft, b1 cites both versions,
+// c1 cites a2 and x (added later), d1 cites b1.
+func testGraph() *Graph {
+	g := NewGraph()
+	g.Add(Work{Hash: hash("a1"), Title: "A", Authors: []string{"ana"}, Members: []crypto.Token{member(1)}, Date: 1}, nil)
+	g.Add(Work{Hash: hash("a2"), Previous: hash("a1"), Title: "A v2", Authors: []string{"ana"}, Members: []crypto.Token{member(1)}, Date: 2}, []crypto.Hash{hash("a1"), hash("a2")})
+	g.Add(Work{Hash: hash("b1"), Title: "B", Authors: []string{"bia"}, Members: []crypto.Token{member(2)}, Date: 3}, []crypto.Hash{hash("a1"), hash("a2"), hash("a1")})
+	g.Add(Work{Hash: hash("c1"), Title: "C \"coletivo\"", Authors: []string{"coletivo"}, Members: []crypto.Token{member(2)}, Collective: "coletivo", Date: 4}, []crypto.Hash{hash("a2"), hash("x")})
+	g.Add(Work{Hash: hash("d1"), Title: "D", Members: []crypto.Token{member(3)}, Date: 5}, []crypto.Hash{hash("b1")})
+	return g
+}
+
+func TestChains(t *testing.T) {
+	g := testGraph()
+	if g.Root(hash("a2")) != hash("a1") || g.Root(hash("x")) != hash("x") {
+		t.Error("Root not working")
+	}
+	if versions := g.Versions(hash("a2")); !reflect.DeepEqual(versions, []crypto.Hash{hash("a1"), hash("a2")}) {
+		t.Errorf("versions %v", versions)
+	}
+	// added twice
+	g.Add(Work{Hash: hash("a1"), Title: "outro"}, []crypto.Hash{hash("d1")})
+	if work, _ := g.Work(hash("a1")); g.Len() != 5 || work.Title != "A" || len(g.References(hash("a1"))) != 0 {
+		t.Error("work added twice")
+	}
+	// references to itself are ignored, to unknown works are kept but hidden
+	if references := g.References(hash("a2")); !reflect.DeepEqual(references, []crypto.Hash{hash("a1")}) {
+		t.Errorf("references of a2 %v", references)
+	}
+	if references := g.References(hash("c1")); !reflect.DeepEqual(references, []crypto.Hash{hash("a2")}) {
+		t.Errorf("references of c1 %v", references)
+	}
+}
+
+func TestCitedBy(t *testing.T) {
+	g := testGraph()
+	expected := []Citation{
+		{Citing: hash("c1"), Cited: hash("a2")},
+		{Citing: hash("b1"), Cited: hash("a1")},
+		{Citing: hash("b1"), Cited: hash("a2")},
+	}
+	for _, version := range []string{"a1", "a2"} {
+		if citations := g.CitedBy(hash(version)); !reflect.DeepEqual(citations, expected) {
+			t.Errorf("cited by of %v: %v", version, citations)
+		}
+	}
+	tests := []struct {
+		work  string
+		count int
+	}{
+		{"a1", 2}, // b and c, the new version a2 does not count
+		{"a2", 2},
+		{"b1", 1},
+		{"c1", 0},
+		{"x", 0}, // not a work
+	}
+	for _, test := range tests {
+		if count := g.Count(hash(test.work)); count != test.count {
+			t.Errorf("%v cited %v times, expected %v", test.work, count, test.count)
+		}
+	}
+	// the reference to x counts once x is added
+	g.Add(Work{Hash: hash("x"), Title: "X", Date: 6}, nil)
+	if references := g.References(hash("c1")); len(references) != 2 || g.Count(hash("x")) != 1 {
+		t.Errorf("late reference not included: %v", references)
+	}
+	if len(g.Citations()) != 6 {
+		t.Errorf("%v citations, expected 6", len(g.Citations()))
+	}
+}
+
+func TestHIndex(t *testing.T) {
+	tests := []struct {
+		counts []int
+		h      int
+	}{
+		{nil, 0},
+		{[]int{0, 0}, 0},
+		{[]int{1}, 1},
+		{[]int{3, 0, 6, 1, 5}, 3},
+		{[]int{10, 10, 10}, 3},
+		{[]int{4, 4, 4, 4, 4}, 4},
+	}
+	for _, test := range tests {
+		if h := HIndex(test.counts); h != test.h {
+			t.Errorf("HIndex(%v) = %v, expected %v", test.counts, h, test.h)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	g := testGraph()
+	for n := 0; n < i10Citations; n++ {
+		name := string(rune('k' + n))
+		g.Add(Work{Hash: hash(name), Members: []crypto.Token{member(9)}, Date: 10}, []crypto.Hash{hash("b1")})
+	}
+	tests := []struct {
+		name     string
+		metrics  Metrics
+		expected Metrics
+	}{
+		{"ana", g.MemberMetrics(member(1)), Metrics{Works: 1, Citations: 2, HIndex: 1}},
+		{"bia", g.MemberMetrics(member(2)), Metrics{Works: 2, Citations: 11, HIndex: 1, I10Index: 1}},
+		{"coletivo", g.CollectiveMetrics("coletivo"), Metrics{Works: 1}},
+		{"nobody", g.MemberMetrics(member(7)), Metrics{}},
+	}
+	for _, test := range tests {
+		if test.metrics != test.expected {
+			t.Errorf("metrics of %v %+v, expected %+v", test.name, test.metrics, test.expected)
+		}
+	}
+}
+
+func TestSubgraph(t *testing.T) {
+	sub := testGraph().Subgraph(hash("b1"))
+	works := make([]string, 0)
+	for _, work := range sub.Works() {
+		works = append(works, work.Title)
+	}
+	if !reflect.DeepEqual(works, []string{"A", "A v2", "B", "D"}) {
+		t.Errorf("subgraph works %v", works)
+	}
+	if sub.Root(hash("a2")) != hash("a1") {
+		t.Error("versions not kept on the subgraph")
+	}
+	if len(sub.Citations()) != 4 {
+		t.Errorf("subgraph citations %v", sub.Citations())
+	}
+	// a2 is cited by c1 but its previous version is outside the subgraph
+	sub = testGraph().Subgraph(hash("c1"))
+	if work, ok := sub.Work(hash("a2")); !ok || work.Previous != crypto.ZeroHash {
+		t.Error("previous version outside the subgraph not removed")
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	var out bytes.Buffer
+	if err := testGraph().WriteDOT(&out); err != nil {
+		t.Fatal(err)
+	}
+	dot := out.String()
+	if !strings.HasPrefix(dot, "digraph citations {") || !strings.HasSuffix(dot, "}\n") {
+		t.Errorf("malformed DOT: %v", dot)
+	}
+	if strings.Count(dot, " -> ") != 6 || strings.Count(dot, "style=dashed") != 1 {
+		t.Errorf("expected 5 citations and 1 version edge: %v", dot)
+	}
+	if !strings.Contains(dot, `label="C \"coletivo\"\ncoletivo"`) {
+		t.Errorf("label not escaped: %v", dot)
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	var out bytes.Buffer
+	if err := testGraph().WriteGraphML(&out); err != nil {
+		t.Fatal(err)
+	}
+	var doc graphML
+	if err := xml.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid GraphML: %v", err)
+	}
+	if len(doc.Graph.Nodes) != 5 || len(doc.Graph.Edges) != 6 {
+		t.Errorf("%v nodes and %v edges, expected 5 and 6", len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+	kinds := make(map[string]int)
+	for _, edge := range doc.Graph.Edges {
+		kinds[edge.Data[0].Value]++
+	}
+	if kinds["cites"] != 5 || kinds["version"] != 1 {
+		t.Errorf("edge kinds %v", kinds)
+	}
+}
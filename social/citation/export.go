@@ -0,0 +1,182 @@
+package citation
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+// Subgraph is the graph around hash: every version of its chain, the works
+// they cite and the works citing them.
+func (g *Graph) Subgraph(hash crypto.Hash) *Graph {
+	included := make(map[crypto.Hash]struct{})
+	for _, version := range g.Versions(hash) {
+		included[version] = struct{}{}
+		for _, cited := range g.References(version) {
+			included[cited] = struct{}{}
+		}
+	}
+	for _, citation := range g.CitedBy(hash) {
+		included[citation.Citing] = struct{}{}
+	}
+	sub := NewGraph()
+	for _, work := range g.Works() {
+		if _, ok := included[work.Hash]; !ok {
+			continue
+		}
+		references := make([]crypto.Hash, 0)
+		for _, cited := range g.References(work.Hash) {
+			if _, ok := included[cited]; ok {
+				references = append(references, cited)
+			}
+		}
+		copied := *work
+		if _, ok := included[copied.Previous]; !ok {
+			copied.Previous = crypto.ZeroHash
+		}
+		sub.Add(copied, references)
+	}
+	return sub
+}
+
+// edges between consecutive versions of the same chain
+func (g *Graph) versionEdges() []Citation {
+	edges := make([]Citation, 0)
+	for _, work := range g.Works() {
+		if _, ok := g.works[work.Previous]; ok && work.Previous != crypto.ZeroHash {
+			edges = append(edges, Citation{Citing: work.Hash, Cited: work.Previous})
+		}
+	}
+	return edges
+}
+
+// escapa texto para uma string entre aspas do DOT
+func dotEscape(text string) string {
+	text = strings.ReplaceAll(text, "\\", "\\\\")
+	text = strings.ReplaceAll(text, "\"", "\\\"")
+	return strings.ReplaceAll(text, "\n", " ")
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language. Citations are solid
+// edges from the citing work to the cited one, new versions point to their
+// previous version with dashed edges.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var text strings.Builder
+	text.WriteString("digraph citations {\n")
+	text.WriteString("\tnode [shape=box];\n")
+	for _, work := range g.Works() {
+		label := dotEscape(work.Title)
+		if len(work.Authors) > 0 {
+			label += "\\n" + dotEscape(strings.Join(work.Authors, ", "))
+		}
+		fmt.Fprintf(&text, "\t\"%v\" [label=\"%v\", epoch=%d];\n", crypto.EncodeHash(work.Hash), label, work.Date)
+	}
+	for _, citation := range g.Citations() {
+		fmt.Fprintf(&text, "\t\"%v\" -> \"%v\";\n", crypto.EncodeHash(citation.Citing), crypto.EncodeHash(citation.Cited))
+	}
+	for _, edge := range g.versionEdges() {
+		fmt.Fprintf(&text, "\t\"%v\" -> \"%v\" [style=dashed, label=\"versão\"];\n", crypto.EncodeHash(edge.Citing), crypto.EncodeHash(edge.Cited))
+	}
+	text.WriteString("}\n")
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the graph in GraphML. Nodes carry the title, authors,
+// collective, epoch and first version of the chain; edges carry their kind
+// ("cites" or "version").
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	doc := graphML{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "title", For: "node", Name: "title", Type: "string"},
+			{ID: "authors", For: "node", Name: "authors", Type: "string"},
+			{ID: "collective", For: "node", Name: "collective", Type: "string"},
+			{ID: "epoch", For: "node", Name: "epoch", Type: "long"},
+			{ID: "root", For: "node", Name: "root", Type: "string"},
+			{ID: "kind", For: "edge", Name: "kind", Type: "string"},
+		},
+		Graph: graphMLGraph{
+			ID:          "citations",
+			EdgeDefault: "directed",
+			Nodes:       make([]graphMLNode, 0, g.Len()),
+			Edges:       make([]graphMLEdge, 0),
+		},
+	}
+	for _, work := range g.Works() {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: crypto.EncodeHash(work.Hash),
+			Data: []graphMLData{
+				{Key: "title", Value: work.Title},
+				{Key: "authors", Value: strings.Join(work.Authors, ", ")},
+				{Key: "collective", Value: work.Collective},
+				{Key: "epoch", Value: strconv.FormatUint(work.Date, 10)},
+				{Key: "root", Value: crypto.EncodeHash(g.Root(work.Hash))},
+			},
+		})
+	}
+	edge := func(citation Citation, kind string) graphMLEdge {
+		return graphMLEdge{
+			Source: crypto.EncodeHash(citation.Citing),
+			Target: crypto.EncodeHash(citation.Cited),
+			Data:   []graphMLData{{Key: "kind", Value: kind}},
+		}
+	}
+	for _, citation := range g.Citations() {
+		doc.Graph.Edges = append(doc.Graph.Edges, edge(citation, "cites"))
+	}
+	for _, version := range g.versionEdges() {
+		doc.Graph.Edges = append(doc.Graph.Edges, edge(version, "version"))
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,337 @@
+// Package diff computes line-level differences between versions of textual
+// media (drafts and edits of type txt or md).
+//
+// Lines are compared with the Myers O(ND) algorithm after trimming the common
+// prefix and suffix. The result is a list of Line (equal, deleted or inserted,
+// with the line numbers on each version) that can be grouped into hunks with
+// surrounding context or paired side by side for rendering.
+package diff
+
+import (
+	"strings"
+)
+
+// beyond this number of edits the differing region is reported as entirely
+// replaced instead of searching for the shortest script (var so that tests
+// can exercise the fallback on small inputs)
+var maxEdits = 4096
+
+type Op byte
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+var opNames = []string{"equal", "delete", "insert"}
+
+func (o Op) String() string {
+	if int(o) >= len(opNames) {
+		return "unknown"
+	}
+	return opNames[o]
+}
+
+func (o Op) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// Line of a diff. Old and New are the 1-based line numbers on each version,
+// zero when the line is absent from that version.
+type Line struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+	Old  int    `json:"old,omitempty"`
+	New  int    `json:"new,omitempty"`
+}
+
+// Hunk is a contiguous region of changes with its context. As on unified
+// diffs, a start refers to the line preceding the hunk when the hunk has no
+// lines on that version.
+type Hunk struct {
+	OldStart int    `json:"oldStart"`
+	OldLines int    `json:"oldLines"`
+	NewStart int    `json:"newStart"`
+	NewLines int    `json:"newLines"`
+	Lines    []Line `json:"lines"`
+}
+
+// Pair is a row of a side by side diff. Left is nil for an inserted line
+// without a deleted counterpart and Right is nil for a deleted line.
+type Pair struct {
+	Left  *Line
+	Right *Line
+}
+
+// Split breaks text into lines. A final line break does not start an empty
+// line and \r\n is taken as a line break.
+func Split(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+	return strings.Split(text, "\n")
+}
+
+// Text returns the line diff from old to new.
+func Text(old, new string) []Line {
+	return Lines(Split(old), Split(new))
+}
+
+// Lines returns the line diff from old to new.
+func Lines(old, new []string) []Line {
+	a, b := intern(old, new)
+	ops := script(a, b)
+	lines := make([]Line, 0, len(ops))
+	o, n := 0, 0
+	for _, op := range ops {
+		switch op {
+		case Equal:
+			lines = append(lines, Line{Op: Equal, Text: old[o], Old: o + 1, New: n + 1})
+			o, n = o+1, n+1
+		case Delete:
+			lines = append(lines, Line{Op: Delete, Text: old[o], Old: o + 1})
+			o++
+		case Insert:
+			lines = append(lines, Line{Op: Insert, Text: new[n], New: n + 1})
+			n++
+		}
+	}
+	return lines
+}
+
+// Stats counts the inserted and deleted lines.
+func Stats(lines []Line) (inserted, deleted int) {
+	for _, line := range lines {
+		switch line.Op {
+		case Insert:
+			inserted++
+		case Delete:
+			deleted++
+		}
+	}
+	return inserted, deleted
+}
+
+// Hunks groups the changed lines with up to context unchanged lines around
+// them. Changes closer than twice the context are merged into one hunk.
+func Hunks(lines []Line, context int) []Hunk {
+	if context < 0 {
+		context = 0
+	}
+	hunks := make([]Hunk, 0)
+	start, end := -1, -1
+	flush := func() {
+		if start < 0 {
+			return
+		}
+		from := start - context
+		if from < 0 {
+			from = 0
+		}
+		to := end + context + 1
+		if to > len(lines) {
+			to = len(lines)
+		}
+		hunks = append(hunks, newHunk(lines, from, to))
+		start, end = -1, -1
+	}
+	for n, line := range lines {
+		if line.Op == Equal {
+			continue
+		}
+		if start >= 0 && n-end-1 > 2*context {
+			flush()
+		}
+		if start < 0 {
+			start = n
+		}
+		end = n
+	}
+	flush()
+	return hunks
+}
+
+func newHunk(lines []Line, from, to int) Hunk {
+	// posicao em cada versao antes do inicio do hunk
+	oldPos, newPos := 0, 0
+	for _, line := range lines[:from] {
+		if line.Op != Insert {
+			oldPos++
+		}
+		if line.Op != Delete {
+			newPos++
+		}
+	}
+	hunk := Hunk{Lines: append([]Line{}, lines[from:to]...)}
+	for _, line := range hunk.Lines {
+		if line.Op != Insert {
+			hunk.OldLines++
+		}
+		if line.Op != Delete {
+			hunk.NewLines++
+		}
+	}
+	hunk.OldStart, hunk.NewStart = oldPos, newPos
+	if hunk.OldLines > 0 {
+		hunk.OldStart++
+	}
+	if hunk.NewLines > 0 {
+		hunk.NewStart++
+	}
+	return hunk
+}
+
+// SideBySide pairs each run of deleted lines with the run of inserted lines
+// that follows it. Unchanged lines appear on both sides.
+func SideBySide(lines []Line) []Pair {
+	pairs := make([]Pair, 0, len(lines))
+	for n := 0; n < len(lines); {
+		if lines[n].Op == Equal {
+			pairs = append(pairs, Pair{Left: &lines[n], Right: &lines[n]})
+			n++
+			continue
+		}
+		deleted := make([]*Line, 0)
+		for n < len(lines) && lines[n].Op == Delete {
+			deleted = append(deleted, &lines[n])
+			n++
+		}
+		inserted := make([]*Line, 0)
+		for n < len(lines) && lines[n].Op == Insert {
+			inserted = append(inserted, &lines[n])
+			n++
+		}
+		for k := 0; k < len(deleted) || k < len(inserted); k++ {
+			var pair Pair
+			if k < len(deleted) {
+				pair.Left = deleted[k]
+			}
+			if k < len(inserted) {
+				pair.Right = inserted[k]
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
+}
+
+// troca cada linha por um inteiro para comparar linhas iguais em O(1)
+func intern(old, new []string) ([]int, []int) {
+	ids := make(map[string]int)
+	id := func(lines []string) []int {
+		out := make([]int, len(lines))
+		for n, line := range lines {
+			value, ok := ids[line]
+			if !ok {
+				value = len(ids)
+				ids[line] = value
+			}
+			out[n] = value
+		}
+		return out
+	}
+	return id(old), id(new)
+}
+
+// script returns the sequence of operations transforming a into b.
+func script(a, b []int) []Op {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ops := make([]Op, 0, len(a)+len(b))
+	for n := 0; n < prefix; n++ {
+		ops = append(ops, Equal)
+	}
+	ops = append(ops, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for n := 0; n < suffix; n++ {
+		ops = append(ops, Equal)
+	}
+	return ops
+}
+
+func myers(a, b []int) []Op {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replaced(n, m)
+	}
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace[d] guarda v[-d..d] ao final do passo d
+	trace := make([][]int, 0)
+	found := false
+	for d := 0; d <= max && !found; d++ {
+		if d > maxEdits {
+			return replaced(n, m)
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+		trace = append(trace, append([]int{}, v[offset-d:offset+d+1]...))
+	}
+	ops := make([]Op, 0, max)
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		previous := trace[d-1]
+		k := x - y
+		var previousK int
+		if k == -d || (k != d && previous[k-1+d-1] < previous[k+1+d-1]) {
+			previousK = k + 1
+		} else {
+			previousK = k - 1
+		}
+		previousX := previous[previousK+d-1]
+		previousY := previousX - previousK
+		for x > previousX && y > previousY {
+			ops = append(ops, Equal)
+			x, y = x-1, y-1
+		}
+		if x == previousX {
+			ops = append(ops, Insert)
+		} else {
+			ops = append(ops, Delete)
+		}
+		x, y = previousX, previousY
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, Equal)
+		x, y = x-1, y-1
+	}
+	for left, right := 0, len(ops)-1; left < right; left, right = left+1, right-1 {
+		ops[left], ops[right] = ops[right], ops[left]
+	}
+	return ops
+}
+
+func replaced(deleted, inserted int) []Op {
+	ops := make([]Op, 0, deleted+inserted)
+	for n := 0; n < deleted; n++ {
+		ops = append(ops, Delete)
+	}
+	for n := 0; n < inserted; n++ {
+		ops = append(ops, Insert)
+	}
+	return ops
+}
@@ -0,0 +1,194 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ops renders a diff as one character per line: = equal, - delete, + insert
+func ops(lines []Line) string {
+	var out strings.Builder
+	for _, line := range lines {
+		out.WriteString([]string{"=", "-", "+"}[line.Op])
+	}
+	return out.String()
+}
+
+// versions rebuilds both versions from a diff
+func versions(lines []Line) (old, new []string) {
+	for _, line := range lines {
+		if line.Op != Insert {
+			old = append(old, line.Text)
+		}
+		if line.Op != Delete {
+			new = append(new, line.Text)
+		}
+	}
+	return old, new
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a\n", []string{"a"}},
+		{"a\r\nb\r\n", []string{"a", "b"}},
+		{"a\n\nb", []string{"a", "", "b"}},
+	}
+	for _, test := range tests {
+		if lines := Split(test.text); !reflect.DeepEqual(lines, test.expected) {
+			t.Errorf("Split(%q) returned %q, expected %q", test.text, lines, test.expected)
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		old, new string
+		expected string
+	}{
+		{"", "", ""},
+		{"a\nb\n", "a\nb\n", "=="},
+		{"", "a\nb\n", "++"},
+		{"a\nb\n", "", "--"},
+		{"a\nb\nc\n", "a\nx\nc\n", "=-+="},
+		{"a\nb\nc\n", "a\nc\n", "=-="},
+		{"a\nc\n", "a\nb\nc\n", "=+="},
+		{"a\nb\nc\nd\n", "b\nc\nd\ne\n", "-===+"},
+		// shortest script: the common lines are kept
+		{"a\nb\nc\na\nb\nb\na\n", "c\nb\na\nb\na\nc\n", "--=+==-=+"},
+	}
+	for _, test := range tests {
+		lines := Text(test.old, test.new)
+		if ops(lines) != test.expected {
+			t.Errorf("diff %q -> %q returned %v, expected %v", test.old, test.new, ops(lines), test.expected)
+		}
+		old, new := versions(lines)
+		if !reflect.DeepEqual(old, Split(test.old)) || !reflect.DeepEqual(new, Split(test.new)) {
+			t.Errorf("diff %q -> %q does not rebuild the versions", test.old, test.new)
+		}
+		o, n := 0, 0
+		for _, line := range lines {
+			if line.Op != Insert {
+				o++
+				if line.Old != o {
+					t.Errorf("diff %q -> %q: line %q numbered %v on the old version, expected %v", test.old, test.new, line.Text, line.Old, o)
+				}
+			}
+			if line.Op != Delete {
+				n++
+				if line.New != n {
+					t.Errorf("diff %q -> %q: line %q numbered %v on the new version, expected %v", test.old, test.new, line.Text, line.New, n)
+				}
+			}
+		}
+	}
+}
+
+func TestMaxEdits(t *testing.T) {
+	max := maxEdits
+	defer func() { maxEdits = max }()
+	maxEdits = 2
+	old := Split("a\n1\nb\n2\nc\n3\nd\n")
+	new := Split("a\nb\nc\nd\n")
+	lines := Lines(old, new)
+	// the common prefix and suffix are still found, the region between them
+	// is replaced entirely
+	if ops(lines) != "=-----++=" {
+		t.Errorf("fallback returned %v", ops(lines))
+	}
+	if o, n := versions(lines); !reflect.DeepEqual(o, old) || !reflect.DeepEqual(n, new) {
+		t.Error("fallback does not rebuild the versions")
+	}
+	maxEdits = max
+	if ops(Lines(old, new)) != "=-=-=-=" {
+		t.Errorf("shortest script returned %v", ops(Lines(old, new)))
+	}
+}
+
+func TestStats(t *testing.T) {
+	inserted, deleted := Stats(Text("a\nb\nc\n", "a\nx\ny\n"))
+	if inserted != 2 || deleted != 2 {
+		t.Errorf("Stats returned %v inserted and %v deleted", inserted, deleted)
+	}
+}
+
+func TestHunks(t *testing.T) {
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	tests := []struct {
+		name     string
+		new      string
+		context  int
+		expected []Hunk
+	}{
+		{"no changes", old, 3, []Hunk{}},
+		{
+			"single change", "1\n2\n3\n4\nx\n6\n7\n8\n9\n10\n", 1,
+			[]Hunk{{OldStart: 4, OldLines: 3, NewStart: 4, NewLines: 3}},
+		},
+		{
+			"distant changes", "x\n2\n3\n4\n5\n6\n7\n8\n9\ny\n", 1,
+			[]Hunk{
+				{OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 2},
+				{OldStart: 9, OldLines: 2, NewStart: 9, NewLines: 2},
+			},
+		},
+		{
+			"close changes merged", "1\nx\n3\n4\ny\n6\n7\n8\n9\n10\n", 1,
+			[]Hunk{{OldStart: 1, OldLines: 6, NewStart: 1, NewLines: 6}},
+		},
+		{
+			"insertion at the start without context", "0\n" + old, 0,
+			[]Hunk{{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1}},
+		},
+		{
+			"deletion at the end without context", "1\n2\n3\n4\n5\n6\n7\n8\n9\n", 0,
+			[]Hunk{{OldStart: 10, OldLines: 1, NewStart: 9, NewLines: 0}},
+		},
+	}
+	for _, test := range tests {
+		lines := Text(old, test.new)
+		hunks := Hunks(lines, test.context)
+		if len(hunks) != len(test.expected) {
+			t.Errorf("%v: %v hunks, expected %v", test.name, len(hunks), len(test.expected))
+			continue
+		}
+		for n, hunk := range hunks {
+			expected := test.expected[n]
+			if hunk.OldStart != expected.OldStart || hunk.OldLines != expected.OldLines || hunk.NewStart != expected.NewStart || hunk.NewLines != expected.NewLines {
+				t.Errorf("%v: hunk @@ -%d,%d +%d,%d @@, expected @@ -%d,%d +%d,%d @@", test.name, hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines, expected.OldStart, expected.OldLines, expected.NewStart, expected.NewLines)
+			}
+		}
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	pairs := SideBySide(Text("a\nb\nc\nd\n", "a\nx\ny\nz\nd\ne\n"))
+	expected := []struct{ left, right string }{
+		{"a", "a"},
+		{"b", "x"},
+		{"c", "y"},
+		{"", "z"},
+		{"d", "d"},
+		{"", "e"},
+	}
+	if len(pairs) != len(expected) {
+		t.Fatalf("%v pairs, expected %v", len(pairs), len(expected))
+	}
+	for n, pair := range pairs {
+		left, right := "", ""
+		if pair.Left != nil {
+			left = pair.Left.Text
+		}
+		if pair.Right != nil {
+			right = pair.Right.Text
+		}
+		if left != expected[n].left || right != expected[n].right {
+			t.Errorf("pair %v is %q|%q, expected %q|%q", n, left, right, expected[n].left, expected[n].right)
+		}
+	}
+}
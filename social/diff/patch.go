@@ -0,0 +1,315 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+A patch is the text of a list of hunks in the unified format:
+
+	@@ -3,3 +3,3 @@
+	 unchanged line
+	-deleted line
+	+inserted line
+	 unchanged line
+
+File headers (--- and +++) and "\ No newline at end of file" markers are
+accepted and ignored. Patches are always relative to the content of the draft
+referenced by the edit that carries them.
+*/
+
+// PatchType is the content type of an edit carrying a patch
+const PatchType = "patch"
+
+var (
+	ErrInvalidPatch = errors.New("invalid patch")
+	ErrPatchApply   = errors.New("patch does not apply")
+)
+
+var opPrefix = []string{" ", "-", "+"}
+
+// Format writes hunks as a unified patch.
+func Format(hunks []Hunk) string {
+	var text strings.Builder
+	for _, hunk := range hunks {
+		fmt.Fprintf(&text, "@@ -%d,%d +%d,%d @@\n", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+		for _, line := range hunk.Lines {
+			text.WriteString(opPrefix[line.Op])
+			text.WriteString(line.Text)
+			text.WriteString("\n")
+		}
+	}
+	return text.String()
+}
+
+// Parse reads a unified patch. Line numbers of the parsed lines are derived
+// from the hunk headers.
+func Parse(patch string) ([]Hunk, error) {
+	hunks := make([]Hunk, 0)
+	var hunk *Hunk
+	oldLine, newLine := 0, 0
+	for n, text := range Split(patch) {
+		switch {
+		case strings.HasPrefix(text, "@@"):
+			if hunk != nil {
+				if err := checkHunk(hunk); err != nil {
+					return nil, err
+				}
+				hunks = append(hunks, *hunk)
+			}
+			parsed, err := parseHeader(text)
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidPatch, n+1, err)
+			}
+			hunk = &parsed
+			oldLine, newLine = hunk.OldStart, hunk.NewStart
+			if hunk.OldLines == 0 {
+				oldLine++
+			}
+			if hunk.NewLines == 0 {
+				newLine++
+			}
+		case hunk == nil && (strings.HasPrefix(text, "---") || strings.HasPrefix(text, "+++")):
+			// cabecalho de arquivo
+		case strings.HasPrefix(text, "\\"):
+			// \ No newline at end of file
+		case hunk == nil:
+			return nil, fmt.Errorf("%w: line %d: content outside a hunk", ErrInvalidPatch, n+1)
+		case strings.HasPrefix(text, "-"):
+			hunk.Lines = append(hunk.Lines, Line{Op: Delete, Text: text[1:], Old: oldLine})
+			oldLine++
+		case strings.HasPrefix(text, "+"):
+			hunk.Lines = append(hunk.Lines, Line{Op: Insert, Text: text[1:], New: newLine})
+			newLine++
+		case strings.HasPrefix(text, " ") || text == "":
+			// linhas de contexto vazias podem perder o espaco inicial
+			if text != "" {
+				text = text[1:]
+			}
+			hunk.Lines = append(hunk.Lines, Line{Op: Equal, Text: text, Old: oldLine, New: newLine})
+			oldLine, newLine = oldLine+1, newLine+1
+		default:
+			return nil, fmt.Errorf("%w: line %d: unexpected %q", ErrInvalidPatch, n+1, text)
+		}
+	}
+	if hunk != nil {
+		if err := checkHunk(hunk); err != nil {
+			return nil, err
+		}
+		hunks = append(hunks, *hunk)
+	}
+	return hunks, nil
+}
+
+// parses "@@ -oldStart,oldLines +newStart,newLines @@" (counts default to 1)
+func parseHeader(text string) (Hunk, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 4 || fields[0] != "@@" || fields[3] != "@@" {
+		return Hunk{}, errors.New("malformed hunk header")
+	}
+	var hunk Hunk
+	var err error
+	if !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return Hunk{}, errors.New("malformed hunk range")
+	}
+	if hunk.OldStart, hunk.OldLines, err = parseRange(fields[1][1:]); err != nil {
+		return Hunk{}, err
+	}
+	if hunk.NewStart, hunk.NewLines, err = parseRange(fields[2][1:]); err != nil {
+		return Hunk{}, err
+	}
+	return hunk, nil
+}
+
+func parseRange(text string) (int, int, error) {
+	start, count, found := strings.Cut(text, ",")
+	first, err := strconv.Atoi(start)
+	if err != nil || first < 0 {
+		return 0, 0, errors.New("malformed hunk range")
+	}
+	lines := 1
+	if found {
+		if lines, err = strconv.Atoi(count); err != nil || lines < 0 {
+			return 0, 0, errors.New("malformed hunk range")
+		}
+	}
+	return first, lines, nil
+}
+
+func checkHunk(hunk *Hunk) error {
+	oldLines, newLines := 0, 0
+	for _, line := range hunk.Lines {
+		if line.Op != Insert {
+			oldLines++
+		}
+		if line.Op != Delete {
+			newLines++
+		}
+	}
+	if oldLines != hunk.OldLines || newLines != hunk.NewLines {
+		return fmt.Errorf("%w: hunk @@ -%d,%d +%d,%d @@ has %d old and %d new lines", ErrInvalidPatch, hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines, oldLines, newLines)
+	}
+	return nil
+}
+
+// change replaces base[start:end] by lines
+type change struct {
+	start int
+	end   int
+	lines []string
+	patch int
+}
+
+func (c change) equal(other change) bool {
+	if c.start != other.start || c.end != other.end || len(c.lines) != len(other.lines) {
+		return false
+	}
+	for n := range c.lines {
+		if c.lines[n] != other.lines[n] {
+			return false
+		}
+	}
+	return true
+}
+
+func (c change) overlaps(other change) bool {
+	if c.start == c.end && other.start == other.end {
+		return c.start == other.start
+	}
+	return c.start < other.end && other.start < c.end
+}
+
+// changes of a patch against base, checking that every context and deleted
+// line of the patch is found on base at the expected position
+func changes(base []string, hunks []Hunk, patch int) ([]change, error) {
+	all := make([]change, 0)
+	last := 0
+	for _, hunk := range hunks {
+		position := hunk.OldStart - 1
+		if hunk.OldLines == 0 {
+			position = hunk.OldStart
+		}
+		if position < last || position > len(base) {
+			return nil, fmt.Errorf("%w: hunk @@ -%d,%d @@ out of place", ErrPatchApply, hunk.OldStart, hunk.OldLines)
+		}
+		var current *change
+		for _, line := range hunk.Lines {
+			if line.Op == Equal {
+				if position >= len(base) || base[position] != line.Text {
+					return nil, fmt.Errorf("%w: line %d differs from the draft", ErrPatchApply, position+1)
+				}
+				if current != nil {
+					all = append(all, *current)
+					current = nil
+				}
+				position++
+				continue
+			}
+			if current == nil {
+				current = &change{start: position, end: position, lines: make([]string, 0), patch: patch}
+			}
+			if line.Op == Delete {
+				if position >= len(base) || base[position] != line.Text {
+					return nil, fmt.Errorf("%w: line %d differs from the draft", ErrPatchApply, position+1)
+				}
+				position++
+				current.end = position
+			} else {
+				current.lines = append(current.lines, line.Text)
+			}
+		}
+		if current != nil {
+			all = append(all, *current)
+		}
+		last = position
+	}
+	return all, nil
+}
+
+func applyChanges(base []string, all []change) []string {
+	sort.SliceStable(all, func(n, m int) bool {
+		if all[n].start != all[m].start {
+			return all[n].start < all[m].start
+		}
+		// insercoes antes de remocoes na mesma posicao
+		return all[n].end < all[m].end
+	})
+	out := make([]string, 0, len(base))
+	position := 0
+	for _, c := range all {
+		out = append(out, base[position:c.start]...)
+		out = append(out, c.lines...)
+		position = c.end
+	}
+	return append(out, base[position:]...)
+}
+
+// Apply applies a patch on base.
+func Apply(base []string, hunks []Hunk) ([]string, error) {
+	all, err := changes(base, hunks, 0)
+	if err != nil {
+		return nil, err
+	}
+	return applyChanges(base, all), nil
+}
+
+// Conflict reports a change of a patch left out of a merge. With is the
+// index of the patch holding an accepted change on the same region, or -1 if
+// the patch does not apply on the base at all.
+type Conflict struct {
+	Patch    int
+	With     int
+	OldStart int
+	OldLines int
+	Reason   string
+}
+
+// Merge applies several patches against the same base. Patches are taken in
+// order: a change overlapping a change already accepted from another patch is
+// left out and reported as a conflict, identical changes are applied once.
+func Merge(base []string, patches [][]Hunk) ([]string, []Conflict) {
+	accepted := make([]change, 0)
+	conflicts := make([]Conflict, 0)
+	for n, hunks := range patches {
+		all, err := changes(base, hunks, n)
+		if err != nil {
+			conflicts = append(conflicts, Conflict{Patch: n, With: -1, Reason: err.Error()})
+			continue
+		}
+	next:
+		for _, c := range all {
+			for _, other := range accepted {
+				if other.patch == n {
+					continue
+				}
+				if c.equal(other) {
+					continue next
+				}
+				if c.overlaps(other) {
+					conflicts = append(conflicts, Conflict{
+						Patch:    n,
+						With:     other.patch,
+						OldStart: c.start + 1,
+						OldLines: c.end - c.start,
+						Reason:   fmt.Sprintf("lines %d-%d changed by another edit", other.start+1, other.end),
+					})
+					continue next
+				}
+			}
+			accepted = append(accepted, c)
+		}
+	}
+	return applyChanges(base, accepted), conflicts
+}
+
+// Join joins lines back into text ending with a line break.
+func Join(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,177 @@
+package diff
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const base = "um\ndois\ntres\nquatro\ncinco\nseis\nsete\noito\n"
+
+// patch returns the unified patch from base to text
+func patch(t *testing.T, text string) []Hunk {
+	t.Helper()
+	hunks, err := Parse(Format(Hunks(Text(base, text), 1)))
+	if err != nil {
+		t.Fatalf("could not parse formatted patch: %v", err)
+	}
+	return hunks
+}
+
+func TestFormatParse(t *testing.T) {
+	hunks := Hunks(Text(base, "um\nDOIS\ntres\nquatro\ncinco\nseis\noito\nnove\n"), 1)
+	parsed, err := Parse(Format(hunks))
+	if err != nil {
+		t.Fatalf("could not parse formatted patch: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, hunks) {
+		t.Errorf("Format and Parse not working:\n%+v\n%+v", parsed, hunks)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		valid bool
+	}{
+		{"empty", "", true},
+		{"file headers", "--- a\n+++ b\n@@ -2,1 +2,1 @@\n-dois\n+DOIS\n", true},
+		{"default counts", "@@ -2 +2 @@\n-dois\n+DOIS\n", true},
+		{"no newline marker", "@@ -8,1 +8,1 @@\n-oito\n\\ No newline at end of file\n+OITO\n", true},
+		{"empty context line", "@@ -1,2 +1,2 @@\n\n-dois\n+DOIS\n", true},
+		{"content outside a hunk", "-dois\n", false},
+		{"malformed header", "@@ -2,1 @@\n-dois\n", false},
+		{"malformed range", "@@ -a,1 +2,1 @@\n-dois\n+DOIS\n", false},
+		{"negative count", "@@ -2,-1 +2,1 @@\n+DOIS\n", false},
+		{"wrong count", "@@ -2,2 +2,1 @@\n-dois\n+DOIS\n", false},
+		{"unexpected line", "@@ -2,1 +2,1 @@\n-dois\n*DOIS\n", false},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.patch)
+		if test.valid && err != nil {
+			t.Errorf("%v: unexpected error %v", test.name, err)
+		}
+		if !test.valid && !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("%v: expected %v, got %v", test.name, ErrInvalidPatch, err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    string
+		expected string
+		err      error
+	}{
+		{"replace", "@@ -1,3 +1,3 @@\n um\n-dois\n+DOIS\n tres\n", "um\nDOIS\ntres\nquatro\ncinco\nseis\nsete\noito\n", nil},
+		{"insert at start", "@@ -0,0 +1,1 @@\n+zero\n", "zero\n" + base, nil},
+		{"append", "@@ -8,1 +8,2 @@\n oito\n+nove\n", base + "nove\n", nil},
+		{"delete", "@@ -4,3 +4,1 @@\n quatro\n-cinco\n-seis\n", "um\ndois\ntres\nquatro\nsete\noito\n", nil},
+		{
+			"two hunks", "@@ -1,1 +1,1 @@\n-um\n+UM\n@@ -8,1 +8,1 @@\n-oito\n+OITO\n",
+			"UM\ndois\ntres\nquatro\ncinco\nseis\nsete\nOITO\n", nil,
+		},
+		{"context differs", "@@ -1,2 +1,2 @@\n um\n-tres\n+TRES\n", "", ErrPatchApply},
+		{"deleted line differs", "@@ -2,1 +2,1 @@\n-DOIS\n+dois\n", "", ErrPatchApply},
+		{"beyond the end", "@@ -9,1 +9,1 @@\n-nove\n+NOVE\n", "", ErrPatchApply},
+		{"hunks out of order", "@@ -8,1 +8,1 @@\n-oito\n+OITO\n@@ -1,1 +1,1 @@\n-um\n+UM\n", "", ErrPatchApply},
+	}
+	for _, test := range tests {
+		hunks, err := Parse(test.patch)
+		if err != nil {
+			t.Errorf("%v: could not parse patch: %v", test.name, err)
+			continue
+		}
+		lines, err := Apply(Split(base), hunks)
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("%v: expected %v, got %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", test.name, err)
+		} else if text := Join(lines); text != test.expected {
+			t.Errorf("%v: patch applied as %q, expected %q", test.name, text, test.expected)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name      string
+		versions  []string
+		expected  string
+		conflicts []Conflict
+	}{
+		{
+			"independent changes",
+			[]string{
+				"UM\ndois\ntres\nquatro\ncinco\nseis\nsete\noito\n",
+				"um\ndois\ntres\nquatro\ncinco\nseis\nsete\nOITO\n",
+			},
+			"UM\ndois\ntres\nquatro\ncinco\nseis\nsete\nOITO\n",
+			[]Conflict{},
+		},
+		{
+			"identical changes applied once",
+			[]string{
+				"um\ndois\ntres\nQUATRO\ncinco\nseis\nsete\noito\n",
+				"um\ndois\ntres\nQUATRO\ncinco\nseis\nsete\noito\n",
+			},
+			"um\ndois\ntres\nQUATRO\ncinco\nseis\nsete\noito\n",
+			[]Conflict{},
+		},
+		{
+			"overlapping changes",
+			[]string{
+				"um\ndois\ntres\nQUATRO\ncinco\nseis\nsete\noito\n",
+				"um\ndois\ntres\nquatro!\ncinco\nseis\nsete\nOITO\n",
+			},
+			"um\ndois\ntres\nQUATRO\ncinco\nseis\nsete\nOITO\n",
+			[]Conflict{{Patch: 1, With: 0, OldStart: 4, OldLines: 1}},
+		},
+		{
+			"insertions at the same place",
+			[]string{
+				"um\ndois\nantes\ntres\nquatro\ncinco\nseis\nsete\noito\n",
+				"um\ndois\noutra\ntres\nquatro\ncinco\nseis\nsete\noito\n",
+			},
+			"um\ndois\nantes\ntres\nquatro\ncinco\nseis\nsete\noito\n",
+			[]Conflict{{Patch: 1, With: 0, OldStart: 3, OldLines: 0}},
+		},
+	}
+	for _, test := range tests {
+		patches := make([][]Hunk, len(test.versions))
+		for n, version := range test.versions {
+			patches[n] = patch(t, version)
+		}
+		lines, conflicts := Merge(Split(base), patches)
+		if text := Join(lines); text != test.expected {
+			t.Errorf("%v: merged as %q, expected %q", test.name, text, test.expected)
+		}
+		for n := range conflicts {
+			conflicts[n].Reason = ""
+		}
+		if !reflect.DeepEqual(conflicts, test.conflicts) {
+			t.Errorf("%v: conflicts %+v, expected %+v", test.name, conflicts, test.conflicts)
+		}
+	}
+}
+
+func TestMergeNotApplicable(t *testing.T) {
+	stale, err := Parse("@@ -2,1 +2,1 @@\n-DOIS\n+dois\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := patch(t, "um\ndois\ntres\nquatro\ncinco\nseis\nsete\nOITO\n")
+	lines, conflicts := Merge(Split(base), [][]Hunk{stale, valid})
+	if Join(lines) != "um\ndois\ntres\nquatro\ncinco\nseis\nsete\nOITO\n" {
+		t.Errorf("valid patch not merged: %q", Join(lines))
+	}
+	if len(conflicts) != 1 || conflicts[0].Patch != 0 || conflicts[0].With != -1 {
+		t.Errorf("patch that does not apply not reported: %+v", conflicts)
+	}
+}
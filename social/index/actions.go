@@ -24,6 +24,8 @@ func (i *Index) ActionToObjects(action actions.Action) []crypto.Hash {
 		return []crypto.Hash{v.EventHash}
 	case *actions.CheckinEvent:
 		return []crypto.Hash{v.EventHash}
+	case *actions.CancelCheckinEvent:
+		return []crypto.Hash{v.EventHash}
 	case *actions.GreetCheckinEvent:
 		return []crypto.Hash{v.EventHash}
 	case *actions.CreateBoard:
@@ -60,6 +62,16 @@ func (i *Index) ActionToObjects(action actions.Action) []crypto.Hash {
 		return []crypto.Hash{crypto.Hasher([]byte(v.Collective))}
 	case *actions.RemoveMember:
 		return []crypto.Hash{crypto.Hasher([]byte(v.OnBehalfOf))}
+	case *actions.Delegate:
+		return []crypto.Hash{crypto.Hasher([]byte(v.Collective))}
+	case *actions.Comment:
+		return []crypto.Hash{v.Target}
+	case *actions.CreateJournal:
+		return []crypto.Hash{crypto.Hasher([]byte(v.OnBehalfOf))}
+	case *actions.JournalEditor:
+		return []crypto.Hash{crypto.Hasher([]byte(v.Journal))}
+	case *actions.JournalIssue:
+		return []crypto.Hash{crypto.Hasher([]byte(v.Journal)), v.Hashed()}
 	case *actions.Signin:
 		return []crypto.Hash{crypto.ZeroHash}
 	}
@@ -78,6 +90,14 @@ func fmtBoard(board string) string {
 	return fmt.Sprintf("<a href=\"./board/%v\">%v</a>", url.QueryEscape(board), board)
 }
 
+func fmtJournal(journal string) string {
+	return fmt.Sprintf("<a href=\"./journal/%v\">%v</a>", url.QueryEscape(journal), journal)
+}
+
+func fmtIssue(number int, hash crypto.Hash) string {
+	return fmt.Sprintf("<a href=\"./issue/%v\">número %v</a>", crypto.EncodeHash(hash), number)
+}
+
 func fmtDraft(draft string, hash crypto.Hash) string {
 	if len(draft) > 40 {
 		draft = draft[:40] + "..."
@@ -149,8 +169,38 @@ func (i *Index) ActionToFormatedString(action actions.Action) (string, string, u
 			handle := i.state.Members[crypto.HashToken(v.Author)]
 			return fmt.Sprintf("%v confirmou participação no evento %v por %v ", fmtHandle(handle), fmtEvent(event.StartAt, v.EventHash), event.Collective.Name), "people", v.Epoch
 		}
+	case *actions.CancelCheckinEvent:
+		if event, ok := i.state.Events[v.EventHash]; ok {
+			handle := i.state.Members[crypto.HashToken(v.Author)]
+			return fmt.Sprintf("%v desistiu de participar do evento %v por %v ", fmtHandle(handle), fmtEvent(event.StartAt, v.EventHash), event.Collective.Name), "people", v.Epoch
+		}
 	case *actions.GreetCheckinEvent:
 		return "", "", 0
+	case *actions.Delegate:
+		return "", "", 0
+	case *actions.Comment:
+		handle := i.state.Members[crypto.HashToken(v.Author)]
+		if v.ReplyTo != crypto.ZeroHash {
+			return fmt.Sprintf("%v respondeu a um comentário", fmtHandle(handle)), "comment", v.Epoch
+		}
+		return fmt.Sprintf("%v comentou", fmtHandle(handle)), "comment", v.Epoch
+	case *actions.CreateJournal:
+		if journal, ok := i.state.Journals[v.Hashed()]; ok {
+			return fmt.Sprintf("%v criou um novo periódico %v", fmtCollective(journal.Collective.Name), fmtJournal(journal.Name)), "new stuff", v.Epoch
+		}
+	case *actions.JournalEditor:
+		if journal, ok := i.state.Journal(v.Journal); ok {
+			editor := i.state.Members[crypto.HashToken(v.Editor)]
+			editorship := "removido da"
+			if v.Insert {
+				editorship = "incluído na"
+			}
+			return fmt.Sprintf("%v %v editoria de %v em nome de %v", fmtHandle(editor), editorship, fmtJournal(journal.Name), fmtCollective(journal.Collective.Name)), "people", v.Epoch
+		}
+	case *actions.JournalIssue:
+		if issue, ok := i.state.Issues[v.Hashed()]; ok {
+			return fmt.Sprintf("%v publicou o %v de %v", fmtCollective(issue.Journal.Collective.Name), fmtIssue(issue.Number, issue.Hash), fmtJournal(issue.Journal.Name)), "new stuff", v.Epoch
+		}
 	case *actions.CreateBoard:
 		boardhash := v.Hashed()
 		if board, ok := i.state.Boards[boardhash]; ok {
@@ -321,6 +371,14 @@ func (i *Index) ActionToString(action actions.Action, status state.ConsensusStat
 			}
 		}
 		return "", "", v.Author, 0, ""
+	case *actions.CancelCheckinEvent:
+		if event, ok := i.state.Events[v.EventHash]; ok {
+			if status == state.Favorable {
+				handle := i.state.Members[crypto.HashToken(v.Author)]
+				return fmt.Sprintf("%v desistiu de participar do evento em %v por %v ", handle, event.StartAt.Format("2006-01-02"), event.Collective.Name), crypto.EncodeHash(v.EventHash), v.Author, v.Epoch, "event cancel checkin"
+			}
+		}
+		return "", "", v.Author, 0, ""
 	case *actions.GreetCheckinEvent:
 		if event, ok := i.state.Events[v.EventHash]; ok {
 			// verificar como status se comporta aqui
@@ -330,6 +388,75 @@ func (i *Index) ActionToString(action actions.Action, status state.ConsensusStat
 			}
 		}
 		return "", "", v.Author, 0, ""
+	case *actions.Delegate:
+		collectivehash := crypto.Hasher([]byte(v.Collective))
+		if collective, ok := i.state.Collectives[collectivehash]; ok {
+			handle := i.state.Members[crypto.HashToken(v.Author)]
+			if v.Revoke {
+				return fmt.Sprintf("%v revogou delegação de voto no coletivo %v", handle, collective.Name), crypto.EncodeHash(collectivehash), v.Author, v.Epoch, "delegate"
+			}
+			delegate := i.state.Members[crypto.HashToken(v.Delegate)]
+			return fmt.Sprintf("%v delegou voto a %v no coletivo %v", handle, delegate, collective.Name), crypto.EncodeHash(collectivehash), v.Author, v.Epoch, "delegate"
+		}
+		return "", "", v.Author, 0, ""
+	case *actions.Comment:
+		handle := i.state.Members[crypto.HashToken(v.Author)]
+		if v.ReplyTo != crypto.ZeroHash {
+			return fmt.Sprintf("%v respondeu a um comentário", handle), crypto.EncodeHash(v.Target), v.Author, v.Epoch, "comment"
+		}
+		return fmt.Sprintf("%v comentou", handle), crypto.EncodeHash(v.Target), v.Author, v.Epoch, "comment"
+	case *actions.CreateJournal:
+		hash := v.Hashed()
+		if status == state.Favorable {
+			if journal, ok := i.state.Journals[hash]; ok {
+				return fmt.Sprintf("periódico %v criado em nome de %v", journal.Name, v.OnBehalfOf), crypto.EncodeHash(hash), v.Author, v.Epoch, "create journal"
+			}
+		}
+		if status == state.Undecided {
+			handle := i.state.Members[crypto.HashToken(v.Author)]
+			return fmt.Sprintf("%v propôs criação do periódico %v em nome de %v", handle, v.Name, v.OnBehalfOf), "", v.Author, v.Epoch, "create journal"
+		}
+		if status == state.Against {
+			return fmt.Sprintf("criação do periódico %v foi negada por %v", v.Name, v.OnBehalfOf), crypto.EncodeHash(hash), v.Author, v.Epoch, "create journal"
+		}
+		return "", "", v.Author, 0, ""
+	case *actions.JournalEditor:
+		hash := crypto.Hasher([]byte(v.Journal))
+		if journal, ok := i.state.Journals[hash]; ok {
+			editor := i.state.Members[crypto.HashToken(v.Editor)]
+			editorship := []string{"removido da", "remoção de", "da", "editor removal"}
+			if v.Insert {
+				editorship = []string{"incluído na", "inclusão de", "na", "editor inclusion"}
+			}
+			if status == state.Favorable {
+				return fmt.Sprintf("%v %v editoria de %v em nome de %v", editor, editorship[0], journal.Name, journal.Collective.Name), crypto.EncodeHash(hash), v.Author, v.Epoch, editorship[3]
+			}
+			handle := i.state.Members[crypto.HashToken(v.Author)]
+			if status == state.Undecided {
+				return fmt.Sprintf("%v propôs %v %v %v editoria de %v em nome de %v", handle, editorship[1], editor, editorship[2], journal.Name, journal.Collective.Name), crypto.EncodeHash(hash), v.Author, v.Epoch, editorship[3]
+			}
+			if status == state.Against {
+				return fmt.Sprintf("%v %v %v editoria de %v em nome de %v foi negada", editorship[1], editor, editorship[2], journal.Name, journal.Collective.Name), crypto.EncodeHash(hash), v.Author, v.Epoch, editorship[3]
+			}
+		}
+		return "", "", v.Author, 0, ""
+	case *actions.JournalIssue:
+		hash := v.Hashed()
+		if journal, ok := i.state.Journal(v.Journal); ok {
+			if status == state.Favorable {
+				if issue, ok := i.state.Issues[hash]; ok {
+					return fmt.Sprintf("número %v de %v publicado em nome de %v", issue.Number, journal.Name, journal.Collective.Name), crypto.EncodeHash(hash), v.Author, v.Epoch, "journal issue"
+				}
+			}
+			if status == state.Undecided {
+				handle := i.state.Members[crypto.HashToken(v.Author)]
+				return fmt.Sprintf("%v propôs novo número de %v em nome de %v", handle, journal.Name, journal.Collective.Name), crypto.EncodeHash(hash), v.Author, v.Epoch, "journal issue"
+			}
+			if status == state.Against {
+				return fmt.Sprintf("novo número de %v foi negado por %v", journal.Name, journal.Collective.Name), crypto.EncodeHash(hash), v.Author, v.Epoch, "journal issue"
+			}
+		}
+		return "", "", v.Author, 0, ""
 	case *actions.CreateBoard:
 		// hash do board eh o hash do nome do board que esta sendo criado
 		boardhash := crypto.Hasher([]byte(v.Name))
@@ -550,8 +677,14 @@ func (i *Index) ActionToString(action actions.Action, status state.ConsensusStat
 }
 
 func (i *Index) ActionToStringWithLinks(action actions.Action, status state.ConsensusState) (string, uint64, string) {
+	return i.ActionToStringWithPrefix(action, status, i.ServerName)
+}
+
+// ActionToStringWithPrefix is ActionToStringWithLinks with links starting with
+// prefix (for instance the full url of the server, for feeds).
+func (i *Index) ActionToStringWithPrefix(action actions.Action, status state.ConsensusState, prefix string) (string, uint64, string) {
 	desc, epoch, reasons := i.actionToStringRaw(action, status)
-	desc = strings.ReplaceAll(desc, `href="./`, `href="`+i.ServerName+`/`)
+	desc = strings.ReplaceAll(desc, `href="./`, `href="`+prefix+`/`)
 	return desc, epoch, reasons
 }
 
@@ -613,11 +746,78 @@ func (i *Index) actionToStringRaw(action actions.Action, status state.ConsensusS
 			handle := i.state.Members[crypto.HashToken(v.Author)]
 			return fmt.Sprintf("%v confirmou participação no evento em %v por %v ", fmtHandle(handle), fmtEvent(event.StartAt, v.EventHash), fmtCollective(event.Collective.Name)), v.Epoch, v.Reasons
 		}
+	case *actions.CancelCheckinEvent:
+		if event, ok := i.state.Events[v.EventHash]; ok {
+			handle := i.state.Members[crypto.HashToken(v.Author)]
+			return fmt.Sprintf("%v desistiu de participar do evento em %v por %v ", fmtHandle(handle), fmtEvent(event.StartAt, v.EventHash), fmtCollective(event.Collective.Name)), v.Epoch, v.Reasons
+		}
 	case *actions.GreetCheckinEvent:
 		if event, ok := i.state.Events[v.EventHash]; ok {
 			handle := i.state.Members[crypto.HashToken(v.Author)]
 			return fmt.Sprintf("%v recebeu boas-vindas para evento em %v por %v ", fmtHandle(handle), fmtEvent(event.StartAt, v.EventHash), fmtCollective(event.Collective.Name)), v.Epoch, v.Reasons
 		}
+	case *actions.Delegate:
+		collectivehash := crypto.Hasher([]byte(v.Collective))
+		if collective, ok := i.state.Collectives[collectivehash]; ok {
+			handle := i.state.Members[crypto.HashToken(v.Author)]
+			if v.Revoke {
+				return fmt.Sprintf("%v revogou delegação de voto no coletivo %v", fmtHandle(handle), fmtCollective(collective.Name)), v.Epoch, v.Reasons
+			}
+			delegate := i.state.Members[crypto.HashToken(v.Delegate)]
+			return fmt.Sprintf("%v delegou voto a %v no coletivo %v", fmtHandle(handle), fmtHandle(delegate), fmtCollective(collective.Name)), v.Epoch, v.Reasons
+		}
+	case *actions.Comment:
+		handle := i.state.Members[crypto.HashToken(v.Author)]
+		if v.ReplyTo != crypto.ZeroHash {
+			return fmt.Sprintf("%v respondeu a um comentário", fmtHandle(handle)), v.Epoch, v.Reasons
+		}
+		return fmt.Sprintf("%v comentou", fmtHandle(handle)), v.Epoch, v.Reasons
+	case *actions.CreateJournal:
+		if status == state.Favorable {
+			if journal, ok := i.state.Journals[v.Hashed()]; ok {
+				return fmt.Sprintf("periódico %v criado em nome de %v", fmtJournal(journal.Name), fmtCollective(v.OnBehalfOf)), v.Epoch, v.Reasons
+			}
+		}
+		if status == state.Undecided {
+			handle := i.state.Members[crypto.HashToken(v.Author)]
+			return fmt.Sprintf("%v propôs criação do periódico %v em nome de %v", fmtHandle(handle), v.Name, fmtCollective(v.OnBehalfOf)), v.Epoch, v.Reasons
+		}
+		if status == state.Against {
+			return fmt.Sprintf("criação do periódico %v em nome de %v foi negada", v.Name, fmtCollective(v.OnBehalfOf)), v.Epoch, v.Reasons
+		}
+	case *actions.JournalEditor:
+		if journal, ok := i.state.Journal(v.Journal); ok {
+			editor := i.state.Members[crypto.HashToken(v.Editor)]
+			editorship := []string{"removido da", "remoção de", "da"}
+			if v.Insert {
+				editorship = []string{"incluído na", "inclusão de", "na"}
+			}
+			if status == state.Favorable {
+				return fmt.Sprintf("%v %v editoria de %v em nome de %v", fmtHandle(editor), editorship[0], fmtJournal(journal.Name), fmtCollective(journal.Collective.Name)), v.Epoch, v.Reasons
+			}
+			if status == state.Undecided {
+				handle := i.state.Members[crypto.HashToken(v.Author)]
+				return fmt.Sprintf("%v propôs %v %v %v editoria de %v em nome de %v", fmtHandle(handle), editorship[1], fmtHandle(editor), editorship[2], fmtJournal(journal.Name), fmtCollective(journal.Collective.Name)), v.Epoch, v.Reasons
+			}
+			if status == state.Against {
+				return fmt.Sprintf("%v %v %v editoria de %v em nome de %v foi negada", editorship[1], fmtHandle(editor), editorship[2], fmtJournal(journal.Name), fmtCollective(journal.Collective.Name)), v.Epoch, v.Reasons
+			}
+		}
+	case *actions.JournalIssue:
+		if journal, ok := i.state.Journal(v.Journal); ok {
+			if status == state.Favorable {
+				if issue, ok := i.state.Issues[v.Hashed()]; ok {
+					return fmt.Sprintf("%v de %v publicado em nome de %v", fmtIssue(issue.Number, issue.Hash), fmtJournal(journal.Name), fmtCollective(journal.Collective.Name)), v.Epoch, v.Reasons
+				}
+			}
+			if status == state.Undecided {
+				handle := i.state.Members[crypto.HashToken(v.Author)]
+				return fmt.Sprintf("%v propôs novo número de %v com %v lançamentos em nome de %v", fmtHandle(handle), fmtJournal(journal.Name), len(v.Drafts), fmtCollective(journal.Collective.Name)), v.Epoch, v.Reasons
+			}
+			if status == state.Against {
+				return fmt.Sprintf("novo número de %v em nome de %v foi negado", fmtJournal(journal.Name), fmtCollective(journal.Collective.Name)), v.Epoch, v.Reasons
+			}
+		}
 	case *actions.CreateBoard:
 		boardhash := v.Hashed()
 		if status == state.Favorable {
@@ -0,0 +1,61 @@
+package index
+
+import (
+	"sort"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/citation"
+	"github.com/freehandle/synergy/social/state"
+)
+
+// Citations is the graph of references between approved drafts.
+func (i *Index) Citations() *citation.Graph {
+	return i.citations
+}
+
+func (i *Index) addCitations(draft *state.Draft) {
+	work := citation.Work{
+		Hash:  draft.DraftHash,
+		Title: draft.Title,
+		Date:  draft.Date,
+	}
+	if draft.PreviousVersion != nil {
+		work.Previous = draft.PreviousVersion.DraftHash
+	}
+	if draft.Authors != nil {
+		if name := draft.Authors.CollectiveName(); name != "" {
+			work.Collective = name
+			work.Authors = []string{name}
+		} else {
+			for token := range draft.Authors.ListOfMembers() {
+				work.Members = append(work.Members, token)
+			}
+			work.Authors = i.handles(draft.Authors.ListOfMembers())
+			sort.Strings(work.Authors)
+		}
+	}
+	i.citations.Add(work, draft.References)
+}
+
+// drafts entram no grafo quando aprovados
+func (i *Index) indexCitations(hash crypto.Hash, status state.ConsensusState) {
+	if status != state.Favorable || i.state == nil {
+		return
+	}
+	if draft, ok := i.state.Drafts[hash]; ok {
+		i.addCitations(draft)
+	}
+}
+
+// drafts aprovados em ordem cronologica, para que versoes anteriores entrem
+// antes das seguintes
+func (i *Index) rebuildCitations(s *state.State) {
+	drafts := make([]*state.Draft, 0, len(s.Drafts))
+	for _, draft := range s.Drafts {
+		drafts = append(drafts, draft)
+	}
+	sort.Slice(drafts, func(n, m int) bool { return drafts[n].Date < drafts[m].Date })
+	for _, draft := range drafts {
+		i.addCitations(draft)
+	}
+}
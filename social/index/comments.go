@@ -0,0 +1,31 @@
+package index
+
+import (
+	"sort"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+// AddCommentToIndex keeps the comments on each object in the order they were
+// incorporated. Threads are assembled from ReplyTo when rendering.
+func (i *Index) AddCommentToIndex(comment *actions.Comment) {
+	i.commentsOnObject[comment.Target] = append(i.commentsOnObject[comment.Target], comment)
+}
+
+// CommentsOn returns every comment (and reply) on the object with hash.
+func (i *Index) CommentsOn(hash crypto.Hash) []*actions.Comment {
+	return i.commentsOnObject[hash]
+}
+
+// comentarios restaurados do snapshot em ordem cronologica
+func (i *Index) rebuildComments(comments map[crypto.Hash]*actions.Comment) {
+	all := make([]*actions.Comment, 0, len(comments))
+	for _, comment := range comments {
+		all = append(all, comment)
+	}
+	sort.SliceStable(all, func(n, m int) bool { return all[n].Epoch < all[m].Epoch })
+	for _, comment := range all {
+		i.AddCommentToIndex(comment)
+	}
+}
@@ -5,6 +5,9 @@ import (
 
 	"github.com/freehandle/breeze/crypto"
 	"github.com/freehandle/synergy/social/actions"
+	"github.com/freehandle/synergy/social/citation"
+	"github.com/freehandle/synergy/social/reputation"
+	"github.com/freehandle/synergy/social/search"
 	"github.com/freehandle/synergy/social/state"
 )
 
@@ -25,6 +28,7 @@ type IndexedAction struct {
 const ActionsCacheCount = 10
 
 type ActionDetails struct {
+	Hash        crypto.Hash // hash da acao
 	Description string
 	ObjectHash  string
 	Author      crypto.Token
@@ -86,10 +90,13 @@ type Index struct {
 
 	objectHashToActionHash map[crypto.Hash]*RecentActions // object to recent actions
 
+	commentsOnObject map[crypto.Hash][]*actions.Comment // comentarios por objeto comentado
+
 	// central connections collectives card
-	collectiveToBoards map[*state.Collective][]*state.Board
-	collectiveToStamps map[*state.Collective][]*state.Stamp
-	collectiveToEvents map[*state.Collective][]*state.Event
+	collectiveToBoards   map[*state.Collective][]*state.Board
+	collectiveToStamps   map[*state.Collective][]*state.Stamp
+	collectiveToEvents   map[*state.Collective][]*state.Event
+	collectiveToJournals map[*state.Collective][]*state.Journal
 
 	RecentActions []*IndexedAction
 
@@ -101,6 +108,18 @@ type Index struct {
 	state *state.State
 
 	stateProposals *state.Proposals
+
+	// busca textual
+	search      *search.Index
+	searchStale map[crypto.Hash]search.Kind // objetos a reindexar ao fim do bloco
+
+	// reputacao de membros e coletivos
+	reputation        *reputation.Ledger
+	reputationReacted map[crypto.Hash]struct{} // hash de (membro, objeto) ja creditados
+	reputationEdits   map[crypto.Hash]struct{} // edits ja creditados como aceitos
+
+	// grafo de citacoes entre drafts aprovados
+	citations *citation.Graph
 }
 
 func (i *Index) Reason(hash crypto.Hash) string {
@@ -269,9 +288,10 @@ func NewIndex() *Index {
 		MemberToEdit:  make(map[crypto.Token][]*state.Edit),
 
 		//memberToEdit:       make(map[string][]*state.Edit),
-		collectiveToBoards: make(map[*state.Collective][]*state.Board),
-		collectiveToStamps: make(map[*state.Collective][]*state.Stamp),
-		collectiveToEvents: make(map[*state.Collective][]*state.Event),
+		collectiveToBoards:   make(map[*state.Collective][]*state.Board),
+		collectiveToStamps:   make(map[*state.Collective][]*state.Stamp),
+		collectiveToEvents:   make(map[*state.Collective][]*state.Event),
+		collectiveToJournals: make(map[*state.Collective][]*state.Journal),
 		// collectiveLastAction: make(map[*state.Collective][]lastaction),
 		//editToDrafts: make(map[*state.Edit][]*state.Draft),
 
@@ -285,7 +305,18 @@ func NewIndex() *Index {
 
 		objectHashToActionHash: make(map[crypto.Hash]*RecentActions),
 
+		commentsOnObject: make(map[crypto.Hash][]*actions.Comment),
+
 		RecentActions: make([]*IndexedAction, 0),
+
+		search:      search.NewIndex(),
+		searchStale: make(map[crypto.Hash]search.Kind),
+
+		reputation:        reputation.NewLedger(reputation.DefaultWeights),
+		reputationReacted: make(map[crypto.Hash]struct{}),
+		reputationEdits:   make(map[crypto.Hash]struct{}),
+
+		citations: citation.NewGraph(),
 	}
 }
 
@@ -324,6 +355,20 @@ type ConcludedActionDetail struct {
 	Action   actions.Action
 	Hash     crypto.Hash
 	Approved bool
+	Expired  bool
+}
+
+// ApprovedActionsOfMember returns the approved actions authored by the member,
+// most recent first.
+func (i *Index) ApprovedActionsOfMember(token crypto.Token) []*IndexedAction {
+	result := make([]*IndexedAction, 0)
+	all := i.memberToAction[token]
+	for n := len(all) - 1; n >= 0 && len(result) < MaxRecentAction; n-- {
+		if all[n].Status == state.Favorable {
+			result = append(result, all[n])
+		}
+	}
+	return result
 }
 
 func (i *Index) GetConcludedActionsDetailed(token crypto.Token) []ConcludedActionDetail {
@@ -338,6 +383,7 @@ func (i *Index) GetConcludedActionsDetailed(token crypto.Token) []ConcludedActio
 				Action:   ia.Action,
 				Hash:     ia.Hash,
 				Approved: status == state.Favorable,
+				Expired:  status == state.Expired,
 			})
 		}
 	}
@@ -358,6 +404,10 @@ func (i *Index) EventsOnCollective(collective *state.Collective) []*state.Event
 	return i.collectiveToEvents[collective]
 }
 
+func (i *Index) JournalsOnCollective(collective *state.Collective) []*state.Journal {
+	return i.collectiveToJournals[collective]
+}
+
 // Objects related to a given member
 
 func (i *Index) CollectivesOnMember(member crypto.Token) []string {
@@ -398,11 +448,10 @@ func (i Index) GetRecentActions(objectHash crypto.Hash) []ActionDetails {
 	}
 	details := make([]ActionDetails, len(recent.actions))
 	for n, r := range recent.actions {
-		status := i.indexActionStatus[recent.actions[len(recent.actions)-1].Hashed()]
-		// status := state.Undecided
-		des, _, _, epoch, _ := i.ActionToString(r, status)
 		votes, status := i.ActionStatus(r)
+		des, _, _, epoch, _ := i.ActionToString(r, status)
 		details[n] = ActionDetails{
+			Hash:        r.Hashed(),
 			Description: des,
 			Votes:       votes,
 			VoteStatus:  status,
@@ -423,6 +472,7 @@ func (i Index) GetRecentActionsWithLinks(objectHash crypto.Hash) []ActionDetails
 		votes, status := i.ActionStatus(r)
 		des, epoch, reasons := i.ActionToStringWithLinks(r, status)
 		details := ActionDetails{
+			Hash:        r.Hashed(),
 			Description: des,
 			Votes:       votes,
 			VoteStatus:  status,
@@ -463,6 +513,9 @@ func (i *Index) IndexAction(action actions.Action) {
 		case *actions.CheckinEvent:
 			newAction.Status = state.Favorable
 			i.indexActionStatus[newAction.Hash] = state.Favorable
+		case *actions.CancelCheckinEvent:
+			newAction.Status = state.Favorable
+			i.indexActionStatus[newAction.Hash] = state.Favorable
 		case *actions.React:
 			newAction.Status = state.Favorable
 			i.indexActionStatus[newAction.Hash] = state.Favorable
@@ -584,6 +637,9 @@ func (i *Index) IndexConsensusAction(action actions.Action) {
 }
 
 func (i *Index) IndexConsensus(hash crypto.Hash, status state.ConsensusState) {
+	i.staleSearch(hash, status)
+	i.creditConsensus(hash, status)
+	i.indexCitations(hash, status)
 	// if status == state.Favorable || status == state.Against {
 	if status == state.Favorable {
 		i.IndexActionToPerson(hash)
@@ -668,6 +724,7 @@ type PendingAction struct {
 type PendingActionDetailed struct {
 	Description string
 	Epoch       uint64
+	Deadline    uint64 // epoch em que a proposta expira (0 se nao expira)
 	Pool        *state.Pool
 }
 
@@ -705,6 +762,7 @@ func (i *Index) GetPendingActionsDetailed(token crypto.Token) []PendingActionDet
 				pending := PendingActionDetailed{
 					Description: description,
 					Epoch:       epoch,
+					Deadline:    i.state.Proposals.Deadline(action.Hash),
 					Pool:        pool,
 				}
 				pendingActions = append(pendingActions, pending)
@@ -780,7 +838,18 @@ func (i *Index) AddCheckin(token crypto.Token, event *state.Event) {
 	}
 }
 
+func (i *Index) RemoveCheckin(token crypto.Token, event *state.Event) {
+	events := i.MemberToCheckin[token]
+	for n, e := range events {
+		if e == event {
+			i.MemberToCheckin[token] = append(events[:n], events[n+1:]...)
+			return
+		}
+	}
+}
+
 func (i *Index) AddBoardToCollective(board *state.Board, collective *state.Collective) {
+	i.searchBoard(board)
 	if boards, ok := i.collectiveToBoards[collective]; ok {
 		i.collectiveToBoards[collective] = append(boards, board)
 	} else {
@@ -789,6 +858,7 @@ func (i *Index) AddBoardToCollective(board *state.Board, collective *state.Colle
 }
 
 func (i *Index) RemoveBoardFromCollective(board *state.Board, collective *state.Collective) {
+	i.search.Remove(board.Hash)
 	if boards, ok := i.collectiveToBoards[collective]; ok {
 		for n, e := range boards {
 			if e == board {
@@ -802,7 +872,12 @@ func (i *Index) RemoveBoardFromCollective(board *state.Board, collective *state.
 	}
 }
 
+func (i *Index) AddJournalToCollective(journal *state.Journal, collective *state.Collective) {
+	i.collectiveToJournals[collective] = append(i.collectiveToJournals[collective], journal)
+}
+
 func (i *Index) AddStampToCollective(stamp *state.Stamp, collective *state.Collective) {
+	i.creditStamp(stamp)
 	if stamps, ok := i.collectiveToStamps[collective]; ok {
 		i.collectiveToStamps[collective] = append(stamps, stamp)
 	} else {
@@ -811,6 +886,7 @@ func (i *Index) AddStampToCollective(stamp *state.Stamp, collective *state.Colle
 }
 
 func (i *Index) AddEventToCollective(event *state.Event, collective *state.Collective) {
+	i.searchEvent(event)
 	if events, ok := i.collectiveToEvents[collective]; ok {
 		i.collectiveToEvents[collective] = append(events, event)
 	} else {
@@ -819,6 +895,7 @@ func (i *Index) AddEventToCollective(event *state.Event, collective *state.Colle
 }
 
 func (i *Index) RemoveEventFromCollective(event *state.Event, collective *state.Collective) {
+	i.search.Remove(event.Hash)
 	if events, ok := i.collectiveToEvents[collective]; ok {
 		for n, e := range events {
 			if e == event {
@@ -833,6 +910,7 @@ func (i *Index) RemoveEventFromCollective(event *state.Event, collective *state.
 }
 
 func (i *Index) AddDraftToIndex(draft *state.Draft) {
+	i.searchDraft(draft)
 	if draft.Authors == nil {
 		return
 	}
@@ -849,6 +927,7 @@ func (i *Index) AddDraftToIndex(draft *state.Draft) {
 }
 
 func (i *Index) AddEditToIndex(edit *state.Edit) {
+	i.searchEdit(edit)
 	if edit.Authors == nil {
 		return
 	}
@@ -0,0 +1,69 @@
+package index
+
+import (
+	"github.com/freehandle/breeze/crypto"
+)
+
+// Interests lista os hashes dos objetos que interessam a um membro: ele
+// proprio, seus coletivos, boards, eventos, drafts e edits, as acoes que
+// propos (para saber quando sao aceitas ou expiram) e as votacoes pendentes em
+// que ainda nao votou. Usado para filtrar notificacoes em tempo
+// real, a partir da goroutine do hub: le o indice sob a trava do estado.
+func (i *Index) Interests(token crypto.Token) map[crypto.Hash]struct{} {
+	if i.state == nil {
+		return i.interests(token)
+	}
+	var interests map[crypto.Hash]struct{}
+	i.state.Read(func() {
+		interests = i.interests(token)
+	})
+	return interests
+}
+
+func (i *Index) interests(token crypto.Token) map[crypto.Hash]struct{} {
+	interests := map[crypto.Hash]struct{}{
+		crypto.HashToken(token): {},
+	}
+	for _, collective := range i.memberToCollective[token] {
+		interests[crypto.Hasher([]byte(collective))] = struct{}{}
+	}
+	for _, board := range i.memberToBoard[token] {
+		interests[crypto.Hasher([]byte(board))] = struct{}{}
+	}
+	for _, event := range i.memberToEvent[token] {
+		interests[event] = struct{}{}
+	}
+	for _, event := range i.MemberToCheckin[token] {
+		interests[event.Hash] = struct{}{}
+	}
+	for _, draft := range i.MemberToDraft[token] {
+		interests[draft.DraftHash] = struct{}{}
+	}
+	for _, edit := range i.MemberToEdit[token] {
+		interests[edit.Edit] = struct{}{}
+	}
+	if person, ok := i.allUsers[token]; ok {
+		for _, collective := range person.Collectives {
+			interests[crypto.Hasher([]byte(collective))] = struct{}{}
+		}
+		for _, board := range person.Boards {
+			interests[crypto.Hasher([]byte(board))] = struct{}{}
+		}
+		for _, event := range person.Events {
+			interests[event] = struct{}{}
+		}
+		for _, draft := range person.Drafts {
+			interests[draft] = struct{}{}
+		}
+		for _, edit := range person.Edits {
+			interests[edit] = struct{}{}
+		}
+	}
+	for _, action := range i.memberToAction[token] {
+		interests[action.Hash] = struct{}{}
+	}
+	for hash := range i.GetVotes(token) {
+		interests[hash] = struct{}{}
+	}
+	return interests
+}
@@ -0,0 +1,49 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+func TestInterests(t *testing.T) {
+	i, s, members := testIndexedState(3)
+	s.SetEpoch(1)
+	collective := &actions.CreateCollective{Epoch: 1, Author: members[0], Name: "interesses", Policy: actions.Policy{Majority: 50, SuperMajority: 50}}
+	draft := testDraft(members[0], 1, "esboco de interesse")
+	request := &actions.RequestMembership{Epoch: 1, Author: members[2], Collective: "interesses", Include: true}
+	for _, action := range []actions.Action{collective, draft, request} {
+		if err := s.Action(action.Serialize()); err != nil {
+			t.Fatalf("could not incorporate %T: %v", action, err)
+		}
+	}
+	tests := []struct {
+		name       string
+		member     crypto.Token
+		hash       crypto.Hash
+		interested bool
+	}{
+		{"own member", members[1], crypto.HashToken(members[1]), true},
+		{"collective of a member", members[0], crypto.Hasher([]byte("interesses")), true},
+		{"draft of the author", members[0], draft.ContentHash, true},
+		{"pending vote", members[0], request.Hashed(), true},
+		{"own request", members[2], request.Hashed(), true},
+		{"draft of another member", members[1], draft.ContentHash, false},
+		{"collective of another member", members[1], crypto.Hasher([]byte("interesses")), false},
+		{"another member", members[1], crypto.HashToken(members[0]), false},
+	}
+	for _, test := range tests {
+		if _, ok := i.Interests(test.member)[test.hash]; ok != test.interested {
+			t.Errorf("%v: interested %v, expected %v", test.name, ok, test.interested)
+		}
+	}
+	// the vote concludes the request, nothing is left to vote on
+	vote := &actions.Vote{Epoch: 1, Author: members[0], Hash: request.Hashed(), Approve: true}
+	if err := s.Action(vote.Serialize()); err != nil {
+		t.Fatalf("could not vote: %v", err)
+	}
+	if _, ok := i.Interests(members[0])[request.Hashed()]; ok {
+		t.Error("concluded vote still of interest")
+	}
+}
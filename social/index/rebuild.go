@@ -0,0 +1,121 @@
+package index
+
+import (
+	"sort"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
+	"github.com/freehandle/synergy/social/actions"
+	"github.com/freehandle/synergy/social/state"
+)
+
+// RebuildFromState popula um indice vazio a partir de um estado restaurado de
+// um snapshot. O historico de acoes concluidas nao faz parte do estado, entao
+// apenas as acoes que originaram proposals ainda pendentes voltam para as
+// acoes recentes e para /pending.
+func (i *Index) RebuildFromState(s *state.State) {
+	i.SetState(s)
+	for handle, token := range s.MembersIndex {
+		i.AddMemberToIndex(token, handle)
+	}
+	for _, collective := range s.Collectives {
+		i.AddCollectiveToIndex(collective)
+		for member := range collective.Members {
+			i.Personal(member).AddCollective(collective.Name)
+			if i.isIndexedMember(member) {
+				i.memberToCollective[member] = appendOrCreate[string](i.memberToCollective[member], collective.Name)
+			}
+		}
+	}
+	for _, board := range s.Boards {
+		i.AddBoardToCollective(board, board.Collective)
+		for editor := range board.Editors.ListOfMembers() {
+			i.Personal(editor).AddBoard(board.Name)
+			if i.isIndexedMember(editor) {
+				i.memberToBoard[editor] = appendOrCreate[string](i.memberToBoard[editor], board.Name)
+			}
+		}
+	}
+	for _, journal := range s.Journals {
+		i.AddJournalToCollective(journal, journal.Collective)
+		for _, issue := range journal.Issues {
+			i.IndexActionStatus(issue.Hash, state.Favorable)
+		}
+	}
+	for hash, event := range s.Events {
+		if event.Live {
+			i.AddEventToCollective(event, event.Collective)
+		}
+		if event.Managers != nil {
+			for manager := range event.Managers.ListOfMembers() {
+				i.Personal(manager).AddEvent(hash)
+				if i.isIndexedMember(manager) {
+					i.memberToEvent[manager] = appendOrCreate[crypto.Hash](i.memberToEvent[manager], hash)
+				}
+			}
+		}
+		for token := range event.Checkin {
+			i.AddCheckin(token, event)
+		}
+		i.IndexActionStatus(hash, state.Favorable)
+	}
+	for _, release := range s.Releases {
+		i.IndexActionStatus(release.Hash, state.Favorable)
+		for _, stamp := range release.Stamps {
+			if stamp.Imprinted {
+				i.AddStampToCollective(stamp, stamp.Reputation)
+				i.IndexActionStatus(stamp.Hash, state.Favorable)
+			}
+		}
+	}
+	drafts := make([]*state.Draft, 0, len(s.Drafts)+len(s.Proposals.Draft))
+	for _, draft := range s.Drafts {
+		drafts = append(drafts, draft)
+		i.IndexActionStatus(draft.DraftHash, state.Favorable)
+	}
+	for _, draft := range s.Proposals.Draft {
+		drafts = append(drafts, draft)
+	}
+	sort.Slice(drafts, func(n, m int) bool { return drafts[n].Date < drafts[m].Date })
+	for _, draft := range drafts {
+		i.AddDraftToIndex(draft)
+		if draft.Authors.CollectiveName() == "" {
+			for author := range draft.Authors.ListOfMembers() {
+				i.Personal(author).AddDraft(draft.DraftHash)
+			}
+		}
+	}
+	edits := make([]*state.Edit, 0, len(s.Edits)+len(s.Proposals.Edit))
+	for _, edit := range s.Edits {
+		edits = append(edits, edit)
+		i.IndexActionStatus(edit.Edit, state.Favorable)
+	}
+	for _, edit := range s.Proposals.Edit {
+		edits = append(edits, edit)
+	}
+	sort.Slice(edits, func(n, m int) bool { return edits[n].Date < edits[m].Date })
+	for _, edit := range edits {
+		i.AddEditToIndex(edit)
+	}
+	i.rebuildComments(s.Comments)
+	i.rebuildReputation(s)
+	i.rebuildCitations(s)
+	// acoes pendentes em ordem cronologica para manter a ordem das recentes
+	pending := make([]actions.Action, 0)
+	for _, reason := range s.Proposals.Reasons() {
+		pending = append(pending, reason)
+	}
+	sort.SliceStable(pending, func(n, m int) bool {
+		return actionEpoch(pending[n]) < actionEpoch(pending[m])
+	})
+	for _, action := range pending {
+		i.IndexAction(action)
+	}
+	s.Proposals.IndexPending()
+}
+
+// epoch das acoes serializadas nos primeiros 8 bytes
+func actionEpoch(action actions.Action) uint64 {
+	epoch, _ := util.ParseUint64(action.Serialize(), 0)
+	return epoch
+}
@@ -0,0 +1,206 @@
+package index
+
+import (
+	"math"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+	"github.com/freehandle/synergy/social/reputation"
+	"github.com/freehandle/synergy/social/state"
+)
+
+// Reputation is the ledger of credits fed by the indexer callbacks.
+func (i *Index) Reputation() *reputation.Ledger {
+	return i.reputation
+}
+
+// SetReputationWeights changes the points of each source of reputation and
+// its decay.
+func (i *Index) SetReputationWeights(weights reputation.Weights) {
+	i.reputation.SetWeights(weights)
+}
+
+// ReputationEpoch is the epoch at which scores should be evaluated.
+func (i *Index) ReputationEpoch() uint64 {
+	if i.state == nil {
+		return 0
+	}
+	return i.state.Epoch
+}
+
+// autores que recebem o credito: o coletivo ou os membros em co-autoria
+func reputationSubject(authors state.Consensual) reputation.Subject {
+	if authors == nil {
+		return reputation.Subject{}
+	}
+	if name := authors.CollectiveName(); name != "" {
+		return reputation.Subject{Collective: name}
+	}
+	members := make([]crypto.Token, 0)
+	for token := range authors.ListOfMembers() {
+		members = append(members, token)
+	}
+	return reputation.Subject{Members: members}
+}
+
+// epoch of the last vote of a concluded proposal
+func concludedAt(votes []actions.Vote, fallback uint64) uint64 {
+	epoch := fallback
+	for _, vote := range votes {
+		if vote.Epoch > epoch {
+			epoch = vote.Epoch
+		}
+	}
+	return epoch
+}
+
+// stamps of larger collectives weigh more (a single member collective weighs 1)
+func stampFactor(collective *state.Collective) float64 {
+	if collective == nil {
+		return 1
+	}
+	return math.Log2(1 + float64(len(collective.Members)))
+}
+
+func (i *Index) creditStamp(stamp *state.Stamp) {
+	if stamp.Release == nil || stamp.Release.Draft == nil {
+		return
+	}
+	draft := stamp.Release.Draft
+	epoch := concludedAt(stamp.Votes, stamp.Release.Epoch)
+	i.reputation.Grant(reputationSubject(draft.Authors), reputation.StampSource, draft.DraftHash, epoch, stampFactor(stamp.Reputation))
+}
+
+// AddReleaseToIndex credits the authors of a released draft.
+func (i *Index) AddReleaseToIndex(release *state.Release) {
+	if release.Draft == nil {
+		return
+	}
+	epoch := concludedAt(release.Votes, release.Epoch)
+	i.reputation.Grant(reputationSubject(release.Draft.Authors), reputation.ReleaseSource, release.Draft.DraftHash, epoch, 1)
+}
+
+// AddPinToIndex credits the authors of a draft pinned to a board.
+func (i *Index) AddPinToIndex(pin *state.Pin) {
+	if pin.Draft == nil || !pin.Pin {
+		return
+	}
+	epoch := concludedAt(pin.Votes, pin.Epoch)
+	i.reputation.Grant(reputationSubject(pin.Draft.Authors), reputation.PinSource, pin.Draft.DraftHash, epoch, 1)
+}
+
+// autoria de um draft ou edit (aprovado ou pendente)
+func (i *Index) authorsOf(hash crypto.Hash) (state.Consensual, uint64, bool) {
+	if i.state == nil {
+		return nil, 0, false
+	}
+	if draft, ok := i.state.Drafts[hash]; ok {
+		return draft.Authors, draft.Date, true
+	}
+	if draft, ok := i.state.Proposals.Draft[hash]; ok {
+		return draft.Authors, draft.Date, true
+	}
+	if edit, ok := i.state.Edits[hash]; ok {
+		return edit.Authors, edit.Date, true
+	}
+	if edit, ok := i.state.Proposals.Edit[hash]; ok {
+		return edit.Authors, edit.Date, true
+	}
+	return nil, 0, false
+}
+
+// AddReactionToIndex credits the authors of the draft or edit reacted to. Each
+// member is counted once per object and reactions to one's own work are
+// ignored.
+func (i *Index) AddReactionToIndex(reaction *actions.React) {
+	authors, _, ok := i.authorsOf(reaction.Hash)
+	if !ok || authors == nil {
+		return
+	}
+	i.creditReaction(authors, reaction.Author, reaction.Hash, reaction.Epoch)
+}
+
+func (i *Index) creditReaction(authors state.Consensual, member crypto.Token, hash crypto.Hash, epoch uint64) {
+	if authors.IsMember(member) {
+		return
+	}
+	key := crypto.Hasher(append(append([]byte{}, member[:]...), hash[:]...))
+	if _, ok := i.reputationReacted[key]; ok {
+		return
+	}
+	i.reputationReacted[key] = struct{}{}
+	i.reputation.Grant(reputationSubject(authors), reputation.ReactionSource, hash, epoch, 1)
+}
+
+// creditAcceptedEdits credits the authors of the edits of the previous version
+// of an approved draft that were taken into it: edits referenced by the new
+// version or whose authors became co-authors of it.
+func (i *Index) creditAcceptedEdits(draft *state.Draft) {
+	previous := draft.PreviousVersion
+	if previous == nil || draft.Authors == nil || previous.Authors == nil {
+		return
+	}
+	referenced := make(map[crypto.Hash]struct{})
+	for _, hash := range draft.References {
+		referenced[hash] = struct{}{}
+	}
+	current := draft.Authors.ListOfMembers()
+	before := previous.Authors.ListOfMembers()
+	epoch := concludedAt(draft.Votes, draft.Date)
+	for _, edit := range previous.Edits {
+		if _, ok := i.reputationEdits[edit.Edit]; ok {
+			continue
+		}
+		_, accepted := referenced[edit.Edit]
+		if !accepted && edit.Authors != nil && edit.Authors.CollectiveName() == "" {
+			for token := range edit.Authors.ListOfMembers() {
+				_, isAuthor := current[token]
+				_, wasAuthor := before[token]
+				accepted = accepted || (isAuthor && !wasAuthor)
+			}
+		}
+		if accepted {
+			i.reputationEdits[edit.Edit] = struct{}{}
+			i.reputation.Grant(reputationSubject(edit.Authors), reputation.EditSource, edit.Edit, epoch, 1)
+		}
+	}
+}
+
+// creditConsensus is called for every concluded proposal: a new version of a
+// draft may accept edits of the previous one.
+func (i *Index) creditConsensus(hash crypto.Hash, status state.ConsensusState) {
+	if status != state.Favorable || i.state == nil {
+		return
+	}
+	if draft, ok := i.state.Drafts[hash]; ok {
+		i.creditAcceptedEdits(draft)
+	}
+}
+
+// creditos restaurados de um snapshot. Stamps sao creditados por
+// AddStampToCollective. Reacoes sao creditadas pela primeira reacao de cada
+// membro guardada no estado, como em AddReactionToIndex, e os edits aceitos
+// pelas versoes aprovadas. O estado nao guarda quando um draft foi afixado:
+// pins contam na data do draft.
+func (i *Index) rebuildReputation(s *state.State) {
+	for _, release := range s.Releases {
+		if release.Released {
+			i.AddReleaseToIndex(release)
+		}
+	}
+	for _, draft := range s.Drafts {
+		for range draft.Pinned {
+			i.reputation.Grant(reputationSubject(draft.Authors), reputation.PinSource, draft.DraftHash, draft.Date, 1)
+		}
+		i.creditAcceptedEdits(draft)
+	}
+	for hash, reactors := range s.Reacted {
+		authors, _, ok := i.authorsOf(hash)
+		if !ok || authors == nil {
+			continue
+		}
+		for member, epoch := range reactors {
+			i.creditReaction(authors, member, hash, epoch)
+		}
+	}
+}
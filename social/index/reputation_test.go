@@ -0,0 +1,91 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+	"github.com/freehandle/synergy/social/reputation"
+	"github.com/freehandle/synergy/social/state"
+)
+
+func incorporate(t *testing.T, s *state.State, action actions.Action) {
+	t.Helper()
+	if err := s.Action(action.Serialize()); err != nil {
+		t.Fatalf("could not incorporate %T: %v", action, err)
+	}
+}
+
+// reputationState: members[0] writes a draft, members[2] edits it and the next
+// version takes the edit in. The first version gets a reaction of its author
+// and reactions of members[2], repeated, and of members[3].
+func reputationState(t *testing.T) (*Index, *state.State, []crypto.Token, crypto.Hash) {
+	t.Helper()
+	i, s, members := testIndexedState(4)
+	s.SetEpoch(1)
+	first := testDraft(members[0], 1, "primeira versao")
+	incorporate(t, s, first)
+	hash := first.ContentHash
+	s.SetEpoch(2)
+	reactions := []*actions.React{
+		{Epoch: 2, Author: members[0], Hash: hash, Reaction: 0},
+		{Epoch: 2, Author: members[2], Hash: hash, Reaction: 0},
+		{Epoch: 2, Author: members[2], Hash: hash, Reaction: 1},
+		{Epoch: 3, Author: members[2], Hash: hash, Reaction: 0},
+		{Epoch: 4, Author: members[3], Hash: hash, Reaction: 2},
+	}
+	for _, reaction := range reactions {
+		s.SetEpoch(reaction.Epoch)
+		incorporate(t, s, reaction)
+	}
+	content := []byte("edicao")
+	edit := &actions.Edit{Epoch: 4, Author: members[2], EditedDraft: hash, ContentType: "md", ContentHash: crypto.Hasher(content), NumberOfParts: 1, Content: content}
+	incorporate(t, s, edit)
+	s.SetEpoch(5)
+	second := testDraft(members[0], 5, "segunda versao")
+	second.PreviousDraft = hash
+	second.References = []crypto.Hash{edit.ContentHash}
+	incorporate(t, s, second)
+	return i, s, members, hash
+}
+
+// a restarted node rebuilds from the snapshot the credits a running node
+// granted: one reaction per member and object, none for one's own work, and
+// the edits taken into a new version
+func TestRebuildReputation(t *testing.T) {
+	i, s, members, hash := reputationState(t)
+	rebuilt := NewIndex()
+	restored, err := state.ParseSnapshot(s.Snapshot(), rebuilt)
+	if err != nil {
+		t.Fatalf("could not parse snapshot: %v", err)
+	}
+	// the media store is persistent across restarts
+	restored.SetMediaStore(s.MediaStore())
+	rebuilt.RebuildFromState(restored)
+	for n, member := range members {
+		live, restart := i.Reputation().Member(member, s.Epoch), rebuilt.Reputation().Member(member, s.Epoch)
+		if live.Total != restart.Total || !reflect.DeepEqual(live.Breakdown, restart.Breakdown) {
+			t.Errorf("member %v: live %+v, rebuilt %+v", n, live.Breakdown, restart.Breakdown)
+		}
+	}
+	author := i.Reputation().Member(members[0], s.Epoch)
+	// members[2] and members[3]: two reactions
+	if reactions := author.Breakdown[reputation.ReactionSource]; reactions.Count != 2 {
+		t.Errorf("%v reactions credited, expected 2", reactions.Count)
+	}
+	if edits := i.Reputation().Member(members[2], s.Epoch).Breakdown; edits[reputation.EditSource].Count != 1 {
+		t.Errorf("accepted edit not credited: %+v", edits)
+	}
+	if !reflect.DeepEqual(i.reputationReacted, rebuilt.reputationReacted) || !reflect.DeepEqual(i.reputationEdits, rebuilt.reputationEdits) {
+		t.Errorf("credited keys not rebuilt: %v reactions, %v edits", len(rebuilt.reputationReacted), len(rebuilt.reputationEdits))
+	}
+	// a member that reacted before the restart is not credited again, a new one
+	// is
+	restored.SetEpoch(6)
+	incorporate(t, restored, &actions.React{Epoch: 6, Author: members[2], Hash: hash, Reaction: 3})
+	incorporate(t, restored, &actions.React{Epoch: 6, Author: members[1], Hash: hash, Reaction: 3})
+	if reactions := rebuilt.Reputation().Member(members[0], restored.Epoch).Breakdown[reputation.ReactionSource]; reactions.Count != 3 {
+		t.Errorf("%v reactions credited after the restart, expected 3", reactions.Count)
+	}
+}
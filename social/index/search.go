@@ -0,0 +1,275 @@
+package index
+
+import (
+	"math"
+	"strings"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+	"github.com/freehandle/synergy/social/search"
+	"github.com/freehandle/synergy/social/state"
+)
+
+// stamps weight more than reactions on the ranking of search results
+const stampBoost = 3
+
+// tipos de media indexados pelo conteudo
+func isTextMedia(mediaType string) bool {
+	switch strings.ToLower(mediaType) {
+	case "txt", "md", "text", "markdown", "text/plain", "text/markdown":
+		return true
+	}
+	return false
+}
+
+func (i *Index) handles(members map[crypto.Token]struct{}) []string {
+	handles := make([]string, 0, len(members))
+	if i.state == nil {
+		return handles
+	}
+	for token := range members {
+		if handle, ok := i.state.Members[crypto.HashToken(token)]; ok {
+			handles = append(handles, handle)
+		}
+	}
+	return handles
+}
+
+// reasons of the action that created the object, if still indexed
+func (i *Index) reasons(hash crypto.Hash) string {
+	if action, ok := i.allPendingactions[hash]; ok && action != nil {
+		return action.Reasoning()
+	}
+	return ""
+}
+
+// text content of a media file, false if it should be there but did not
+// arrive yet (multipart media still pending)
+func (i *Index) mediaText(hash crypto.Hash, mediaType string) (string, bool) {
+	if !isTextMedia(mediaType) || i.state == nil {
+		return "", true
+	}
+	media, ok := i.state.GetMedia(hash)
+	if !ok {
+		return "", false
+	}
+	return string(media), true
+}
+
+func (i *Index) searchDraft(draft *state.Draft) {
+	if draft == nil {
+		return
+	}
+	content, complete := i.mediaText(draft.DraftHash, draft.DraftType)
+	doc := search.Document{
+		Hash:     draft.DraftHash,
+		Kind:     search.DraftDocument,
+		Title:    draft.Title,
+		Body:     strings.Join([]string{draft.Description, i.reasons(draft.DraftHash), content}, "\n"),
+		Keywords: draft.Keywords,
+		Date:     draft.Date,
+	}
+	if draft.Authors != nil {
+		doc.Authors = i.handles(draft.Authors.ListOfMembers())
+		doc.Collective = draft.Authors.CollectiveName()
+	}
+	i.search.Add(&doc)
+	if !complete {
+		i.searchStale[draft.DraftHash] = search.DraftDocument
+	}
+}
+
+func (i *Index) searchEdit(edit *state.Edit) {
+	if edit == nil {
+		return
+	}
+	content, complete := i.mediaText(edit.Edit, edit.EditType)
+	doc := search.Document{
+		Hash: edit.Edit,
+		Kind: search.EditDocument,
+		Body: strings.Join([]string{edit.Reasons, content}, "\n"),
+		Date: edit.Date,
+	}
+	if edit.Draft != nil {
+		doc.Title = edit.Draft.Title
+	}
+	if edit.Authors != nil {
+		doc.Authors = i.handles(edit.Authors.ListOfMembers())
+		doc.Collective = edit.Authors.CollectiveName()
+	}
+	i.search.Add(&doc)
+	if !complete {
+		i.searchStale[edit.Edit] = search.EditDocument
+	}
+}
+
+func (i *Index) searchBoard(board *state.Board) {
+	if board == nil {
+		return
+	}
+	doc := search.Document{
+		Hash:     board.Hash,
+		Kind:     search.BoardDocument,
+		Title:    board.Name,
+		Body:     strings.Join([]string{board.Description, i.reasons(board.Hash)}, "\n"),
+		Keywords: board.Keyword,
+	}
+	if board.Editors != nil {
+		doc.Authors = i.handles(board.Editors.ListOfMembers())
+	}
+	if board.Collective != nil {
+		doc.Collective = board.Collective.Name
+	}
+	i.search.Add(&doc)
+}
+
+func (i *Index) searchCollective(collective *state.Collective) {
+	if collective == nil {
+		return
+	}
+	hash := crypto.Hasher([]byte(collective.Name))
+	doc := search.Document{
+		Hash:       hash,
+		Kind:       search.CollectiveDocument,
+		Title:      collective.Name,
+		Body:       strings.Join([]string{collective.Description, i.reasons(hash)}, "\n"),
+		Authors:    i.handles(collective.Members),
+		Collective: collective.Name,
+	}
+	i.search.Add(&doc)
+}
+
+func (i *Index) searchEvent(event *state.Event) {
+	if event == nil {
+		return
+	}
+	doc := search.Document{
+		Hash: event.Hash,
+		Kind: search.EventDocument,
+		Body: strings.Join([]string{event.Description, event.Venue, event.EventReasons}, "\n"),
+	}
+	if event.Managers != nil {
+		doc.Authors = i.handles(event.Managers.ListOfMembers())
+	}
+	if event.Collective != nil {
+		doc.Collective = event.Collective.Name
+		doc.Title = event.Collective.Name
+	}
+	if i.state != nil && event.StartAt.After(i.state.GenesisTime) {
+		doc.Date = uint64(event.StartAt.Sub(i.state.GenesisTime).Seconds())
+	}
+	i.search.Add(&doc)
+}
+
+// AddCollectiveToIndex indexa um coletivo recem criado para a busca
+func (i *Index) AddCollectiveToIndex(collective *state.Collective) {
+	i.searchCollective(collective)
+}
+
+// marca para reindexacao os objetos alterados por uma proposta aprovada ou os
+// drafts e edits cuja proposta foi concluida (rejeitados saem da busca)
+func (i *Index) staleSearch(hash crypto.Hash, status state.ConsensusState) {
+	if doc := i.search.Document(hash); doc != nil {
+		i.searchStale[hash] = doc.Kind
+	}
+	if status != state.Favorable {
+		return
+	}
+	switch v := i.allPendingactions[hash].(type) {
+	case *actions.UpdateBoard:
+		i.searchStale[crypto.Hasher([]byte(v.Board))] = search.BoardDocument
+	case *actions.UpdateCollective:
+		i.searchStale[crypto.Hasher([]byte(v.OnBehalfOf))] = search.CollectiveDocument
+	case *actions.RequestMembership:
+		i.searchStale[crypto.Hasher([]byte(v.Collective))] = search.CollectiveDocument
+	case *actions.RemoveMember:
+		i.searchStale[crypto.Hasher([]byte(v.OnBehalfOf))] = search.CollectiveDocument
+	case *actions.BoardEditor:
+		i.searchStale[crypto.Hasher([]byte(v.Board))] = search.BoardDocument
+	case *actions.UpdateEvent:
+		i.searchStale[v.EventHash] = search.EventDocument
+	}
+}
+
+// IndexBlock reindexa para a busca os objetos alterados durante o bloco.
+func (i *Index) IndexBlock(epoch uint64) {
+	i.refreshSearch()
+}
+
+// reindexa os objetos marcados a partir do estado atual. Objetos que nao
+// existem mais sao removidos da busca. Chamada na goroutine dos blocos, com o
+// estado bloqueado (ver State.SetEpoch).
+func (i *Index) refreshSearch() {
+	if i.state == nil {
+		return
+	}
+	stale := i.searchStale
+	i.searchStale = make(map[crypto.Hash]search.Kind)
+	for hash, kind := range stale {
+		i.search.Remove(hash)
+		switch kind {
+		case search.DraftDocument:
+			if draft, ok := i.state.Drafts[hash]; ok {
+				i.searchDraft(draft)
+			} else if draft, ok := i.state.Proposals.Draft[hash]; ok {
+				i.searchDraft(draft)
+			}
+		case search.EditDocument:
+			if edit, ok := i.state.Edits[hash]; ok {
+				i.searchEdit(edit)
+			} else if edit, ok := i.state.Proposals.Edit[hash]; ok {
+				i.searchEdit(edit)
+			}
+		case search.BoardDocument:
+			if board, ok := i.state.Boards[hash]; ok {
+				i.searchBoard(board)
+			}
+		case search.CollectiveDocument:
+			if collective, ok := i.state.Collectives[hash]; ok {
+				i.searchCollective(collective)
+			}
+		case search.EventDocument:
+			if event, ok := i.state.Events[hash]; ok && event.Live {
+				i.searchEvent(event)
+			}
+		}
+	}
+}
+
+// popularity of an object: reactions of any kind plus imprinted stamps
+func (i *Index) searchBoost(hash crypto.Hash) float64 {
+	if i.state == nil {
+		return 0
+	}
+	popularity := 0
+	for n := 0; n < state.ReactionsCount; n++ {
+		popularity += int(i.state.Reactions[n][hash])
+	}
+	if release, ok := i.state.Releases[hash]; ok {
+		for _, stamp := range release.Stamps {
+			if stamp.Imprinted {
+				popularity += stampBoost
+			}
+		}
+	}
+	return math.Log1p(float64(popularity))
+}
+
+// Search interpreta a expressao de busca (ver search.ParseQuery) e retorna os
+// objetos encontrados, ordenados por relevancia, reacoes e selos. A busca
+// apenas le o indice e o estado, bloqueado contra os blocos: os objetos
+// alterados sao reindexados ao fim de cada bloco (IndexBlock).
+func (i *Index) Search(text string) []search.Result {
+	query := search.ParseQuery(text)
+	if query.Empty() {
+		return nil
+	}
+	if i.state == nil {
+		return i.search.Search(query, i.searchBoost)
+	}
+	var results []search.Result
+	i.state.Read(func() {
+		results = i.search.Search(query, i.searchBoost)
+	})
+	return results
+}
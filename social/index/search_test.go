@@ -0,0 +1,79 @@
+package index
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+	"github.com/freehandle/synergy/social/state"
+)
+
+func testIndexedState(n int) (*Index, *state.State, []crypto.Token) {
+	i := NewIndex()
+	s := state.GenesisState(i)
+	i.SetState(s)
+	members := make([]crypto.Token, n)
+	for m := range members {
+		members[m], _ = crypto.RandomAsymetricKey()
+		handle := fmt.Sprintf("member%v", m)
+		s.Members[crypto.HashToken(members[m])] = handle
+		s.MembersIndex[handle] = members[m]
+		i.AddMemberToIndex(members[m], handle)
+	}
+	return i, s, members
+}
+
+func testDraft(author crypto.Token, epoch uint64, title string) *actions.Draft {
+	content := []byte(title)
+	return &actions.Draft{
+		Epoch:         epoch,
+		Author:        author,
+		Title:         title,
+		Keywords:      []string{"busca"},
+		Description:   "esboco para a busca",
+		ContentType:   "md",
+		ContentHash:   crypto.Hasher(content),
+		NumberOfParts: 1,
+		Content:       content,
+	}
+}
+
+// searches run on the http goroutines while the blocks are incorporated
+func TestSearchConcurrent(t *testing.T) {
+	i, s, members := testIndexedState(2)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for n := 0; n < 4; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					i.Search("esboco")
+					i.Search("kind:draft busca")
+				}
+			}
+		}()
+	}
+	const drafts = 50
+	for n := 1; n <= drafts; n++ {
+		s.SetEpoch(uint64(n))
+		draft := testDraft(members[n%2], uint64(n), fmt.Sprintf("esboco %v", n))
+		if err := s.Action(draft.Serialize()); err != nil {
+			t.Fatalf("could not incorporate draft: %v", err)
+		}
+		react := actions.React{Epoch: uint64(n), Author: members[(n+1)%2], Hash: draft.ContentHash, Reaction: 1}
+		s.Action(react.Serialize())
+	}
+	s.SetEpoch(drafts + 1)
+	close(done)
+	wg.Wait()
+	if results := i.Search("esboco"); len(results) != drafts {
+		t.Errorf("%v drafts found, expected %v", len(results), drafts)
+	}
+}
@@ -81,3 +81,30 @@ func (i *Index) GetVotes(token crypto.Token) map[crypto.Hash]struct{} {
 	}
 	return open
 }
+
+// GetCastVotes returns the votes already cast by token on proposals still
+// open. These can be changed or retracted until the proposal concludes.
+func (i *Index) GetCastVotes(token crypto.Token) map[crypto.Hash]actions.Vote {
+	cast := make(map[crypto.Hash]actions.Vote)
+	hashes := i.indexVotes[token]
+	if hashes == nil {
+		return cast
+	}
+	for hash := range hashes.All() {
+		for _, vote := range i.stateProposals.Votes(hash) {
+			if vote.Author.Equal(token) {
+				cast[hash] = vote
+			}
+		}
+	}
+	return cast
+}
+
+// VoteHistory returns every vote incorporated on a proposal, including the
+// votes later changed or retracted.
+func (i *Index) VoteHistory(hash crypto.Hash) []actions.Vote {
+	if i.state == nil {
+		return nil
+	}
+	return i.state.VoteHistory[hash]
+}
@@ -1,25 +1,29 @@
 package social
 
 import (
+	"context"
 	"log"
 	"sync"
 
 	"github.com/freehandle/breeze/crypto"
 	"github.com/freehandle/breeze/socket"
 	"github.com/freehandle/breeze/util"
+	"github.com/freehandle/synergy/network"
 	"github.com/freehandle/synergy/social/state"
 )
 
 type Proxy struct {
-	mu      sync.Mutex
-	state   *state.State
-	conn    *socket.SignedConnection
-	viewers []chan uint64
-	epoch   uint64
+	mu         sync.Mutex
+	state      *state.State
+	supervisor *network.Supervisor
+	outgoing   chan []byte
+	cancel     context.CancelFunc
+	viewers    []chan uint64
+	epoch      uint64
 }
 
 func (p *Proxy) Stop() {
-	p.conn.Shutdown()
+	p.cancel()
 }
 
 func (p *Proxy) State() *state.State {
@@ -30,11 +34,15 @@ func (p *Proxy) Epoch() uint64 {
 	return p.epoch
 }
 
+// Action is sent dressed to the host, which undresses it, or buffered until
+// the connection is restored.
 func (p *Proxy) Action(data []byte) {
-	undressed := Undress(data)
-	if err := p.conn.Send(undressed); err != nil {
-		log.Printf("error sending action: %v", err)
-	}
+	p.outgoing <- data
+}
+
+// Supervisor gives access to the health of the connection to the host.
+func (p *Proxy) Supervisor() *network.Supervisor {
+	return p.supervisor
 }
 
 func (p *Proxy) Register() chan uint64 {
@@ -46,91 +54,37 @@ func (p *Proxy) Register() chan uint64 {
 }
 
 func SelfProxyState(host string, hostToken crypto.Token, credential crypto.PrivateKey, genesis *state.State) *Proxy {
-	conn, err := socket.Dial("", host, credential, hostToken)
-	if err != nil {
-		log.Fatalf("could not connect to host: %v", err)
+	dial := func() (network.Connection, error) {
+		return socket.Dial("", host, credential, hostToken)
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan *network.Signal)
+	outgoing := make(chan []byte)
 	proxy := &Proxy{
-		mu:      sync.Mutex{},
-		state:   genesis,
-		conn:    conn,
-		viewers: make([]chan uint64, 0),
-		epoch:   0,
+		mu:         sync.Mutex{},
+		state:      genesis,
+		supervisor: network.NewSupervisor(dial, genesis.Epoch, signals, outgoing),
+		outgoing:   outgoing,
+		cancel:     cancel,
+		viewers:    make([]chan uint64, 0),
+		epoch:      0,
 	}
+	proxy.supervisor.Start(ctx)
 
 	go func() {
-		for {
-			data, err := conn.Read()
-			if err != nil {
-				log.Printf("error reading from host: %v", err)
-				continue
-			}
-			if data[0] == 0 {
-				if len(data) == 9 {
-					proxy.epoch, _ = util.ParseUint64(data, 1)
-					proxy.mu.Lock()
-					for _, v := range proxy.viewers {
-						v <- proxy.epoch
-					}
-					proxy.mu.Unlock()
-				} else {
-					log.Print("invalid epoch message")
-				}
-			} else if data[0] == 1 {
-				if len(data) > 1 {
-					action := data[1:]
-					if err := proxy.state.Action(action); err != nil {
-						log.Printf("invalid action: %v", err)
-					} else {
-					}
-				}
-			} else if data[0] == 2 {
-				blocks := ParseMultiBlocks(data)
-				if len(blocks) == 0 {
-					log.Printf("invalid multiblocv: %v", err)
-				} else {
-					log.Printf("multiple blocks: %v", len(blocks))
-				}
-
-				for _, block := range blocks {
-					proxy.mu.Lock()
-					proxy.epoch = block.epoch
-					for _, v := range proxy.viewers {
-						v <- proxy.epoch
-					}
-					proxy.mu.Unlock()
-					for _, action := range block.actions {
-						if err := proxy.state.Action(action); err != nil {
-							log.Printf("invalid action: %v", err)
-						}
-					}
+		for signal := range signals {
+			if signal.Signal == network.BlockMessage {
+				proxy.mu.Lock()
+				proxy.epoch, _ = util.ParseUint64(signal.Data, 0)
+				proxy.state.SetEpoch(proxy.epoch)
+				for _, v := range proxy.viewers {
+					v <- proxy.epoch
 				}
-			} else {
-				log.Printf("invalid message type: %v", data[0])
+				proxy.mu.Unlock()
+			} else if err := proxy.state.Action(signal.Data); err != nil {
+				log.Printf("invalid action: %v", err)
 			}
 		}
 	}()
 	return proxy
 }
-
-type blockdata struct {
-	epoch   uint64
-	actions [][]byte
-}
-
-func ParseMultiBlocks(data []byte) []*blockdata {
-	if len(data) < 9 {
-		return nil
-	}
-	blocks := make([]*blockdata, 0)
-	position := 1
-	for {
-		block := blockdata{}
-		block.epoch, position = util.ParseUint64(data, position)
-		block.actions, position = util.ParseActionsArray(data, position)
-		blocks = append(blocks, &block)
-		if position >= len(data) {
-			return blocks
-		}
-	}
-}
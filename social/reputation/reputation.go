@@ -0,0 +1,252 @@
+// Package reputation scores members and collectives by the recognition their
+// work received on the network: stamps imprinted by collectives, releases,
+// pins on boards, edits accepted into later versions of a draft and
+// reactions.
+//
+// Like social/search, the ledger knows nothing about the state. Credits are
+// fed by social/index from the Indexer callbacks and scores are computed on
+// demand for a given epoch, so older credits decay without any bookkeeping.
+package reputation
+
+import (
+	"math"
+	"sort"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+type Source byte
+
+const (
+	StampSource Source = iota
+	ReleaseSource
+	PinSource
+	EditSource
+	ReactionSource
+	UnknownSource
+)
+
+var sourceNames = []string{"stamp", "release", "pin", "edit", "reaction"}
+
+func (s Source) String() string {
+	if s >= UnknownSource {
+		return "unknown"
+	}
+	return sourceNames[s]
+}
+
+// Weights are the points of each source of credit. A credit loses half of its
+// value every HalfLife epochs (zero disables decay).
+type Weights struct {
+	Stamp    float64
+	Release  float64
+	Pin      float64
+	Edit     float64
+	Reaction float64
+	HalfLife uint64
+}
+
+// one epoch per second: credits lose half of their value in about six months
+var DefaultWeights = Weights{
+	Stamp:    10,
+	Release:  5,
+	Pin:      3,
+	Edit:     4,
+	Reaction: 1,
+	HalfLife: 180 * 24 * 60 * 60,
+}
+
+func (w Weights) points(source Source) float64 {
+	switch source {
+	case StampSource:
+		return w.Stamp
+	case ReleaseSource:
+		return w.Release
+	case PinSource:
+		return w.Pin
+	case EditSource:
+		return w.Edit
+	case ReactionSource:
+		return w.Reaction
+	}
+	return 0
+}
+
+// decay is the fraction of a credit from epoch still valid at now
+func (w Weights) decay(epoch, now uint64) float64 {
+	if w.HalfLife == 0 || epoch >= now {
+		return 1
+	}
+	return math.Exp2(-float64(now-epoch) / float64(w.HalfLife))
+}
+
+// Subject receiving a credit: a collective (by name) or the members sharing
+// the authorship of the credited object.
+type Subject struct {
+	Collective string
+	Members    []crypto.Token
+}
+
+// credit granted, valued with the current weights when scoring
+type entry struct {
+	source Source
+	object crypto.Hash
+	epoch  uint64
+	share  float64 // fraction of the points of the source
+}
+
+// Credit is a single recognition valued at the epoch of the score.
+type Credit struct {
+	Source Source
+	Object crypto.Hash
+	Epoch  uint64
+	Points float64
+}
+
+// Breakdown is the decayed score obtained from a source.
+type Breakdown struct {
+	Source Source
+	Count  int
+	Points float64
+}
+
+type Score struct {
+	Total     float64
+	Breakdown []Breakdown // one entry per source, in the order of the sources
+	Credits   []Credit    // most recent first
+}
+
+type MemberRank struct {
+	Token crypto.Token
+	Score float64
+}
+
+type CollectiveRank struct {
+	Name  string
+	Score float64
+}
+
+type Ledger struct {
+	weights     Weights
+	members     map[crypto.Token][]entry
+	collectives map[string][]entry
+}
+
+func NewLedger(weights Weights) *Ledger {
+	return &Ledger{
+		weights:     weights,
+		members:     make(map[crypto.Token][]entry),
+		collectives: make(map[string][]entry),
+	}
+}
+
+func (l *Ledger) Weights() Weights {
+	return l.weights
+}
+
+// SetWeights changes the weights, also for the credits already granted.
+func (l *Ledger) SetWeights(weights Weights) {
+	l.weights = weights
+}
+
+// Grant credits the points of source, multiplied by factor, to subject. Points
+// credited to a group of members are split equally among them.
+func (l *Ledger) Grant(subject Subject, source Source, object crypto.Hash, epoch uint64, factor float64) {
+	if source >= UnknownSource || factor == 0 {
+		return
+	}
+	if subject.Collective != "" {
+		l.collectives[subject.Collective] = append(l.collectives[subject.Collective], entry{source: source, object: object, epoch: epoch, share: factor})
+		return
+	}
+	if len(subject.Members) == 0 {
+		return
+	}
+	share := factor / float64(len(subject.Members))
+	for _, token := range subject.Members {
+		l.members[token] = append(l.members[token], entry{source: source, object: object, epoch: epoch, share: share})
+	}
+}
+
+func (l *Ledger) value(e entry, now uint64) float64 {
+	return l.weights.points(e.source) * e.share * l.weights.decay(e.epoch, now)
+}
+
+func (l *Ledger) score(entries []entry, now uint64) Score {
+	score := Score{
+		Breakdown: make([]Breakdown, UnknownSource),
+		Credits:   make([]Credit, 0, len(entries)),
+	}
+	for n := range score.Breakdown {
+		score.Breakdown[n].Source = Source(n)
+	}
+	for _, e := range entries {
+		points := l.value(e, now)
+		score.Total += points
+		score.Breakdown[e.source].Count++
+		score.Breakdown[e.source].Points += points
+		score.Credits = append(score.Credits, Credit{Source: e.source, Object: e.object, Epoch: e.epoch, Points: points})
+	}
+	sort.SliceStable(score.Credits, func(n, m int) bool { return score.Credits[n].Epoch > score.Credits[m].Epoch })
+	return score
+}
+
+func (l *Ledger) total(entries []entry, now uint64) float64 {
+	total := 0.0
+	for _, e := range entries {
+		total += l.value(e, now)
+	}
+	return total
+}
+
+// Member is the score of a member at epoch now.
+func (l *Ledger) Member(token crypto.Token, now uint64) Score {
+	return l.score(l.members[token], now)
+}
+
+// Collective is the score of a collective at epoch now.
+func (l *Ledger) Collective(name string, now uint64) Score {
+	return l.score(l.collectives[name], now)
+}
+
+// Members ranks the members with positive score at epoch now, at most count
+// of them (all if count is not positive).
+func (l *Ledger) Members(now uint64, count int) []MemberRank {
+	ranks := make([]MemberRank, 0, len(l.members))
+	for token, entries := range l.members {
+		if total := l.total(entries, now); total > 0 {
+			ranks = append(ranks, MemberRank{Token: token, Score: total})
+		}
+	}
+	sort.Slice(ranks, func(n, m int) bool {
+		if ranks[n].Score != ranks[m].Score {
+			return ranks[n].Score > ranks[m].Score
+		}
+		return string(ranks[n].Token[:]) < string(ranks[m].Token[:])
+	})
+	if count > 0 && len(ranks) > count {
+		ranks = ranks[:count]
+	}
+	return ranks
+}
+
+// Collectives ranks the collectives with positive score at epoch now, at most
+// count of them (all if count is not positive).
+func (l *Ledger) Collectives(now uint64, count int) []CollectiveRank {
+	ranks := make([]CollectiveRank, 0, len(l.collectives))
+	for name, entries := range l.collectives {
+		if total := l.total(entries, now); total > 0 {
+			ranks = append(ranks, CollectiveRank{Name: name, Score: total})
+		}
+	}
+	sort.Slice(ranks, func(n, m int) bool {
+		if ranks[n].Score != ranks[m].Score {
+			return ranks[n].Score > ranks[m].Score
+		}
+		return ranks[n].Name < ranks[m].Name
+	})
+	if count > 0 && len(ranks) > count {
+		ranks = ranks[:count]
+	}
+	return ranks
+}
@@ -0,0 +1,132 @@
+package reputation
+
+import (
+	"math"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+var weights = Weights{Stamp: 10, Release: 5, Pin: 3, Edit: 4, Reaction: 1, HalfLife: 100}
+
+func token(n byte) crypto.Token {
+	var t crypto.Token
+	t[0] = n
+	return t
+}
+
+func object(name string) crypto.Hash {
+	return crypto.Hasher([]byte(name))
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestDecay(t *testing.T) {
+	tests := []struct {
+		weights    Weights
+		epoch, now uint64
+		expected   float64
+	}{
+		{weights, 10, 10, 1},
+		{weights, 10, 5, 1}, // credits from the future do not grow
+		{weights, 10, 110, 0.5},
+		{weights, 10, 210, 0.25},
+		{Weights{HalfLife: 0}, 10, 1000000, 1},
+	}
+	for _, test := range tests {
+		if decay := test.weights.decay(test.epoch, test.now); !near(decay, test.expected) {
+			t.Errorf("decay from %v to %v with half life %v is %v, expected %v", test.epoch, test.now, test.weights.HalfLife, decay, test.expected)
+		}
+	}
+}
+
+func TestGrant(t *testing.T) {
+	ledger := NewLedger(weights)
+	authors := Subject{Members: []crypto.Token{token(1), token(2)}}
+	ledger.Grant(authors, StampSource, object("draft"), 0, 1)
+	ledger.Grant(Subject{Members: []crypto.Token{token(1)}}, ReactionSource, object("draft"), 100, 3)
+	ledger.Grant(Subject{Collective: "coletivo"}, ReleaseSource, object("draft"), 0, 1)
+	// ignored
+	ledger.Grant(Subject{}, PinSource, object("draft"), 0, 1)
+	ledger.Grant(authors, UnknownSource, object("draft"), 0, 1)
+	ledger.Grant(authors, PinSource, object("draft"), 0, 0)
+
+	score := ledger.Member(token(1), 100)
+	// half of a stamp decayed to a half plus three fresh reactions
+	if !near(score.Total, 10*0.5*0.5+3) {
+		t.Errorf("member score %v, expected %v", score.Total, 10*0.5*0.5+3)
+	}
+	if score.Breakdown[StampSource].Count != 1 || !near(score.Breakdown[StampSource].Points, 2.5) {
+		t.Errorf("stamp breakdown %+v", score.Breakdown[StampSource])
+	}
+	if score.Breakdown[ReactionSource].Count != 1 || !near(score.Breakdown[ReactionSource].Points, 3) {
+		t.Errorf("reaction breakdown %+v", score.Breakdown[ReactionSource])
+	}
+	if score.Breakdown[PinSource].Count != 0 {
+		t.Error("ignored grants credited")
+	}
+	if len(score.Credits) != 2 || score.Credits[0].Source != ReactionSource {
+		t.Errorf("credits not ordered by most recent first: %+v", score.Credits)
+	}
+	if total := ledger.Member(token(2), 100).Total; !near(total, 2.5) {
+		t.Errorf("coauthor score %v, expected 2.5", total)
+	}
+	if total := ledger.Collective("coletivo", 0).Total; !near(total, 5) {
+		t.Errorf("collective score %v, expected 5", total)
+	}
+	if total := ledger.Member(token(3), 100).Total; total != 0 {
+		t.Errorf("unknown member scored %v", total)
+	}
+}
+
+func TestSetWeights(t *testing.T) {
+	ledger := NewLedger(weights)
+	ledger.Grant(Subject{Collective: "coletivo"}, PinSource, object("draft"), 0, 1)
+	ledger.SetWeights(Weights{Pin: 7})
+	if total := ledger.Collective("coletivo", 1000).Total; !near(total, 7) {
+		t.Errorf("new weights not applied to granted credits: %v", total)
+	}
+	if ledger.Weights().Pin != 7 {
+		t.Error("weights not changed")
+	}
+}
+
+func TestRanks(t *testing.T) {
+	ledger := NewLedger(weights)
+	ledger.Grant(Subject{Members: []crypto.Token{token(1)}}, ReactionSource, object("a"), 0, 1)
+	ledger.Grant(Subject{Members: []crypto.Token{token(2)}}, StampSource, object("b"), 0, 1)
+	ledger.Grant(Subject{Members: []crypto.Token{token(3)}}, StampSource, object("c"), 0, 1)
+	ledger.Grant(Subject{Members: []crypto.Token{token(4)}}, EditSource, object("d"), 0, 1)
+	ledger.Grant(Subject{Members: []crypto.Token{token(5)}}, PinSource, object("e"), 0, -1)
+
+	ranks := ledger.Members(0, 0)
+	expected := []crypto.Token{token(2), token(3), token(4), token(1)}
+	if len(ranks) != len(expected) {
+		t.Fatalf("%v members ranked, expected %v (only positive scores)", len(ranks), len(expected))
+	}
+	for n, rank := range ranks {
+		if rank.Token != expected[n] {
+			t.Errorf("member %v ranked at %v", rank.Token[0], n)
+		}
+	}
+	if top := ledger.Members(0, 2); len(top) != 2 || top[0].Token != token(2) {
+		t.Errorf("top members %+v", top)
+	}
+
+	ledger.Grant(Subject{Collective: "b"}, PinSource, object("a"), 0, 1)
+	ledger.Grant(Subject{Collective: "a"}, PinSource, object("b"), 0, 1)
+	ledger.Grant(Subject{Collective: "c"}, StampSource, object("c"), 0, 1)
+	collectives := ledger.Collectives(0, 0)
+	names := make([]string, len(collectives))
+	for n, rank := range collectives {
+		names[n] = rank.Name
+	}
+	if len(names) != 3 || names[0] != "c" || names[1] != "a" || names[2] != "b" {
+		t.Errorf("collectives ranked as %v, expected [c a b]", names)
+	}
+	if top := ledger.Collectives(0, 1); len(top) != 1 || top[0].Name != "c" {
+		t.Errorf("top collectives %+v", top)
+	}
+}
@@ -0,0 +1,412 @@
+// Package search implements an in-process inverted index over the textual
+// content of synergy objects (drafts, edits, boards, collectives and events).
+//
+// The index knows nothing about the state: documents are assembled and kept
+// up to date by social/index, which also provides the popularity boost used
+// for ranking.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+type Kind byte
+
+const (
+	DraftDocument Kind = iota
+	EditDocument
+	BoardDocument
+	CollectiveDocument
+	EventDocument
+	UnknownDocument
+)
+
+var kindNames = []string{"draft", "edit", "board", "collective", "event"}
+
+func (k Kind) String() string {
+	if k >= UnknownDocument {
+		return "unknown"
+	}
+	return kindNames[k]
+}
+
+func KindFromString(name string) Kind {
+	for n, kind := range kindNames {
+		if kind == name {
+			return Kind(n)
+		}
+	}
+	return UnknownDocument
+}
+
+// matches on the title weight more than matches on the body
+const titleWeight = 3.0
+
+type Document struct {
+	Hash       crypto.Hash
+	Kind       Kind
+	Title      string
+	Body       string // description, reasons and text content
+	Keywords   []string
+	Authors    []string // handles
+	Collective string
+	Date       uint64
+	title      []string
+	body       []string
+	keywords   map[string]struct{}
+}
+
+type posting struct {
+	title int
+	body  int
+}
+
+type Index struct {
+	documents map[crypto.Hash]*Document
+	postings  map[string]map[crypto.Hash]*posting
+}
+
+func NewIndex() *Index {
+	return &Index{
+		documents: make(map[crypto.Hash]*Document),
+		postings:  make(map[string]map[crypto.Hash]*posting),
+	}
+}
+
+// fold removes portuguese diacritics so that "esboço" matches "esboco"
+func fold(r rune) rune {
+	switch r {
+	case 'á', 'à', 'â', 'ã', 'ä':
+		return 'a'
+	case 'é', 'è', 'ê', 'ë':
+		return 'e'
+	case 'í', 'ì', 'î', 'ï':
+		return 'i'
+	case 'ó', 'ò', 'ô', 'õ', 'ö':
+		return 'o'
+	case 'ú', 'ù', 'û', 'ü':
+		return 'u'
+	case 'ç':
+		return 'c'
+	case 'ñ':
+		return 'n'
+	}
+	return r
+}
+
+// Tokenize splits text into lower case, diacritic free terms
+func Tokenize(text string) []string {
+	terms := make([]string, 0)
+	var term strings.Builder
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			term.WriteRune(fold(unicode.ToLower(r)))
+		} else if term.Len() > 0 {
+			terms = append(terms, term.String())
+			term.Reset()
+		}
+	}
+	if term.Len() > 0 {
+		terms = append(terms, term.String())
+	}
+	return terms
+}
+
+func normalize(text string) string {
+	return strings.Join(Tokenize(text), " ")
+}
+
+// Add indexes the document, replacing any previous document with the same
+// hash.
+func (idx *Index) Add(doc *Document) {
+	if doc == nil {
+		return
+	}
+	idx.Remove(doc.Hash)
+	doc.title = Tokenize(doc.Title)
+	doc.body = Tokenize(doc.Body)
+	doc.keywords = make(map[string]struct{})
+	for _, keyword := range doc.Keywords {
+		doc.keywords[normalize(keyword)] = struct{}{}
+		// keywords are also searchable as free text
+		doc.body = append(doc.body, Tokenize(keyword)...)
+	}
+	idx.documents[doc.Hash] = doc
+	for _, term := range doc.title {
+		idx.posting(term, doc.Hash).title++
+	}
+	for _, term := range doc.body {
+		idx.posting(term, doc.Hash).body++
+	}
+}
+
+func (idx *Index) posting(term string, hash crypto.Hash) *posting {
+	docs, ok := idx.postings[term]
+	if !ok {
+		docs = make(map[crypto.Hash]*posting)
+		idx.postings[term] = docs
+	}
+	post, ok := docs[hash]
+	if !ok {
+		post = &posting{}
+		docs[hash] = post
+	}
+	return post
+}
+
+func (idx *Index) Remove(hash crypto.Hash) {
+	doc, ok := idx.documents[hash]
+	if !ok {
+		return
+	}
+	for _, terms := range [][]string{doc.title, doc.body} {
+		for _, term := range terms {
+			if docs, ok := idx.postings[term]; ok {
+				delete(docs, hash)
+				if len(docs) == 0 {
+					delete(idx.postings, term)
+				}
+			}
+		}
+	}
+	delete(idx.documents, hash)
+}
+
+func (idx *Index) Document(hash crypto.Hash) *Document {
+	return idx.documents[hash]
+}
+
+func (idx *Index) Len() int {
+	return len(idx.documents)
+}
+
+// Query is a parsed search expression. Free terms must all be present,
+// phrases must appear as consecutive terms and filters must all match.
+type Query struct {
+	Terms       []string
+	Phrases     [][]string
+	Keywords    []string
+	Authors     []string
+	Collectives []string
+	Kinds       []Kind
+}
+
+func (q Query) Empty() bool {
+	return len(q.Terms) == 0 && len(q.Phrases) == 0 && len(q.Keywords) == 0 && len(q.Authors) == 0 && len(q.Collectives) == 0 && len(q.Kinds) == 0
+}
+
+// ParseQuery interprets a search expression such as
+//
+//	"democracia liquida" keyword:politica author:joao collective:motiro type:draft
+//
+// Quoted text is a phrase, the prefixes keyword: (or kw:), author:,
+// collective: and type: are filters and everything else are free terms.
+// Filter values may be quoted to include spaces.
+func ParseQuery(text string) Query {
+	var query Query
+	for _, field := range splitQuery(text) {
+		if strings.HasPrefix(field, `"`) {
+			if phrase := Tokenize(field); len(phrase) > 1 {
+				query.Phrases = append(query.Phrases, phrase)
+			} else if len(phrase) == 1 {
+				query.Terms = append(query.Terms, phrase[0])
+			}
+			continue
+		}
+		prefix, value, found := strings.Cut(field, ":")
+		if found && value != "" {
+			value = strings.Trim(value, `"`)
+			switch strings.ToLower(prefix) {
+			case "keyword", "kw":
+				query.Keywords = append(query.Keywords, normalize(value))
+				continue
+			case "author":
+				query.Authors = append(query.Authors, value)
+				continue
+			case "collective":
+				query.Collectives = append(query.Collectives, value)
+				continue
+			case "type":
+				query.Kinds = append(query.Kinds, KindFromString(strings.ToLower(value)))
+				continue
+			}
+		}
+		query.Terms = append(query.Terms, Tokenize(field)...)
+	}
+	return query
+}
+
+// splits on spaces outside of quotes
+func splitQuery(text string) []string {
+	fields := make([]string, 0)
+	var field strings.Builder
+	quoted := false
+	for _, r := range text {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if unicode.IsSpace(r) && !quoted {
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+			continue
+		}
+		field.WriteRune(r)
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+type Result struct {
+	Document *Document
+	Score    float64
+}
+
+// Search returns the documents matching the query ordered by score. Text
+// relevance is multiplied by 1 + boost(hash), the boost being provided by the
+// caller (reactions, stamps...). A query with no text ranks by boost alone.
+func (idx *Index) Search(query Query, boost func(crypto.Hash) float64) []Result {
+	terms := query.Terms
+	for _, phrase := range query.Phrases {
+		terms = append(terms, phrase...)
+	}
+	var candidates map[crypto.Hash]struct{}
+	if len(terms) == 0 {
+		candidates = make(map[crypto.Hash]struct{}, len(idx.documents))
+		for hash := range idx.documents {
+			candidates[hash] = struct{}{}
+		}
+	} else {
+		candidates = idx.intersect(terms)
+	}
+	results := make([]Result, 0)
+	for hash := range candidates {
+		doc := idx.documents[hash]
+		if !idx.matches(doc, query) {
+			continue
+		}
+		score := 1.0
+		if len(terms) > 0 {
+			score = idx.relevance(doc, terms)
+		}
+		if boost != nil {
+			score = score * (1 + boost(hash))
+		}
+		results = append(results, Result{Document: doc, Score: score})
+	}
+	sort.Slice(results, func(n, m int) bool {
+		if results[n].Score != results[m].Score {
+			return results[n].Score > results[m].Score
+		}
+		return results[n].Document.Date > results[m].Document.Date
+	})
+	return results
+}
+
+func (idx *Index) intersect(terms []string) map[crypto.Hash]struct{} {
+	// start from the rarest term
+	sorted := append([]string{}, terms...)
+	sort.Slice(sorted, func(n, m int) bool {
+		return len(idx.postings[sorted[n]]) < len(idx.postings[sorted[m]])
+	})
+	candidates := make(map[crypto.Hash]struct{})
+	for hash := range idx.postings[sorted[0]] {
+		candidates[hash] = struct{}{}
+	}
+	for _, term := range sorted[1:] {
+		docs := idx.postings[term]
+		for hash := range candidates {
+			if _, ok := docs[hash]; !ok {
+				delete(candidates, hash)
+			}
+		}
+	}
+	return candidates
+}
+
+func (idx *Index) matches(doc *Document, query Query) bool {
+	for _, phrase := range query.Phrases {
+		if !containsPhrase(doc.title, phrase) && !containsPhrase(doc.body, phrase) {
+			return false
+		}
+	}
+	for _, keyword := range query.Keywords {
+		if _, ok := doc.keywords[keyword]; !ok {
+			return false
+		}
+	}
+	if len(query.Kinds) > 0 && !containsKind(query.Kinds, doc.Kind) {
+		return false
+	}
+	for _, author := range query.Authors {
+		if !containsFold(doc.Authors, author) {
+			return false
+		}
+	}
+	for _, collective := range query.Collectives {
+		if !strings.EqualFold(doc.Collective, collective) {
+			return false
+		}
+	}
+	return true
+}
+
+// tf-idf with title matches weighted by titleWeight
+func (idx *Index) relevance(doc *Document, terms []string) float64 {
+	total := float64(len(idx.documents))
+	length := float64(len(doc.title) + len(doc.body) + 1)
+	score := 0.0
+	for _, term := range terms {
+		docs := idx.postings[term]
+		post, ok := docs[doc.Hash]
+		if !ok {
+			continue
+		}
+		idf := math.Log(1 + total/float64(len(docs)))
+		tf := (titleWeight*float64(post.title) + float64(post.body)) / math.Sqrt(length)
+		score += tf * idf
+	}
+	return score
+}
+
+func containsPhrase(terms, phrase []string) bool {
+	for n := 0; n+len(phrase) <= len(terms); n++ {
+		match := true
+		for m, term := range phrase {
+			if terms[n+m] != term {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKind(kinds []Kind, kind Kind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+func testIndex() (*Index, []*Document) {
+	docs := []*Document{
+		{
+			Hash:       crypto.Hasher([]byte("draft")),
+			Kind:       DraftDocument,
+			Title:      "Democracia líquida",
+			Body:       "um esboço sobre delegação de votos",
+			Keywords:   []string{"política", "votação"},
+			Authors:    []string{"joao"},
+			Collective: "motiro",
+			Date:       10,
+		},
+		{
+			Hash:       crypto.Hasher([]byte("board")),
+			Kind:       BoardDocument,
+			Title:      "Mural de política",
+			Body:       "textos sobre democracia",
+			Keywords:   []string{"política"},
+			Collective: "motiro",
+			Date:       20,
+		},
+		{
+			Hash:       crypto.Hasher([]byte("event")),
+			Kind:       EventDocument,
+			Title:      "Encontro",
+			Body:       "debate sobre democracia líquida e orçamento",
+			Collective: "Praça",
+			Date:       30,
+		},
+	}
+	idx := NewIndex()
+	for _, doc := range docs {
+		idx.Add(doc)
+	}
+	return idx, docs
+}
+
+func hashes(results []Result) []crypto.Hash {
+	found := make([]crypto.Hash, len(results))
+	for n, result := range results {
+		found[n] = result.Document.Hash
+	}
+	return found
+}
+
+func TestTokenize(t *testing.T) {
+	terms := Tokenize("Esboço: Democracia-LÍQUIDA, 2023!")
+	expected := []string{"esboco", "democracia", "liquida", "2023"}
+	if !reflect.DeepEqual(terms, expected) {
+		t.Errorf("Tokenize returned %v, expected %v", terms, expected)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	query := ParseQuery(`"democracia líquida" orçamento kw:"Política Pública" author:joao collective:motiro type:draft hora:10`)
+	expected := Query{
+		Terms:       []string{"orcamento", "hora", "10"},
+		Phrases:     [][]string{{"democracia", "liquida"}},
+		Keywords:    []string{"politica publica"},
+		Authors:     []string{"joao"},
+		Collectives: []string{"motiro"},
+		Kinds:       []Kind{DraftDocument},
+	}
+	if !reflect.DeepEqual(query, expected) {
+		t.Errorf("ParseQuery returned %+v, expected %+v", query, expected)
+	}
+	if !ParseQuery("  ").Empty() {
+		t.Error("blank query not empty")
+	}
+	if kinds := ParseQuery("type:nada").Kinds; len(kinds) != 1 || kinds[0] != UnknownDocument {
+		t.Errorf("unknown type parsed as %v", kinds)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	idx, docs := testIndex()
+	draft, board, event := docs[0].Hash, docs[1].Hash, docs[2].Hash
+	tests := []struct {
+		query    string
+		expected []crypto.Hash
+	}{
+		// matches on the title are ranked above matches on the body, ties
+		// by the most recent
+		{"democracia", []crypto.Hash{draft, event, board}},
+		{"democracia orcamento", []crypto.Hash{event}},
+		{`"liquida democracia"`, []crypto.Hash{}},
+		{`"democracia liquida"`, []crypto.Hash{draft, event}},
+		{"keyword:politica", []crypto.Hash{board, draft}},
+		{"democracia type:board", []crypto.Hash{board}},
+		{"author:JOAO", []crypto.Hash{draft}},
+		{"collective:praça", []crypto.Hash{event}},
+		// keywords are also free text
+		{"votacao", []crypto.Hash{draft}},
+		{"inexistente", []crypto.Hash{}},
+	}
+	for _, test := range tests {
+		found := hashes(idx.Search(ParseQuery(test.query), nil))
+		if !reflect.DeepEqual(found, test.expected) {
+			t.Errorf("search %q returned %v, expected %v", test.query, found, test.expected)
+		}
+	}
+}
+
+func TestSearchBoost(t *testing.T) {
+	idx, docs := testIndex()
+	boost := func(hash crypto.Hash) float64 {
+		if hash == docs[2].Hash {
+			return 10
+		}
+		return 0
+	}
+	found := hashes(idx.Search(ParseQuery("democracia"), boost))
+	if len(found) != 3 || found[0] != docs[2].Hash {
+		t.Errorf("boost not applied: %v", found)
+	}
+	// without text the ranking is by boost and then by date
+	found = hashes(idx.Search(ParseQuery("collective:motiro"), nil))
+	if !reflect.DeepEqual(found, []crypto.Hash{docs[1].Hash, docs[0].Hash}) {
+		t.Errorf("ranking by date not working: %v", found)
+	}
+}
+
+func TestIndexReplaceAndRemove(t *testing.T) {
+	idx, docs := testIndex()
+	replaced := *docs[0]
+	replaced.Title = "Orçamento participativo"
+	replaced.Body = ""
+	replaced.Keywords = nil
+	idx.Add(&replaced)
+	if idx.Len() != 3 {
+		t.Errorf("replaced document counted twice: %v documents", idx.Len())
+	}
+	if found := hashes(idx.Search(ParseQuery("delegacao"), nil)); len(found) != 0 {
+		t.Errorf("terms of replaced document still indexed: %v", found)
+	}
+	if found := hashes(idx.Search(ParseQuery("participativo"), nil)); !reflect.DeepEqual(found, []crypto.Hash{docs[0].Hash}) {
+		t.Errorf("replaced document not indexed: %v", found)
+	}
+	idx.Remove(docs[0].Hash)
+	idx.Remove(docs[0].Hash)
+	if idx.Len() != 2 || idx.Document(docs[0].Hash) != nil {
+		t.Error("document not removed")
+	}
+	if _, ok := idx.postings["participativo"]; ok {
+		t.Error("postings of removed document not removed")
+	}
+}
@@ -1,8 +1,6 @@
 package state
 
 import (
-	"errors"
-
 	"github.com/freehandle/breeze/crypto"
 	"github.com/freehandle/synergy/social/actions"
 )
@@ -24,7 +22,7 @@ type Board struct {
 func (b *Board) Pin(d *Draft) error {
 	for _, pinned := range b.Pinned {
 		if pinned == d {
-			return errors.New("already pinned")
+			return ErrAlreadyPinned
 		}
 	}
 	b.Pinned = append(b.Pinned, d)
@@ -38,7 +36,7 @@ func (b *Board) Remove(d *Draft) error {
 			return nil
 		}
 	}
-	return errors.New("not pinned")
+	return ErrNotPinned
 }
 
 func (b *Board) Last(n int) []*Draft {
@@ -66,14 +64,22 @@ type PendingUpdateBoard struct {
 }
 
 func (b *PendingUpdateBoard) IncorporateVote(vote actions.Vote, state *State) error {
-	IsNewValidVote(vote, b.Votes, b.Hash)
-	b.Votes = append(b.Votes, vote)
-	consensus := b.Board.Collective.Consensus(vote.Hash, b.Votes)
+	votes, err := castVote(b.Hash, vote, b.Votes)
+	if err != nil {
+		return err
+	}
+	b.Votes = votes
+	return b.Evaluate(state)
+}
+
+// Evaluate concludes the proposal if the votes cast reach consensus
+func (b *PendingUpdateBoard) Evaluate(state *State) error {
+	consensus := b.Board.Collective.Consensus(b.Hash, b.Votes)
 	state.index.IndexActionStatus(b.Hash, consensus)
 	if consensus == Undecided {
 		return nil
 	}
-	state.IndexConsensus(vote.Hash, consensus)
+	state.IndexConsensus(b.Hash, consensus)
 	state.Proposals.Delete(b.Hash)
 	if consensus == Against {
 		return nil
@@ -98,15 +104,22 @@ type PendingBoard struct {
 }
 
 func (b *PendingBoard) IncorporateVote(vote actions.Vote, state *State) error {
-	if err := IsNewValidVote(vote, b.Votes, b.Hash); err != nil {
+	votes, err := castVote(b.Hash, vote, b.Votes)
+	if err != nil {
+		return err
 	}
-	b.Votes = append(b.Votes, vote)
-	consensus := b.Board.Collective.Consensus(vote.Hash, b.Votes)
+	b.Votes = votes
+	return b.Evaluate(state)
+}
+
+// Evaluate concludes the proposal if the votes cast reach consensus
+func (b *PendingBoard) Evaluate(state *State) error {
+	consensus := b.Board.Collective.Consensus(b.Hash, b.Votes)
 	state.index.IndexActionStatus(b.Hash, consensus)
 	if consensus == Undecided {
 		return nil
 	}
-	state.IndexConsensus(vote.Hash, consensus)
+	state.IndexConsensus(b.Hash, consensus)
 	state.Proposals.Delete(b.Hash)
 	if consensus == Against {
 		return nil
@@ -116,7 +129,7 @@ func (b *PendingBoard) IncorporateVote(vote actions.Vote, state *State) error {
 	}
 	hash := crypto.Hasher([]byte(b.Board.Name))
 	if _, ok := state.Boards[hash]; ok {
-		return errors.New("board already exists")
+		return ErrNameTaken
 	}
 	state.Boards[hash] = b.Board
 	return nil
@@ -132,16 +145,22 @@ type Pin struct {
 }
 
 func (p *Pin) IncorporateVote(vote actions.Vote, state *State) error {
-	if err := IsNewValidVote(vote, p.Votes, p.Hash); err != nil {
+	votes, err := castVote(p.Hash, vote, p.Votes)
+	if err != nil {
 		return err
 	}
-	p.Votes = append(p.Votes, vote)
-	consensus := p.Board.Editors.Consensus(vote.Hash, p.Votes)
+	p.Votes = votes
+	return p.Evaluate(state)
+}
+
+// Evaluate concludes the proposal if the votes cast reach consensus
+func (p *Pin) Evaluate(state *State) error {
+	consensus := p.Board.Editors.Consensus(p.Hash, p.Votes)
 	state.index.IndexActionStatus(p.Hash, consensus)
 	if consensus == Undecided {
 		return nil
 	}
-	state.IndexConsensus(vote.Hash, consensus)
+	state.IndexConsensus(p.Hash, consensus)
 	state.Proposals.Delete(p.Hash)
 	if consensus == Against {
 		return nil
@@ -149,8 +168,13 @@ func (p *Pin) IncorporateVote(vote actions.Vote, state *State) error {
 	if p.Pin {
 		// coloca o pin no draft
 		p.Draft.Pinned = append(p.Draft.Pinned, p.Board)
-		err := p.Board.Pin(p.Draft)
-		return err
+		if err := p.Board.Pin(p.Draft); err != nil {
+			return err
+		}
+		if state.index != nil {
+			state.index.AddPinToIndex(p)
+		}
+		return nil
 	}
 	// aqui eh um unpin
 	if len(p.Draft.Pinned) > 0 {
@@ -176,15 +200,23 @@ type BoardEditor struct {
 }
 
 func (e *BoardEditor) IncorporateVote(vote actions.Vote, state *State) error {
-	IsNewValidVote(vote, e.Votes, e.Hash)
-	e.Votes = append(e.Votes, vote)
-	consensus := e.Board.Collective.Consensus(vote.Hash, e.Votes)
+	votes, err := castVote(e.Hash, vote, e.Votes)
+	if err != nil {
+		return err
+	}
+	e.Votes = votes
+	return e.Evaluate(state)
+}
+
+// Evaluate concludes the proposal if the votes cast reach consensus
+func (e *BoardEditor) Evaluate(state *State) error {
+	consensus := e.Board.Collective.Consensus(e.Hash, e.Votes)
 	state.index.IndexActionStatus(e.Hash, consensus)
 	if consensus == Undecided {
 		return nil
 	}
-	state.IndexConsensus(vote.Hash, consensus)
-	state.Proposals.Delete(vote.Hash)
+	state.IndexConsensus(e.Hash, consensus)
+	state.Proposals.Delete(e.Hash)
 	if consensus == Against {
 		return nil
 	}
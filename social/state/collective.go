@@ -1,7 +1,8 @@
 package state
 
 import (
-	"errors"
+	"bytes"
+	"sort"
 
 	"github.com/freehandle/breeze/crypto"
 	"github.com/freehandle/synergy/social/actions"
@@ -13,6 +14,7 @@ const (
 	Undecided ConsensusState = iota
 	Favorable
 	Against
+	Expired // prazo da proposta terminou sem consenso
 )
 
 type Collective struct {
@@ -20,6 +22,18 @@ type Collective struct {
 	Members     map[crypto.Token]struct{}
 	Description string
 	Policy      actions.Policy
+	Expiry      uint64                        // prazo em epochs das propostas do coletivo (0 usa ProposalDeadline)
+	Delegations map[crypto.Token][]Delegation // delegacoes de voto por membro
+	closed      map[crypto.Hash]struct{}      // votacoes encerradas pelo prazo (QuorumPolicy)
+	kinds       map[crypto.Hash]byte          // tipo das propostas pendentes do coletivo
+	live        *Collective                   // coletivo de origem de uma foto (Photo)
+}
+
+// Delegation of the vote of a member to another member of the collective.
+// Kinds restricts it to those kinds of proposal; empty means every kind.
+type Delegation struct {
+	To    crypto.Token
+	Kinds []byte
 }
 
 func (c *Collective) GetPolicy() (majority int, supermajority int) {
@@ -40,6 +54,9 @@ func (c *Collective) CollectiveName() string {
 	return c.Name
 }
 
+// Photo freezes members and policy of the collective for a proposal that
+// changes them. Vote delegations are not frozen: as for every other kind of
+// proposal they are resolved on the live collective.
 func (c *Collective) Photo() *Collective {
 	cloned := Collective{
 		Name:    c.Name,
@@ -47,8 +64,13 @@ func (c *Collective) Photo() *Collective {
 		Policy: actions.Policy{
 			Majority:      c.Policy.Majority,
 			SuperMajority: c.Policy.SuperMajority,
+			Kind:          c.Policy.Kind,
+			Quorum:        c.Policy.Quorum,
+			Veto:          append([]crypto.Token{}, c.Policy.Veto...),
 		},
 		Description: c.Description,
+		Expiry:      c.Expiry,
+		live:        c,
 	}
 	for member, _ := range c.Members {
 		cloned.Members[member] = struct{}{}
@@ -56,12 +78,21 @@ func (c *Collective) Photo() *Collective {
 	return &cloned
 }
 
+// delegations in force for the votes on the collective
+func (c *Collective) delegations() map[crypto.Token][]Delegation {
+	if c.live != nil {
+		return c.live.Delegations
+	}
+	return c.Delegations
+}
+
 func (c *Collective) IncludeMember(token crypto.Token) {
 	c.Members[token] = struct{}{}
 }
 
 func (c *Collective) RemoveMember(token crypto.Token) {
 	delete(c.Members, token)
+	delete(c.Delegations, token)
 }
 
 func (c *Collective) ChangeMajority(majority int) {
@@ -69,11 +100,7 @@ func (c *Collective) ChangeMajority(majority int) {
 }
 
 func (c *Collective) Consensus(hash crypto.Hash, votes []actions.Vote) ConsensusState {
-	required := len(c.Members)*c.Policy.Majority/100 + 1
-	if required > len(c.Members) {
-		required = len(c.Members)
-	}
-	return consensus(c.Members, required, len(c.Members), hash, votes)
+	return c.policyConsensus(c.Policy.Majority, hash, votes)
 }
 
 func (c *Collective) ConsensusEpoch(votes []actions.Vote) uint64 {
@@ -81,22 +108,227 @@ func (c *Collective) ConsensusEpoch(votes []actions.Vote) uint64 {
 	if required > len(c.Members) {
 		required = len(c.Members)
 	}
+	if len(votes) > 0 {
+		votes = c.withDelegatedVotes(votes[0].Hash, votes)
+	}
 	return consensusEpoch(c.Members, required, votes)
 }
 
 func (c *Collective) Unanimous(hash crypto.Hash, votes []actions.Vote) ConsensusState {
+	votes = c.withDelegatedVotes(hash, votes)
+	if c.vetoed(hash, votes) {
+		return Against
+	}
 	required := len(c.Members)
 	return consensus(c.Members, required, len(c.Members), hash, votes)
 }
 
 func (c *Collective) SuperConsensus(hash crypto.Hash, votes []actions.Vote) ConsensusState {
-	required := len(c.Members)*c.Policy.SuperMajority/100 + 1
+	return c.policyConsensus(c.Policy.SuperMajority, hash, votes)
+}
+
+// policyConsensus applies the collective policy with the given majority
+// percentage. A vote against by a veto member rejects the proposal in any
+// kind of policy. Under QuorumPolicy the outcome is only known once the vote
+// is closed at the proposal deadline. Votes of members that delegated and did
+// not vote directly are cast by their delegates, the veto included.
+func (c *Collective) policyConsensus(majority int, hash crypto.Hash, votes []actions.Vote) ConsensusState {
+	votes = c.withDelegatedVotes(hash, votes)
+	if c.vetoed(hash, votes) {
+		return Against
+	}
+	if c.Policy.Kind == actions.QuorumPolicy {
+		if _, closed := c.closed[hash]; !closed {
+			return Undecided
+		}
+		return quorumConsensus(c.Members, majority, c.Policy.Quorum, hash, votes)
+	}
+	required := len(c.Members)*majority/100 + 1
 	if required > len(c.Members) {
 		required = len(c.Members)
 	}
 	return consensus(c.Members, required, len(c.Members), hash, votes)
 }
 
+func (c *Collective) vetoed(hash crypto.Hash, votes []actions.Vote) bool {
+	if len(c.Policy.Veto) == 0 {
+		return false
+	}
+	for _, vote := range votes {
+		if vote.Hash != hash || vote.Approve || vote.Abstain || !c.IsMember(vote.Author) {
+			continue
+		}
+		for _, token := range c.Policy.Veto {
+			if token == vote.Author {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// closeVote marks the vote on hash as closed so that a QuorumPolicy
+// collective decides it with the votes cast so far.
+func (c *Collective) closeVote(hash crypto.Hash) {
+	if c.closed == nil {
+		c.closed = make(map[crypto.Hash]struct{})
+	}
+	c.closed[hash] = struct{}{}
+}
+
+func (c *Collective) reopenVote(hash crypto.Hash) {
+	delete(c.closed, hash)
+}
+
+func (c *Collective) bindProposal(hash crypto.Hash, kind byte) {
+	if c.kinds == nil {
+		c.kinds = make(map[crypto.Hash]byte)
+	}
+	c.kinds[hash] = kind
+}
+
+func (c *Collective) unbindProposal(hash crypto.Hash) {
+	delete(c.kinds, hash)
+}
+
+// Delegate replaces the delegations of member for the given kinds of
+// proposal (every kind if empty) by a delegation to the member to.
+func (c *Collective) Delegate(member, to crypto.Token, kinds []byte) {
+	c.Revoke(member, kinds)
+	if c.Delegations == nil {
+		c.Delegations = make(map[crypto.Token][]Delegation)
+	}
+	c.Delegations[member] = append(c.Delegations[member], Delegation{To: to, Kinds: kinds})
+}
+
+// Revoke removes the delegations of member for the given kinds of proposal.
+// With no kinds every delegation of member is removed.
+func (c *Collective) Revoke(member crypto.Token, kinds []byte) {
+	if len(kinds) == 0 {
+		delete(c.Delegations, member)
+		return
+	}
+	remaining := make([]Delegation, 0)
+	for _, delegation := range c.Delegations[member] {
+		if len(delegation.Kinds) == 0 {
+			remaining = append(remaining, delegation)
+			continue
+		}
+		scope := make([]byte, 0)
+		for _, kind := range delegation.Kinds {
+			if !bytes.Contains(kinds, []byte{kind}) {
+				scope = append(scope, kind)
+			}
+		}
+		if len(scope) > 0 {
+			remaining = append(remaining, Delegation{To: delegation.To, Kinds: scope})
+		}
+	}
+	if len(remaining) == 0 {
+		delete(c.Delegations, member)
+	} else {
+		c.Delegations[member] = remaining
+	}
+}
+
+// delegateFor returns the member entrusted with the vote of member on
+// proposals of the given kind. A delegation scoped to the kind takes
+// precedence over one for every kind.
+func (c *Collective) delegateFor(member crypto.Token, kind byte, known bool) (crypto.Token, bool) {
+	var all *Delegation
+	delegations := c.delegations()[member]
+	for n, delegation := range delegations {
+		if len(delegation.Kinds) == 0 {
+			all = &delegations[n]
+		} else if known && bytes.Contains(delegation.Kinds, []byte{kind}) {
+			return delegation.To, true
+		}
+	}
+	if all != nil {
+		return all.To, true
+	}
+	return crypto.ZeroToken, false
+}
+
+// Delegated returns for each member that did not vote directly on hash the
+// member whose vote was cast on their behalf. Delegation is transitive: if
+// the delegate did not vote either, the delegate's own delegation is
+// followed.
+func (c *Collective) Delegated(hash crypto.Hash, votes []actions.Vote) map[crypto.Token]crypto.Token {
+	delegated := make(map[crypto.Token]crypto.Token)
+	table := c.delegations()
+	if len(table) == 0 {
+		return delegated
+	}
+	direct := make(map[crypto.Token]struct{})
+	for _, vote := range votes {
+		if vote.Hash == hash && c.IsMember(vote.Author) {
+			direct[vote.Author] = struct{}{}
+		}
+	}
+	kind, known := c.kinds[hash]
+	for member := range table {
+		if _, voted := direct[member]; voted || !c.IsMember(member) {
+			continue
+		}
+		visited := map[crypto.Token]struct{}{member: {}}
+		current := member
+		for {
+			to, ok := c.delegateFor(current, kind, known)
+			if !ok {
+				break
+			}
+			if _, cycle := visited[to]; cycle {
+				break
+			}
+			if _, voted := direct[to]; voted {
+				delegated[member] = to
+				break
+			}
+			visited[to] = struct{}{}
+			current = to
+		}
+	}
+	return delegated
+}
+
+// withDelegatedVotes adds to votes the votes cast by delegates on behalf of
+// members that did not vote directly. Votes are kept in epoch order.
+func (c *Collective) withDelegatedVotes(hash crypto.Hash, votes []actions.Vote) []actions.Vote {
+	delegated := c.Delegated(hash, votes)
+	if len(delegated) == 0 {
+		return votes
+	}
+	cast := make(map[crypto.Token]actions.Vote)
+	for _, vote := range votes {
+		if vote.Hash == hash {
+			cast[vote.Author] = vote
+		}
+	}
+	all := append(make([]actions.Vote, 0, len(votes)+len(delegated)), votes...)
+	for member, delegate := range delegated {
+		vote := cast[delegate]
+		all = append(all, actions.Vote{
+			Epoch:   vote.Epoch,
+			Author:  member,
+			Hash:    hash,
+			Approve: vote.Approve,
+			Abstain: vote.Abstain,
+		})
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Epoch < all[j].Epoch })
+	return all
+}
+
+// ProposalExpiry is the number of epochs a proposal on behalf of the
+// collective stays open for votes.
+func (c *Collective) ProposalExpiry() uint64 {
+	if c.Expiry == 0 {
+		return ProposalDeadline
+	}
+	return c.Expiry
+}
+
 func (c *Collective) IsMember(token crypto.Token) bool {
 	_, ok := c.Members[token]
 	return ok
@@ -172,10 +404,16 @@ type PendingUpdate struct {
 }
 
 func (p *PendingUpdate) IncorporateVote(vote actions.Vote, state *State) error {
-	if err := isValidVote(p.Hash, vote, p.Votes); err != nil {
+	votes, err := castVote(p.Hash, vote, p.Votes)
+	if err != nil {
 		return err
 	}
-	p.Votes = append(p.Votes, vote)
+	p.Votes = votes
+	return p.Evaluate(state)
+}
+
+// Evaluate concludes the proposal if the votes cast reach consensus
+func (p *PendingUpdate) Evaluate(state *State) error {
 	consensus := Undecided
 	if p.ChangePolicy {
 		consensus = p.Collective.SuperConsensus(p.Hash, p.Votes)
@@ -186,7 +424,7 @@ func (p *PendingUpdate) IncorporateVote(vote actions.Vote, state *State) error {
 	if consensus == Undecided {
 		return nil
 	}
-	state.IndexConsensus(vote.Hash, consensus)
+	state.IndexConsensus(p.Hash, consensus)
 	state.Proposals.Delete(p.Hash)
 	if consensus == Against {
 		return nil
@@ -206,6 +444,9 @@ func (p *PendingUpdate) IncorporateVote(vote actions.Vote, state *State) error {
 		newPolicy := actions.Policy{
 			Majority:      p.Collective.Policy.Majority,
 			SuperMajority: p.Collective.Policy.SuperMajority,
+			Kind:          p.Collective.Policy.Kind,
+			Quorum:        p.Collective.Policy.Quorum,
+			Veto:          p.Collective.Policy.Veto,
 		}
 
 		if p.Update.Majority != nil {
@@ -215,6 +456,9 @@ func (p *PendingUpdate) IncorporateVote(vote actions.Vote, state *State) error {
 			newPolicy.SuperMajority = int(*p.Update.SuperMajority)
 		}
 		collective.Policy = newPolicy
+		if p.Update.Expiry != nil {
+			collective.Expiry = *p.Update.Expiry
+		}
 	}
 	return nil
 }
@@ -230,23 +474,29 @@ func (p *PendingRequestMembership) IncorporateVote(vote actions.Vote, state *Sta
 	// if err := isValidVote(p.Hash, vote, p.Votes); err != nil {
 	// 	return err
 	// }
-	if err := IsNewValidVote(vote, p.Votes, p.Hash); err != nil {
+	votes, err := castVote(p.Hash, vote, p.Votes)
+	if err != nil {
 		return err
 	}
-	p.Votes = append(p.Votes, vote)
-	consensus := p.Collective.Consensus(vote.Hash, p.Votes)
+	p.Votes = votes
+	return p.Evaluate(state)
+}
+
+// Evaluate concludes the proposal if the votes cast reach consensus
+func (p *PendingRequestMembership) Evaluate(state *State) error {
+	consensus := p.Collective.Consensus(p.Hash, p.Votes)
 	state.index.IndexActionStatus(p.Hash, consensus)
 	if consensus == Undecided {
 		return nil
 	}
-	state.IndexConsensus(vote.Hash, consensus)
+	state.IndexConsensus(p.Hash, consensus)
 	state.Proposals.Delete(p.Hash)
 	if consensus == Against {
 		return nil
 	}
 	collective, ok := state.Collective(p.Collective.Name)
 	if !ok {
-		return errors.New("collective not found")
+		return ErrUnknownCollective
 	}
 	collective.Members[p.Request.Author] = struct{}{}
 	return nil
@@ -260,23 +510,29 @@ type PendingRemoveMember struct {
 }
 
 func (p *PendingRemoveMember) IncorporateVote(vote actions.Vote, state *State) error {
-	if err := isValidVote(p.Hash, vote, p.Votes); err != nil {
+	votes, err := castVote(p.Hash, vote, p.Votes)
+	if err != nil {
 		return err
 	}
-	p.Votes = append(p.Votes, vote)
+	p.Votes = votes
+	return p.Evaluate(state)
+}
+
+// Evaluate concludes the proposal if the votes cast reach consensus
+func (p *PendingRemoveMember) Evaluate(state *State) error {
 	consensus := p.Collective.Consensus(p.Hash, p.Votes)
 	state.index.IndexActionStatus(p.Hash, consensus)
 	if consensus == Undecided {
 		return nil
 	}
-	state.IndexConsensus(vote.Hash, consensus)
+	state.IndexConsensus(p.Hash, consensus)
 	state.Proposals.Delete(p.Hash)
 	if consensus == Against {
 		return nil
 	}
 	collective, ok := state.Collective(p.Collective.Name)
 	if !ok {
-		return errors.New("collective not found")
+		return ErrUnknownCollective
 	}
 	delete(collective.Members, p.Remove.Member)
 	return nil
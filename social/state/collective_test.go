@@ -0,0 +1,227 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+// requestMembership incorporates a request of candidate to join the
+// collective and the votes of the members, returning the proposal hash.
+func requestMembership(t *testing.T, s *State, collective string, candidate crypto.Token, votes ...*actions.Vote) crypto.Hash {
+	t.Helper()
+	request := &actions.RequestMembership{Epoch: s.Epoch, Author: candidate, Collective: collective, Include: true}
+	incorporate(t, s, request)
+	hash := request.Hashed()
+	for _, vote := range votes {
+		vote.Hash = hash
+		incorporate(t, s, vote)
+	}
+	return hash
+}
+
+func abstention(s *State, author crypto.Token) *actions.Vote {
+	return &actions.Vote{Epoch: s.Epoch, Author: author, Abstain: true}
+}
+
+func TestQuorumPolicy(t *testing.T) {
+	policy := actions.Policy{Majority: 50, SuperMajority: 50, Kind: actions.QuorumPolicy, Quorum: 50}
+	tests := []struct {
+		name     string
+		votes    func(s *State, m []crypto.Token) []*actions.Vote
+		accepted bool
+	}{
+		{
+			// uma abstencao conta para o quorum mas nao para a maioria
+			name: "approved with abstention",
+			votes: func(s *State, m []crypto.Token) []*actions.Vote {
+				return []*actions.Vote{vote(s, m[0], crypto.ZeroHash, true), abstention(s, m[1])}
+			},
+			accepted: true,
+		},
+		{
+			name: "rejected by majority of participants",
+			votes: func(s *State, m []crypto.Token) []*actions.Vote {
+				return []*actions.Vote{vote(s, m[0], crypto.ZeroHash, true), vote(s, m[1], crypto.ZeroHash, false), vote(s, m[2], crypto.ZeroHash, false)}
+			},
+		},
+		{
+			name: "no quorum",
+			votes: func(s *State, m []crypto.Token) []*actions.Vote {
+				return []*actions.Vote{vote(s, m[0], crypto.ZeroHash, true)}
+			},
+		},
+	}
+	for _, test := range tests {
+		s, members := testState(5)
+		s.SetEpoch(1)
+		collective := testCollective(t, s, "quorum", policy, members[:4]...)
+		collective.Expiry = 10
+		hash := requestMembership(t, s, "quorum", members[4], test.votes(s, members)...)
+		// a votacao so e decidida no prazo
+		if !s.Proposals.Has(hash) {
+			t.Errorf("%v: decided before the deadline", test.name)
+			continue
+		}
+		s.SetEpoch(11)
+		if s.Proposals.Has(hash) {
+			t.Errorf("%v: still pending after the deadline", test.name)
+		}
+		if collective.IsMember(members[4]) != test.accepted {
+			t.Errorf("%v: expected accepted %v", test.name, test.accepted)
+		}
+	}
+}
+
+func TestQuorumConsensus(t *testing.T) {
+	members := make(map[crypto.Token]struct{})
+	tokens := make([]crypto.Token, 4)
+	for n := range tokens {
+		tokens[n] = crypto.Token{byte(n + 1)}
+		members[tokens[n]] = struct{}{}
+	}
+	hash := crypto.Hasher([]byte("proposal"))
+	approve := func(n int) actions.Vote { return actions.Vote{Author: tokens[n], Hash: hash, Approve: true} }
+	against := func(n int) actions.Vote { return actions.Vote{Author: tokens[n], Hash: hash} }
+	abstain := func(n int) actions.Vote { return actions.Vote{Author: tokens[n], Hash: hash, Abstain: true} }
+	tests := []struct {
+		name   string
+		votes  []actions.Vote
+		result ConsensusState
+	}{
+		{"below quorum", []actions.Vote{approve(0)}, Undecided},
+		{"only abstentions", []actions.Vote{abstain(0), abstain(1)}, Against},
+		{"tie is rejected", []actions.Vote{approve(0), against(1)}, Against},
+		{"majority of cast votes", []actions.Vote{approve(0), approve(1), against(2), abstain(3)}, Favorable},
+		{"vote counted once", []actions.Vote{approve(0), approve(0), against(1)}, Against},
+		{"outsider ignored", []actions.Vote{approve(0), {Author: crypto.Token{9}, Hash: hash, Approve: true}}, Undecided},
+	}
+	for _, test := range tests {
+		if result := quorumConsensus(members, 50, 50, hash, test.votes); result != test.result {
+			t.Errorf("%v: expected %v, got %v", test.name, test.result, result)
+		}
+	}
+}
+
+func TestVeto(t *testing.T) {
+	s, members := testState(5)
+	s.SetEpoch(1)
+	policy := actions.Policy{Majority: 50, SuperMajority: 50, Veto: []crypto.Token{members[3]}}
+	collective := testCollective(t, s, "veto", policy, members[:4]...)
+	// um voto contra de quem tem veto rejeita a proposta na hora
+	hash := requestMembership(t, s, "veto", members[4],
+		vote(s, members[0], crypto.ZeroHash, true),
+		vote(s, members[1], crypto.ZeroHash, true),
+		vote(s, members[3], crypto.ZeroHash, false),
+	)
+	if s.Proposals.Has(hash) {
+		t.Error("vetoed proposal still pending")
+	}
+	if collective.IsMember(members[4]) {
+		t.Error("vetoed request accepted")
+	}
+	// uma abstencao de quem tem veto nao bloqueia
+	s2, members2 := testState(5)
+	s2.SetEpoch(1)
+	policy.Veto = []crypto.Token{members2[3]}
+	collective = testCollective(t, s2, "veto", policy, members2[:4]...)
+	requestMembership(t, s2, "veto", members2[4],
+		abstention(s2, members2[3]),
+		vote(s2, members2[0], crypto.ZeroHash, true),
+		vote(s2, members2[1], crypto.ZeroHash, true),
+		vote(s2, members2[2], crypto.ZeroHash, true),
+	)
+	if !collective.IsMember(members2[4]) {
+		t.Error("abstention of veto member blocked the request")
+	}
+}
+
+func delegation(s *State, collective string, from, to crypto.Token, kinds ...byte) *actions.Delegate {
+	return &actions.Delegate{Epoch: s.Epoch, Author: from, Collective: collective, Delegate: to, Kinds: kinds}
+}
+
+func TestDelegatedTally(t *testing.T) {
+	policy := actions.Policy{Majority: 50, SuperMajority: 50}
+	tests := []struct {
+		name     string
+		run      func(t *testing.T, s *State, m []crypto.Token) crypto.Hash
+		pending  bool
+		accepted bool
+	}{
+		{
+			// a delegacao feita depois de aberta a proposta vale para ela
+			name: "delegation after the proposal",
+			run: func(t *testing.T, s *State, m []crypto.Token) crypto.Hash {
+				hash := requestMembership(t, s, "delegation", m[4])
+				incorporate(t, s, delegation(s, "delegation", m[1], m[0]))
+				incorporate(t, s, &actions.Vote{Epoch: s.Epoch, Author: m[0], Hash: hash, Approve: true})
+				incorporate(t, s, &actions.Vote{Epoch: s.Epoch, Author: m[2], Hash: hash, Approve: true})
+				return hash
+			},
+			accepted: true,
+		},
+		{
+			name: "delegation scoped to another kind",
+			run: func(t *testing.T, s *State, m []crypto.Token) crypto.Hash {
+				incorporate(t, s, delegation(s, "delegation", m[1], m[0], UpdateCollectiveProposal))
+				return requestMembership(t, s, "delegation", m[4],
+					vote(s, m[0], crypto.ZeroHash, true),
+					vote(s, m[2], crypto.ZeroHash, true),
+				)
+			},
+			pending: true,
+		},
+		{
+			name: "direct vote overrides delegation",
+			run: func(t *testing.T, s *State, m []crypto.Token) crypto.Hash {
+				incorporate(t, s, delegation(s, "delegation", m[1], m[0]))
+				return requestMembership(t, s, "delegation", m[4],
+					vote(s, m[1], crypto.ZeroHash, false),
+					vote(s, m[0], crypto.ZeroHash, true),
+					vote(s, m[2], crypto.ZeroHash, true),
+				)
+			},
+			pending: true,
+		},
+		{
+			name: "transitive delegation",
+			run: func(t *testing.T, s *State, m []crypto.Token) crypto.Hash {
+				incorporate(t, s, delegation(s, "delegation", m[1], m[2]))
+				incorporate(t, s, delegation(s, "delegation", m[2], m[0]))
+				return requestMembership(t, s, "delegation", m[4],
+					vote(s, m[0], crypto.ZeroHash, true),
+				)
+			},
+			accepted: true,
+		},
+	}
+	for _, test := range tests {
+		s, members := testState(5)
+		s.SetEpoch(1)
+		collective := testCollective(t, s, "delegation", policy, members[:4]...)
+		hash := test.run(t, s, members)
+		if s.Proposals.Has(hash) != test.pending {
+			t.Errorf("%v: expected pending %v", test.name, test.pending)
+		}
+		if collective.IsMember(members[4]) != test.accepted {
+			t.Errorf("%v: expected accepted %v", test.name, test.accepted)
+		}
+	}
+}
+
+func TestDelegatedVeto(t *testing.T) {
+	s, members := testState(5)
+	s.SetEpoch(1)
+	policy := actions.Policy{Majority: 50, SuperMajority: 50, Veto: []crypto.Token{members[3]}}
+	collective := testCollective(t, s, "veto", policy, members[:4]...)
+	incorporate(t, s, delegation(s, "veto", members[3], members[2]))
+	hash := requestMembership(t, s, "veto", members[4],
+		vote(s, members[0], crypto.ZeroHash, true),
+		vote(s, members[1], crypto.ZeroHash, true),
+		vote(s, members[2], crypto.ZeroHash, false),
+	)
+	if s.Proposals.Has(hash) || collective.IsMember(members[4]) {
+		t.Error("veto cast by delegate not applied")
+	}
+}
@@ -1,8 +1,6 @@
 package state
 
 import (
-	"errors"
-
 	"github.com/freehandle/breeze/crypto"
 	"github.com/freehandle/synergy/social/actions"
 )
@@ -26,7 +24,7 @@ func consensus(members map[crypto.Token]struct{}, votesRequired int, votesCount
 	against := 0
 	for _, vote := range votes {
 		_, isMember := members[vote.Author]
-		if isMember && hash == vote.Hash {
+		if isMember && hash == vote.Hash && !vote.Abstain {
 			if vote.Approve {
 				favor += 1
 				if favor >= votesRequired {
@@ -43,6 +41,45 @@ func consensus(members map[crypto.Token]struct{}, votesRequired int, votesCount
 	return Undecided
 }
 
+// quorumConsensus decides a closed vote among the participating members. If
+// less than quorum percent of the members took part the vote stays undecided
+// (and the proposal expires). Abstentions count for the quorum but not for
+// the majority.
+func quorumConsensus(members map[crypto.Token]struct{}, majority int, quorum int, hash crypto.Hash, votes []actions.Vote) ConsensusState {
+	favor, against, abstain := 0, 0, 0
+	counted := make(map[crypto.Token]struct{})
+	for _, vote := range votes {
+		_, isMember := members[vote.Author]
+		_, isCounted := counted[vote.Author]
+		if !isMember || isCounted || hash != vote.Hash {
+			continue
+		}
+		counted[vote.Author] = struct{}{}
+		if vote.Abstain {
+			abstain += 1
+		} else if vote.Approve {
+			favor += 1
+		} else {
+			against += 1
+		}
+	}
+	if (favor+against+abstain)*100 < quorum*len(members) {
+		return Undecided
+	}
+	cast := favor + against
+	if cast == 0 {
+		return Against
+	}
+	required := cast*majority/100 + 1
+	if required > cast {
+		required = cast
+	}
+	if favor >= required {
+		return Favorable
+	}
+	return Against
+}
+
 func consensusEpoch(members map[crypto.Token]struct{}, votesRequired int, votes []actions.Vote) uint64 {
 	count := 0
 	for _, vote := range votes {
@@ -57,16 +94,43 @@ func consensusEpoch(members map[crypto.Token]struct{}, votesRequired int, votes
 	return 0
 }
 
-func isValidVote(hash crypto.Hash, vote actions.Vote, signatures []actions.Vote) error {
+// castVote returns votes with vote incorporated. While the proposal is open
+// members can change their minds: a new vote by the same author replaces the
+// previous one (the latest vote counts) and a retraction withdraws it. The
+// history of every vote cast is kept by the state on VoteHistory.
+func castVote(hash crypto.Hash, vote actions.Vote, votes []actions.Vote) ([]actions.Vote, error) {
 	if vote.Hash != hash {
-		return errors.New("invalid hash")
+		return votes, ErrHashMismatch
 	}
-	for _, cast := range signatures {
+	updated := make([]actions.Vote, 0, len(votes)+1)
+	found := false
+	for _, cast := range votes {
 		if cast.Author == vote.Author {
-			return errors.New("vote already cast")
+			found = true
+			continue
+		}
+		updated = append(updated, cast)
+	}
+	if vote.Retract {
+		if !found {
+			return votes, ErrNoVoteToRetract
 		}
+		return updated, nil
+	}
+	return append(updated, vote), nil
+}
+
+// concludedVote records a vote on a concluded proposal as it always was: the
+// first vote of each author is kept and does not change the outcome. Votes
+// can only be changed or retracted while the proposal is open.
+func concludedVote(hash crypto.Hash, vote actions.Vote, votes []actions.Vote) ([]actions.Vote, error) {
+	if vote.Retract {
+		return votes, ErrProposalConcluded
+	}
+	if err := IsNewValidVote(vote, votes, hash); err != nil {
+		return votes, err
 	}
-	return nil
+	return append(votes, vote), nil
 }
 
 func Authors(majority int, tokens ...crypto.Token) Consensual {
@@ -0,0 +1,91 @@
+package state
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+// membershipState returns a state with a collective of three members and a
+// pending request of a fourth member to join it.
+func membershipState(t *testing.T) (*State, []crypto.Token, crypto.Hash) {
+	t.Helper()
+	s, members := testState(4)
+	s.SetEpoch(1)
+	testCollective(t, s, "votacao", actions.Policy{Majority: 50, SuperMajority: 50}, members[:3]...)
+	request := &actions.RequestMembership{Epoch: 1, Author: members[3], Collective: "votacao", Include: true}
+	incorporate(t, s, request)
+	return s, members, request.Hashed()
+}
+
+// a changed vote replaces the previous one: the approval given by members[0]
+// no longer counts once changed, so the request is rejected instead.
+func TestChangedVote(t *testing.T) {
+	s, members, hash := membershipState(t)
+	incorporate(t, s, vote(s, members[0], hash, true))
+	incorporate(t, s, vote(s, members[0], hash, false))
+	incorporate(t, s, vote(s, members[1], hash, true))
+	if !s.Proposals.Has(hash) {
+		t.Fatal("request concluded with one vote in favor and one against")
+	}
+	incorporate(t, s, vote(s, members[2], hash, false))
+	if s.Proposals.Has(hash) {
+		t.Fatal("request not concluded")
+	}
+	if collective, _ := s.Collective("votacao"); collective.IsMember(members[3]) {
+		t.Error("request approved by a changed vote")
+	}
+	if history := s.VoteHistory[hash]; len(history) != 4 || !history[0].Approve || history[1].Approve {
+		t.Errorf("vote history %+v", history)
+	}
+}
+
+func TestRetractVote(t *testing.T) {
+	s, members, hash := membershipState(t)
+	retract := vote(s, members[0], hash, true)
+	retract.Retract = true
+	data := retract.Serialize()
+	if err := s.Action(data); !errors.Is(err, ErrNoVoteToRetract) {
+		t.Errorf("retraction without a vote: expected %v, got %v", ErrNoVoteToRetract, err)
+	}
+	if len(s.VoteHistory[hash]) != 0 {
+		t.Error("rejected retraction recorded")
+	}
+	// once retracted the vote no longer counts for the consensus
+	incorporate(t, s, vote(s, members[0], hash, true))
+	incorporate(t, s, retract)
+	incorporate(t, s, vote(s, members[1], hash, true))
+	if !s.Proposals.Has(hash) {
+		t.Fatal("request approved by a retracted vote")
+	}
+	incorporate(t, s, vote(s, members[2], hash, true))
+	if collective, _ := s.Collective("votacao"); !collective.IsMember(members[3]) {
+		t.Error("request not approved")
+	}
+	if history := s.VoteHistory[hash]; len(history) != 4 || !history[1].Retract {
+		t.Errorf("vote history %+v", history)
+	}
+}
+
+// once concluded a pending proposal is gone and the late vote is rejected
+// by Validate and Action alike
+func TestVoteOnConcludedProposal(t *testing.T) {
+	s, members, hash := membershipState(t)
+	incorporate(t, s, vote(s, members[0], hash, true))
+	incorporate(t, s, vote(s, members[1], hash, true))
+	data := vote(s, members[2], hash, false).Serialize()
+	if err := s.Validate(data); !errors.Is(err, ErrProposalNotFound) {
+		t.Errorf("Validate returned %v, expected %v", err, ErrProposalNotFound)
+	}
+	if err := s.Action(data); !errors.Is(err, ErrProposalNotFound) {
+		t.Errorf("Action returned %v, expected %v", err, ErrProposalNotFound)
+	}
+	if collective, _ := s.Collective("votacao"); !collective.IsMember(members[3]) {
+		t.Error("outcome changed by a late vote")
+	}
+	if history := s.VoteHistory[hash]; len(history) != 2 {
+		t.Errorf("late vote recorded: %+v", history)
+	}
+}
@@ -87,10 +87,24 @@ func (d *Draft) Consensus() ConsensusState {
 // IncorpoateVote checks if vote scope is correct (hash) if vote was not alrerady
 // cast. If new valid vote returns if the new vote is sufficient for consensus
 func (d *Draft) IncorporateVote(vote actions.Vote, state *State) error {
-	if err := IsNewValidVote(vote, d.Votes, d.DraftHash); err != nil {
+	if d.Aproved {
+		votes, err := concludedVote(d.DraftHash, vote, d.Votes)
+		if err != nil {
+			return err
+		}
+		d.Votes = votes
+		return nil
+	}
+	votes, err := castVote(d.DraftHash, vote, d.Votes)
+	if err != nil {
 		return err
 	}
-	d.Votes = append(d.Votes, vote)
+	d.Votes = votes
+	return d.Evaluate(state)
+}
+
+// Evaluate concludes the proposal if the votes cast reach consensus
+func (d *Draft) Evaluate(state *State) error {
 	if d.Aproved {
 		return nil
 	}
@@ -36,10 +36,24 @@ func (e *Edit) Consensus() ConsensusState {
 }
 
 func (e *Edit) IncorporateVote(vote actions.Vote, state *State) error {
-	if err := IsNewValidVote(vote, e.Votes, e.Edit); err != nil {
+	if e.Approved {
+		votes, err := concludedVote(e.Edit, vote, e.Votes)
+		if err != nil {
+			return err
+		}
+		e.Votes = votes
+		return nil
+	}
+	votes, err := castVote(e.Edit, vote, e.Votes)
+	if err != nil {
 		return err
 	}
-	e.Votes = append(e.Votes, vote)
+	e.Votes = votes
+	return e.Evaluate(state)
+}
+
+// Evaluate concludes the proposal if the votes cast reach consensus
+func (e *Edit) Evaluate(state *State) error {
 	if e.Approved {
 		return nil
 	}
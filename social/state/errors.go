@@ -0,0 +1,68 @@
+package state
+
+import "errors"
+
+// Validation errors returned by Action and Validate. They depend only on the
+// action and on the state, so every node reaches the same verdict and the
+// attorney can tell the author why an action was refused.
+var (
+	ErrInvalidAction = errors.New("could not parse action")
+	ErrUnknownAction = errors.New("unrecognized action")
+
+	// membros
+	ErrNotMember           = errors.New("not a member of synergy")
+	ErrAlreadyMember       = errors.New("already a member")
+	ErrUnknownHandle       = errors.New("not a recognized member of axe")
+	ErrNotCollectiveMember = errors.New("not a member of collective")
+	ErrNotAuthor           = errors.New("not an author")
+	ErrNotEditor           = errors.New("not an editor")
+	ErrNotManager          = errors.New("not a manager of the event")
+	ErrSelfDelegation      = errors.New("cannot delegate to oneself")
+
+	// objetos
+	ErrUnknownCollective = errors.New("unknown collective")
+	ErrUnknownBoard      = errors.New("unknown board")
+	ErrUnknownJournal    = errors.New("unknown journal")
+	ErrUnknownDraft      = errors.New("unknown draft")
+	ErrUnknownRelease    = errors.New("unknown release")
+	ErrUnknownEvent      = errors.New("unknown event")
+	ErrUnknownOccurrence = errors.New("unknown occurrence")
+	ErrUnknownCheckin    = errors.New("unknown checkin")
+	ErrUnknownMedia      = errors.New("unknown media")
+	ErrUnknownComment    = errors.New("unknown comment")
+	ErrCollectiveExists  = errors.New("collective already exists")
+	ErrNameTaken         = errors.New("name already used by a board or journal")
+	ErrHashClaimed       = errors.New("hash already claimed")
+	ErrHashMismatch      = errors.New("content does not match hash")
+	ErrDuplicateProposal = errors.New("proposal already exists")
+
+	// conteudo das acoes
+	ErrInvalidProposalKind = errors.New("invalid proposal kind")
+	ErrInvalidReaction     = errors.New("invalid reaction")
+	ErrInvalidParts        = errors.New("incompatible number of parts")
+	ErrInvalidPatch        = errors.New("patch edits require a txt or md draft")
+	ErrDraftUnavailable    = errors.New("draft content not available")
+	ErrEmptyIssue          = errors.New("empty issue")
+	ErrDuplicateDraft      = errors.New("draft included twice")
+	ErrDraftNotReleased    = errors.New("draft not released")
+	ErrReleaseNotStamped   = errors.New("release without stamp")
+	ErrAlreadyReleased     = errors.New("already released")
+	ErrIssuePublished      = errors.New("issue already published")
+	ErrEmptyComment        = errors.New("empty comment")
+	ErrInvalidTarget       = errors.New("invalid comment target")
+	ErrReplyOtherTarget    = errors.New("reply to comment on another object")
+	ErrDuplicateComment    = errors.New("comment already incorporated")
+	ErrAlreadyPinned       = errors.New("already pinned")
+	ErrNotPinned           = errors.New("not pinned")
+
+	// eventos
+	ErrEventCancelled   = errors.New("event cancelled")
+	ErrAlreadyCheckedIn = errors.New("already checked in")
+	ErrRSVPClosed       = errors.New("rsvp closed")
+	ErrAlreadyGreeted   = errors.New("checkin already greeted")
+
+	// votos
+	ErrVoteCast          = errors.New("vote already cast")
+	ErrNoVoteToRetract   = errors.New("no vote to retract")
+	ErrProposalConcluded = errors.New("proposal already concluded")
+)
@@ -28,13 +28,139 @@ type Event struct {
 	CheckinReasons map[crypto.Token]string
 	Live           bool
 	EventReasons   string
+	Sequence       int    // revisao do evento (iCalendar), incrementada a cada atualizacao ou cancelamento aceito
+	Modified       uint64 // epoch da ultima alteracao aceita
+	Capacity       uint64 // numero maximo de participantes (0 sem limite)
+	RSVPClose      uint64 // epoch a partir do qual nao se aceita check-in (0 sem prazo)
+	Waitlist       []Waiting
+	Recurrence     actions.Recurrence
+	Exceptions     map[uint64]*Occurrence // ocorrencias canceladas ou alteradas
+}
+
+// Occurrence is one date of a recurring event. Occurrences are numbered from 1
+// and keep their number when rescheduled.
+type Occurrence struct {
+	Number       uint64
+	StartAt      time.Time
+	EstimatedEnd time.Time
+	Description  string
+	Venue        string
+	Cancelled    bool
+	Overridden   bool
+}
+
+// scheduled is the occurrence as given by the recurrence rule, without
+// exceptions.
+func (e *Event) scheduled(n uint64) Occurrence {
+	start := e.Recurrence.Start(e.StartAt, n)
+	return Occurrence{
+		Number:       n,
+		StartAt:      start,
+		EstimatedEnd: start.Add(e.EstimatedEnd.Sub(e.StartAt)),
+		Description:  e.Description,
+		Venue:        e.Venue,
+	}
+}
+
+// Occurrence returns the n-th occurrence of the event with its exception, if
+// any.
+func (e *Event) Occurrence(n uint64) (Occurrence, bool) {
+	if n == 0 || n > e.Recurrence.Occurrences() {
+		return Occurrence{}, false
+	}
+	if exception, ok := e.Exceptions[n]; ok {
+		return *exception, true
+	}
+	return e.scheduled(n), true
+}
+
+// Occurrences expands the series of the event. A non-recurring event has a
+// single occurrence.
+func (e *Event) Occurrences() []Occurrence {
+	count := e.Recurrence.Occurrences()
+	occurrences := make([]Occurrence, 0, count)
+	for n := uint64(1); n <= count; n++ {
+		occurrence, _ := e.Occurrence(n)
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences
+}
+
+// ScheduledStart is the start of the n-th occurrence given by the recurrence
+// rule (the RECURRENCE-ID of iCalendar).
+func (e *Event) ScheduledStart(n uint64) time.Time {
+	return e.Recurrence.Start(e.StartAt, n)
+}
+
+func (e *Event) exception(n uint64) *Occurrence {
+	if exception, ok := e.Exceptions[n]; ok {
+		return exception
+	}
+	exception := e.scheduled(n)
+	if e.Exceptions == nil {
+		e.Exceptions = make(map[uint64]*Occurrence)
+	}
+	e.Exceptions[n] = &exception
+	return &exception
+}
+
+// Waiting is a member on the waitlist of a full event, in order of check-in.
+type Waiting struct {
+	Token        crypto.Token
+	EphemeralKey crypto.Token
+}
+
+// Full tells if the checked-in members reached the capacity of the event.
+func (e *Event) Full() bool {
+	return e.Capacity > 0 && uint64(len(e.Checkin)) >= e.Capacity
+}
+
+// RSVPClosed tells if check-ins are no longer accepted at the epoch.
+func (e *Event) RSVPClosed(epoch uint64) bool {
+	return e.RSVPClose > 0 && epoch > e.RSVPClose
+}
+
+// limitsAttendance tells if the event uses a capacity or an RSVP deadline.
+// Only these events refuse check-ins once cancelled, the others accept them
+// as they always did.
+func (e *Event) limitsAttendance() bool {
+	return e.Capacity > 0 || e.RSVPClose > 0
+}
+
+// WaitlistPosition returns the position (starting at 1) of the member on the
+// waitlist or 0 if the member is not waiting.
+func (e *Event) WaitlistPosition(token crypto.Token) int {
+	for n, waiting := range e.Waitlist {
+		if waiting.Token == token {
+			return n + 1
+		}
+	}
+	return 0
+}
+
+// promote checks in members from the waitlist while there are vacant places.
+func (e *Event) promote(state *State) {
+	for len(e.Waitlist) > 0 && !e.Full() {
+		next := e.Waitlist[0]
+		e.Waitlist = e.Waitlist[1:]
+		e.Checkin[next.Token] = &Greeting{Action: nil, EphemeralKey: next.EphemeralKey}
+		if state.index != nil {
+			state.index.AddCheckin(next.Token, e)
+		}
+	}
 }
 
 func (p *Event) IncorporateVote(vote actions.Vote, state *State) error {
-	if err := IsNewValidVote(vote, p.Votes, p.Hash); err != nil {
+	votes, err := castVote(p.Hash, vote, p.Votes)
+	if err != nil {
 		return err
 	}
-	p.Votes = append(p.Votes, vote)
+	p.Votes = votes
+	return p.Evaluate(state)
+}
+
+// Evaluate concludes the proposal if the votes cast reach consensus
+func (p *Event) Evaluate(state *State) error {
 	if p.Live {
 		return nil
 	}
@@ -48,6 +174,7 @@ func (p *Event) IncorporateVote(vote actions.Vote, state *State) error {
 	state.Proposals.Delete(p.Hash)
 	if consensus == Favorable {
 		p.Live = true
+		p.Modified = state.Epoch
 		if state.index != nil {
 			state.index.AddEventToCollective(p, p.Collective)
 		}
@@ -70,6 +197,9 @@ type EventUpdate struct {
 	Open         *bool
 	Public       *bool
 	Managers     *UnamedCollective
+	Capacity     *uint64
+	RSVPClose    *uint64
+	Occurrence   uint64 // 0 altera a serie
 	Votes        []actions.Vote
 	Hash         crypto.Hash
 	Updated      bool
@@ -77,10 +207,16 @@ type EventUpdate struct {
 }
 
 func (p *EventUpdate) IncorporateVote(vote actions.Vote, state *State) error {
-	if err := IsNewValidVote(vote, p.Votes, p.Hash); err != nil {
+	votes, err := castVote(p.Hash, vote, p.Votes)
+	if err != nil {
 		return err
 	}
-	p.Votes = append(p.Votes, vote)
+	p.Votes = votes
+	return p.Evaluate(state)
+}
+
+// Evaluate concludes the proposal if the votes cast reach consensus
+func (p *EventUpdate) Evaluate(state *State) error {
 	if p.Updated {
 		return nil
 	}
@@ -90,13 +226,35 @@ func (p *EventUpdate) IncorporateVote(vote actions.Vote, state *State) error {
 		return nil
 	}
 	// new consensus, update event details
-	state.IndexConsensus(vote.Hash, consensus)
+	state.IndexConsensus(p.Hash, consensus)
 	state.Proposals.Delete(p.Hash)
 	if consensus == Against {
 		return nil
 	}
 	p.Updated = true
+	if event := p.Event; event != nil && p.Occurrence > 0 {
+		// altera apenas a ocorrencia
+		event.Sequence++
+		event.Modified = state.Epoch
+		exception := event.exception(p.Occurrence)
+		exception.Overridden = true
+		if p.StartAt != nil {
+			exception.StartAt = *p.StartAt
+		}
+		if p.EstimatedEnd != nil {
+			exception.EstimatedEnd = *p.EstimatedEnd
+		}
+		if p.Description != nil {
+			exception.Description = *p.Description
+		}
+		if p.Venue != nil {
+			exception.Venue = *p.Venue
+		}
+		return nil
+	}
 	if event := p.Event; event != nil {
+		event.Sequence++
+		event.Modified = state.Epoch
 		if p.StartAt != nil {
 			event.StartAt = *p.StartAt
 		}
@@ -118,23 +276,38 @@ func (p *EventUpdate) IncorporateVote(vote actions.Vote, state *State) error {
 		if p.Managers != nil {
 			event.Managers = p.Managers
 		}
+		if p.RSVPClose != nil {
+			event.RSVPClose = *p.RSVPClose
+		}
+		if p.Capacity != nil {
+			// quem ja confirmou mantem o lugar se a capacidade diminuir
+			event.Capacity = *p.Capacity
+			event.promote(state)
+		}
 		return nil
 	}
-	return errors.New("event not found")
+	return ErrUnknownEvent
 }
 
 type CancelEvent struct {
-	Event   *Event
-	Hash    crypto.Hash
-	Votes   []actions.Vote
-	Reasons string
+	Event      *Event
+	Hash       crypto.Hash
+	Votes      []actions.Vote
+	Reasons    string
+	Occurrence uint64 // 0 cancela a serie
 }
 
 func (p *CancelEvent) IncorporateVote(vote actions.Vote, state *State) error {
-	if err := IsNewValidVote(vote, p.Votes, p.Hash); err != nil {
+	votes, err := castVote(p.Hash, vote, p.Votes)
+	if err != nil {
 		return err
 	}
-	p.Votes = append(p.Votes, vote)
+	p.Votes = votes
+	return p.Evaluate(state)
+}
+
+// Evaluate concludes the proposal if the votes cast reach consensus
+func (p *CancelEvent) Evaluate(state *State) error {
 	if !p.Event.Live {
 		return nil
 	}
@@ -144,9 +317,15 @@ func (p *CancelEvent) IncorporateVote(vote actions.Vote, state *State) error {
 		return nil
 	}
 	// new consensus, update event details
-	state.IndexConsensus(vote.Hash, consensus)
-	if consensus == Favorable {
+	state.IndexConsensus(p.Hash, consensus)
+	if consensus == Favorable && p.Occurrence > 0 {
+		p.Event.exception(p.Occurrence).Cancelled = true
+		p.Event.Sequence++
+		p.Event.Modified = state.Epoch
+	} else if consensus == Favorable {
 		p.Event.Live = false
+		p.Event.Sequence++
+		p.Event.Modified = state.Epoch
 		if state.index != nil {
 			state.index.RemoveEventFromCollective(p.Event, p.Event.Collective)
 		}
@@ -164,11 +343,11 @@ type EventCheckinGreet struct {
 func (p *EventCheckinGreet) IncorporateGreet(greet actions.GreetCheckinEvent, state *State) error {
 
 	if greet.EventHash != p.Hash {
-		return errors.New("invalid hash")
+		return ErrHashMismatch
 	}
 	for _, cast := range p.Greets {
 		if cast.CheckedIn == greet.CheckedIn {
-			return errors.New("checkin already greeted")
+			return ErrAlreadyGreeted
 		}
 	}
 	p.Greets = append(p.Greets, greet)
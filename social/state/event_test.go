@@ -0,0 +1,157 @@
+package state
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+// eventState returns a state with five members and a live event managed by
+// members[0] with the given capacity and RSVP deadline.
+func eventState(t *testing.T, capacity, rsvp uint64) (*State, []crypto.Token, crypto.Hash) {
+	t.Helper()
+	s, members := testState(5)
+	s.SetEpoch(1)
+	testCollective(t, s, "eventos", actions.Policy{Majority: 50, SuperMajority: 50}, members[0])
+	create := &actions.CreateEvent{
+		Epoch:           1,
+		Author:          members[0],
+		OnBehalfOf:      "eventos",
+		StartAt:         time.Date(2023, 6, 1, 19, 0, 0, 0, time.UTC),
+		EstimatedEnd:    time.Date(2023, 6, 1, 21, 0, 0, 0, time.UTC),
+		Description:     "evento",
+		Venue:           "sede",
+		ManagerMajority: 50,
+		Managers:        []crypto.Token{members[0]},
+		Capacity:        capacity,
+		RSVPClose:       rsvp,
+	}
+	incorporate(t, s, create)
+	if event, ok := s.Events[create.Hashed()]; !ok || !event.Live {
+		t.Fatal("event not live")
+	}
+	return s, members, create.Hashed()
+}
+
+func checkin(s *State, author crypto.Token, hash crypto.Hash) *actions.CheckinEvent {
+	return &actions.CheckinEvent{Epoch: s.Epoch, Author: author, EventHash: hash}
+}
+
+func cancelCheckin(s *State, author crypto.Token, hash crypto.Hash) *actions.CancelCheckinEvent {
+	return &actions.CancelCheckinEvent{Epoch: s.Epoch, Author: author, EventHash: hash}
+}
+
+// attendance checks the members checked in and the order of the waitlist
+func attendance(t *testing.T, event *Event, checked []crypto.Token, waiting []crypto.Token) {
+	t.Helper()
+	if len(event.Checkin) != len(checked) {
+		t.Errorf("%v members checked in, expected %v", len(event.Checkin), len(checked))
+	}
+	for _, token := range checked {
+		if _, ok := event.Checkin[token]; !ok {
+			t.Errorf("%v not checked in", token)
+		}
+	}
+	if len(event.Waitlist) != len(waiting) {
+		t.Errorf("%v members waiting, expected %v", len(event.Waitlist), len(waiting))
+	}
+	for n, token := range waiting {
+		if position := event.WaitlistPosition(token); position != n+1 {
+			t.Errorf("%v at position %v on the waitlist, expected %v", token, position, n+1)
+		}
+	}
+}
+
+func TestCheckinWaitlist(t *testing.T) {
+	s, members, hash := eventState(t, 2, 0)
+	event := s.Events[hash]
+	for _, member := range members[1:] {
+		incorporate(t, s, checkin(s, member, hash))
+	}
+	if !event.Full() {
+		t.Error("event not full")
+	}
+	attendance(t, event, members[1:3], members[3:])
+	for _, member := range []crypto.Token{members[1], members[3]} {
+		if err := s.Action(checkin(s, member, hash).Serialize()); !errors.Is(err, ErrAlreadyCheckedIn) {
+			t.Errorf("second check-in: expected %v, got %v", ErrAlreadyCheckedIn, err)
+		}
+	}
+
+	// leaving the waitlist keeps the order of the others
+	incorporate(t, s, cancelCheckin(s, members[3], hash))
+	attendance(t, event, members[1:3], members[4:])
+	// a vacant place goes to the first on the waitlist
+	incorporate(t, s, cancelCheckin(s, members[1], hash))
+	attendance(t, event, []crypto.Token{members[2], members[4]}, nil)
+	if _, ok := event.CheckinReasons[members[1]]; ok {
+		t.Error("reasons of a cancelled check-in kept")
+	}
+	if err := s.Action(cancelCheckin(s, members[1], hash).Serialize()); !errors.Is(err, ErrUnknownCheckin) {
+		t.Errorf("second cancel: expected %v, got %v", ErrUnknownCheckin, err)
+	}
+}
+
+func TestCheckinCapacityChange(t *testing.T) {
+	s, members, hash := eventState(t, 2, 0)
+	event := s.Events[hash]
+	for _, member := range members[1:] {
+		incorporate(t, s, checkin(s, member, hash))
+	}
+	// members checked in keep their place when the capacity is reduced
+	capacity := uint64(1)
+	incorporate(t, s, &actions.UpdateEvent{Epoch: 1, Author: members[0], EventHash: hash, Capacity: &capacity})
+	attendance(t, event, members[1:3], members[3:])
+	incorporate(t, s, cancelCheckin(s, members[1], hash))
+	attendance(t, event, members[2:3], members[3:])
+	// and the waitlist moves when it is increased
+	capacity = 3
+	incorporate(t, s, &actions.UpdateEvent{Epoch: 1, Author: members[0], EventHash: hash, Capacity: &capacity, Reasons: "mais lugares"})
+	attendance(t, event, members[2:], nil)
+}
+
+func TestCheckinRSVPClosed(t *testing.T) {
+	s, members, hash := eventState(t, 0, 3)
+	s.SetEpoch(3)
+	incorporate(t, s, checkin(s, members[1], hash))
+	s.SetEpoch(4)
+	if err := s.Validate(checkin(s, members[2], hash).Serialize()); !errors.Is(err, ErrRSVPClosed) {
+		t.Errorf("Validate returned %v, expected %v", err, ErrRSVPClosed)
+	}
+	if err := s.Action(checkin(s, members[2], hash).Serialize()); !errors.Is(err, ErrRSVPClosed) {
+		t.Errorf("Action returned %v, expected %v", err, ErrRSVPClosed)
+	}
+	attendance(t, s.Events[hash], members[1:2], nil)
+}
+
+// cancelled events refuse check-ins only if they limit the attendance, the
+// others accept them as they always did
+func TestCheckinCancelledEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity uint64
+		rsvp     uint64
+		err      error
+	}{
+		{"without limits", 0, 0, nil},
+		{"with capacity", 10, 0, ErrEventCancelled},
+		{"with RSVP deadline", 0, 100, ErrEventCancelled},
+	}
+	for _, test := range tests {
+		s, members, hash := eventState(t, test.capacity, test.rsvp)
+		incorporate(t, s, &actions.CancelEvent{Epoch: 1, Author: members[0], Hash: hash})
+		if s.Events[hash].Live {
+			t.Fatalf("%v: event not cancelled", test.name)
+		}
+		data := checkin(s, members[1], hash).Serialize()
+		if err := s.Validate(data); !errors.Is(err, test.err) {
+			t.Errorf("%v: Validate returned %v, expected %v", test.name, err, test.err)
+		}
+		if err := s.Action(data); !errors.Is(err, test.err) {
+			t.Errorf("%v: Action returned %v, expected %v", test.name, err, test.err)
+		}
+	}
+}
@@ -8,7 +8,11 @@ import (
 type Indexer interface {
 	AddBoardToCollective(*Board, *Collective)
 	RemoveBoardFromCollective(*Board, *Collective)
+	AddJournalToCollective(*Journal, *Collective)
 	AddStampToCollective(*Stamp, *Collective)
+	AddReleaseToIndex(*Release)
+	AddPinToIndex(*Pin)
+	AddReactionToIndex(*actions.React)
 	AddEventToCollective(*Event, *Collective)
 	RemoveEventFromCollective(*Event, *Collective)
 	IndexConsensus(crypto.Hash, ConsensusState)
@@ -19,5 +23,11 @@ type Indexer interface {
 	AddDraftToIndex(*Draft)
 	AddEditToIndex(*Edit)
 	AddCheckin(crypto.Token, *Event)
+	RemoveCheckin(crypto.Token, *Event)
 	AddMemberToIndex(crypto.Token, string)
+	AddCollectiveToIndex(*Collective)
+	AddCommentToIndex(*actions.Comment)
+	// IndexBlock is called at the end of each block, with every action of the
+	// block incorporated.
+	IndexBlock(epoch uint64)
 }
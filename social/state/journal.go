@@ -0,0 +1,183 @@
+package state
+
+import (
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+// Trata do objeto journal (periodico): um coletivo cria o periodico, os
+// editores propoem numeros com lancamentos que receberam selo e o coletivo
+// aprova cada numero. Os editores seguem o modelo dos editores de board.
+
+type Journal struct {
+	Name        string
+	Description string
+	Keywords    []string
+	Collective  *Collective
+	Editors     *UnamedCollective // editores propoem numeros, quem aprova eh o coletivo
+	Issues      []*Issue          // em ordem de numero
+	Hash        crypto.Hash
+}
+
+// Issue returns the issue with the given number (starting at 1).
+func (j *Journal) Issue(number int) (*Issue, bool) {
+	if number < 1 || number > len(j.Issues) {
+		return nil, false
+	}
+	return j.Issues[number-1], true
+}
+
+// Published reports if the draft is part of an issue of the journal.
+func (j *Journal) Published(draft *Draft) bool {
+	for _, issue := range j.Issues {
+		for _, published := range issue.Drafts {
+			if published == draft {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Issue is a numbered collection of released drafts of a journal. The hash of
+// the issue is the hash of the action that proposed it.
+type Issue struct {
+	Journal     *Journal
+	Number      int // zero enquanto a proposta nao for aprovada
+	Title       string
+	Description string
+	Drafts      []*Draft // sumario, na ordem proposta
+	Epoch       uint64   // epoch da aprovacao
+	Hash        crypto.Hash
+}
+
+// stamped checks that the draft was released and has at least one stamp
+// imprinted.
+func stamped(s *State, hash crypto.Hash) (*Draft, error) {
+	release, ok := s.Releases[hash]
+	if !ok || !release.Released {
+		return nil, ErrDraftNotReleased
+	}
+	for _, stamp := range release.Stamps {
+		if stamp.Imprinted {
+			return release.Draft, nil
+		}
+	}
+	return nil, ErrReleaseNotStamped
+}
+
+type PendingJournal struct {
+	Origin  *actions.CreateJournal
+	Journal *Journal
+	Hash    crypto.Hash
+	Votes   []actions.Vote
+}
+
+func (j *PendingJournal) IncorporateVote(vote actions.Vote, state *State) error {
+	votes, err := castVote(j.Hash, vote, j.Votes)
+	if err != nil {
+		return err
+	}
+	j.Votes = votes
+	return j.Evaluate(state)
+}
+
+// Evaluate concludes the proposal if the votes cast reach consensus
+func (j *PendingJournal) Evaluate(state *State) error {
+	consensus := j.Journal.Collective.Consensus(j.Hash, j.Votes)
+	state.index.IndexActionStatus(j.Hash, consensus)
+	if consensus == Undecided {
+		return nil
+	}
+	state.IndexConsensus(j.Hash, consensus)
+	state.Proposals.Delete(j.Hash)
+	if consensus == Against {
+		return nil
+	}
+	if _, ok := state.Journals[j.Journal.Hash]; ok {
+		return ErrNameTaken
+	}
+	state.Journals[j.Journal.Hash] = j.Journal
+	if state.index != nil {
+		state.index.AddJournalToCollective(j.Journal, j.Journal.Collective)
+	}
+	return nil
+}
+
+type JournalEditor struct {
+	Hash    crypto.Hash
+	Epoch   uint64
+	Journal *Journal
+	Editor  crypto.Token
+	Insert  bool
+	Votes   []actions.Vote
+}
+
+func (e *JournalEditor) IncorporateVote(vote actions.Vote, state *State) error {
+	votes, err := castVote(e.Hash, vote, e.Votes)
+	if err != nil {
+		return err
+	}
+	e.Votes = votes
+	return e.Evaluate(state)
+}
+
+// Evaluate concludes the proposal if the votes cast reach consensus
+func (e *JournalEditor) Evaluate(state *State) error {
+	consensus := e.Journal.Collective.Consensus(e.Hash, e.Votes)
+	state.index.IndexActionStatus(e.Hash, consensus)
+	if consensus == Undecided {
+		return nil
+	}
+	state.IndexConsensus(e.Hash, consensus)
+	state.Proposals.Delete(e.Hash)
+	if consensus == Against {
+		return nil
+	}
+	if e.Insert {
+		e.Journal.Editors.IncludeMember(e.Editor)
+	} else {
+		e.Journal.Editors.RemoveMember(e.Editor)
+	}
+	return nil
+}
+
+type PendingIssue struct {
+	Origin *actions.JournalIssue
+	Issue  *Issue
+	Hash   crypto.Hash
+	Votes  []actions.Vote
+}
+
+func (p *PendingIssue) IncorporateVote(vote actions.Vote, state *State) error {
+	votes, err := castVote(p.Hash, vote, p.Votes)
+	if err != nil {
+		return err
+	}
+	p.Votes = votes
+	return p.Evaluate(state)
+}
+
+// Evaluate concludes the proposal if the votes cast reach consensus. The
+// approved issue gets the next number of the journal.
+func (p *PendingIssue) Evaluate(state *State) error {
+	journal := p.Issue.Journal
+	consensus := journal.Collective.Consensus(p.Hash, p.Votes)
+	state.index.IndexActionStatus(p.Hash, consensus)
+	if consensus == Undecided {
+		return nil
+	}
+	state.IndexConsensus(p.Hash, consensus)
+	state.Proposals.Delete(p.Hash)
+	if consensus == Against {
+		return nil
+	}
+	if _, ok := state.Issues[p.Hash]; ok {
+		return ErrIssuePublished
+	}
+	p.Issue.Number = len(journal.Issues) + 1
+	p.Issue.Epoch = state.Epoch
+	journal.Issues = append(journal.Issues, p.Issue)
+	state.Issues[p.Hash] = p.Issue
+	return nil
+}
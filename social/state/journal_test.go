@@ -0,0 +1,220 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+// journalState returns a state with the collective "revista" of members[0]
+// and members[1], its journal "periodico" with members[0] as editor and
+// three drafts of members[0]: two released and stamped by the collective and
+// one released without a stamp.
+func journalState(t *testing.T) (*State, []crypto.Token, []crypto.Hash) {
+	t.Helper()
+	s, members := testState(3)
+	s.SetEpoch(1)
+	testCollective(t, s, "revista", actions.Policy{Majority: 50, SuperMajority: 50}, members[0], members[1])
+	create := &actions.CreateJournal{Epoch: 1, Author: members[0], OnBehalfOf: "revista", Name: "periodico", Keywords: []string{"periodico"}}
+	incorporate(t, s, create)
+	incorporate(t, s, vote(s, members[1], create.Hashed(), true))
+	drafts := make([]crypto.Hash, 3)
+	for n := range drafts {
+		content := []byte(fmt.Sprintf("artigo %v", n))
+		draft := &actions.Draft{
+			Epoch:         1,
+			Author:        members[0],
+			Title:         fmt.Sprintf("artigo %v", n),
+			Keywords:      []string{"artigo"},
+			ContentType:   "md",
+			ContentHash:   crypto.Hasher(content),
+			NumberOfParts: 1,
+			Content:       content,
+		}
+		incorporate(t, s, draft)
+		incorporate(t, s, &actions.ReleaseDraft{Epoch: 1, Author: members[0], ContentHash: draft.ContentHash})
+		drafts[n] = draft.ContentHash
+	}
+	for _, hash := range drafts[:2] {
+		stamp := &actions.ImprintStamp{Epoch: 1, Author: members[0], OnBehalfOf: "revista", Hash: hash}
+		incorporate(t, s, stamp)
+		incorporate(t, s, vote(s, members[1], stamp.Hashed(), true))
+	}
+	return s, members, drafts
+}
+
+func TestCreateJournal(t *testing.T) {
+	s, members, _ := journalState(t)
+	journal, ok := s.Journal("periodico")
+	if !ok {
+		t.Fatal("journal not created")
+	}
+	if !journal.Editors.IsMember(members[0]) || journal.Editors.IsMember(members[1]) {
+		t.Error("author is not the only editor")
+	}
+	incorporate(t, s, &actions.CreateBoard{Epoch: 1, Author: members[0], OnBehalfOf: "revista", Name: "mural", Keywords: []string{"mural"}, PinMajority: 1})
+	// pending board: the name is not taken yet
+	create := &actions.CreateJournal{Epoch: 1, Author: members[0], OnBehalfOf: "revista", Name: "mural", Keywords: []string{"mural"}}
+	if err := s.Validate(create.Serialize()); err != nil {
+		t.Errorf("journal named after a pending board: %v", err)
+	}
+	for hash := range s.Proposals.CreateBoard {
+		incorporate(t, s, vote(s, members[1], hash, true))
+	}
+	tests := []struct {
+		name   string
+		action actions.Action
+	}{
+		{"journal named after a board", &actions.CreateJournal{Epoch: 1, Author: members[0], OnBehalfOf: "revista", Name: "mural", Keywords: []string{"mural"}}},
+		{"journal named after a journal", &actions.CreateJournal{Epoch: 1, Author: members[1], OnBehalfOf: "revista", Name: "periodico", Keywords: []string{"periodico"}}},
+		{"board named after a journal", &actions.CreateBoard{Epoch: 1, Author: members[0], OnBehalfOf: "revista", Name: "periodico", Keywords: []string{"periodico"}, PinMajority: 1}},
+	}
+	for _, test := range tests {
+		if err := s.Action(test.action.Serialize()); !errors.Is(err, ErrNameTaken) {
+			t.Errorf("%v: expected %v, got %v", test.name, ErrNameTaken, err)
+		}
+	}
+}
+
+func TestJournalEditor(t *testing.T) {
+	s, members, drafts := journalState(t)
+	journal, _ := s.Journal("periodico")
+	issue := &actions.JournalIssue{Epoch: 1, Author: members[2], Journal: "periodico", Title: "numero", Drafts: drafts[:1]}
+	if err := s.Action(issue.Serialize()); !errors.Is(err, ErrNotEditor) {
+		t.Errorf("issue by a member that is not an editor: expected %v, got %v", ErrNotEditor, err)
+	}
+	editor := &actions.JournalEditor{Epoch: 1, Author: members[0], Journal: "periodico", Editor: members[2], Insert: true}
+	incorporate(t, s, editor)
+	if journal.Editors.IsMember(members[2]) {
+		t.Fatal("editor included without the consent of the collective")
+	}
+	incorporate(t, s, vote(s, members[1], editor.Hashed(), true))
+	if !journal.Editors.IsMember(members[2]) {
+		t.Fatal("editor not included")
+	}
+	// editors propose issues but are not members of the collective
+	incorporate(t, s, issue)
+	if err := s.Action(vote(s, members[2], issue.Hashed(), true).Serialize()); err != nil {
+		t.Fatalf("could not vote: %v", err)
+	}
+	if !s.Proposals.Has(issue.Hashed()) {
+		t.Error("issue approved by the editor")
+	}
+}
+
+func TestJournalIssues(t *testing.T) {
+	s, members, drafts := journalState(t)
+	journal, _ := s.Journal("periodico")
+	first := &actions.JournalIssue{Epoch: 1, Author: members[0], Journal: "periodico", Title: "primeiro", Drafts: []crypto.Hash{drafts[1], drafts[0]}}
+	second := &actions.JournalIssue{Epoch: 1, Author: members[0], Journal: "periodico", Title: "segundo", Drafts: drafts[:1]}
+	incorporate(t, s, first)
+	incorporate(t, s, second)
+	// numbered in the order of approval
+	s.SetEpoch(2)
+	incorporate(t, s, vote(s, members[1], second.Hashed(), true))
+	s.SetEpoch(3)
+	incorporate(t, s, vote(s, members[1], first.Hashed(), true))
+	if len(journal.Issues) != 2 {
+		t.Fatalf("%v issues, expected 2", len(journal.Issues))
+	}
+	for n, expected := range []*actions.JournalIssue{second, first} {
+		issue, ok := journal.Issue(n + 1)
+		if !ok || issue.Hash != expected.Hashed() || issue.Number != n+1 || issue.Epoch != uint64(n+2) || s.Issues[issue.Hash] != issue {
+			t.Errorf("issue %v: %+v", n+1, issue)
+		}
+	}
+	if issue, _ := journal.Issue(2); issue.Drafts[0] != s.Drafts[drafts[1]] || issue.Drafts[1] != s.Drafts[drafts[0]] {
+		t.Error("table of contents out of order")
+	}
+	if !journal.Published(s.Drafts[drafts[0]]) || journal.Published(s.Drafts[drafts[2]]) {
+		t.Error("published drafts")
+	}
+}
+
+func TestJournalIssueRejected(t *testing.T) {
+	s, members, drafts := journalState(t)
+	content := []byte("nao lancado")
+	unreleased := &actions.Draft{
+		Epoch:         1,
+		Author:        members[0],
+		Title:         "nao lancado",
+		Keywords:      []string{"artigo"},
+		ContentType:   "md",
+		ContentHash:   crypto.Hasher(content),
+		NumberOfParts: 1,
+		Content:       content,
+	}
+	incorporate(t, s, unreleased)
+	pending := &actions.JournalIssue{Epoch: 1, Author: members[0], Journal: "periodico", Title: "pendente", Drafts: drafts[:1]}
+	incorporate(t, s, pending)
+	tests := []struct {
+		name   string
+		drafts []crypto.Hash
+		err    error
+	}{
+		{"no drafts", nil, ErrEmptyIssue},
+		{"unstamped release", []crypto.Hash{drafts[0], drafts[2]}, ErrReleaseNotStamped},
+		{"unreleased draft", []crypto.Hash{unreleased.ContentHash}, ErrDraftNotReleased},
+		{"duplicate draft", []crypto.Hash{drafts[0], drafts[1], drafts[0]}, ErrDuplicateDraft},
+		{"duplicate proposal", drafts[:1], ErrDuplicateProposal},
+	}
+	for _, test := range tests {
+		issue := &actions.JournalIssue{Epoch: 1, Author: members[0], Journal: "periodico", Title: "pendente", Drafts: test.drafts}
+		data := issue.Serialize()
+		if err := s.Validate(data); !errors.Is(err, test.err) {
+			t.Errorf("%v: Validate returned %v, expected %v", test.name, err, test.err)
+		}
+		if err := s.Action(data); !errors.Is(err, test.err) {
+			t.Errorf("%v: Action returned %v, expected %v", test.name, err, test.err)
+		}
+	}
+	unknown := &actions.JournalIssue{Epoch: 1, Author: members[0], Journal: "desconhecido", Title: "numero", Drafts: drafts[:1]}
+	if err := s.Action(unknown.Serialize()); !errors.Is(err, ErrUnknownJournal) {
+		t.Errorf("unknown journal: expected %v, got %v", ErrUnknownJournal, err)
+	}
+}
+
+func TestJournalSnapshot(t *testing.T) {
+	s, members, drafts := journalState(t)
+	published := &actions.JournalIssue{Epoch: 1, Author: members[0], Journal: "periodico", Title: "publicado", Drafts: drafts[:2]}
+	incorporate(t, s, published)
+	incorporate(t, s, vote(s, members[1], published.Hashed(), true))
+	incorporate(t, s, &actions.JournalIssue{Epoch: 1, Author: members[0], Journal: "periodico", Title: "pendente", Drafts: drafts[1:2]})
+	incorporate(t, s, &actions.JournalEditor{Epoch: 1, Author: members[0], Journal: "periodico", Editor: members[2], Insert: true})
+	if len(s.Issues) != 1 || len(s.Proposals.JournalIssue) != 1 || len(s.Proposals.JournalEditor) != 1 {
+		t.Fatalf("unexpected state before snapshot: %v issues, %v pending issues", len(s.Issues), len(s.Proposals.JournalIssue))
+	}
+	restored, err := ParseSnapshot(s.Snapshot(), testIndexer{})
+	if err != nil {
+		t.Fatalf("could not parse snapshot: %v", err)
+	}
+	fields := []struct {
+		name              string
+		original, restore any
+	}{
+		{"Journals", s.Journals, restored.Journals},
+		{"Issues", s.Issues, restored.Issues},
+		{"pending issues", s.Proposals.JournalIssue, restored.Proposals.JournalIssue},
+		{"pending editors", s.Proposals.JournalEditor, restored.Proposals.JournalEditor},
+	}
+	for _, field := range fields {
+		if !reflect.DeepEqual(field.original, field.restore) {
+			t.Errorf("%v not restored:\n%+v\n%+v", field.name, field.original, field.restore)
+		}
+	}
+	// the restored objects are shared as in the original state
+	journal, _ := restored.Journal("periodico")
+	issue, _ := journal.Issue(1)
+	if restored.Issues[published.Hashed()] != issue || issue.Journal != journal || issue.Drafts[0] != restored.Drafts[drafts[0]] {
+		t.Error("restored issue not shared with its journal and drafts")
+	}
+	for _, pending := range restored.Proposals.JournalIssue {
+		if pending.Issue.Journal != journal {
+			t.Error("pending issue not bound to the restored journal")
+		}
+	}
+}
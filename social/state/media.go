@@ -1,36 +1,57 @@
 package state
 
 import (
-	"errors"
-
 	"github.com/freehandle/breeze/crypto"
 	"github.com/freehandle/synergy/social/actions"
 )
 
+// PendingMedia keeps track of the parts of a multipart media already received.
+// The content of the parts is kept on the MediaStore (see partKey), Parts
+// only hold their metadata.
 type PendingMedia struct {
 	Hash          crypto.Hash
 	NumberOfParts byte
 	Parts         []*actions.MultipartMedia
 }
 
-func (p *PendingMedia) Append(m *actions.MultipartMedia) ([]byte, error) {
+// Append stores the part and, if all parts were received, returns the entire
+// content. Returns nil if parts are still missing.
+func (p *PendingMedia) Append(m *actions.MultipartMedia, store MediaStore) ([]byte, error) {
 	if m.Of != p.NumberOfParts || m.Part > m.Of-1 {
-		return nil, errors.New("incompatible number of parts")
+		return nil, ErrInvalidParts
+	}
+	if err := store.Put(partKey(p.Hash, m.Part), m.Data); err != nil {
+		return nil, err
+	}
+	p.Parts[m.Part] = &actions.MultipartMedia{
+		Epoch:  m.Epoch,
+		Author: m.Author,
+		Hash:   m.Hash,
+		Part:   m.Part,
+		Of:     m.Of,
 	}
-	p.Parts[m.Part] = m
-	size := 0
 	for _, part := range p.Parts {
 		if part == nil {
 			return nil, nil
 		}
-		size += len(m.Data)
 	}
-	concanate := make([]byte, 0, size)
-	for _, part := range p.Parts {
-		concanate = append(concanate, part.Data...)
+	concanate := make([]byte, 0)
+	for n := range p.Parts {
+		data, err := store.Get(partKey(p.Hash, byte(n)))
+		if err != nil {
+			return nil, err
+		}
+		concanate = append(concanate, data...)
 	}
 	if crypto.Hasher(concanate) != p.Hash {
-		return nil, errors.New("incompatible hash")
+		return nil, ErrHashMismatch
 	}
 	return concanate, nil
 }
+
+// Discard removes the parts from the store
+func (p *PendingMedia) Discard(store MediaStore) {
+	for n := range p.Parts {
+		store.Delete(partKey(p.Hash, byte(n)))
+	}
+}
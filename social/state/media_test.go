@@ -0,0 +1,56 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+// the parts are joined on the store and must match the hash of the content
+func TestPendingMedia(t *testing.T) {
+	content := []byte("primeira parte, segunda parte")
+	hash := crypto.Hasher(content)
+	parts := [][]byte{content[:15], content[15:]}
+	tests := []struct {
+		name  string
+		parts [][]byte
+		err   error
+	}{
+		{"valid parts", parts, nil},
+		{"corrupted part", [][]byte{parts[0], []byte("outra parte")}, ErrHashMismatch},
+	}
+	for name, store := range testStores(t) {
+		for _, test := range tests {
+			pending := &PendingMedia{Hash: hash, NumberOfParts: 2, Parts: make([]*actions.MultipartMedia, 2)}
+			// parts arrive in any order
+			second := &actions.MultipartMedia{Hash: hash, Part: 1, Of: 2, Data: test.parts[1]}
+			if data, err := pending.Append(second, store); data != nil || err != nil {
+				t.Errorf("%v %v: incomplete media returned %q, %v", name, test.name, data, err)
+			}
+			first := &actions.MultipartMedia{Hash: hash, Part: 0, Of: 2, Data: test.parts[0]}
+			data, err := pending.Append(first, store)
+			if !errors.Is(err, test.err) {
+				t.Errorf("%v %v: expected %v, got %v", name, test.name, test.err, err)
+			}
+			if err == nil && !bytes.Equal(data, content) {
+				t.Errorf("%v %v: got %q", name, test.name, data)
+			}
+			pending.Discard(store)
+			for n := range pending.Parts {
+				if store.Has(partKey(hash, byte(n))) {
+					t.Errorf("%v %v: part %v not discarded", name, test.name, n)
+				}
+			}
+		}
+		pending := &PendingMedia{Hash: hash, NumberOfParts: 2, Parts: make([]*actions.MultipartMedia, 2)}
+		if _, err := pending.Append(&actions.MultipartMedia{Hash: hash, Part: 2, Of: 2}, store); !errors.Is(err, ErrInvalidParts) {
+			t.Errorf("%v: part out of range returned %v", name, err)
+		}
+		if _, err := pending.Append(&actions.MultipartMedia{Hash: hash, Part: 0, Of: 3}, store); !errors.Is(err, ErrInvalidParts) {
+			t.Errorf("%v: part of another count returned %v", name, err)
+		}
+	}
+}
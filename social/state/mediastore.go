@@ -0,0 +1,273 @@
+package state
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+/*
+MediaStore keeps the content of media files (drafts, edits and the parts of
+multipart uploads still pending) out of the State. Content is addressed by
+its hash: the State only records which hashes were incorporated, the bytes
+live on the store.
+
+NewDiskMediaStore keeps each file on its own file on disk, so that an
+instance can hold large amounts of media without holding them in memory.
+NewMemoryMediaStore is meant for tests and ephemeral instances.
+*/
+
+var ErrMediaNotFound = errors.New("media not found")
+
+var ErrMediaRange = errors.New("invalid media range")
+
+type MediaStore interface {
+	// Put stores data under hash. Storing an existing hash is a no-op.
+	Put(hash crypto.Hash, data []byte) error
+	// Get returns the entire content.
+	Get(hash crypto.Hash) ([]byte, error)
+	Has(hash crypto.Hash) bool
+	// Stat returns the size in bytes of the content.
+	Stat(hash crypto.Hash) (int64, error)
+	// Range returns length bytes of the content starting at offset. Reads
+	// past the end of the content are truncated.
+	Range(hash crypto.Hash, offset, length int64) ([]byte, error)
+	Delete(hash crypto.Hash) error
+}
+
+// MemoryMediaStore is a MediaStore backed by a map.
+type MemoryMediaStore struct {
+	mu    sync.RWMutex
+	media map[crypto.Hash][]byte
+}
+
+func NewMemoryMediaStore() *MemoryMediaStore {
+	return &MemoryMediaStore{media: make(map[crypto.Hash][]byte)}
+}
+
+func (m *MemoryMediaStore) Put(hash crypto.Hash, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.media[hash]; !ok {
+		m.media[hash] = append([]byte{}, data...)
+	}
+	return nil
+}
+
+func (m *MemoryMediaStore) Get(hash crypto.Hash) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.media[hash]
+	if !ok {
+		return nil, ErrMediaNotFound
+	}
+	return data, nil
+}
+
+func (m *MemoryMediaStore) Has(hash crypto.Hash) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.media[hash]
+	return ok
+}
+
+func (m *MemoryMediaStore) Stat(hash crypto.Hash) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.media[hash]
+	if !ok {
+		return 0, ErrMediaNotFound
+	}
+	return int64(len(data)), nil
+}
+
+func (m *MemoryMediaStore) Range(hash crypto.Hash, offset, length int64) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.media[hash]
+	if !ok {
+		return nil, ErrMediaNotFound
+	}
+	if offset < 0 || length < 0 || offset > int64(len(data)) {
+		return nil, ErrMediaRange
+	}
+	end := offset + length
+	if end > int64(len(data)) {
+		end = int64(len(data))
+	}
+	return data[offset:end], nil
+}
+
+func (m *MemoryMediaStore) Delete(hash crypto.Hash) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.media, hash)
+	return nil
+}
+
+// DiskMediaStore is a content addressed MediaStore on a directory. Each file
+// is kept at dir/xx/hash where xx are the first two hex digits of the hash.
+type DiskMediaStore struct {
+	dir string
+}
+
+func NewDiskMediaStore(dir string) (*DiskMediaStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DiskMediaStore{dir: dir}, nil
+}
+
+func (d *DiskMediaStore) path(hash crypto.Hash) string {
+	name := hex.EncodeToString(hash[:])
+	return filepath.Join(d.dir, name[:2], name)
+}
+
+// Put writes on a temporary file that is renamed in place, so that a crash
+// never leaves a truncated file under a valid hash.
+func (d *DiskMediaStore) Put(hash crypto.Hash, data []byte) error {
+	path := d.path(hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), "tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func (d *DiskMediaStore) Get(hash crypto.Hash) ([]byte, error) {
+	data, err := os.ReadFile(d.path(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrMediaNotFound
+	}
+	return data, err
+}
+
+func (d *DiskMediaStore) Has(hash crypto.Hash) bool {
+	_, err := os.Stat(d.path(hash))
+	return err == nil
+}
+
+func (d *DiskMediaStore) Stat(hash crypto.Hash) (int64, error) {
+	info, err := os.Stat(d.path(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, ErrMediaNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (d *DiskMediaStore) Range(hash crypto.Hash, offset, length int64) ([]byte, error) {
+	file, err := os.Open(d.path(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrMediaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 || length < 0 || offset > info.Size() {
+		return nil, ErrMediaRange
+	}
+	if offset+length > info.Size() {
+		length = info.Size() - offset
+	}
+	data := make([]byte, length)
+	if _, err := file.ReadAt(data, offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (d *DiskMediaStore) Delete(hash crypto.Hash) error {
+	err := os.Remove(d.path(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// MediaReader is an io.ReadSeeker over a media on a MediaStore, reading only
+// the requested ranges (to be used with http.ServeContent).
+type MediaReader struct {
+	store  MediaStore
+	hash   crypto.Hash
+	size   int64
+	offset int64
+}
+
+func NewMediaReader(store MediaStore, hash crypto.Hash) (*MediaReader, error) {
+	size, err := store.Stat(hash)
+	if err != nil {
+		return nil, err
+	}
+	return &MediaReader{store: store, hash: hash, size: size}, nil
+}
+
+func (r *MediaReader) Size() int64 {
+	return r.size
+}
+
+func (r *MediaReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	data, err := r.store.Range(r.hash, r.offset, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, data)
+	r.offset += int64(n)
+	return n, nil
+}
+
+func (r *MediaReader) Seek(offset int64, whence int) (int64, error) {
+	var position int64
+	switch whence {
+	case io.SeekStart:
+		position = offset
+	case io.SeekCurrent:
+		position = r.offset + offset
+	case io.SeekEnd:
+		position = r.size + offset
+	default:
+		return 0, fmt.Errorf("invalid whence %v", whence)
+	}
+	if position < 0 {
+		return 0, ErrMediaRange
+	}
+	r.offset = position
+	return position, nil
+}
+
+// parts of pending multipart media are kept on the store under a key derived
+// from the hash of the entire content and the part number
+func partKey(hash crypto.Hash, part byte) crypto.Hash {
+	return crypto.Hasher(append(append([]byte{}, hash[:]...), part))
+}
@@ -0,0 +1,161 @@
+package state
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+
+	"github.com/freehandle/breeze/crypto"
+)
+
+// testStores returns an empty store of each kind
+func testStores(t *testing.T) map[string]MediaStore {
+	t.Helper()
+	disk, err := NewDiskMediaStore(filepath.Join(t.TempDir(), "media"))
+	if err != nil {
+		t.Fatalf("could not create disk store: %v", err)
+	}
+	return map[string]MediaStore{"memory": NewMemoryMediaStore(), "disk": disk}
+}
+
+func TestMediaStore(t *testing.T) {
+	content := []byte("conteudo do media")
+	hash := crypto.Hasher(content)
+	missing := crypto.Hasher([]byte("ausente"))
+	for name, store := range testStores(t) {
+		if err := store.Put(hash, content); err != nil {
+			t.Fatalf("%v: could not put: %v", name, err)
+		}
+		// content addressed: the first content stored under a hash stays
+		if err := store.Put(hash, []byte("outro conteudo")); err != nil {
+			t.Errorf("%v: put of an existing hash: %v", name, err)
+		}
+		if data, err := store.Get(hash); err != nil || !bytes.Equal(data, content) {
+			t.Errorf("%v: get returned %q, %v", name, data, err)
+		}
+		if !store.Has(hash) || store.Has(missing) {
+			t.Errorf("%v: has", name)
+		}
+		if size, err := store.Stat(hash); err != nil || size != int64(len(content)) {
+			t.Errorf("%v: stat returned %v, %v", name, size, err)
+		}
+		if _, err := store.Get(missing); !errors.Is(err, ErrMediaNotFound) {
+			t.Errorf("%v: get of a missing hash returned %v", name, err)
+		}
+		if _, err := store.Stat(missing); !errors.Is(err, ErrMediaNotFound) {
+			t.Errorf("%v: stat of a missing hash returned %v", name, err)
+		}
+		if _, err := store.Range(missing, 0, 1); !errors.Is(err, ErrMediaNotFound) {
+			t.Errorf("%v: range of a missing hash returned %v", name, err)
+		}
+		if err := store.Delete(hash); err != nil {
+			t.Errorf("%v: could not delete: %v", name, err)
+		}
+		if store.Has(hash) {
+			t.Errorf("%v: media not deleted", name)
+		}
+		if err := store.Delete(hash); err != nil {
+			t.Errorf("%v: delete of a missing hash: %v", name, err)
+		}
+	}
+}
+
+func TestMediaStoreRange(t *testing.T) {
+	content := []byte("0123456789")
+	hash := crypto.Hasher(content)
+	tests := []struct {
+		name           string
+		offset, length int64
+		expected       string
+		err            error
+	}{
+		{"start", 0, 4, "0123", nil},
+		{"middle", 3, 4, "3456", nil},
+		{"truncated at the end", 8, 10, "89", nil},
+		{"empty at the end", 10, 5, "", nil},
+		{"offset past the end", 11, 1, "", ErrMediaRange},
+		{"negative offset", -1, 2, "", ErrMediaRange},
+		{"negative length", 0, -1, "", ErrMediaRange},
+	}
+	for name, store := range testStores(t) {
+		store.Put(hash, content)
+		for _, test := range tests {
+			data, err := store.Range(hash, test.offset, test.length)
+			if !errors.Is(err, test.err) {
+				t.Errorf("%v %v: expected %v, got %v", name, test.name, test.err, err)
+			}
+			if err == nil && string(data) != test.expected {
+				t.Errorf("%v %v: got %q, expected %q", name, test.name, data, test.expected)
+			}
+		}
+	}
+}
+
+func TestDiskMediaStoreLayout(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewDiskMediaStore(dir)
+	if err != nil {
+		t.Fatalf("could not create disk store: %v", err)
+	}
+	content := []byte("no disco")
+	hash := crypto.Hasher(content)
+	store.Put(hash, content)
+	name := hex.EncodeToString(hash[:])
+	data, err := os.ReadFile(filepath.Join(dir, name[:2], name))
+	if err != nil || !bytes.Equal(data, content) {
+		t.Fatalf("media not at its content address: %q, %v", data, err)
+	}
+	// no temporary file is left behind
+	files, _ := os.ReadDir(filepath.Join(dir, name[:2]))
+	if len(files) != 1 {
+		t.Errorf("%v files on the directory", len(files))
+	}
+	// another instance on the same directory sees the media
+	reopened, _ := NewDiskMediaStore(dir)
+	if !reopened.Has(hash) {
+		t.Error("media not found after reopening the store")
+	}
+}
+
+func TestMediaReader(t *testing.T) {
+	content := bytes.Repeat([]byte("synergy "), 100)
+	hash := crypto.Hasher(content)
+	for name, store := range testStores(t) {
+		store.Put(hash, content)
+		reader, err := NewMediaReader(store, hash)
+		if err != nil {
+			t.Fatalf("%v: could not open reader: %v", name, err)
+		}
+		if reader.Size() != int64(len(content)) {
+			t.Errorf("%v: size %v", name, reader.Size())
+		}
+		if err := iotest.TestReader(reader, content); err != nil {
+			t.Errorf("%v: %v", name, err)
+		}
+		if position, err := reader.Seek(-4, io.SeekEnd); err != nil || position != int64(len(content))-4 {
+			t.Errorf("%v: seek from the end returned %v, %v", name, position, err)
+		}
+		if data, err := io.ReadAll(reader); err != nil || string(data) != "rgy " {
+			t.Errorf("%v: read after seek returned %q, %v", name, data, err)
+		}
+		// past the end reads nothing
+		reader.Seek(10, io.SeekEnd)
+		if n, err := reader.Read(make([]byte, 4)); n != 0 || err != io.EOF {
+			t.Errorf("%v: read past the end returned %v, %v", name, n, err)
+		}
+		if _, err := reader.Seek(-1, io.SeekStart); !errors.Is(err, ErrMediaRange) {
+			t.Errorf("%v: negative seek returned %v", name, err)
+		}
+		if _, err := reader.Seek(0, 5); err == nil {
+			t.Errorf("%v: invalid whence accepted", name)
+		}
+		if _, err := NewMediaReader(store, crypto.Hasher([]byte("ausente"))); !errors.Is(err, ErrMediaNotFound) {
+			t.Errorf("%v: reader of a missing media returned %v", name, err)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package state
 
-import "github.com/freehandle/breeze/crypto"
+import (
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
 
 /*
 Notify implements an interface to send notification messages through a
@@ -28,6 +31,8 @@ const (
 	AcceptProposal
 	SigninAction
 	MediaUpload
+	EventAction
+	CommentAction
 )
 
 type Object byte
@@ -56,3 +61,95 @@ type Notifier chan Updated
 func (n Notifier) Notify(origin Action, affects Object, id crypto.Hash) {
 	n <- Updated{Action: origin, Object: affects, Hash: id}
 }
+
+func hashName(name string) crypto.Hash {
+	return crypto.Hasher([]byte(name))
+}
+
+// notificationTargets classifica a acao e lista os objetos afetados por ela. O
+// autor da acao e sempre notificado.
+func notificationTargets(action actions.Action) (Action, []crypto.Hash) {
+	targets := []crypto.Hash{crypto.HashToken(action.Authored())}
+	switch v := action.(type) {
+	case *actions.Vote:
+		return VoteAction, append(targets, v.Hash)
+	case *actions.CreateCollective:
+		return CollectiveAction, append(targets, hashName(v.Name))
+	case *actions.UpdateCollective:
+		return CollectiveAction, append(targets, hashName(v.OnBehalfOf))
+	case *actions.RequestMembership:
+		return CollectiveAction, append(targets, hashName(v.Collective))
+	case *actions.RemoveMember:
+		return CollectiveAction, append(targets, hashName(v.OnBehalfOf), crypto.HashToken(v.Member))
+	case *actions.Draft:
+		targets = append(targets, v.ContentHash)
+		if v.OnBehalfOf != "" {
+			targets = append(targets, hashName(v.OnBehalfOf))
+		}
+		if v.PreviousDraft != crypto.ZeroHash {
+			targets = append(targets, v.PreviousDraft)
+		}
+		return DraftAction, targets
+	case *actions.Edit:
+		return EditAction, append(targets, v.ContentHash, v.EditedDraft)
+	case *actions.MultipartMedia:
+		return MediaUpload, append(targets, v.Hash)
+	case *actions.CreateBoard:
+		return BoardAction, append(targets, hashName(v.Name), hashName(v.OnBehalfOf))
+	case *actions.UpdateBoard:
+		return BoardAction, append(targets, hashName(v.Board))
+	case *actions.Pin:
+		return PinAction, append(targets, hashName(v.Board), v.Draft)
+	case *actions.BoardEditor:
+		return BoardAction, append(targets, hashName(v.Board), crypto.HashToken(v.Editor))
+	case *actions.ReleaseDraft:
+		return PublishAction, append(targets, v.ContentHash)
+	case *actions.ImprintStamp:
+		return PublishAction, append(targets, v.Hash, hashName(v.OnBehalfOf))
+	case *actions.React:
+		return ReactAction, append(targets, v.Hash)
+	case *actions.CreateEvent:
+		return EventAction, append(targets, v.Hashed(), hashName(v.OnBehalfOf))
+	case *actions.CancelEvent:
+		return EventAction, append(targets, v.Hash)
+	case *actions.UpdateEvent:
+		return EventAction, append(targets, v.EventHash)
+	case *actions.CheckinEvent:
+		return EventAction, append(targets, v.EventHash)
+	case *actions.CancelCheckinEvent:
+		return EventAction, append(targets, v.EventHash)
+	case *actions.GreetCheckinEvent:
+		return EventAction, append(targets, v.EventHash, crypto.HashToken(v.CheckedIn))
+	case *actions.CreateJournal:
+		return JournalAction, append(targets, hashName(v.Name), hashName(v.OnBehalfOf))
+	case *actions.JournalEditor:
+		return JournalAction, append(targets, hashName(v.Journal), crypto.HashToken(v.Editor))
+	case *actions.JournalIssue:
+		return JournalAction, append(targets, v.Hashed(), hashName(v.Journal))
+	case *actions.Delegate:
+		return CollectiveAction, append(targets, hashName(v.Collective), crypto.HashToken(v.Delegate))
+	case *actions.Comment:
+		return CommentAction, append(targets, v.Target)
+	case *actions.Signin:
+		return SigninAction, targets
+	}
+	return 0, nil
+}
+
+// notifica todos os objetos afetados por uma acao ja incorporada ao estado
+func (s *State) notifyAction(data []byte) {
+	action := actions.ParseAction(data)
+	if action == nil {
+		return
+	}
+	origin, targets := notificationTargets(action)
+	// a resposta tambem notifica o autor do comentario respondido
+	if comment, ok := action.(*actions.Comment); ok {
+		if reply, ok := s.Comments[comment.ReplyTo]; ok {
+			targets = append(targets, crypto.HashToken(reply.Author))
+		}
+	}
+	for _, hash := range targets {
+		s.Notify(origin, hash)
+	}
+}
@@ -0,0 +1,60 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+// notified incorporates the action and returns the hashes notified
+func notified(t *testing.T, s *State, notifier Notifier, action actions.Action) (Action, []crypto.Hash) {
+	t.Helper()
+	incorporate(t, s, action)
+	var origin Action
+	hashes := make([]crypto.Hash, 0)
+	for {
+		select {
+		case update := <-notifier:
+			origin = update.Action
+			hashes = append(hashes, update.Hash)
+		default:
+			return origin, hashes
+		}
+	}
+}
+
+func TestNotifyTargets(t *testing.T) {
+	s, members, draft := validateState(t)
+	collective, _ := s.Collective("validacao")
+	collective.IncludeMember(members[1])
+	notifier := make(Notifier, 16)
+	s.SetNotifier(notifier)
+	comment := &actions.Comment{Epoch: 1, Author: members[1], Target: draft.ContentHash, Body: "comentario"}
+	reply := &actions.Comment{Epoch: 1, Author: members[0], Target: draft.ContentHash, ReplyTo: comment.Hashed(), Body: "resposta"}
+	tests := []struct {
+		name    string
+		action  actions.Action
+		origin  Action
+		targets []crypto.Hash
+	}{
+		{"comment", comment, CommentAction, []crypto.Hash{crypto.HashToken(members[1]), draft.ContentHash}},
+		{"reply", reply, CommentAction, []crypto.Hash{crypto.HashToken(members[0]), draft.ContentHash, crypto.HashToken(members[1])}},
+		{
+			"delegation",
+			&actions.Delegate{Epoch: 1, Author: members[0], Collective: "validacao", Delegate: members[1]},
+			CollectiveAction,
+			[]crypto.Hash{crypto.HashToken(members[0]), crypto.Hasher([]byte("validacao")), crypto.HashToken(members[1])},
+		},
+	}
+	for _, test := range tests {
+		origin, targets := notified(t, s, notifier, test.action)
+		if origin != test.origin {
+			t.Errorf("%v: notified as %v", test.name, origin)
+		}
+		if !reflect.DeepEqual(targets, test.targets) {
+			t.Errorf("%v: notified %v, expected %v", test.name, targets, test.targets)
+		}
+	}
+}
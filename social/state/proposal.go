@@ -26,6 +26,9 @@ const (
 	CancelEventProposal
 	UpdateEventProposal
 	EventCheckinGreetProposal
+	CreateJournalProposal
+	JournalEditorProposal
+	JournalIssueProposal
 	UnkownProposal
 )
 
@@ -45,6 +48,10 @@ var proposalNames = []string{
 	"Create Event",
 	"Cancel Event",
 	"Update Event",
+	"Greet Checkin",
+	"Create Journal",
+	"Journal Editor",
+	"Journal Issue",
 	"Unkown",
 }
 
@@ -52,12 +59,16 @@ var ErrProposalNotFound = errors.New("proposal not found")
 
 type Proposal interface {
 	IncorporateVote(vote actions.Vote, state *State) error
+	// Evaluate concludes the proposal with the votes already cast
+	Evaluate(state *State) error
 }
 
 func NewProposals(i Indexer) *Proposals {
 	return &Proposals{
 		mu:         &sync.Mutex{},
 		all:        make(map[crypto.Hash]byte),
+		reasons:    make(map[crypto.Hash]actions.Action),
+		deadlines:  make(map[crypto.Hash]uint64),
 		stateIndex: i,
 		//index:             make(map[crypto.Token]*SetOfHashes),
 		UpdateCollective:  make(map[crypto.Hash]*PendingUpdate),
@@ -76,6 +87,10 @@ func NewProposals(i Indexer) *Proposals {
 		CancelEvent:  make(map[crypto.Hash]*CancelEvent),
 		UpdateEvent:  make(map[crypto.Hash]*EventUpdate),
 		GreetCheckin: make(map[crypto.Hash]*EventCheckinGreet),
+		// periodicos
+		CreateJournal: make(map[crypto.Hash]*PendingJournal),
+		JournalEditor: make(map[crypto.Hash]*JournalEditor),
+		JournalIssue:  make(map[crypto.Hash]*PendingIssue),
 	}
 }
 
@@ -111,7 +126,9 @@ func (s *SetOfHashes) All() map[crypto.Hash]struct{} {
 
 type Proposals struct {
 	mu         *sync.Mutex
-	all        map[crypto.Hash]byte // hash do que ta pendente pro tipo de proposal
+	all        map[crypto.Hash]byte           // hash do que ta pendente pro tipo de proposal
+	reasons    map[crypto.Hash]actions.Action // acao que originou a proposal
+	deadlines  map[crypto.Hash]uint64         // epoch em que a proposal expira
 	stateIndex Indexer
 	//index             map[crypto.Token]*SetOfHashes // token do membro pra um conjunto de hashs dos votos que ele precisa dar
 	UpdateCollective  map[crypto.Hash]*PendingUpdate
@@ -130,6 +147,10 @@ type Proposals struct {
 	CancelEvent  map[crypto.Hash]*CancelEvent
 	UpdateEvent  map[crypto.Hash]*EventUpdate
 	GreetCheckin map[crypto.Hash]*EventCheckinGreet
+	// periodicos
+	CreateJournal map[crypto.Hash]*PendingJournal
+	JournalEditor map[crypto.Hash]*JournalEditor
+	JournalIssue  map[crypto.Hash]*PendingIssue
 }
 
 func (p *Proposals) GetEvent(hash crypto.Hash) *Event {
@@ -140,10 +161,15 @@ func (p *Proposals) GetEvent(hash crypto.Hash) *Event {
 func (p *Proposals) Delete(hash crypto.Hash) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	for _, collective := range p.Governing(hash) {
+		collective.unbindProposal(hash)
+	}
 	if p.stateIndex != nil {
 		p.stateIndex.RemoveVoteHash(hash)
 	}
 	delete(p.all, hash)
+	delete(p.reasons, hash)
+	delete(p.deadlines, hash)
 	delete(p.UpdateCollective, hash)
 	delete(p.RequestMembership, hash)
 	delete(p.RemoveMember, hash)
@@ -163,6 +189,9 @@ func (p *Proposals) Delete(hash crypto.Hash) {
 		hashes.Remove(hash)
 	}*/
 	delete(p.GreetCheckin, hash)
+	delete(p.CreateJournal, hash)
+	delete(p.JournalEditor, hash)
+	delete(p.JournalIssue, hash)
 }
 
 func (p *Proposals) Kind(hash crypto.Hash) byte {
@@ -222,19 +251,25 @@ func casted(votes []actions.Vote, token crypto.Token) bool {
 func (p *Proposals) AddUpdateCollective(update *PendingUpdate, reason actions.Action) {
 	p.indexHash(update.Collective, update.Hash)
 	p.all[update.Hash] = UpdateCollectiveProposal
+	p.reasons[update.Hash] = reason
 	p.UpdateCollective[update.Hash] = update
+	p.bind(update.Hash)
 }
 
 func (p *Proposals) AddRequestMembership(update *PendingRequestMembership, reason actions.Action) {
 	p.indexHash(update.Collective, update.Hash)
 	p.all[update.Hash] = RequestMembershipProposal
+	p.reasons[update.Hash] = reason
 	p.RequestMembership[update.Hash] = update
+	p.bind(update.Hash)
 }
 
 func (p *Proposals) AddPendingRemoveMember(update *PendingRemoveMember, reason actions.Action) {
 	p.indexHash(update.Collective, update.Hash)
 	p.all[update.Hash] = RemoveMemberProposal
+	p.reasons[update.Hash] = reason
 	p.RemoveMember[update.Hash] = update
+	p.bind(update.Hash)
 }
 
 func (p *Proposals) AddDraft(update *Draft, reason actions.Action) {
@@ -243,26 +278,34 @@ func (p *Proposals) AddDraft(update *Draft, reason actions.Action) {
 		p.indexHash(update.PreviousVersion.Authors, update.DraftHash)
 	}
 	p.all[update.DraftHash] = DraftProposal
+	p.reasons[update.DraftHash] = reason
 	p.Draft[update.DraftHash] = update
+	p.bind(update.DraftHash)
 }
 
 func (p *Proposals) AddEdit(update *Edit, reason actions.Action) {
 	p.indexHash(update.Draft.Authors, update.Edit)
 	p.indexHash(update.Authors, update.Edit)
 	p.all[update.Edit] = EditProposal
+	p.reasons[update.Edit] = reason
 	p.Edit[update.Edit] = update
+	p.bind(update.Edit)
 }
 
 func (p *Proposals) AddPendingBoard(update *PendingBoard, reason actions.Action) {
 	p.indexHash(update.Board.Collective, update.Hash)
 	p.all[update.Hash] = CreateBoardProposal
+	p.reasons[update.Hash] = reason
 	p.CreateBoard[update.Hash] = update
+	p.bind(update.Hash)
 }
 
 func (p *Proposals) AddPendingUpdateBoard(update *PendingUpdateBoard, reason actions.Action) {
 	p.indexHash(update.Board.Editors, update.Hash)
 	p.all[update.Hash] = UpdateBoardProposal
+	p.reasons[update.Hash] = reason
 	p.UpdateBoard[update.Hash] = update
+	p.bind(update.Hash)
 }
 
 // adicionando aos proposals o que chegou pra ser votado
@@ -270,49 +313,89 @@ func (p *Proposals) AddPin(update *Pin, reason actions.Action) {
 	// quem vai receber o pedido de voto
 	p.indexHash(update.Board.Editors, update.Hash)
 	p.all[update.Hash] = PinProposal // adiciona a
+	p.reasons[update.Hash] = reason
 	p.Pin[update.Hash] = update
+	p.bind(update.Hash)
 }
 
 func (p *Proposals) AddBoardEditor(update *BoardEditor, reason actions.Action) {
 	p.indexHash(update.Board.Collective, update.Hash)
 	p.all[update.Hash] = BoardEditorProposal
+	p.reasons[update.Hash] = reason
 	p.BoardEditor[update.Hash] = update
+	p.bind(update.Hash)
 }
 
 func (p *Proposals) AddRelease(update *Release, reason actions.Action) {
 	p.indexHash(update.Draft.Authors, update.Hash)
 	p.all[update.Hash] = ReleaseDraftProposal
+	p.reasons[update.Hash] = reason
 	p.ReleaseDraft[update.Hash] = update
+	p.bind(update.Hash)
 }
 
 func (p *Proposals) AddStamp(update *Stamp, reason actions.Action) {
 	p.indexHash(update.Reputation, update.Hash) // reputation aqui é = um membro ou coletivo que vai dar o stamp ??
 	p.all[update.Hash] = ImprintStampProposal
+	p.reasons[update.Hash] = reason
 	p.ImprintStamp[update.Hash] = update
+	p.bind(update.Hash)
 }
 
 func (p *Proposals) AddEvent(update *Event, reason actions.Action) {
 	p.indexHash(update.Collective, update.Hash)
 	p.all[update.Hash] = CreateEventProposal
+	p.reasons[update.Hash] = reason
 	p.CreateEvent[update.Hash] = update
+	p.bind(update.Hash)
 }
 
 func (p *Proposals) AddCancelEvent(update *CancelEvent, reason actions.Action) {
 	p.indexHash(update.Event.Collective, update.Hash)
 	p.all[update.Hash] = CancelEventProposal
+	p.reasons[update.Hash] = reason
 	p.CancelEvent[update.Hash] = update
+	p.bind(update.Hash)
 }
 
 func (p *Proposals) AddEventUpdate(update *EventUpdate, reason actions.Action) {
 	p.indexHash(update.Event.Managers, update.Hash)
 	p.all[update.Hash] = UpdateEventProposal
+	p.reasons[update.Hash] = reason
 	p.UpdateEvent[update.Hash] = update
+	p.bind(update.Hash)
 }
 
 func (p *Proposals) AddEventCheckinGreet(update *EventCheckinGreet, reason actions.Action) {
 	// p.indexHash(update.Event.Greets, update.Hash)
 	p.all[update.Hash] = EventCheckinGreetProposal
+	p.reasons[update.Hash] = reason
 	p.GreetCheckin[update.Hash] = update
+	p.bind(update.Hash)
+}
+
+func (p *Proposals) AddPendingJournal(update *PendingJournal, reason actions.Action) {
+	p.indexHash(update.Journal.Collective, update.Hash)
+	p.all[update.Hash] = CreateJournalProposal
+	p.reasons[update.Hash] = reason
+	p.CreateJournal[update.Hash] = update
+	p.bind(update.Hash)
+}
+
+func (p *Proposals) AddJournalEditor(update *JournalEditor, reason actions.Action) {
+	p.indexHash(update.Journal.Collective, update.Hash)
+	p.all[update.Hash] = JournalEditorProposal
+	p.reasons[update.Hash] = reason
+	p.JournalEditor[update.Hash] = update
+	p.bind(update.Hash)
+}
+
+func (p *Proposals) AddPendingIssue(update *PendingIssue, reason actions.Action) {
+	p.indexHash(update.Issue.Journal.Collective, update.Hash)
+	p.all[update.Hash] = JournalIssueProposal
+	p.reasons[update.Hash] = reason
+	p.JournalIssue[update.Hash] = update
+	p.bind(update.Hash)
 }
 
 func (p *Proposals) Has(hash crypto.Hash) bool {
@@ -320,13 +403,105 @@ func (p *Proposals) Has(hash crypto.Hash) bool {
 	return ok
 }
 
+// SetDeadline registra o epoch em que a proposal expira
+func (p *Proposals) SetDeadline(hash crypto.Hash, epoch uint64) {
+	p.deadlines[hash] = epoch
+}
+
+// Deadline devolve o epoch em que a proposal expira (0 se nao tem prazo)
+func (p *Proposals) Deadline(hash crypto.Hash) uint64 {
+	return p.deadlines[hash]
+}
+
+// Reason devolve a acao que originou a proposal pendente (nil se nao houver)
+func (p *Proposals) Reason(hash crypto.Hash) actions.Action {
+	return p.reasons[hash]
+}
+
+// Reasons devolve todas as acoes que originaram proposals ainda pendentes
+func (p *Proposals) Reasons() map[crypto.Hash]actions.Action {
+	all := make(map[crypto.Hash]actions.Action)
+	for hash, reason := range p.reasons {
+		all[hash] = reason
+	}
+	return all
+}
+
+// IndexPending refaz a indexacao de quem precisa votar em cada proposal
+// pendente. Usado quando o indexer e reconstruido a partir de um snapshot.
+func (p *Proposals) IndexPending() {
+	for hash, update := range p.UpdateCollective {
+		p.indexHash(update.Collective, hash)
+	}
+	for hash, update := range p.RequestMembership {
+		p.indexHash(update.Collective, hash)
+	}
+	for hash, update := range p.RemoveMember {
+		p.indexHash(update.Collective, hash)
+	}
+	for hash, update := range p.Draft {
+		p.indexHash(update.Authors, hash)
+		if update.PreviousVersion != nil {
+			p.indexHash(update.PreviousVersion.Authors, hash)
+		}
+	}
+	for hash, update := range p.Edit {
+		p.indexHash(update.Draft.Authors, hash)
+		p.indexHash(update.Authors, hash)
+	}
+	for hash, update := range p.CreateBoard {
+		p.indexHash(update.Board.Collective, hash)
+	}
+	for hash, update := range p.UpdateBoard {
+		p.indexHash(update.Board.Editors, hash)
+	}
+	for hash, update := range p.Pin {
+		p.indexHash(update.Board.Editors, hash)
+	}
+	for hash, update := range p.BoardEditor {
+		p.indexHash(update.Board.Collective, hash)
+	}
+	for hash, update := range p.ReleaseDraft {
+		p.indexHash(update.Draft.Authors, hash)
+	}
+	for hash, update := range p.ImprintStamp {
+		p.indexHash(update.Reputation, hash)
+	}
+	for hash, update := range p.CreateEvent {
+		p.indexHash(update.Collective, hash)
+	}
+	for hash, update := range p.CancelEvent {
+		p.indexHash(update.Event.Collective, hash)
+	}
+	for hash, update := range p.UpdateEvent {
+		p.indexHash(update.Event.Managers, hash)
+	}
+	for hash, update := range p.CreateJournal {
+		p.indexHash(update.Journal.Collective, hash)
+	}
+	for hash, update := range p.JournalEditor {
+		p.indexHash(update.Journal.Collective, hash)
+	}
+	for hash, update := range p.JournalIssue {
+		p.indexHash(update.Issue.Journal.Collective, hash)
+	}
+}
+
 func (p *Proposals) IncorporateVote(vote actions.Vote, state *State) error {
-	hash := vote.Hash
+	proposal := p.Get(vote.Hash)
+	if proposal == nil {
+		return ErrProposalNotFound
+	}
+	return proposal.IncorporateVote(vote, state)
+}
+
+// Get returns the pending proposal associated to hash or nil
+func (p *Proposals) Get(hash crypto.Hash) Proposal {
 	var proposal Proposal
 	// qual tipo de proposta ta associado ao hash
 	kind, ok := p.all[hash]
 	if !ok {
-		return ErrProposalNotFound
+		return nil
 	}
 	switch kind {
 	case UpdateCollectiveProposal:
@@ -359,17 +534,83 @@ func (p *Proposals) IncorporateVote(vote actions.Vote, state *State) error {
 		proposal = p.CancelEvent[hash]
 	case UpdateEventProposal:
 		proposal = p.UpdateEvent[hash]
+	case CreateJournalProposal:
+		proposal = p.CreateJournal[hash]
+	case JournalEditorProposal:
+		proposal = p.JournalEditor[hash]
+	case JournalIssueProposal:
+		proposal = p.JournalIssue[hash]
 	}
-	if proposal == nil {
-		return ErrProposalNotFound
+	return proposal
+}
+
+// bind tells the collectives governing the proposal its kind, so that vote
+// delegations scoped by kind of proposal can be resolved.
+func (p *Proposals) bind(hash crypto.Hash) {
+	kind, ok := p.all[hash]
+	if !ok {
+		return
 	}
-	return proposal.IncorporateVote(vote, state)
+	for _, collective := range p.Governing(hash) {
+		collective.bindProposal(hash, kind)
+	}
+}
+
+// Governing returns the named collectives whose consensus decides the
+// proposal associated to hash.
+func (p *Proposals) Governing(hash crypto.Hash) []*Collective {
+	kind, ok := p.all[hash]
+	if !ok {
+		return nil
+	}
+	governing := make([]*Collective, 0)
+	named := func(c Consensual) {
+		if collective, ok := c.(*Collective); ok && collective != nil {
+			governing = append(governing, collective)
+		}
+	}
+	switch kind {
+	case UpdateCollectiveProposal:
+		governing = append(governing, p.UpdateCollective[hash].Collective)
+	case RequestMembershipProposal:
+		governing = append(governing, p.RequestMembership[hash].Collective)
+	case RemoveMemberProposal:
+		governing = append(governing, p.RemoveMember[hash].Collective)
+	case DraftProposal:
+		draft := p.Draft[hash]
+		named(draft.Authors)
+		if draft.PreviousVersion != nil {
+			named(draft.PreviousVersion.Authors)
+		}
+	case EditProposal:
+		named(p.Edit[hash].Authors)
+	case CreateBoardProposal:
+		governing = append(governing, p.CreateBoard[hash].Board.Collective)
+	case UpdateBoardProposal:
+		governing = append(governing, p.UpdateBoard[hash].Board.Collective)
+	case BoardEditorProposal:
+		governing = append(governing, p.BoardEditor[hash].Board.Collective)
+	case ReleaseDraftProposal:
+		named(p.ReleaseDraft[hash].Draft.Authors)
+	case ImprintStampProposal:
+		governing = append(governing, p.ImprintStamp[hash].Reputation)
+	case CreateEventProposal:
+		governing = append(governing, p.CreateEvent[hash].Collective)
+	case CreateJournalProposal:
+		governing = append(governing, p.CreateJournal[hash].Journal.Collective)
+	case JournalEditorProposal:
+		governing = append(governing, p.JournalEditor[hash].Journal.Collective)
+	case JournalIssueProposal:
+		governing = append(governing, p.JournalIssue[hash].Issue.Journal.Collective)
+	}
+	return governing
 }
 
 type Pool struct {
-	Voters   map[crypto.Token]struct{}
-	Majority int
-	Votes    []actions.Vote
+	Voters    map[crypto.Token]struct{}
+	Majority  int
+	Votes     []actions.Vote
+	Delegated map[crypto.Token]crypto.Token // membro -> delegado que votou por ele
 }
 
 func DeepCopyMembers(m map[crypto.Token]struct{}) map[crypto.Token]struct{} {
@@ -381,6 +622,17 @@ func DeepCopyMembers(m map[crypto.Token]struct{}) map[crypto.Token]struct{} {
 }
 
 func (p *Proposals) Pooling(hash crypto.Hash) *Pool {
+	pool := p.pooling(hash)
+	if pool == nil {
+		return nil
+	}
+	if governing := p.Governing(hash); len(governing) > 0 {
+		pool.Delegated = governing[0].Delegated(hash, pool.Votes)
+	}
+	return pool
+}
+
+func (p *Proposals) pooling(hash crypto.Hash) *Pool {
 	kind, ok := p.all[hash]
 	if !ok {
 		return nil
@@ -489,6 +741,27 @@ func (p *Proposals) Pooling(hash crypto.Hash) *Pool {
 			Majority: proposal.Event.Collective.Policy.Majority,
 			Votes:    proposal.Votes,
 		}
+	case CreateJournalProposal:
+		proposal := p.CreateJournal[hash]
+		return &Pool{
+			Voters:   DeepCopyMembers(proposal.Journal.Collective.ListOfMembers()),
+			Majority: proposal.Journal.Collective.Policy.Majority,
+			Votes:    proposal.Votes,
+		}
+	case JournalEditorProposal:
+		proposal := p.JournalEditor[hash]
+		return &Pool{
+			Voters:   DeepCopyMembers(proposal.Journal.Collective.ListOfMembers()),
+			Majority: proposal.Journal.Collective.Policy.Majority,
+			Votes:    proposal.Votes,
+		}
+	case JournalIssueProposal:
+		proposal := p.JournalIssue[hash]
+		return &Pool{
+			Voters:   DeepCopyMembers(proposal.Issue.Journal.Collective.ListOfMembers()),
+			Majority: proposal.Issue.Journal.Collective.Policy.Majority,
+			Votes:    proposal.Votes,
+		}
 	}
 	return nil
 }
@@ -543,6 +816,15 @@ func (p *Proposals) Votes(hash crypto.Hash) []actions.Vote {
 	case UpdateEventProposal:
 		proposal := p.UpdateEvent[hash]
 		return proposal.Votes
+	case CreateJournalProposal:
+		proposal := p.CreateJournal[hash]
+		return proposal.Votes
+	case JournalEditorProposal:
+		proposal := p.JournalEditor[hash]
+		return proposal.Votes
+	case JournalIssueProposal:
+		proposal := p.JournalIssue[hash]
+		return proposal.Votes
 	}
 	return nil
 }
@@ -594,6 +876,15 @@ func (p *Proposals) OnBehalfOf(hash crypto.Hash) string {
 	case UpdateEventProposal:
 		proposal := p.UpdateEvent[hash]
 		return proposal.Event.Collective.Name
+	case CreateJournalProposal:
+		proposal := p.CreateJournal[hash]
+		return proposal.Journal.Collective.Name
+	case JournalEditorProposal:
+		proposal := p.JournalEditor[hash]
+		return proposal.Journal.Collective.Name
+	case JournalIssueProposal:
+		proposal := p.JournalIssue[hash]
+		return proposal.Issue.Journal.Collective.Name
 	}
 	return ""
 }
@@ -0,0 +1,1673 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/breeze/util"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+/*
+Snapshot implements a versioned binary format for the State. A snapshot is
+written every SnapshotInterval epochs so that a restarting server only needs
+to replay the blocks after the snapshot epoch instead of the entire chain.
+
+Objects that are shared by pointer within the state (drafts, boards, edits,
+releases, stamps, events, journals, issues, collectives) are written once on their own tables
+and referenced by hash (or by name in the case of collectives). The loader
+reads the tables first and then resolves the references.
+
+Media content is not part of the snapshot: only the hashes of incorporated
+media are written, the content must be kept on a persistent MediaStore.
+*/
+
+// SnapshotVersion must be incremented whenever the binary layout changes.
+const SnapshotVersion byte = 13
+
+// SnapshotInterval is the default number of epochs between snapshots.
+const SnapshotInterval = 60 * 60
+
+var ErrSnapshotVersion = errors.New("incompatible snapshot version")
+
+var ErrSnapshotCorrupted = errors.New("corrupted snapshot")
+
+const (
+	unamedAuthors byte = iota
+	collectiveAuthors
+)
+
+// WriteSnapshot persists the state on path.
+func (s *State) WriteSnapshot(path string) error {
+	return SaveSnapshot(path, s.Snapshot())
+}
+
+// SaveSnapshot persists the bytes of a snapshot on path. The snapshot is
+// first written to a temporary file and then renamed so that a crash never
+// leaves a truncated snapshot behind. Only the serialization must run on the
+// goroutine that mutates the state: the bytes may be saved on another one.
+func SaveSnapshot(path string, data []byte) error {
+	temp := fmt.Sprintf("%v.tmp", path)
+	if err := os.WriteFile(temp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// LoadSnapshot reads the snapshot on path and restores the state. Returns
+// os.ErrNotExist (wrapped) if there is no snapshot file.
+func LoadSnapshot(path string, indexer Indexer) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSnapshot(data, indexer)
+}
+
+// Snapshot serializes the entire state.
+func (s *State) Snapshot() []byte {
+	bytes := []byte{SnapshotVersion}
+	util.PutUint64(s.Epoch, &bytes)
+	util.PutTime(s.GenesisTime, &bytes)
+
+	// members
+	handles := make([]string, 0, len(s.MembersIndex))
+	for handle := range s.MembersIndex {
+		handles = append(handles, handle)
+	}
+	sort.Strings(handles)
+	putCount(len(handles), &bytes)
+	for _, handle := range handles {
+		util.PutString(handle, &bytes)
+		util.PutToken(s.MembersIndex[handle], &bytes)
+	}
+
+	// media
+	putCount(len(s.Media), &bytes)
+	for hash := range s.Media {
+		util.PutHash(hash, &bytes)
+	}
+	putCount(len(s.PendingMedia), &bytes)
+	for hash, pending := range s.PendingMedia {
+		util.PutHash(hash, &bytes)
+		util.PutByte(pending.NumberOfParts, &bytes)
+		for _, part := range pending.Parts {
+			if part != nil {
+				util.PutByte(1, &bytes)
+				util.PutByteArray(part.Serialize(), &bytes)
+			} else {
+				util.PutByte(0, &bytes)
+			}
+		}
+	}
+
+	// collectives
+	putCount(len(s.Collectives), &bytes)
+	for hash, collective := range s.Collectives {
+		util.PutHash(hash, &bytes)
+		putCollective(collective, &bytes)
+	}
+
+	// drafts (approved and pending)
+	drafts := make(map[crypto.Hash]*Draft)
+	for hash, draft := range s.Drafts {
+		drafts[hash] = draft
+	}
+	for hash, draft := range s.Proposals.Draft {
+		drafts[hash] = draft
+	}
+	putCount(len(drafts), &bytes)
+	for hash, draft := range drafts {
+		util.PutHash(hash, &bytes)
+		_, live := s.Drafts[hash]
+		util.PutBool(live, &bytes)
+		util.PutString(draft.Title, &bytes)
+		util.PutUint64(draft.Date, &bytes)
+		util.PutString(draft.Description, &bytes)
+		putConsensual(draft.Authors, &bytes)
+		util.PutString(draft.DraftType, &bytes)
+		util.PutHash(draft.DraftHash, &bytes)
+		if draft.PreviousVersion != nil {
+			util.PutByte(1, &bytes)
+			util.PutHash(draft.PreviousVersion.DraftHash, &bytes)
+		} else {
+			util.PutByte(0, &bytes)
+		}
+		putStrings(draft.Keywords, &bytes)
+		actions.PutHashArray(draft.References, &bytes)
+		putVotes(draft.Votes, &bytes)
+		pinned := make([]crypto.Hash, len(draft.Pinned))
+		for n, board := range draft.Pinned {
+			pinned[n] = board.Hash
+		}
+		actions.PutHashArray(pinned, &bytes)
+		edits := make([]crypto.Hash, len(draft.Edits))
+		for n, edit := range draft.Edits {
+			edits[n] = edit.Edit
+		}
+		actions.PutHashArray(edits, &bytes)
+		util.PutBool(draft.Aproved, &bytes)
+	}
+
+	// boards (live and pending creation)
+	boards := make(map[crypto.Hash]*Board)
+	boardKeys := make(map[crypto.Hash]crypto.Hash)
+	for key, board := range s.Boards {
+		boards[board.Hash] = board
+		boardKeys[board.Hash] = key
+	}
+	for _, pending := range s.Proposals.CreateBoard {
+		boards[pending.Board.Hash] = pending.Board
+	}
+	putCount(len(boards), &bytes)
+	for hash, board := range boards {
+		util.PutHash(hash, &bytes)
+		if key, ok := boardKeys[hash]; ok {
+			util.PutByte(1, &bytes)
+			util.PutHash(key, &bytes)
+		} else {
+			util.PutByte(0, &bytes)
+		}
+		util.PutString(board.Name, &bytes)
+		putStrings(board.Keyword, &bytes)
+		util.PutString(board.Description, &bytes)
+		util.PutString(board.Collective.Name, &bytes)
+		putUnamed(board.Editors, &bytes)
+		pinned := make([]crypto.Hash, len(board.Pinned))
+		for n, draft := range board.Pinned {
+			pinned[n] = draft.DraftHash
+		}
+		actions.PutHashArray(pinned, &bytes)
+	}
+
+	// journals (live and pending creation)
+	journals := make(map[crypto.Hash]*Journal)
+	for hash, journal := range s.Journals {
+		journals[hash] = journal
+	}
+	for hash, pending := range s.Proposals.CreateJournal {
+		journals[hash] = pending.Journal
+	}
+	putCount(len(journals), &bytes)
+	for hash, journal := range journals {
+		util.PutHash(hash, &bytes)
+		_, live := s.Journals[hash]
+		util.PutBool(live, &bytes)
+		util.PutString(journal.Name, &bytes)
+		util.PutString(journal.Description, &bytes)
+		putStrings(journal.Keywords, &bytes)
+		util.PutString(journal.Collective.Name, &bytes)
+		putUnamed(journal.Editors, &bytes)
+	}
+
+	// issues (published and pending)
+	issues := make(map[crypto.Hash]*Issue)
+	for hash, issue := range s.Issues {
+		issues[hash] = issue
+	}
+	for hash, pending := range s.Proposals.JournalIssue {
+		issues[hash] = pending.Issue
+	}
+	putCount(len(issues), &bytes)
+	for hash, issue := range issues {
+		util.PutHash(hash, &bytes)
+		_, live := s.Issues[hash]
+		util.PutBool(live, &bytes)
+		util.PutHash(issue.Journal.Hash, &bytes)
+		util.PutUint64(uint64(issue.Number), &bytes)
+		util.PutString(issue.Title, &bytes)
+		util.PutString(issue.Description, &bytes)
+		contents := make([]crypto.Hash, len(issue.Drafts))
+		for n, draft := range issue.Drafts {
+			contents[n] = draft.DraftHash
+		}
+		actions.PutHashArray(contents, &bytes)
+		util.PutUint64(issue.Epoch, &bytes)
+	}
+
+	// edits (approved and pending)
+	edits := make(map[crypto.Hash]*Edit)
+	for hash, edit := range s.Edits {
+		edits[hash] = edit
+	}
+	for hash, edit := range s.Proposals.Edit {
+		edits[hash] = edit
+	}
+	putCount(len(edits), &bytes)
+	for hash, edit := range edits {
+		util.PutHash(hash, &bytes)
+		_, live := s.Edits[hash]
+		util.PutBool(live, &bytes)
+		putConsensual(edit.Authors, &bytes)
+		util.PutUint64(edit.Date, &bytes)
+		util.PutString(edit.Reasons, &bytes)
+		util.PutHash(edit.Draft.DraftHash, &bytes)
+		util.PutString(edit.EditType, &bytes)
+		util.PutHash(edit.Edit, &bytes)
+		putVotes(edit.Votes, &bytes)
+		util.PutBool(edit.Approved, &bytes)
+	}
+
+	// releases (released and pending) and stamps
+	releases := make(map[crypto.Hash]*Release)
+	releaseKeys := make(map[crypto.Hash]crypto.Hash)
+	stamps := make(map[crypto.Hash]*Stamp)
+	for key, release := range s.Releases {
+		releases[release.Hash] = release
+		releaseKeys[release.Hash] = key
+	}
+	for hash, release := range s.Proposals.ReleaseDraft {
+		releases[hash] = release
+	}
+	for _, release := range releases {
+		for _, stamp := range release.Stamps {
+			stamps[stamp.Hash] = stamp
+		}
+	}
+	for hash, stamp := range s.Proposals.ImprintStamp {
+		stamps[hash] = stamp
+	}
+	putCount(len(releases), &bytes)
+	for hash, release := range releases {
+		util.PutHash(hash, &bytes)
+		if key, ok := releaseKeys[hash]; ok {
+			util.PutByte(1, &bytes)
+			util.PutHash(key, &bytes)
+		} else {
+			util.PutByte(0, &bytes)
+		}
+		util.PutUint64(release.Epoch, &bytes)
+		util.PutHash(release.Draft.DraftHash, &bytes)
+		putVotes(release.Votes, &bytes)
+		util.PutBool(release.Released, &bytes)
+		stamped := make([]crypto.Hash, len(release.Stamps))
+		for n, stamp := range release.Stamps {
+			stamped[n] = stamp.Hash
+		}
+		actions.PutHashArray(stamped, &bytes)
+	}
+	putCount(len(stamps), &bytes)
+	for hash, stamp := range stamps {
+		util.PutHash(hash, &bytes)
+		util.PutString(stamp.Reputation.Name, &bytes)
+		util.PutHash(stamp.Release.Hash, &bytes)
+		putVotes(stamp.Votes, &bytes)
+		util.PutBool(stamp.Imprinted, &bytes)
+	}
+
+	// events (live and pending)
+	events := make(map[crypto.Hash]*Event)
+	for hash, event := range s.Events {
+		events[hash] = event
+	}
+	for hash, event := range s.Proposals.CreateEvent {
+		events[hash] = event
+	}
+	putCount(len(events), &bytes)
+	for hash, event := range events {
+		util.PutHash(hash, &bytes)
+		_, live := s.Events[hash]
+		util.PutBool(live, &bytes)
+		putEvent(event, &bytes)
+	}
+
+	// pending proposals
+	putProposals(s.Proposals, &bytes)
+
+	// deadlines
+	putCount(len(s.Deadline), &bytes)
+	for epoch, hashes := range s.Deadline {
+		util.PutUint64(epoch, &bytes)
+		actions.PutHashArray(hashes, &bytes)
+	}
+
+	// reactions
+	for n := 0; n < ReactionsCount; n++ {
+		putCount(len(s.Reactions[n]), &bytes)
+		for hash, count := range s.Reactions[n] {
+			util.PutHash(hash, &bytes)
+			util.PutUint64(uint64(count), &bytes)
+		}
+	}
+
+	// comments
+	putCount(len(s.Comments), &bytes)
+	for _, comment := range s.Comments {
+		util.PutByteArray(comment.Serialize(), &bytes)
+	}
+
+	// vote history
+	putCount(len(s.VoteHistory), &bytes)
+	for hash, votes := range s.VoteHistory {
+		util.PutHash(hash, &bytes)
+		putVotes(votes, &bytes)
+	}
+
+	// reactors
+	putCount(len(s.Reacted), &bytes)
+	for hash, reactors := range s.Reacted {
+		util.PutHash(hash, &bytes)
+		putCount(len(reactors), &bytes)
+		for token, epoch := range reactors {
+			util.PutToken(token, &bytes)
+			util.PutUint64(epoch, &bytes)
+		}
+	}
+	return bytes
+}
+
+// ParseSnapshot restores a state from its snapshot. The indexer is attached
+// to the restored state but is not populated: the caller is expected to
+// rebuild the index from the returned state.
+func ParseSnapshot(data []byte, indexer Indexer) (*State, error) {
+	if len(data) == 0 {
+		return nil, ErrSnapshotCorrupted
+	}
+	if data[0] != SnapshotVersion {
+		return nil, ErrSnapshotVersion
+	}
+	s := GenesisState(indexer)
+	var count int
+	position := 1
+	s.Epoch, position = util.ParseUint64(data, position)
+	s.GenesisTime, position = util.ParseTime(data, position)
+
+	// members
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var handle string
+		var token crypto.Token
+		handle, position = util.ParseString(data, position)
+		token, position = util.ParseToken(data, position)
+		s.MembersIndex[handle] = token
+		s.Members[crypto.HashToken(token)] = handle
+	}
+
+	// media
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var hash crypto.Hash
+		hash, position = util.ParseHash(data, position)
+		s.Media[hash] = struct{}{}
+	}
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		pending := PendingMedia{}
+		pending.Hash, position = util.ParseHash(data, position)
+		pending.NumberOfParts, position = util.ParseByte(data, position)
+		pending.Parts = make([]*actions.MultipartMedia, pending.NumberOfParts)
+		for part := 0; part < int(pending.NumberOfParts); part++ {
+			var exists bool
+			exists, position = util.ParseBool(data, position)
+			if exists {
+				var bytes []byte
+				bytes, position = util.ParseByteArray(data, position)
+				if pending.Parts[part] = actions.ParseMultipartMedia(bytes); pending.Parts[part] == nil {
+					return nil, ErrSnapshotCorrupted
+				}
+			}
+		}
+		s.PendingMedia[pending.Hash] = &pending
+	}
+
+	// collectives
+	named := make(map[string]*Collective)
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var hash crypto.Hash
+		var collective *Collective
+		hash, position = util.ParseHash(data, position)
+		collective, position = parseCollective(data, position)
+		s.Collectives[hash] = collective
+		named[collective.Name] = collective
+	}
+
+	// drafts
+	drafts := make(map[crypto.Hash]*Draft)
+	previous := make(map[*Draft]crypto.Hash)
+	draftPins := make(map[*Draft][]crypto.Hash)
+	draftEdits := make(map[*Draft][]crypto.Hash)
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var hash crypto.Hash
+		var live, hasPrevious bool
+		var err error
+		draft := Draft{}
+		hash, position = util.ParseHash(data, position)
+		live, position = util.ParseBool(data, position)
+		draft.Title, position = util.ParseString(data, position)
+		draft.Date, position = util.ParseUint64(data, position)
+		draft.Description, position = util.ParseString(data, position)
+		if draft.Authors, position, err = parseConsensual(data, position, named); err != nil {
+			return nil, err
+		}
+		draft.DraftType, position = util.ParseString(data, position)
+		draft.DraftHash, position = util.ParseHash(data, position)
+		hasPrevious, position = util.ParseBool(data, position)
+		if hasPrevious {
+			previous[&draft], position = util.ParseHash(data, position)
+		}
+		draft.Keywords, position = parseStrings(data, position)
+		draft.References, position = actions.ParseHashArray(data, position)
+		if draft.Votes, position, err = parseVotes(data, position); err != nil {
+			return nil, err
+		}
+		draftPins[&draft], position = actions.ParseHashArray(data, position)
+		draftEdits[&draft], position = actions.ParseHashArray(data, position)
+		draft.Aproved, position = util.ParseBool(data, position)
+		drafts[hash] = &draft
+		if live {
+			s.Drafts[hash] = &draft
+		}
+	}
+	for draft, hash := range previous {
+		if draft.PreviousVersion = drafts[hash]; draft.PreviousVersion == nil {
+			return nil, fmt.Errorf("%w: unknown previous version", ErrSnapshotCorrupted)
+		}
+	}
+
+	// boards
+	boards := make(map[crypto.Hash]*Board)
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var keyed bool
+		var key crypto.Hash
+		var collective string
+		var pinned []crypto.Hash
+		board := Board{}
+		board.Hash, position = util.ParseHash(data, position)
+		keyed, position = util.ParseBool(data, position)
+		if keyed {
+			key, position = util.ParseHash(data, position)
+		}
+		board.Name, position = util.ParseString(data, position)
+		board.Keyword, position = parseStrings(data, position)
+		board.Description, position = util.ParseString(data, position)
+		collective, position = util.ParseString(data, position)
+		if board.Collective = named[collective]; board.Collective == nil {
+			return nil, fmt.Errorf("%w: unknown collective %v", ErrSnapshotCorrupted, collective)
+		}
+		board.Editors, position = parseUnamed(data, position)
+		pinned, position = actions.ParseHashArray(data, position)
+		board.Pinned = make([]*Draft, 0, len(pinned))
+		for _, hash := range pinned {
+			draft, ok := drafts[hash]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown pinned draft", ErrSnapshotCorrupted)
+			}
+			board.Pinned = append(board.Pinned, draft)
+		}
+		boards[board.Hash] = &board
+		if keyed {
+			s.Boards[key] = &board
+		}
+	}
+	for draft, pins := range draftPins {
+		if len(pins) == 0 {
+			continue
+		}
+		draft.Pinned = make([]*Board, 0, len(pins))
+		for _, hash := range pins {
+			board, ok := boards[hash]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown board", ErrSnapshotCorrupted)
+			}
+			draft.Pinned = append(draft.Pinned, board)
+		}
+	}
+
+	// journals
+	journals := make(map[crypto.Hash]*Journal)
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var live bool
+		var collective string
+		journal := Journal{Issues: make([]*Issue, 0)}
+		journal.Hash, position = util.ParseHash(data, position)
+		live, position = util.ParseBool(data, position)
+		journal.Name, position = util.ParseString(data, position)
+		journal.Description, position = util.ParseString(data, position)
+		journal.Keywords, position = parseStrings(data, position)
+		collective, position = util.ParseString(data, position)
+		if journal.Collective = named[collective]; journal.Collective == nil {
+			return nil, fmt.Errorf("%w: unknown collective %v", ErrSnapshotCorrupted, collective)
+		}
+		journal.Editors, position = parseUnamed(data, position)
+		journals[journal.Hash] = &journal
+		if live {
+			s.Journals[journal.Hash] = &journal
+		}
+	}
+
+	// issues
+	issues := make(map[crypto.Hash]*Issue)
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var live bool
+		var journal crypto.Hash
+		var number uint64
+		var contents []crypto.Hash
+		issue := Issue{}
+		issue.Hash, position = util.ParseHash(data, position)
+		live, position = util.ParseBool(data, position)
+		journal, position = util.ParseHash(data, position)
+		if issue.Journal = journals[journal]; issue.Journal == nil {
+			return nil, fmt.Errorf("%w: unknown journal", ErrSnapshotCorrupted)
+		}
+		number, position = util.ParseUint64(data, position)
+		issue.Number = int(number)
+		issue.Title, position = util.ParseString(data, position)
+		issue.Description, position = util.ParseString(data, position)
+		contents, position = actions.ParseHashArray(data, position)
+		issue.Drafts = make([]*Draft, 0, len(contents))
+		for _, hash := range contents {
+			draft, ok := drafts[hash]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown draft on issue", ErrSnapshotCorrupted)
+			}
+			issue.Drafts = append(issue.Drafts, draft)
+		}
+		issue.Epoch, position = util.ParseUint64(data, position)
+		issues[issue.Hash] = &issue
+		if live {
+			s.Issues[issue.Hash] = &issue
+			issue.Journal.Issues = append(issue.Journal.Issues, &issue)
+		}
+	}
+	for _, journal := range journals {
+		sort.Slice(journal.Issues, func(n, m int) bool { return journal.Issues[n].Number < journal.Issues[m].Number })
+	}
+
+	// edits
+	edits := make(map[crypto.Hash]*Edit)
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var hash, draftHash crypto.Hash
+		var live bool
+		var err error
+		edit := Edit{}
+		hash, position = util.ParseHash(data, position)
+		live, position = util.ParseBool(data, position)
+		if edit.Authors, position, err = parseConsensual(data, position, named); err != nil {
+			return nil, err
+		}
+		edit.Date, position = util.ParseUint64(data, position)
+		edit.Reasons, position = util.ParseString(data, position)
+		draftHash, position = util.ParseHash(data, position)
+		if edit.Draft = drafts[draftHash]; edit.Draft == nil {
+			return nil, fmt.Errorf("%w: unknown edited draft", ErrSnapshotCorrupted)
+		}
+		edit.EditType, position = util.ParseString(data, position)
+		edit.Edit, position = util.ParseHash(data, position)
+		if edit.Votes, position, err = parseVotes(data, position); err != nil {
+			return nil, err
+		}
+		edit.Approved, position = util.ParseBool(data, position)
+		edits[hash] = &edit
+		if live {
+			s.Edits[hash] = &edit
+		}
+	}
+	for draft, hashes := range draftEdits {
+		if len(hashes) == 0 {
+			continue
+		}
+		draft.Edits = make([]*Edit, 0, len(hashes))
+		for _, hash := range hashes {
+			edit, ok := edits[hash]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown edit", ErrSnapshotCorrupted)
+			}
+			draft.Edits = append(draft.Edits, edit)
+		}
+	}
+
+	// releases and stamps
+	releases := make(map[crypto.Hash]*Release)
+	releaseStamps := make(map[*Release][]crypto.Hash)
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var keyed bool
+		var key, draftHash crypto.Hash
+		var err error
+		release := Release{}
+		release.Hash, position = util.ParseHash(data, position)
+		keyed, position = util.ParseBool(data, position)
+		if keyed {
+			key, position = util.ParseHash(data, position)
+		}
+		release.Epoch, position = util.ParseUint64(data, position)
+		draftHash, position = util.ParseHash(data, position)
+		if release.Draft = drafts[draftHash]; release.Draft == nil {
+			return nil, fmt.Errorf("%w: unknown released draft", ErrSnapshotCorrupted)
+		}
+		if release.Votes, position, err = parseVotes(data, position); err != nil {
+			return nil, err
+		}
+		release.Released, position = util.ParseBool(data, position)
+		releaseStamps[&release], position = actions.ParseHashArray(data, position)
+		releases[release.Hash] = &release
+		if keyed {
+			s.Releases[key] = &release
+		}
+	}
+	stamps := make(map[crypto.Hash]*Stamp)
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var reputation string
+		var releaseHash crypto.Hash
+		var err error
+		stamp := Stamp{}
+		stamp.Hash, position = util.ParseHash(data, position)
+		reputation, position = util.ParseString(data, position)
+		if stamp.Reputation = named[reputation]; stamp.Reputation == nil {
+			return nil, fmt.Errorf("%w: unknown collective %v", ErrSnapshotCorrupted, reputation)
+		}
+		releaseHash, position = util.ParseHash(data, position)
+		if stamp.Release = releases[releaseHash]; stamp.Release == nil {
+			return nil, fmt.Errorf("%w: unknown release", ErrSnapshotCorrupted)
+		}
+		if stamp.Votes, position, err = parseVotes(data, position); err != nil {
+			return nil, err
+		}
+		stamp.Imprinted, position = util.ParseBool(data, position)
+		stamps[stamp.Hash] = &stamp
+	}
+	for release, hashes := range releaseStamps {
+		release.Stamps = make([]*Stamp, 0, len(hashes))
+		for _, hash := range hashes {
+			stamp, ok := stamps[hash]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown stamp", ErrSnapshotCorrupted)
+			}
+			release.Stamps = append(release.Stamps, stamp)
+		}
+	}
+
+	// events
+	events := make(map[crypto.Hash]*Event)
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var hash crypto.Hash
+		var live bool
+		var event *Event
+		var err error
+		hash, position = util.ParseHash(data, position)
+		live, position = util.ParseBool(data, position)
+		if event, position, err = parseEvent(data, position, named); err != nil {
+			return nil, err
+		}
+		events[hash] = event
+		if live {
+			s.Events[hash] = event
+		}
+	}
+
+	// pending proposals
+	objects := snapshotObjects{
+		drafts:   drafts,
+		edits:    edits,
+		boards:   boards,
+		releases: releases,
+		stamps:   stamps,
+		events:   events,
+		journals: journals,
+		issues:   issues,
+	}
+	var err error
+	if position, err = parseProposals(data, position, s.Proposals, objects); err != nil {
+		return nil, err
+	}
+	for hash := range s.Proposals.all {
+		s.Proposals.bind(hash)
+	}
+	// as fotos resolvem as delegacoes no coletivo vivo
+	photos := make([]*Collective, 0)
+	for _, update := range s.Proposals.UpdateCollective {
+		photos = append(photos, update.Collective)
+	}
+	for _, request := range s.Proposals.RequestMembership {
+		photos = append(photos, request.Collective)
+	}
+	for _, remove := range s.Proposals.RemoveMember {
+		photos = append(photos, remove.Collective)
+	}
+	for _, photo := range photos {
+		if live, ok := s.Collective(photo.Name); ok {
+			photo.live = live
+			photo.Delegations = nil
+		}
+	}
+
+	// deadlines
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var epoch uint64
+		epoch, position = util.ParseUint64(data, position)
+		s.Deadline[epoch], position = actions.ParseHashArray(data, position)
+		for _, hash := range s.Deadline[epoch] {
+			if s.Proposals.Has(hash) {
+				s.Proposals.SetDeadline(hash, epoch)
+			}
+		}
+	}
+
+	// reactions
+	for reaction := 0; reaction < ReactionsCount; reaction++ {
+		count, position = parseCount(data, position)
+		for n := 0; n < count; n++ {
+			var hash crypto.Hash
+			var value uint64
+			hash, position = util.ParseHash(data, position)
+			value, position = util.ParseUint64(data, position)
+			s.Reactions[reaction][hash] = uint(value)
+		}
+	}
+
+	// comments
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var bytes []byte
+		bytes, position = util.ParseByteArray(data, position)
+		comment := actions.ParseComment(bytes)
+		if comment == nil {
+			return nil, fmt.Errorf("%w: invalid comment", ErrSnapshotCorrupted)
+		}
+		s.Comments[comment.Hashed()] = comment
+	}
+
+	// vote history
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var hash crypto.Hash
+		hash, position = util.ParseHash(data, position)
+		var votes []actions.Vote
+		votes, position, err = parseVotes(data, position)
+		if err != nil {
+			return nil, err
+		}
+		s.VoteHistory[hash] = votes
+	}
+
+	// reactors
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var hash crypto.Hash
+		hash, position = util.ParseHash(data, position)
+		var reactors int
+		reactors, position = parseCount(data, position)
+		s.Reacted[hash] = make(map[crypto.Token]uint64, reactors)
+		for m := 0; m < reactors; m++ {
+			var token crypto.Token
+			var epoch uint64
+			token, position = util.ParseToken(data, position)
+			epoch, position = util.ParseUint64(data, position)
+			s.Reacted[hash][token] = epoch
+		}
+	}
+	if position != len(data) {
+		return nil, ErrSnapshotCorrupted
+	}
+	return s, nil
+}
+
+// objects already restored and referenced by the pending proposals
+type snapshotObjects struct {
+	drafts   map[crypto.Hash]*Draft
+	edits    map[crypto.Hash]*Edit
+	boards   map[crypto.Hash]*Board
+	releases map[crypto.Hash]*Release
+	stamps   map[crypto.Hash]*Stamp
+	events   map[crypto.Hash]*Event
+	journals map[crypto.Hash]*Journal
+	issues   map[crypto.Hash]*Issue
+}
+
+func putProposals(p *Proposals, bytes *[]byte) {
+	// originating actions
+	putCount(len(p.reasons), bytes)
+	for hash, reason := range p.reasons {
+		util.PutHash(hash, bytes)
+		util.PutByteArray(reason.Serialize(), bytes)
+	}
+	// kind of every pending proposal
+	putCount(len(p.all), bytes)
+	for hash, kind := range p.all {
+		util.PutHash(hash, bytes)
+		util.PutByte(kind, bytes)
+	}
+	// proposals stored on their own tables need only to be referenced by hash
+	putHashKeys(p.Draft, bytes)
+	putHashKeys(p.Edit, bytes)
+	putHashKeys(p.ReleaseDraft, bytes)
+	putHashKeys(p.ImprintStamp, bytes)
+	putHashKeys(p.CreateEvent, bytes)
+
+	putCount(len(p.UpdateCollective), bytes)
+	for hash, update := range p.UpdateCollective {
+		util.PutHash(hash, bytes)
+		util.PutByteArray(update.Update.Serialize(), bytes)
+		putCollective(update.Collective, bytes)
+		util.PutBool(update.ChangePolicy, bytes)
+		putVotes(update.Votes, bytes)
+	}
+	putCount(len(p.RequestMembership), bytes)
+	for hash, request := range p.RequestMembership {
+		util.PutHash(hash, bytes)
+		util.PutByteArray(request.Request.Serialize(), bytes)
+		putCollective(request.Collective, bytes)
+		putVotes(request.Votes, bytes)
+	}
+	putCount(len(p.RemoveMember), bytes)
+	for hash, remove := range p.RemoveMember {
+		util.PutHash(hash, bytes)
+		util.PutByteArray(remove.Remove.Serialize(), bytes)
+		putCollective(remove.Collective, bytes)
+		putVotes(remove.Votes, bytes)
+	}
+	putCount(len(p.CreateBoard), bytes)
+	for hash, board := range p.CreateBoard {
+		util.PutHash(hash, bytes)
+		util.PutByteArray(board.Origin.Serialize(), bytes)
+		util.PutHash(board.Board.Hash, bytes)
+		putVotes(board.Votes, bytes)
+	}
+	putCount(len(p.UpdateBoard), bytes)
+	for hash, update := range p.UpdateBoard {
+		util.PutHash(hash, bytes)
+		util.PutByteArray(update.Origin.Serialize(), bytes)
+		util.PutHash(update.Board.Hash, bytes)
+		putVotes(update.Votes, bytes)
+	}
+	putCount(len(p.Pin), bytes)
+	for hash, pin := range p.Pin {
+		util.PutHash(hash, bytes)
+		util.PutUint64(pin.Epoch, bytes)
+		util.PutHash(pin.Board.Hash, bytes)
+		util.PutHash(pin.Draft.DraftHash, bytes)
+		util.PutBool(pin.Pin, bytes)
+		putVotes(pin.Votes, bytes)
+	}
+	putCount(len(p.BoardEditor), bytes)
+	for hash, editor := range p.BoardEditor {
+		util.PutHash(hash, bytes)
+		util.PutUint64(editor.Epoch, bytes)
+		util.PutHash(editor.Board.Hash, bytes)
+		util.PutToken(editor.Editor, bytes)
+		util.PutBool(editor.Insert, bytes)
+		putVotes(editor.Votes, bytes)
+	}
+	putCount(len(p.CancelEvent), bytes)
+	for hash, cancel := range p.CancelEvent {
+		util.PutHash(hash, bytes)
+		util.PutHash(cancel.Event.Hash, bytes)
+		util.PutString(cancel.Reasons, bytes)
+		util.PutUint64(cancel.Occurrence, bytes)
+		putVotes(cancel.Votes, bytes)
+	}
+	putCount(len(p.UpdateEvent), bytes)
+	for hash, update := range p.UpdateEvent {
+		util.PutHash(hash, bytes)
+		util.PutHash(update.Event.Hash, bytes)
+		putEventUpdate(update, bytes)
+	}
+	putCount(len(p.GreetCheckin), bytes)
+	for hash, greet := range p.GreetCheckin {
+		util.PutHash(hash, bytes)
+		util.PutHash(greet.Event.Hash, bytes)
+		putCount(len(greet.Greets), bytes)
+		for _, action := range greet.Greets {
+			util.PutByteArray(action.Serialize(), bytes)
+		}
+	}
+	putCount(len(p.CreateJournal), bytes)
+	for hash, journal := range p.CreateJournal {
+		util.PutHash(hash, bytes)
+		util.PutByteArray(journal.Origin.Serialize(), bytes)
+		util.PutHash(journal.Journal.Hash, bytes)
+		putVotes(journal.Votes, bytes)
+	}
+	putCount(len(p.JournalEditor), bytes)
+	for hash, editor := range p.JournalEditor {
+		util.PutHash(hash, bytes)
+		util.PutUint64(editor.Epoch, bytes)
+		util.PutHash(editor.Journal.Hash, bytes)
+		util.PutToken(editor.Editor, bytes)
+		util.PutBool(editor.Insert, bytes)
+		putVotes(editor.Votes, bytes)
+	}
+	// o numero pendente ja esta na tabela de numeros
+	putCount(len(p.JournalIssue), bytes)
+	for hash, issue := range p.JournalIssue {
+		util.PutHash(hash, bytes)
+		util.PutByteArray(issue.Origin.Serialize(), bytes)
+		putVotes(issue.Votes, bytes)
+	}
+}
+
+func parseProposals(data []byte, position int, p *Proposals, objects snapshotObjects) (int, error) {
+	var count int
+	var err error
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var hash crypto.Hash
+		var bytes []byte
+		hash, position = util.ParseHash(data, position)
+		bytes, position = util.ParseByteArray(data, position)
+		reason := actions.ParseAction(bytes)
+		if reason == nil {
+			return position, fmt.Errorf("%w: invalid proposal action", ErrSnapshotCorrupted)
+		}
+		p.reasons[hash] = reason
+	}
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var hash crypto.Hash
+		hash, position = util.ParseHash(data, position)
+		p.all[hash], position = util.ParseByte(data, position)
+	}
+	var hashes []crypto.Hash
+	hashes, position = parseHashKeys(data, position)
+	for _, hash := range hashes {
+		if p.Draft[hash] = objects.drafts[hash]; p.Draft[hash] == nil {
+			return position, fmt.Errorf("%w: unknown pending draft", ErrSnapshotCorrupted)
+		}
+	}
+	hashes, position = parseHashKeys(data, position)
+	for _, hash := range hashes {
+		if p.Edit[hash] = objects.edits[hash]; p.Edit[hash] == nil {
+			return position, fmt.Errorf("%w: unknown pending edit", ErrSnapshotCorrupted)
+		}
+	}
+	hashes, position = parseHashKeys(data, position)
+	for _, hash := range hashes {
+		if p.ReleaseDraft[hash] = objects.releases[hash]; p.ReleaseDraft[hash] == nil {
+			return position, fmt.Errorf("%w: unknown pending release", ErrSnapshotCorrupted)
+		}
+	}
+	hashes, position = parseHashKeys(data, position)
+	for _, hash := range hashes {
+		if p.ImprintStamp[hash] = objects.stamps[hash]; p.ImprintStamp[hash] == nil {
+			return position, fmt.Errorf("%w: unknown pending stamp", ErrSnapshotCorrupted)
+		}
+	}
+	hashes, position = parseHashKeys(data, position)
+	for _, hash := range hashes {
+		if p.CreateEvent[hash] = objects.events[hash]; p.CreateEvent[hash] == nil {
+			return position, fmt.Errorf("%w: unknown pending event", ErrSnapshotCorrupted)
+		}
+	}
+
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var bytes []byte
+		update := PendingUpdate{}
+		update.Hash, position = util.ParseHash(data, position)
+		bytes, position = util.ParseByteArray(data, position)
+		if update.Update = actions.ParseUpdateCollective(bytes); update.Update == nil {
+			return position, fmt.Errorf("%w: invalid update collective", ErrSnapshotCorrupted)
+		}
+		update.Collective, position = parseCollective(data, position)
+		update.ChangePolicy, position = util.ParseBool(data, position)
+		if update.Votes, position, err = parseVotes(data, position); err != nil {
+			return position, err
+		}
+		p.UpdateCollective[update.Hash] = &update
+	}
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var bytes []byte
+		request := PendingRequestMembership{}
+		request.Hash, position = util.ParseHash(data, position)
+		bytes, position = util.ParseByteArray(data, position)
+		if request.Request = actions.ParseRequestMembership(bytes); request.Request == nil {
+			return position, fmt.Errorf("%w: invalid request membership", ErrSnapshotCorrupted)
+		}
+		request.Collective, position = parseCollective(data, position)
+		if request.Votes, position, err = parseVotes(data, position); err != nil {
+			return position, err
+		}
+		p.RequestMembership[request.Hash] = &request
+	}
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var bytes []byte
+		remove := PendingRemoveMember{}
+		remove.Hash, position = util.ParseHash(data, position)
+		bytes, position = util.ParseByteArray(data, position)
+		if remove.Remove = actions.ParseRemoveMember(bytes); remove.Remove == nil {
+			return position, fmt.Errorf("%w: invalid remove member", ErrSnapshotCorrupted)
+		}
+		remove.Collective, position = parseCollective(data, position)
+		if remove.Votes, position, err = parseVotes(data, position); err != nil {
+			return position, err
+		}
+		p.RemoveMember[remove.Hash] = &remove
+	}
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var bytes []byte
+		var board crypto.Hash
+		pending := PendingBoard{}
+		pending.Hash, position = util.ParseHash(data, position)
+		bytes, position = util.ParseByteArray(data, position)
+		if pending.Origin = actions.ParseCreateBoard(bytes); pending.Origin == nil {
+			return position, fmt.Errorf("%w: invalid create board", ErrSnapshotCorrupted)
+		}
+		board, position = util.ParseHash(data, position)
+		if pending.Board = objects.boards[board]; pending.Board == nil {
+			return position, fmt.Errorf("%w: unknown pending board", ErrSnapshotCorrupted)
+		}
+		if pending.Votes, position, err = parseVotes(data, position); err != nil {
+			return position, err
+		}
+		p.CreateBoard[pending.Hash] = &pending
+	}
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var bytes []byte
+		var board crypto.Hash
+		update := PendingUpdateBoard{}
+		update.Hash, position = util.ParseHash(data, position)
+		bytes, position = util.ParseByteArray(data, position)
+		if update.Origin = actions.ParseUpdateBoard(bytes); update.Origin == nil {
+			return position, fmt.Errorf("%w: invalid update board", ErrSnapshotCorrupted)
+		}
+		update.Keywords = update.Origin.Keywords
+		update.Description = update.Origin.Description
+		update.PinMajority = update.Origin.PinMajority
+		board, position = util.ParseHash(data, position)
+		if update.Board = objects.boards[board]; update.Board == nil {
+			return position, fmt.Errorf("%w: unknown board", ErrSnapshotCorrupted)
+		}
+		if update.Votes, position, err = parseVotes(data, position); err != nil {
+			return position, err
+		}
+		p.UpdateBoard[update.Hash] = &update
+	}
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var board, draft crypto.Hash
+		pin := Pin{}
+		pin.Hash, position = util.ParseHash(data, position)
+		pin.Epoch, position = util.ParseUint64(data, position)
+		board, position = util.ParseHash(data, position)
+		draft, position = util.ParseHash(data, position)
+		pin.Board, pin.Draft = objects.boards[board], objects.drafts[draft]
+		if pin.Board == nil || pin.Draft == nil {
+			return position, fmt.Errorf("%w: unknown pin board or draft", ErrSnapshotCorrupted)
+		}
+		pin.Pin, position = util.ParseBool(data, position)
+		if pin.Votes, position, err = parseVotes(data, position); err != nil {
+			return position, err
+		}
+		p.Pin[pin.Hash] = &pin
+	}
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var board crypto.Hash
+		editor := BoardEditor{}
+		editor.Hash, position = util.ParseHash(data, position)
+		editor.Epoch, position = util.ParseUint64(data, position)
+		board, position = util.ParseHash(data, position)
+		if editor.Board = objects.boards[board]; editor.Board == nil {
+			return position, fmt.Errorf("%w: unknown board", ErrSnapshotCorrupted)
+		}
+		editor.Editor, position = util.ParseToken(data, position)
+		editor.Insert, position = util.ParseBool(data, position)
+		if editor.Votes, position, err = parseVotes(data, position); err != nil {
+			return position, err
+		}
+		p.BoardEditor[editor.Hash] = &editor
+	}
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var event crypto.Hash
+		cancel := CancelEvent{}
+		cancel.Hash, position = util.ParseHash(data, position)
+		event, position = util.ParseHash(data, position)
+		if cancel.Event = objects.events[event]; cancel.Event == nil {
+			return position, fmt.Errorf("%w: unknown event", ErrSnapshotCorrupted)
+		}
+		cancel.Reasons, position = util.ParseString(data, position)
+		cancel.Occurrence, position = util.ParseUint64(data, position)
+		if cancel.Votes, position, err = parseVotes(data, position); err != nil {
+			return position, err
+		}
+		p.CancelEvent[cancel.Hash] = &cancel
+	}
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var hash, event crypto.Hash
+		var update *EventUpdate
+		hash, position = util.ParseHash(data, position)
+		event, position = util.ParseHash(data, position)
+		if update, position, err = parseEventUpdate(data, position); err != nil {
+			return position, err
+		}
+		if update.Event = objects.events[event]; update.Event == nil {
+			return position, fmt.Errorf("%w: unknown event", ErrSnapshotCorrupted)
+		}
+		p.UpdateEvent[hash] = update
+	}
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var event crypto.Hash
+		var greets int
+		greet := EventCheckinGreet{}
+		greet.Hash, position = util.ParseHash(data, position)
+		event, position = util.ParseHash(data, position)
+		if greet.Event = objects.events[event]; greet.Event == nil {
+			return position, fmt.Errorf("%w: unknown event", ErrSnapshotCorrupted)
+		}
+		greets, position = parseCount(data, position)
+		greet.Greets = make([]actions.GreetCheckinEvent, 0, greets)
+		for g := 0; g < greets; g++ {
+			var bytes []byte
+			bytes, position = util.ParseByteArray(data, position)
+			action := actions.ParseGreetCheckinEvent(bytes)
+			if action == nil {
+				return position, fmt.Errorf("%w: invalid greet checkin", ErrSnapshotCorrupted)
+			}
+			greet.Greets = append(greet.Greets, *action)
+		}
+		p.GreetCheckin[greet.Hash] = &greet
+	}
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var bytes []byte
+		var journal crypto.Hash
+		pending := PendingJournal{}
+		pending.Hash, position = util.ParseHash(data, position)
+		bytes, position = util.ParseByteArray(data, position)
+		if pending.Origin = actions.ParseCreateJournal(bytes); pending.Origin == nil {
+			return position, fmt.Errorf("%w: invalid create journal", ErrSnapshotCorrupted)
+		}
+		journal, position = util.ParseHash(data, position)
+		if pending.Journal = objects.journals[journal]; pending.Journal == nil {
+			return position, fmt.Errorf("%w: unknown pending journal", ErrSnapshotCorrupted)
+		}
+		if pending.Votes, position, err = parseVotes(data, position); err != nil {
+			return position, err
+		}
+		p.CreateJournal[pending.Hash] = &pending
+	}
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var journal crypto.Hash
+		editor := JournalEditor{}
+		editor.Hash, position = util.ParseHash(data, position)
+		editor.Epoch, position = util.ParseUint64(data, position)
+		journal, position = util.ParseHash(data, position)
+		if editor.Journal = objects.journals[journal]; editor.Journal == nil {
+			return position, fmt.Errorf("%w: unknown journal", ErrSnapshotCorrupted)
+		}
+		editor.Editor, position = util.ParseToken(data, position)
+		editor.Insert, position = util.ParseBool(data, position)
+		if editor.Votes, position, err = parseVotes(data, position); err != nil {
+			return position, err
+		}
+		p.JournalEditor[editor.Hash] = &editor
+	}
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var bytes []byte
+		pending := PendingIssue{}
+		pending.Hash, position = util.ParseHash(data, position)
+		bytes, position = util.ParseByteArray(data, position)
+		if pending.Origin = actions.ParseJournalIssue(bytes); pending.Origin == nil {
+			return position, fmt.Errorf("%w: invalid journal issue", ErrSnapshotCorrupted)
+		}
+		if pending.Issue = objects.issues[pending.Hash]; pending.Issue == nil {
+			return position, fmt.Errorf("%w: unknown pending issue", ErrSnapshotCorrupted)
+		}
+		if pending.Votes, position, err = parseVotes(data, position); err != nil {
+			return position, err
+		}
+		p.JournalIssue[pending.Hash] = &pending
+	}
+	return position, nil
+}
+
+func putEvent(event *Event, bytes *[]byte) {
+	util.PutString(event.Collective.Name, bytes)
+	util.PutTime(event.StartAt, bytes)
+	util.PutTime(event.EstimatedEnd, bytes)
+	util.PutString(event.Description, bytes)
+	util.PutString(event.Venue, bytes)
+	util.PutBool(event.Open, bytes)
+	util.PutBool(event.Public, bytes)
+	util.PutHash(event.Hash, bytes)
+	if event.Managers != nil {
+		util.PutByte(1, bytes)
+		putUnamed(event.Managers, bytes)
+	} else {
+		util.PutByte(0, bytes)
+	}
+	putVotes(event.Votes, bytes)
+	putCount(len(event.Checkin), bytes)
+	for token, greeting := range event.Checkin {
+		util.PutToken(token, bytes)
+		util.PutToken(greeting.EphemeralKey, bytes)
+		if greeting.Action != nil {
+			util.PutByte(1, bytes)
+			util.PutByteArray(greeting.Action.Serialize(), bytes)
+		} else {
+			util.PutByte(0, bytes)
+		}
+	}
+	putCount(len(event.CheckinReasons), bytes)
+	for token, reasons := range event.CheckinReasons {
+		util.PutToken(token, bytes)
+		util.PutString(reasons, bytes)
+	}
+	util.PutBool(event.Live, bytes)
+	util.PutString(event.EventReasons, bytes)
+	util.PutUint64(uint64(event.Sequence), bytes)
+	util.PutUint64(event.Modified, bytes)
+	util.PutUint64(event.Capacity, bytes)
+	util.PutUint64(event.RSVPClose, bytes)
+	putCount(len(event.Waitlist), bytes)
+	for _, waiting := range event.Waitlist {
+		util.PutToken(waiting.Token, bytes)
+		util.PutToken(waiting.EphemeralKey, bytes)
+	}
+	actions.PutRecurrence(event.Recurrence, bytes)
+	putCount(len(event.Exceptions), bytes)
+	for _, exception := range event.Exceptions {
+		util.PutUint64(exception.Number, bytes)
+		util.PutTime(exception.StartAt, bytes)
+		util.PutTime(exception.EstimatedEnd, bytes)
+		util.PutString(exception.Description, bytes)
+		util.PutString(exception.Venue, bytes)
+		util.PutBool(exception.Cancelled, bytes)
+		util.PutBool(exception.Overridden, bytes)
+	}
+}
+
+func parseEvent(data []byte, position int, named map[string]*Collective) (*Event, int, error) {
+	var collective string
+	var hasManagers bool
+	var count int
+	var err error
+	event := Event{
+		Checkin:        make(map[crypto.Token]*Greeting),
+		CheckinReasons: make(map[crypto.Token]string),
+	}
+	collective, position = util.ParseString(data, position)
+	if event.Collective = named[collective]; event.Collective == nil {
+		return nil, position, fmt.Errorf("%w: unknown collective %v", ErrSnapshotCorrupted, collective)
+	}
+	event.StartAt, position = util.ParseTime(data, position)
+	event.EstimatedEnd, position = util.ParseTime(data, position)
+	event.Description, position = util.ParseString(data, position)
+	event.Venue, position = util.ParseString(data, position)
+	event.Open, position = util.ParseBool(data, position)
+	event.Public, position = util.ParseBool(data, position)
+	event.Hash, position = util.ParseHash(data, position)
+	hasManagers, position = util.ParseBool(data, position)
+	if hasManagers {
+		event.Managers, position = parseUnamed(data, position)
+	}
+	if event.Votes, position, err = parseVotes(data, position); err != nil {
+		return nil, position, err
+	}
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var token crypto.Token
+		var greeted bool
+		greeting := Greeting{}
+		token, position = util.ParseToken(data, position)
+		greeting.EphemeralKey, position = util.ParseToken(data, position)
+		greeted, position = util.ParseBool(data, position)
+		if greeted {
+			var bytes []byte
+			bytes, position = util.ParseByteArray(data, position)
+			if greeting.Action = actions.ParseGreetCheckinEvent(bytes); greeting.Action == nil {
+				return nil, position, fmt.Errorf("%w: invalid greet checkin", ErrSnapshotCorrupted)
+			}
+		}
+		event.Checkin[token] = &greeting
+	}
+	count, position = parseCount(data, position)
+	for n := 0; n < count; n++ {
+		var token crypto.Token
+		token, position = util.ParseToken(data, position)
+		event.CheckinReasons[token], position = util.ParseString(data, position)
+	}
+	event.Live, position = util.ParseBool(data, position)
+	event.EventReasons, position = util.ParseString(data, position)
+	var sequence uint64
+	sequence, position = util.ParseUint64(data, position)
+	event.Sequence = int(sequence)
+	event.Modified, position = util.ParseUint64(data, position)
+	event.Capacity, position = util.ParseUint64(data, position)
+	event.RSVPClose, position = util.ParseUint64(data, position)
+	count, position = parseCount(data, position)
+	event.Waitlist = make([]Waiting, count)
+	for n := 0; n < count; n++ {
+		event.Waitlist[n].Token, position = util.ParseToken(data, position)
+		event.Waitlist[n].EphemeralKey, position = util.ParseToken(data, position)
+	}
+	event.Recurrence, position = actions.ParseRecurrence(data, position)
+	count, position = parseCount(data, position)
+	event.Exceptions = make(map[uint64]*Occurrence)
+	for n := 0; n < count; n++ {
+		exception := Occurrence{}
+		exception.Number, position = util.ParseUint64(data, position)
+		exception.StartAt, position = util.ParseTime(data, position)
+		exception.EstimatedEnd, position = util.ParseTime(data, position)
+		exception.Description, position = util.ParseString(data, position)
+		exception.Venue, position = util.ParseString(data, position)
+		exception.Cancelled, position = util.ParseBool(data, position)
+		exception.Overridden, position = util.ParseBool(data, position)
+		event.Exceptions[exception.Number] = &exception
+	}
+	return &event, position, nil
+}
+
+func putEventUpdate(update *EventUpdate, bytes *[]byte) {
+	if update.StartAt != nil {
+		util.PutByte(1, bytes)
+		util.PutTime(*update.StartAt, bytes)
+	} else {
+		util.PutByte(0, bytes)
+	}
+	if update.EstimatedEnd != nil {
+		util.PutByte(1, bytes)
+		util.PutTime(*update.EstimatedEnd, bytes)
+	} else {
+		util.PutByte(0, bytes)
+	}
+	if update.Description != nil {
+		util.PutByte(1, bytes)
+		util.PutString(*update.Description, bytes)
+	} else {
+		util.PutByte(0, bytes)
+	}
+	if update.Venue != nil {
+		util.PutByte(1, bytes)
+		util.PutString(*update.Venue, bytes)
+	} else {
+		util.PutByte(0, bytes)
+	}
+	if update.Open != nil {
+		util.PutByte(1, bytes)
+		util.PutBool(*update.Open, bytes)
+	} else {
+		util.PutByte(0, bytes)
+	}
+	if update.Public != nil {
+		util.PutByte(1, bytes)
+		util.PutBool(*update.Public, bytes)
+	} else {
+		util.PutByte(0, bytes)
+	}
+	if update.Managers != nil {
+		util.PutByte(1, bytes)
+		putUnamed(update.Managers, bytes)
+	} else {
+		util.PutByte(0, bytes)
+	}
+	if update.Capacity != nil {
+		util.PutByte(1, bytes)
+		util.PutUint64(*update.Capacity, bytes)
+	} else {
+		util.PutByte(0, bytes)
+	}
+	if update.RSVPClose != nil {
+		util.PutByte(1, bytes)
+		util.PutUint64(*update.RSVPClose, bytes)
+	} else {
+		util.PutByte(0, bytes)
+	}
+	util.PutUint64(update.Occurrence, bytes)
+	putVotes(update.Votes, bytes)
+	util.PutHash(update.Hash, bytes)
+	util.PutBool(update.Updated, bytes)
+	util.PutString(update.Reasons, bytes)
+}
+
+func parseEventUpdate(data []byte, position int) (*EventUpdate, int, error) {
+	var exists bool
+	var err error
+	update := EventUpdate{}
+	if exists, position = util.ParseBool(data, position); exists {
+		var t time.Time
+		t, position = util.ParseTime(data, position)
+		update.StartAt = &t
+	}
+	if exists, position = util.ParseBool(data, position); exists {
+		var t time.Time
+		t, position = util.ParseTime(data, position)
+		update.EstimatedEnd = &t
+	}
+	if exists, position = util.ParseBool(data, position); exists {
+		var description string
+		description, position = util.ParseString(data, position)
+		update.Description = &description
+	}
+	if exists, position = util.ParseBool(data, position); exists {
+		var venue string
+		venue, position = util.ParseString(data, position)
+		update.Venue = &venue
+	}
+	if exists, position = util.ParseBool(data, position); exists {
+		var open bool
+		open, position = util.ParseBool(data, position)
+		update.Open = &open
+	}
+	if exists, position = util.ParseBool(data, position); exists {
+		var public bool
+		public, position = util.ParseBool(data, position)
+		update.Public = &public
+	}
+	if exists, position = util.ParseBool(data, position); exists {
+		update.Managers, position = parseUnamed(data, position)
+	}
+	if exists, position = util.ParseBool(data, position); exists {
+		var capacity uint64
+		capacity, position = util.ParseUint64(data, position)
+		update.Capacity = &capacity
+	}
+	if exists, position = util.ParseBool(data, position); exists {
+		var deadline uint64
+		deadline, position = util.ParseUint64(data, position)
+		update.RSVPClose = &deadline
+	}
+	update.Occurrence, position = util.ParseUint64(data, position)
+	if update.Votes, position, err = parseVotes(data, position); err != nil {
+		return nil, position, err
+	}
+	update.Hash, position = util.ParseHash(data, position)
+	update.Updated, position = util.ParseBool(data, position)
+	update.Reasons, position = util.ParseString(data, position)
+	return &update, position, nil
+}
+
+func putCount(count int, bytes *[]byte) {
+	util.PutUint64(uint64(count), bytes)
+}
+
+// parseCount protects the loader against absurd counts on corrupted data.
+func parseCount(data []byte, position int) (int, int) {
+	var count uint64
+	count, position = util.ParseUint64(data, position)
+	if count > uint64(len(data)) {
+		return 0, len(data) + 1
+	}
+	return int(count), position
+}
+
+func putStrings(words []string, bytes *[]byte) {
+	putCount(len(words), bytes)
+	for _, word := range words {
+		util.PutString(word, bytes)
+	}
+}
+
+func parseStrings(data []byte, position int) ([]string, int) {
+	var count int
+	count, position = parseCount(data, position)
+	words := make([]string, count)
+	for n := 0; n < count; n++ {
+		words[n], position = util.ParseString(data, position)
+	}
+	return words, position
+}
+
+func putHashKeys[T any](values map[crypto.Hash]T, bytes *[]byte) {
+	hashes := make([]crypto.Hash, 0, len(values))
+	for hash := range values {
+		hashes = append(hashes, hash)
+	}
+	actions.PutHashArray(hashes, bytes)
+}
+
+func parseHashKeys(data []byte, position int) ([]crypto.Hash, int) {
+	return actions.ParseHashArray(data, position)
+}
+
+func putTokenSet(tokens map[crypto.Token]struct{}, bytes *[]byte) {
+	array := make([]crypto.Token, 0, len(tokens))
+	for token := range tokens {
+		array = append(array, token)
+	}
+	actions.PutTokenArray(array, bytes)
+}
+
+func parseTokenSet(data []byte, position int) (map[crypto.Token]struct{}, int) {
+	var array []crypto.Token
+	array, position = actions.ParseTokenArray(data, position)
+	tokens := make(map[crypto.Token]struct{})
+	for _, token := range array {
+		tokens[token] = struct{}{}
+	}
+	return tokens, position
+}
+
+func putVotes(votes []actions.Vote, bytes *[]byte) {
+	putCount(len(votes), bytes)
+	for _, vote := range votes {
+		util.PutByteArray(vote.Serialize(), bytes)
+	}
+}
+
+func parseVotes(data []byte, position int) ([]actions.Vote, int, error) {
+	var count int
+	count, position = parseCount(data, position)
+	votes := make([]actions.Vote, 0, count)
+	for n := 0; n < count; n++ {
+		var bytes []byte
+		bytes, position = util.ParseByteArray(data, position)
+		vote := actions.ParseVote(bytes)
+		if vote == nil {
+			return nil, position, fmt.Errorf("%w: invalid vote", ErrSnapshotCorrupted)
+		}
+		votes = append(votes, *vote)
+	}
+	return votes, position, nil
+}
+
+func putCollective(c *Collective, bytes *[]byte) {
+	util.PutString(c.Name, bytes)
+	util.PutString(c.Description, bytes)
+	actions.PutPolicy(c.Policy, bytes)
+	util.PutUint64(c.Expiry, bytes)
+	putTokenSet(c.Members, bytes)
+	putCount(len(c.Delegations), bytes)
+	for member, delegations := range c.Delegations {
+		util.PutToken(member, bytes)
+		putCount(len(delegations), bytes)
+		for _, delegation := range delegations {
+			util.PutToken(delegation.To, bytes)
+			util.PutByteArray(delegation.Kinds, bytes)
+		}
+	}
+}
+
+func parseCollective(data []byte, position int) (*Collective, int) {
+	collective := Collective{}
+	collective.Name, position = util.ParseString(data, position)
+	collective.Description, position = util.ParseString(data, position)
+	if position+2 > len(data) {
+		return &collective, len(data) + 1
+	}
+	collective.Policy, position = actions.ParsePolicy(data, position)
+	collective.Expiry, position = util.ParseUint64(data, position)
+	collective.Members, position = parseTokenSet(data, position)
+	var count int
+	count, position = parseCount(data, position)
+	for n := 0; n < count && position < len(data); n++ {
+		var member crypto.Token
+		var delegations int
+		member, position = util.ParseToken(data, position)
+		delegations, position = parseCount(data, position)
+		for d := 0; d < delegations && position < len(data); d++ {
+			delegation := Delegation{}
+			delegation.To, position = util.ParseToken(data, position)
+			delegation.Kinds, position = util.ParseByteArray(data, position)
+			if len(delegation.Kinds) == 0 {
+				delegation.Kinds = nil
+			}
+			if collective.Delegations == nil {
+				collective.Delegations = make(map[crypto.Token][]Delegation)
+			}
+			collective.Delegations[member] = append(collective.Delegations[member], delegation)
+		}
+	}
+	return &collective, position
+}
+
+func putUnamed(c *UnamedCollective, bytes *[]byte) {
+	util.PutUint64(uint64(c.Majority), bytes)
+	putTokenSet(c.Members, bytes)
+}
+
+func parseUnamed(data []byte, position int) (*UnamedCollective, int) {
+	var majority uint64
+	collective := UnamedCollective{}
+	majority, position = util.ParseUint64(data, position)
+	collective.Majority = int(majority)
+	collective.Members, position = parseTokenSet(data, position)
+	return &collective, position
+}
+
+// named collectives are written by reference since authorship follows the
+// live composition of the collective
+func putConsensual(c Consensual, bytes *[]byte) {
+	if collective, ok := c.(*Collective); ok {
+		util.PutByte(collectiveAuthors, bytes)
+		util.PutString(collective.Name, bytes)
+		return
+	}
+	util.PutByte(unamedAuthors, bytes)
+	unamed, _ := c.(*UnamedCollective)
+	if unamed == nil {
+		unamed = &UnamedCollective{Members: make(map[crypto.Token]struct{})}
+	}
+	putUnamed(unamed, bytes)
+}
+
+func parseConsensual(data []byte, position int, named map[string]*Collective) (Consensual, int, error) {
+	var kind byte
+	kind, position = util.ParseByte(data, position)
+	if kind == collectiveAuthors {
+		var name string
+		name, position = util.ParseString(data, position)
+		collective, ok := named[name]
+		if !ok {
+			return nil, position, fmt.Errorf("%w: unknown collective %v", ErrSnapshotCorrupted, name)
+		}
+		return collective, position, nil
+	}
+	if kind != unamedAuthors {
+		return nil, position, ErrSnapshotCorrupted
+	}
+	unamed, position := parseUnamed(data, position)
+	return unamed, position, nil
+}
@@ -0,0 +1,150 @@
+package state
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+// snapshotState builds a state through actions with approved and pending
+// objects of most kinds.
+func snapshotState(t *testing.T) *State {
+	t.Helper()
+	s, members := testState(3)
+	s.GenesisTime = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.SetEpoch(1)
+	testCollective(t, s, "snapshot", actions.Policy{Majority: 50, SuperMajority: 75}, members...)
+	s.SetEpoch(2)
+	content := []byte("conteudo do esboco")
+	draft := &actions.Draft{
+		Epoch:         2,
+		Author:        members[0],
+		Title:         "esboco",
+		Keywords:      []string{"snapshot"},
+		Description:   "esboco de teste",
+		ContentType:   "md",
+		ContentHash:   crypto.Hasher(content),
+		NumberOfParts: 1,
+		Content:       content,
+	}
+	incorporate(t, s, draft)
+	board := &actions.CreateBoard{
+		Epoch:       2,
+		Author:      members[0],
+		OnBehalfOf:  "snapshot",
+		Name:        "mural",
+		Description: "mural de teste",
+		Keywords:    []string{"mural"},
+		PinMajority: 1,
+	}
+	incorporate(t, s, board)
+	incorporate(t, s, vote(s, members[1], board.Hashed(), true))
+	s.SetEpoch(3)
+	incorporate(t, s, &actions.Pin{Epoch: 3, Author: members[0], Board: "mural", Draft: draft.ContentHash, Pin: true})
+	incorporate(t, s, &actions.React{Epoch: 3, Author: members[1], Hash: draft.ContentHash, Reaction: 1})
+	incorporate(t, s, &actions.Comment{Epoch: 3, Author: members[2], Target: draft.ContentHash, Body: "comentario"})
+	// pending proposals
+	incorporate(t, s, &actions.CreateEvent{
+		Epoch:        3,
+		Author:       members[0],
+		OnBehalfOf:   "snapshot",
+		StartAt:      time.Date(2023, 6, 1, 19, 0, 0, 0, time.UTC),
+		EstimatedEnd: time.Date(2023, 6, 1, 21, 0, 0, 0, time.UTC),
+		Description:  "evento",
+		Venue:        "sede",
+		Managers:     []crypto.Token{members[0]},
+		Capacity:     10,
+	})
+	pending := []byte("esboco coletivo")
+	incorporate(t, s, &actions.Draft{
+		Epoch:         3,
+		Author:        members[1],
+		OnBehalfOf:    "snapshot",
+		Title:         "coletivo",
+		Keywords:      []string{"coletivo"},
+		ContentType:   "txt",
+		ContentHash:   crypto.Hasher(pending),
+		NumberOfParts: 1,
+		Content:       pending,
+		References:    []crypto.Hash{draft.ContentHash},
+	})
+	s.SetEpoch(4)
+	return s
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	s := snapshotState(t)
+	if len(s.Boards) != 1 || len(s.Drafts) != 1 || len(s.Proposals.Draft) != 1 || len(s.Deadline) == 0 {
+		t.Fatalf("unexpected state before snapshot: %v boards, %v drafts, %v pending drafts", len(s.Boards), len(s.Drafts), len(s.Proposals.Draft))
+	}
+	restored, err := ParseSnapshot(s.Snapshot(), testIndexer{})
+	if err != nil {
+		t.Fatalf("could not parse snapshot: %v", err)
+	}
+	fields := []struct {
+		name              string
+		original, restore any
+	}{
+		{"Epoch", s.Epoch, restored.Epoch},
+		{"MembersIndex", s.MembersIndex, restored.MembersIndex},
+		{"Members", s.Members, restored.Members},
+		{"Media", s.Media, restored.Media},
+		{"Collectives", s.Collectives, restored.Collectives},
+		{"Drafts", s.Drafts, restored.Drafts},
+		{"Boards", s.Boards, restored.Boards},
+		{"Events", s.Events, restored.Events},
+		{"Comments", s.Comments, restored.Comments},
+		{"VoteHistory", s.VoteHistory, restored.VoteHistory},
+		{"Reactions", s.Reactions, restored.Reactions},
+		{"Reacted", s.Reacted, restored.Reacted},
+		{"Deadline", s.Deadline, restored.Deadline},
+		{"pending drafts", s.Proposals.Draft, restored.Proposals.Draft},
+		{"pending events", s.Proposals.CreateEvent, restored.Proposals.CreateEvent},
+		{"pending pins", s.Proposals.Pin, restored.Proposals.Pin},
+	}
+	if !restored.GenesisTime.Equal(s.GenesisTime) {
+		t.Errorf("GenesisTime not restored: %v", restored.GenesisTime)
+	}
+	for _, field := range fields {
+		if !reflect.DeepEqual(field.original, field.restore) {
+			t.Errorf("%v not restored:\n%+v\n%+v", field.name, field.original, field.restore)
+		}
+	}
+	// the restored state goes on incorporating actions
+	members := make([]crypto.Token, 0)
+	for _, handle := range []string{"member0", "member1", "member2"} {
+		members = append(members, restored.MembersIndex[handle])
+	}
+	for hash := range restored.Proposals.Draft {
+		if err := restored.Action(vote(restored, members[0], hash, true).Serialize()); err != nil {
+			t.Fatalf("could not vote on restored state: %v", err)
+		}
+		if _, ok := restored.Drafts[hash]; !ok {
+			t.Error("pending draft not approved on restored state")
+		}
+	}
+}
+
+func TestSnapshotFile(t *testing.T) {
+	s := snapshotState(t)
+	path := filepath.Join(t.TempDir(), "state.snapshot")
+	if err := SaveSnapshot(path, s.Snapshot()); err != nil {
+		t.Fatalf("could not write snapshot: %v", err)
+	}
+	restored, err := LoadSnapshot(path, testIndexer{})
+	if err != nil {
+		t.Fatalf("could not load snapshot: %v", err)
+	}
+	if restored.Epoch != s.Epoch || len(restored.Drafts) != len(s.Drafts) {
+		t.Errorf("snapshot file restored epoch %v and %v drafts", restored.Epoch, len(restored.Drafts))
+	}
+	data := s.Snapshot()
+	data[0] = SnapshotVersion + 1
+	if _, err := ParseSnapshot(data, testIndexer{}); err != ErrSnapshotVersion {
+		t.Errorf("expected %v, got %v", ErrSnapshotVersion, err)
+	}
+}
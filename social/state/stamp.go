@@ -1,8 +1,6 @@
 package state
 
 import (
-	"errors"
-
 	"github.com/freehandle/breeze/crypto"
 	"github.com/freehandle/synergy/social/actions"
 )
@@ -16,23 +14,40 @@ type Stamp struct {
 }
 
 func (p *Stamp) IncorporateVote(vote actions.Vote, state *State) error {
-	if err := IsNewValidVote(vote, p.Votes, p.Hash); err != nil {
+	if p.Imprinted {
+		votes, err := concludedVote(p.Hash, vote, p.Votes)
+		if err != nil {
+			return err
+		}
+		if !p.Reputation.IsMember(vote.Author) {
+			return ErrNotCollectiveMember
+		}
+		p.Votes = votes
+		return nil
+	}
+	votes, err := castVote(p.Hash, vote, p.Votes)
+	if err != nil {
 		return err
 	}
 	if !p.Reputation.IsMember(vote.Author) {
-		return errors.New("author is not a recognized member of the collective")
+		return ErrNotCollectiveMember
 	}
-	p.Votes = append(p.Votes, vote)
+	p.Votes = votes
+	return p.Evaluate(state)
+}
+
+// Evaluate concludes the proposal if the votes cast reach consensus
+func (p *Stamp) Evaluate(state *State) error {
 	if p.Imprinted {
 		return nil
 	}
-	consensus := p.Reputation.Consensus(vote.Hash, p.Votes)
+	consensus := p.Reputation.Consensus(p.Hash, p.Votes)
 	state.index.IndexActionStatus(p.Hash, consensus)
 	if consensus == Undecided {
 		return nil
 	}
 	// new consensus
-	state.IndexConsensus(vote.Hash, consensus)
+	state.IndexConsensus(p.Hash, consensus)
 	if consensus == Favorable {
 		p.Imprinted = true
 		if state.index != nil {
@@ -58,10 +73,24 @@ type Release struct {
 }
 
 func (p *Release) IncorporateVote(vote actions.Vote, state *State) error {
-	if err := IsNewValidVote(vote, p.Votes, p.Hash); err != nil {
+	if p.Released {
+		votes, err := concludedVote(p.Hash, vote, p.Votes)
+		if err != nil {
+			return err
+		}
+		p.Votes = votes
+		return nil
+	}
+	votes, err := castVote(p.Hash, vote, p.Votes)
+	if err != nil {
 		return err
 	}
-	p.Votes = append(p.Votes, vote)
+	p.Votes = votes
+	return p.Evaluate(state)
+}
+
+// Evaluate concludes the proposal if the votes cast reach consensus
+func (p *Release) Evaluate(state *State) error {
 	if p.Released {
 		return nil
 	}
@@ -71,14 +100,17 @@ func (p *Release) IncorporateVote(vote actions.Vote, state *State) error {
 		return nil
 	}
 	// new consensus
-	state.IndexConsensus(vote.Hash, consensus)
+	state.IndexConsensus(p.Hash, consensus)
 	state.Proposals.Delete(p.Hash)
 	if consensus == Favorable {
 		p.Released = true
 		if _, ok := state.Releases[p.Draft.DraftHash]; !ok {
 			state.Releases[p.Draft.DraftHash] = p
+			if state.index != nil {
+				state.index.AddReleaseToIndex(p)
+			}
 			return nil
 		}
 	}
-	return errors.New("already released")
+	return ErrAlreadyReleased
 }
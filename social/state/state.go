@@ -2,13 +2,14 @@ package state
 
 import (
 	"encoding/json"
-	"errors"
-	"fmt"
 	"log"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/freehandle/breeze/crypto"
 	"github.com/freehandle/synergy/social/actions"
+	"github.com/freehandle/synergy/social/diff"
 )
 
 const (
@@ -30,21 +31,30 @@ type State struct {
 	MembersIndex map[string]crypto.Token       // mapa do handle to token
 	Members      map[crypto.Hash]string        // mapa do hash do token para o handle
 	PendingMedia map[crypto.Hash]*PendingMedia // multi-part media file
-	Media        map[crypto.Hash][]byte        // quando termina de receber todas as partes vira o media
+	Media        map[crypto.Hash]struct{}      // quando termina de receber todas as partes vira o media (conteudo no MediaStore)
 	Drafts       map[crypto.Hash]*Draft        // o hash do draft eh o hash da media dele
 	Edits        map[crypto.Hash]*Edit         // o hash do edit eh o hash da media dele
 	Releases     map[crypto.Hash]*Release      // hash do draft para instancia do release
 	Events       map[crypto.Hash]*Event        // hash do evento eh hash da acao do evento
 	Collectives  map[crypto.Hash]*Collective   // hash do coletivo eh o hash do nome
 	Boards       map[crypto.Hash]*Board        // hash do board eh o hash do nome
+	Journals     map[crypto.Hash]*Journal      // hash do periodico eh o hash do nome
+	Issues       map[crypto.Hash]*Issue        // hash do numero eh o hash da acao que o propos
 	Proposals    *Proposals                    // map[crypto.Hash]Proposal // proposals pending vote actions
 	Deadline     map[uint64][]crypto.Hash      // map do epoch que morre para o array de hash dos elementos que vao morrer naquele epoch
 	Reactions    [ReactionsCount]map[crypto.Hash]uint
+	Reacted      map[crypto.Hash]map[crypto.Token]uint64 // epoch da primeira reacao de cada membro a cada objeto
+	Comments     map[crypto.Hash]*actions.Comment        // hash do comentario para o comentario
+	VoteHistory  map[crypto.Hash][]actions.Vote          // todos os votos dados em cada objeto, inclusive alterados
 	Axe          HandleProvider
 	GenesisTime  time.Time
 	index        Indexer
-	action       Notifier // pra ser usado pra notificacao real time
-
+	action       Notifier   // pra ser usado pra notificacao real time
+	media        MediaStore // conteudo dos media e das partes pendentes
+	expired      uint64     // ultimo epoch cujos prazos ja foram processados
+	// Action e SetEpoch alteram o estado na goroutine dos blocos, Validate
+	// apenas le, a partir das goroutines do servidor http
+	mu sync.RWMutex
 }
 
 func (s *State) TimeOfEpoch(epoch uint64) time.Time {
@@ -102,6 +112,16 @@ func logAction(a actions.Action) {
 		des = "Checkin Event"
 	case *actions.GreetCheckinEvent:
 		des = "Greet Checkin Event"
+	case *actions.CancelCheckinEvent:
+		des = "Cancel Checkin Event"
+	case *actions.Comment:
+		des = "Comment"
+	case *actions.CreateJournal:
+		des = "Create Journal"
+	case *actions.JournalEditor:
+		des = "Journal Editor"
+	case *actions.JournalIssue:
+		des = "Journal Issue"
 	}
 	text, _ := json.Marshal(a)
 	log.Printf("%v: %v\n", des, string(text))
@@ -110,13 +130,23 @@ func logAction(a actions.Action) {
 // funcao que esta sendo chamada no SelfGateway do genesis
 // valida e incorpora a acao
 func (s *State) Action(data []byte) error {
+	s.mu.Lock()
+	err := s.incorporate(data)
+	s.mu.Unlock()
+	if err == nil && s.action != nil {
+		s.notifyAction(data)
+	}
+	return err
+}
+
+func (s *State) incorporate(data []byte) error {
 	kind := actions.ActionKind(data)
 	// verifica qual o tipo de acao ta sendo processado segundo o byte
 	switch kind {
 	case actions.AVote:
 		action := actions.ParseVote(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -125,7 +155,7 @@ func (s *State) Action(data []byte) error {
 	case actions.ACreateCollective:
 		action := actions.ParseCreateCollective(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -134,7 +164,7 @@ func (s *State) Action(data []byte) error {
 	case actions.AUpdateCollective:
 		action := actions.ParseUpdateCollective(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -143,7 +173,7 @@ func (s *State) Action(data []byte) error {
 	case actions.ARequestMembership:
 		action := actions.ParseRequestMembership(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -152,7 +182,7 @@ func (s *State) Action(data []byte) error {
 	case actions.ARemoveMember:
 		action := actions.ParseRemoveMember(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -161,7 +191,7 @@ func (s *State) Action(data []byte) error {
 	case actions.ADraft:
 		action := actions.ParseDraft(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -171,7 +201,7 @@ func (s *State) Action(data []byte) error {
 	case actions.AEdit:
 		action := actions.ParseEdit(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -180,7 +210,7 @@ func (s *State) Action(data []byte) error {
 	case actions.AMultipartMedia:
 		action := actions.ParseMultipartMedia(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		// no need to index
@@ -189,7 +219,7 @@ func (s *State) Action(data []byte) error {
 	case actions.ACreateBoard:
 		action := actions.ParseCreateBoard(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -199,7 +229,7 @@ func (s *State) Action(data []byte) error {
 	case actions.AUpdateBoard:
 		action := actions.ParseUpdateBoard(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -209,7 +239,7 @@ func (s *State) Action(data []byte) error {
 	case actions.APin:
 		action := actions.ParsePin(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -218,7 +248,7 @@ func (s *State) Action(data []byte) error {
 	case actions.ABoardEditor:
 		action := actions.ParseBoardEditor(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -227,7 +257,7 @@ func (s *State) Action(data []byte) error {
 	case actions.AReleaseDraft:
 		action := actions.ParseReleaseDraft(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -236,7 +266,7 @@ func (s *State) Action(data []byte) error {
 	case actions.AImprintStamp:
 		action := actions.ParseImprintStamp(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -245,7 +275,7 @@ func (s *State) Action(data []byte) error {
 	case actions.AReact:
 		action := actions.ParseReact(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -254,7 +284,7 @@ func (s *State) Action(data []byte) error {
 	case actions.ASignIn:
 		action := actions.ParseSignIn(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		// should index signin???
@@ -263,7 +293,7 @@ func (s *State) Action(data []byte) error {
 	case actions.ACreateEvent:
 		action := actions.ParseCreateEvent(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -273,7 +303,7 @@ func (s *State) Action(data []byte) error {
 	case actions.ACancelEvent:
 		action := actions.ParseCancelEvent(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -282,7 +312,7 @@ func (s *State) Action(data []byte) error {
 	case actions.AUpdateEvent:
 		action := actions.ParseUpdateEvent(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -291,7 +321,7 @@ func (s *State) Action(data []byte) error {
 	case actions.ACheckinEvent:
 		action := actions.ParseCheckinEvent(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
@@ -300,14 +330,62 @@ func (s *State) Action(data []byte) error {
 	case actions.AGreetCheckinEvent:
 		action := actions.ParseGreetCheckinEvent(data)
 		if action == nil {
-			return errors.New("cound not parse action")
+			return ErrInvalidAction
 		}
 		logAction(action)
 		s.IndexAction(action)
 		err := s.GreetCheckinEvent(action)
 		return err
+	case actions.ACancelCheckinEvent:
+		action := actions.ParseCancelCheckinEvent(data)
+		if action == nil {
+			return ErrInvalidAction
+		}
+		logAction(action)
+		s.IndexAction(action)
+		return s.CancelCheckinEvent(action)
+	case actions.ADelegate:
+		action := actions.ParseDelegate(data)
+		if action == nil {
+			return ErrInvalidAction
+		}
+		logAction(action)
+		s.IndexAction(action)
+		return s.Delegate(action)
+	case actions.AComment:
+		action := actions.ParseComment(data)
+		if action == nil {
+			return ErrInvalidAction
+		}
+		logAction(action)
+		s.IndexAction(action)
+		return s.Comment(action)
+	case actions.ACreateJournal:
+		action := actions.ParseCreateJournal(data)
+		if action == nil {
+			return ErrInvalidAction
+		}
+		logAction(action)
+		s.IndexAction(action)
+		return s.CreateJournal(action)
+	case actions.AJournalEditor:
+		action := actions.ParseJournalEditor(data)
+		if action == nil {
+			return ErrInvalidAction
+		}
+		logAction(action)
+		s.IndexAction(action)
+		return s.JournalEditor(action)
+	case actions.AJournalIssue:
+		action := actions.ParseJournalIssue(data)
+		if action == nil {
+			return ErrInvalidAction
+		}
+		logAction(action)
+		s.IndexAction(action)
+		return s.JournalIssue(action)
 	}
-	return errors.New("unrecognized action")
+	return ErrUnknownAction
 }
 
 // cria o estado inicial
@@ -317,16 +395,22 @@ func GenesisState(indexer Indexer) *State {
 		MembersIndex: make(map[string]crypto.Token),
 		Members:      make(map[crypto.Hash]string),
 		PendingMedia: make(map[crypto.Hash]*PendingMedia),
-		Media:        make(map[crypto.Hash][]byte),
+		Media:        make(map[crypto.Hash]struct{}),
 		Drafts:       make(map[crypto.Hash]*Draft),
 		Edits:        make(map[crypto.Hash]*Edit),
 		Releases:     make(map[crypto.Hash]*Release),
 		Events:       make(map[crypto.Hash]*Event),
 		Collectives:  make(map[crypto.Hash]*Collective),
 		Boards:       make(map[crypto.Hash]*Board),
+		Journals:     make(map[crypto.Hash]*Journal),
+		Issues:       make(map[crypto.Hash]*Issue),
 		Proposals:    NewProposals(indexer),
 		Deadline:     make(map[uint64][]crypto.Hash),
+		Comments:     make(map[crypto.Hash]*actions.Comment),
+		VoteHistory:  make(map[crypto.Hash][]actions.Vote),
+		Reacted:      make(map[crypto.Hash]map[crypto.Token]uint64),
 		index:        indexer,
+		media:        NewMemoryMediaStore(),
 	}
 	for n := 0; n < ReactionsCount; n++ {
 		state.Reactions[n] = make(map[crypto.Hash]uint)
@@ -353,23 +437,135 @@ func (s *State) Board(name string) (*Board, bool) {
 	return col, ok
 }
 
+func (s *State) Journal(name string) (*Journal, bool) {
+	hash := crypto.Hasher([]byte(name))
+	journal, ok := s.Journals[hash]
+	return journal, ok
+}
+
 func (s *State) IsMember(token crypto.Token) bool {
 	hash := crypto.HashToken(token)
 	_, ok := s.Members[hash]
 	return ok
 }
 
+// SetNotifier liga o canal de notificacao em tempo real. Sem notifier as
+// notificacoes sao descartadas.
+func (s *State) SetNotifier(notifier Notifier) {
+	s.action = notifier
+}
+
 func (s *State) Notify(origin Action, objHash crypto.Hash) {
+	if s.action == nil {
+		return
+	}
 	s.action.Notify(origin, s.hashToObjectType(objHash), objHash)
 }
 
-func (s *State) NextBlock() {
-	if deadline, ok := s.Deadline[s.Epoch]; ok {
-		for _, hash := range deadline {
-			s.Proposals.Delete(hash)
-			s.Notify(ExpireProposal, hash)
+// SetEpoch avanca o estado para o epoch e expira as propostas cujo prazo
+// terminou ate ele.
+func (s *State) SetEpoch(epoch uint64) {
+	s.mu.Lock()
+	if epoch > s.Epoch {
+		s.Epoch = epoch
+	}
+	expired := s.NextBlock()
+	if s.index != nil {
+		s.index.IndexBlock(s.Epoch)
+	}
+	s.mu.Unlock()
+	// como em Action, notifica fora da trava: o hub le o indice com Read
+	for _, hash := range expired {
+		s.Notify(ExpireProposal, hash)
+	}
+}
+
+// Read runs f with the state locked against Action and SetEpoch, for the
+// reads of the http goroutines that walk maps changed by the blocks.
+func (s *State) Read(f func()) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f()
+}
+
+// NextBlock expira todas as propostas com prazo ate o epoch atual que ainda
+// estao pendentes. A proposta sai de Proposals, o indice guarda o estado
+// Expired e os votos ja dados, e as propostas expiradas sao retornadas para
+// que os interessados sejam notificados. Apenas os epochs desde a ultima
+// chamada sao consultados; na primeira (genesis ou estado restaurado) todos os
+// prazos sao verificados.
+func (s *State) NextBlock() []crypto.Hash {
+	expired := make([]crypto.Hash, 0)
+	epochs := make([]uint64, 0)
+	if s.expired == 0 {
+		for epoch := range s.Deadline {
+			if epoch <= s.Epoch {
+				epochs = append(epochs, epoch)
+			}
+		}
+		sort.Slice(epochs, func(n, m int) bool { return epochs[n] < epochs[m] })
+	} else {
+		// setDeadline so aceita prazos posteriores ao epoch atual
+		for epoch := s.expired + 1; epoch <= s.Epoch; epoch++ {
+			if _, ok := s.Deadline[epoch]; ok {
+				epochs = append(epochs, epoch)
+			}
 		}
 	}
+	for _, epoch := range epochs {
+		for _, hash := range s.Deadline[epoch] {
+			if s.expire(hash) {
+				expired = append(expired, hash)
+			}
+		}
+		delete(s.Deadline, epoch)
+	}
+	if s.Epoch > s.expired {
+		s.expired = s.Epoch
+	}
+	return expired
+}
+
+// expire returns true if the proposal expired without a decision.
+func (s *State) expire(hash crypto.Hash) bool {
+	if !s.Proposals.Has(hash) {
+		// ja concluida por votacao
+		return false
+	}
+	if s.closeVote(hash) {
+		// decidida no prazo pelos membros que votaram
+		return false
+	}
+	if s.index != nil {
+		s.index.IndexActionStatus(hash, Expired)
+	}
+	s.IndexConsensus(hash, Expired)
+	s.Proposals.Delete(hash)
+	return true
+}
+
+// closeVote decides a proposal governed by a QuorumPolicy collective with the
+// votes cast until the deadline. Returns true if the proposal was concluded.
+func (s *State) closeVote(hash crypto.Hash) bool {
+	proposal := s.Proposals.Get(hash)
+	if proposal == nil {
+		return false
+	}
+	closed := false
+	for _, collective := range s.Proposals.Governing(hash) {
+		if collective.Policy.Kind == actions.QuorumPolicy {
+			collective.closeVote(hash)
+			defer collective.reopenVote(hash)
+			closed = true
+		}
+	}
+	if !closed {
+		return false
+	}
+	if err := proposal.Evaluate(s); err != nil {
+		log.Printf("PANIC BUG: could not evaluate proposal %v at deadline: %v", crypto.EncodeHash(hash), err)
+	}
+	return !s.Proposals.Has(hash)
 }
 
 func (s *State) hashToObjectType(hash crypto.Hash) Object {
@@ -385,13 +581,39 @@ func (s *State) hashToObjectType(hash crypto.Hash) Object {
 	if _, ok := s.Media[hash]; ok {
 		return MediaObject
 	}
+	if _, ok := s.Boards[hash]; ok {
+		return BoardObject
+	}
+	if _, ok := s.Journals[hash]; ok {
+		return JournalObject
+	}
+	if _, ok := s.Issues[hash]; ok {
+		return JournalObject
+	}
+	if _, ok := s.Collectives[hash]; ok {
+		return CollectiveObject
+	}
+	if _, ok := s.Events[hash]; ok {
+		return EventObject
+	}
 	return NoObject
 }
 
+// prazo da proposta a partir do epoch em que foi feita: o do coletivo em
+// nome do qual e feita ou ProposalDeadline
+func (s *State) setProposalDeadline(epoch uint64, hash crypto.Hash, c Consensual) {
+	expiry := uint64(ProposalDeadline)
+	if collective, ok := c.(*Collective); ok && collective != nil {
+		expiry = collective.ProposalExpiry()
+	}
+	s.setDeadline(epoch+expiry, hash)
+}
+
 func (s *State) setDeadline(epoch uint64, hash crypto.Hash) {
 	if epoch <= s.Epoch {
 		return
 	}
+	s.Proposals.SetDeadline(hash, epoch)
 	if deadlines, ok := s.Deadline[epoch]; ok {
 		s.Deadline[epoch] = append(deadlines, hash)
 	} else {
@@ -400,30 +622,20 @@ func (s *State) setDeadline(epoch uint64, hash crypto.Hash) {
 }
 
 func (s *State) GreetCheckinEvent(greet *actions.GreetCheckinEvent) error {
-	event, ok := s.Events[greet.EventHash]
-	if !ok {
-		return errors.New("event not found")
-	}
-	greeting, ok := event.Checkin[greet.CheckedIn]
-	if !ok {
-		return errors.New("checkin not found")
+	if err := s.validateGreetCheckinEvent(greet); err != nil {
+		return err
 	}
+	greeting := s.Events[greet.EventHash].Checkin[greet.CheckedIn]
 	greeting.Action = greet
 	return nil
 }
 
 func (s *State) ImprintStamp(stamp *actions.ImprintStamp) error {
-	if !s.IsMember(stamp.Author) {
-		return errors.New("not a member")
-	}
-	release, ok := s.Releases[stamp.Hash]
-	if !ok {
-		return errors.New("release not found")
-	}
-	collective, ok := s.Collective(stamp.OnBehalfOf)
-	if !ok {
-		return errors.New("collective not found")
+	if err := s.validateImprintStamp(stamp); err != nil {
+		return err
 	}
+	release := s.Releases[stamp.Hash]
+	collective, _ := s.Collective(stamp.OnBehalfOf)
 	hash := stamp.Hashed()
 	vote := actions.Vote{
 		Epoch:   stamp.Epoch,
@@ -439,36 +651,55 @@ func (s *State) ImprintStamp(stamp *actions.ImprintStamp) error {
 		Votes:      []actions.Vote{},
 	}
 	s.Proposals.AddStamp(&newStamp, stamp)
+	s.setProposalDeadline(stamp.Epoch, hash, collective)
 	return newStamp.IncorporateVote(vote, s)
 }
 
 func (s *State) CheckinEvent(checkin *actions.CheckinEvent) error {
-	if !s.IsMember(checkin.Author) {
-		return errors.New("not an author")
-	}
-	event, ok := s.Events[checkin.EventHash]
-	if !ok {
-		return errors.New("event not found")
+	if err := s.validateCheckinEvent(checkin); err != nil {
+		return err
 	}
-	if _, ok := event.Checkin[checkin.Author]; ok {
-		return errors.New("already checkin")
+	event := s.Events[checkin.EventHash]
+	event.CheckinReasons[checkin.Author] = checkin.Reasons
+	if event.Full() {
+		event.Waitlist = append(event.Waitlist, Waiting{Token: checkin.Author, EphemeralKey: checkin.EphemeralToken})
+		return nil
 	}
 	event.Checkin[checkin.Author] = &Greeting{Action: nil, EphemeralKey: checkin.EphemeralToken}
-	event.CheckinReasons[checkin.Author] = checkin.Reasons
 	if s.index != nil {
 		s.index.AddCheckin(checkin.Author, event)
 	}
 	return nil
 }
 
-func (s *State) UpdateEvent(update *actions.UpdateEvent) error {
-	event, ok := s.Events[update.EventHash]
-	if !ok {
-		return errors.New("event not found")
+// CancelCheckinEvent withdraws the author from the event or from its waitlist.
+// A vacant place goes to the first member on the waitlist.
+func (s *State) CancelCheckinEvent(cancel *actions.CancelCheckinEvent) error {
+	if err := s.validateCancelCheckinEvent(cancel); err != nil {
+		return err
+	}
+	event := s.Events[cancel.EventHash]
+	if position := event.WaitlistPosition(cancel.Author); position > 0 {
+		event.Waitlist = append(event.Waitlist[:position-1], event.Waitlist[position:]...)
+		delete(event.CheckinReasons, cancel.Author)
+		return nil
 	}
-	if !event.Managers.IsMember(update.Author) {
-		return errors.New("not a manager of the event")
+	delete(event.Checkin, cancel.Author)
+	delete(event.CheckinReasons, cancel.Author)
+	if s.index != nil {
+		s.index.RemoveCheckin(cancel.Author, event)
+	}
+	if event.Live {
+		event.promote(s)
 	}
+	return nil
+}
+
+func (s *State) UpdateEvent(update *actions.UpdateEvent) error {
+	if err := s.validateUpdateEvent(update); err != nil {
+		return err
+	}
+	event := s.Events[update.EventHash]
 	hash := update.Hashed()
 	selfVote := actions.Vote{
 		Epoch:   update.Epoch,
@@ -485,6 +716,9 @@ func (s *State) UpdateEvent(update *actions.UpdateEvent) error {
 		Venue:        update.Venue,
 		Open:         update.Open,
 		Public:       update.Public,
+		Capacity:     update.Capacity,
+		RSVPClose:    update.RSVPClose,
+		Occurrence:   update.Occurrence,
 		Hash:         hash,
 		Votes:        []actions.Vote{},
 	}
@@ -505,17 +739,15 @@ func (s *State) UpdateEvent(update *actions.UpdateEvent) error {
 		pending.Managers.Majority = event.Managers.Majority
 	}
 	s.Proposals.AddEventUpdate(&pending, update)
+	s.setProposalDeadline(update.Epoch, hash, event.Collective)
 	return pending.IncorporateVote(selfVote, s)
 }
 
 func (s *State) CancelEvent(cancel *actions.CancelEvent) error {
-	event, ok := s.Events[cancel.Hash]
-	if !ok {
-		return fmt.Errorf("event not found: %v", crypto.EncodeHash(cancel.Hash))
-	}
-	if !event.Managers.IsMember(cancel.Author) {
-		return errors.New("not a manager")
+	if err := s.validateCancelEvent(cancel); err != nil {
+		return err
 	}
+	event := s.Events[cancel.Hash]
 	hash := cancel.Hashed()
 	selfVote := actions.Vote{
 		Epoch:   cancel.Epoch,
@@ -525,23 +757,22 @@ func (s *State) CancelEvent(cancel *actions.CancelEvent) error {
 		Approve: true,
 	}
 	pending := CancelEvent{
-		Event:   event,
-		Hash:    hash,
-		Votes:   []actions.Vote{},
-		Reasons: cancel.Reasons,
+		Event:      event,
+		Hash:       hash,
+		Votes:      []actions.Vote{},
+		Reasons:    cancel.Reasons,
+		Occurrence: cancel.Occurrence,
 	}
 	s.Proposals.AddCancelEvent(&pending, cancel)
+	s.setProposalDeadline(cancel.Epoch, hash, event.Collective)
 	return pending.IncorporateVote(selfVote, s)
 }
 
 func (s *State) CreateEvent(create *actions.CreateEvent) error {
-	if !s.IsMember(create.Author) {
-		return errors.New("not a member")
-	}
-	collective, ok := s.Collective(create.OnBehalfOf)
-	if !ok {
-		return errors.New("collective not found")
+	if err := s.validateCreateEvent(create); err != nil {
+		return err
 	}
+	collective, _ := s.Collective(create.OnBehalfOf)
 	hash := create.Hashed()
 	vote := actions.Vote{
 		Epoch:   create.Epoch,
@@ -564,6 +795,11 @@ func (s *State) CreateEvent(create *actions.CreateEvent) error {
 		CheckinReasons: make(map[crypto.Token]string),
 		Live:           false,
 		EventReasons:   create.Reasons,
+		Capacity:       create.Capacity,
+		RSVPClose:      create.RSVPClose,
+		Waitlist:       make([]Waiting, 0),
+		Recurrence:     create.Recurrence,
+		Exceptions:     make(map[uint64]*Occurrence),
 	}
 	if len(create.Managers) > 0 {
 		managers := make(map[crypto.Token]struct{})
@@ -575,38 +811,74 @@ func (s *State) CreateEvent(create *actions.CreateEvent) error {
 			Majority: int(create.ManagerMajority),
 		}
 	}
-	if s.Proposals.Has(hash) {
-		return errors.New("event already booked")
-	}
 	s.Proposals.AddEvent(&event, create)
+	s.setProposalDeadline(create.Epoch, hash, collective)
 	return event.IncorporateVote(vote, s)
 }
 
+// abre um media pendente a partir da primeira parte (enviada no draft ou edit)
+func (s *State) pendingMedia(first *actions.MultipartMedia) error {
+	pending := PendingMedia{
+		Hash:          first.Hash,
+		NumberOfParts: first.Of,
+		Parts:         make([]*actions.MultipartMedia, int(first.Of)),
+	}
+	if _, err := pending.Append(first, s.media); err != nil {
+		return err
+	}
+	s.PendingMedia[first.Hash] = &pending
+	return nil
+}
+
+// SetMediaStore substitui o armazenamento do conteudo dos media. Deve ser
+// chamado antes de incorporar qualquer acao.
+func (s *State) SetMediaStore(store MediaStore) {
+	s.media = store
+}
+
+func (s *State) MediaStore() MediaStore {
+	return s.media
+}
+
+// GetMedia retorna o conteudo de um media incorporado ao estado
+func (s *State) GetMedia(hash crypto.Hash) ([]byte, bool) {
+	if _, ok := s.Media[hash]; !ok {
+		return nil, false
+	}
+	data, err := s.media.Get(hash)
+	if err != nil {
+		log.Printf("PANIC BUG: media %v incorporated but not on the store: %v", crypto.EncodeHash(hash), err)
+		return nil, false
+	}
+	return data, true
+}
+
 func (s *State) MultipartMedia(media *actions.MultipartMedia) error {
-	pending, ok := s.PendingMedia[media.Hash]
-	if !ok {
-		return errors.New("referred media not found")
+	if err := s.validateMultipartMedia(media); err != nil {
+		return err
 	}
-	total, err := pending.Append(media)
+	pending := s.PendingMedia[media.Hash]
+	total, err := pending.Append(media, s.media)
 	if err != nil {
 		return err
 	}
 	if total != nil {
+		if err := s.media.Put(media.Hash, total); err != nil {
+			return err
+		}
+		pending.Discard(s.media)
 		delete(s.PendingMedia, media.Hash)
-		s.Media[media.Hash] = total
+		s.Media[media.Hash] = struct{}{}
 		//s.Notify(MediaUpload, media.Hash)
 	}
 	return nil
 }
 
 func (s *State) ReleaseDraft(release *actions.ReleaseDraft) error {
-	draft, ok := s.Drafts[release.ContentHash]
-	if !ok {
-		return errors.New("draft not found")
-	}
-	if !draft.Authors.IsMember(release.Author) {
-		return errors.New("not an author")
+	if err := s.validateReleaseDraft(release); err != nil {
+		return err
 	}
+	draft := s.Drafts[release.ContentHash]
 	hash := release.Hashed()
 	vote := actions.Vote{
 		Epoch:   release.Epoch,
@@ -633,20 +905,15 @@ func (s *State) ReleaseDraft(release *actions.ReleaseDraft) error {
 	// 	return nil
 	// }
 	s.Proposals.AddRelease(&newRelease, release)
+	s.setProposalDeadline(release.Epoch, hash, draft.Authors)
 	return newRelease.IncorporateVote(vote, s)
 }
 
 func (s *State) UpdateBoard(update *actions.UpdateBoard) error {
-	if !s.IsMember(update.Author) {
-		return errors.New("not a member")
-	}
-	board, ok := s.Board(update.Board)
-	if !ok {
-		return errors.New("board not found")
-	}
-	if (!board.Collective.IsMember(update.Author)) && (!board.Editors.IsMember(update.Author)) {
-		return errors.New("not a member of collective or and editor")
+	if err := s.validateUpdateBoard(update); err != nil {
+		return err
 	}
+	board, _ := s.Board(update.Board)
 	// hash := crypto.Hasher([]byte(update.Serialize()))
 	hash := update.Hashed()
 	vote := actions.Vote{
@@ -678,21 +945,16 @@ func (s *State) UpdateBoard(update *actions.UpdateBoard) error {
 		Votes:       []actions.Vote{},
 	}
 	s.Proposals.AddPendingUpdateBoard(&pending, update)
+	s.setProposalDeadline(update.Epoch, hash, board.Collective)
 	return pending.IncorporateVote(vote, s)
 	// TODO notify
 }
 
 func (s *State) CreateBoard(board *actions.CreateBoard) error {
-	if !s.IsMember(board.Author) {
-		return errors.New("not a member")
-	}
-	if _, ok := s.Board(board.Name); ok {
-		return errors.New("board already exists")
-	}
-	collective, ok := s.Collective(board.OnBehalfOf)
-	if !ok {
-		return errors.New("collective unkown")
+	if err := s.validateCreateBoard(board); err != nil {
+		return err
 	}
+	collective, _ := s.Collective(board.OnBehalfOf)
 	hash := board.Hashed()
 	newBoard := Board{
 		Name:        board.Name,
@@ -724,19 +986,17 @@ func (s *State) CreateBoard(board *actions.CreateBoard) error {
 		Votes:  []actions.Vote{},
 	}
 	s.Proposals.AddPendingBoard(pendingboard, board)
+	s.setProposalDeadline(board.Epoch, hash, collective)
 	// TODO: notify
 	return pendingboard.IncorporateVote(vote, s)
 }
 
 func (s *State) SignIn(signin *actions.Signin) error {
-	hash := crypto.HashToken(signin.Author)
-	if _, ok := s.Members[hash]; ok {
-		return errors.New("already a member of synergy")
+	if err := s.validateSignIn(signin); err != nil {
+		return err
 	}
+	hash := crypto.HashToken(signin.Author)
 	user := s.Axe.Handle(signin.Author)
-	if user == nil {
-		return errors.New("protocol error: not a recognized member of axe")
-	}
 	s.Members[hash] = user.Handle
 	s.MembersIndex[user.Handle] = signin.Author
 	if s.index != nil {
@@ -747,37 +1007,36 @@ func (s *State) SignIn(signin *actions.Signin) error {
 }
 
 func (s *State) CreateCollective(create *actions.CreateCollective) error {
-	if !s.IsMember(create.Author) {
-		return errors.New("not a member of synergy")
-	}
-	if _, ok := s.Collective(create.Name); ok {
-		return errors.New("collective already exists")
-	}
-	if create.Policy.Majority < 0 || create.Policy.Majority > 100 || create.Policy.SuperMajority < 0 || create.Policy.SuperMajority > 100 {
-		return errors.New("invalid policy")
+	if err := s.validateCreateCollective(create); err != nil {
+		return err
 	}
 	// hash := crypto.Hasher([]byte(create.Name))
 	hash := create.Hashed()
-	s.Collectives[hash] = &Collective{
+	collective := &Collective{
 		Name:        create.Name,
 		Members:     map[crypto.Token]struct{}{create.Author: {}},
 		Description: create.Description,
 		Policy: actions.Policy{
 			Majority:      create.Policy.Majority,
 			SuperMajority: create.Policy.SuperMajority,
+			Kind:          create.Policy.Kind,
+			Quorum:        create.Policy.Quorum,
+			Veto:          create.Policy.Veto,
 		},
+		Expiry: create.Expiry,
+	}
+	s.Collectives[hash] = collective
+	if s.index != nil {
+		s.index.AddCollectiveToIndex(collective)
 	}
 	return nil
 }
 
 func (s *State) UpdateCollective(update *actions.UpdateCollective) error {
-	collective, ok := s.Collective(update.OnBehalfOf)
-	if !ok {
-		return errors.New("unkown collective")
-	}
-	if !collective.IsMember(update.Author) {
-		return errors.New("not a member of collective")
+	if err := s.validateUpdateCollective(update); err != nil {
+		return err
 	}
+	collective, _ := s.Collective(update.OnBehalfOf)
 	hash := crypto.Hasher(update.Serialize()) // proposal hash = hash of instruction
 	vote := actions.Vote{
 		Epoch:   update.Epoch,
@@ -829,29 +1088,20 @@ func (s *State) UpdateCollective(update *actions.UpdateCollective) error {
 		Hash:       hash,
 		Votes:      []actions.Vote{},
 	}
-	if update.Majority != nil || update.SuperMajority != nil {
+	if update.Majority != nil || update.SuperMajority != nil || update.Expiry != nil {
 		pending.ChangePolicy = true
 	}
 	s.Proposals.AddUpdateCollective(&pending, update)
-	s.setDeadline(update.Epoch+ProposalDeadline, hash)
+	s.setProposalDeadline(update.Epoch, hash, collective)
 	return pending.IncorporateVote(vote, s)
 
 }
 
 func (s *State) RequestMembership(request *actions.RequestMembership) error {
-	if !s.IsMember(request.Author) {
-		return errors.New("not a member of synergy")
-	}
-	collective, ok := s.Collective(request.Collective)
-	if !ok {
-		return errors.New("collective not found")
-	}
-	if request.Include && collective.IsMember(request.Author) {
-		return errors.New("already a member")
-	}
-	if (!request.Include) && (!collective.IsMember(request.Author)) {
-		return errors.New("not a member of collective")
+	if err := s.validateRequestMembership(request); err != nil {
+		return err
 	}
+	collective, _ := s.Collective(request.Collective)
 	if !request.Include {
 		delete(collective.Members, request.Author)
 		return nil
@@ -872,21 +1122,15 @@ func (s *State) RequestMembership(request *actions.RequestMembership) error {
 		Approve: true,
 	}
 	s.Proposals.AddRequestMembership(&pending, request)
-	s.setDeadline(request.Epoch+ProposalDeadline, hash)
+	s.setProposalDeadline(request.Epoch, hash, collective)
 	return pending.IncorporateVote(vote, s)
 }
 
 func (s *State) RemoveMember(remove *actions.RemoveMember) error {
-	collective, ok := s.Collective(remove.OnBehalfOf)
-	if !ok {
-		return errors.New("collective not found")
-	}
-	if !collective.IsMember(remove.Author) {
-		return errors.New("author not a member of collective")
-	}
-	if !collective.IsMember(remove.Member) {
-		return errors.New("member to be removed not a member of collective")
+	if err := s.validateRemoveMember(remove); err != nil {
+		return err
 	}
+	collective, _ := s.Collective(remove.OnBehalfOf)
 	if remove.Author.Equal(remove.Member) {
 		delete(collective.Members, remove.Author)
 		if s.index != nil {
@@ -914,13 +1158,26 @@ func (s *State) RemoveMember(remove *actions.RemoveMember) error {
 		Votes:      []actions.Vote{},
 	}
 	s.Proposals.AddPendingRemoveMember(&pending, remove)
-	s.setDeadline(remove.Epoch+ProposalDeadline, hash)
+	s.setProposalDeadline(remove.Epoch, hash, collective)
 	return pending.IncorporateVote(vote, s)
 }
 
+func (s *State) Delegate(delegate *actions.Delegate) error {
+	if err := s.validateDelegate(delegate); err != nil {
+		return err
+	}
+	collective, _ := s.Collective(delegate.Collective)
+	if delegate.Revoke {
+		collective.Revoke(delegate.Author, delegate.Kinds)
+		return nil
+	}
+	collective.Delegate(delegate.Author, delegate.Delegate, delegate.Kinds)
+	return nil
+}
+
 func (s *State) React(reaction *actions.React) error {
-	if reaction.Reaction >= ReactionsCount {
-		return errors.New("invalid reaction")
+	if err := s.validateReact(reaction); err != nil {
+		return err
 	}
 	// TODO: should check if hash is known?
 	if count, ok := s.Reactions[reaction.Reaction][reaction.Hash]; ok {
@@ -928,37 +1185,43 @@ func (s *State) React(reaction *actions.React) error {
 	} else {
 		s.Reactions[reaction.Reaction][reaction.Hash] = 1
 	}
+	if s.Reacted[reaction.Hash] == nil {
+		s.Reacted[reaction.Hash] = make(map[crypto.Token]uint64)
+	}
+	if _, ok := s.Reacted[reaction.Hash][reaction.Author]; !ok {
+		s.Reacted[reaction.Hash][reaction.Author] = reaction.Epoch
+	}
+	if s.index != nil {
+		s.index.AddReactionToIndex(reaction)
+	}
 	return nil
 }
 
 func (s *State) Edit(edit *actions.Edit) error {
-	if _, ok := s.Media[edit.ContentHash]; ok {
-		return errors.New("hash already claimed")
-	}
-	if _, ok := s.PendingMedia[edit.ContentHash]; ok {
-		return errors.New("hash already claimed")
+	if err := s.validateEditMedia(edit); err != nil {
+		return err
 	}
 	if edit.NumberOfParts > 1 {
-		pending := PendingMedia{
-			Hash:          edit.ContentHash,
-			NumberOfParts: edit.NumberOfParts,
-			Parts:         make([]*actions.MultipartMedia, int(edit.NumberOfParts)),
-		}
-		s.PendingMedia[edit.ContentHash] = &pending
-		pending.Parts[0] = &actions.MultipartMedia{
+		first := actions.MultipartMedia{
 			Hash: edit.ContentHash,
 			Part: 0,
 			Of:   edit.NumberOfParts,
 			Data: edit.Content,
 		}
+		if err := s.pendingMedia(&first); err != nil {
+			return err
+		}
 	} else {
-		s.Media[edit.ContentHash] = edit.Content
+		if err := s.media.Put(edit.ContentHash, edit.Content); err != nil {
+			return err
+		}
+		s.Media[edit.ContentHash] = struct{}{}
 	}
-	draft, ok := s.Drafts[edit.EditedDraft]
-	if !ok {
-		return errors.New("unkown draft")
+	// o hash fica reservado mesmo que a edicao seja rejeitada
+	if err := s.validateEditDraft(edit); err != nil {
+		return err
 	}
-
+	draft := s.Drafts[edit.EditedDraft]
 	newEdit := Edit{
 		Date:     edit.Epoch,
 		Reasons:  edit.Reasons,
@@ -977,13 +1240,7 @@ func (s *State) Edit(edit *actions.Edit) error {
 	}
 
 	if edit.OnBehalfOf != "" {
-		collective, ok := s.Collective(edit.OnBehalfOf)
-		if !ok {
-			return errors.New("collective unkown")
-		}
-		if !collective.IsMember(edit.Author) {
-			return errors.New("not a member of collective")
-		}
+		collective, _ := s.Collective(edit.OnBehalfOf)
 		newEdit.Authors = collective
 		//if collective.Consensus(edit.ContentHash, newEdit.Votes) {
 		//	s.Edits[edit.ContentHash] = &newEdit
@@ -1000,6 +1257,7 @@ func (s *State) Edit(edit *actions.Edit) error {
 		//draft.Edits = append(draft.Edits, &newEdit)
 	}
 	s.Proposals.AddEdit(&newEdit, edit)
+	s.setProposalDeadline(edit.Epoch, edit.ContentHash, newEdit.Authors)
 	if err := newEdit.IncorporateVote(newVote, s); err != nil {
 		return err
 	}
@@ -1031,16 +1289,11 @@ func (s *State) Edit(edit *actions.Edit) error {
 //
 // d)
 func (s *State) Draft(draft *actions.Draft) error {
-	if _, ok := s.Media[draft.ContentHash]; ok {
-		return errors.New("media file already drafted")
+	if err := s.validateDraftMedia(draft); err != nil {
+		return err
 	}
 	if draft.NumberOfParts > 1 {
-		pending := PendingMedia{
-			Hash:          draft.ContentHash,
-			NumberOfParts: draft.NumberOfParts,
-			Parts:         make([]*actions.MultipartMedia, draft.NumberOfParts),
-		}
-		pending.Parts[0] = &actions.MultipartMedia{
+		first := actions.MultipartMedia{
 			Epoch:  draft.Epoch,
 			Author: draft.Author,
 			Hash:   draft.ContentHash,
@@ -1048,23 +1301,21 @@ func (s *State) Draft(draft *actions.Draft) error {
 			Of:     draft.NumberOfParts,
 			Data:   draft.Content,
 		}
-		s.PendingMedia[draft.ContentHash] = &pending
+		if err := s.pendingMedia(&first); err != nil {
+			return err
+		}
 	} else {
-		if !crypto.Hasher(draft.Content).Equal(draft.ContentHash) {
-			return errors.New("hash não está batendo")
+		if err := s.media.Put(draft.ContentHash, draft.Content); err != nil {
+			return err
 		}
-		s.Media[draft.ContentHash] = draft.Content
+		s.Media[draft.ContentHash] = struct{}{}
+	}
+	// o hash fica reservado mesmo que o esboco seja rejeitado
+	if err := s.validateDraftAuthors(draft); err != nil {
+		return err
 	}
 	var previous *Draft
 	if draft.PreviousDraft != crypto.ZeroHash && draft.PreviousDraft != crypto.ZeroValueHash {
-		if previous, ok := s.Drafts[draft.PreviousDraft]; !ok {
-			return errors.New("versão anterior inválida")
-		} else {
-			isPreviousAuthor := previous.Authors.IsMember(draft.Author)
-			if !isPreviousAuthor {
-				return errors.New("versão não autorizada - sem autoria na versão original")
-			}
-		}
 		previous = s.Drafts[draft.PreviousDraft]
 	}
 	selfVote := actions.Vote{
@@ -1093,10 +1344,7 @@ func (s *State) Draft(draft *actions.Draft) error {
 			//newDraft.Aproved = true
 			//s.Drafts[newDraft.DraftHash] = newDraft
 		} else {
-			behalf, ok := s.Collective(draft.OnBehalfOf)
-			if !ok {
-				return errors.New("nome do coletivo não foi reconhecido")
-			}
+			behalf, _ := s.Collective(draft.OnBehalfOf)
 			newDraft.Authors = behalf
 			//if behalf.Consensus(newDraft.DraftHash, newDraft.Votes) {
 			//	newDraft.Aproved = true
@@ -1119,6 +1367,7 @@ func (s *State) Draft(draft *actions.Draft) error {
 	//	s.action.Notify(DraftAction, DraftObject, draft.PreviousDraft)
 	//}
 	s.Proposals.AddDraft(newDraft, draft)
+	s.setProposalDeadline(draft.Epoch, draft.ContentHash, newDraft.Authors)
 	if err := newDraft.IncorporateVote(selfVote, s); err != nil {
 		return err
 	}
@@ -1129,23 +1378,28 @@ func (s *State) Draft(draft *actions.Draft) error {
 }
 
 func (s *State) Vote(vote *actions.Vote) error {
+	if err := s.validateVote(vote); err != nil {
+		return err
+	}
+	var err error
 	if draft, ok := s.Drafts[vote.Hash]; ok {
-		return draft.IncorporateVote(*vote, s)
+		err = draft.IncorporateVote(*vote, s)
+	} else {
+		err = s.Proposals.IncorporateVote(*vote, s)
+	}
+	if err == nil {
+		s.VoteHistory[vote.Hash] = append(s.VoteHistory[vote.Hash], *vote)
 	}
-	return s.Proposals.IncorporateVote(*vote, s)
+	return err
 }
 
 func (s *State) Pin(pin *actions.Pin) error {
-	// existe o board no state?
-	board, ok := s.Board(pin.Board)
-	if !ok {
-		return errors.New("invalid board")
-	}
-	// existe o draft no state?
-	draft, ok := s.Drafts[pin.Draft]
-	if !ok {
-		return errors.New("invalid draft")
+	// existem o board e o draft no state?
+	if err := s.validatePin(pin); err != nil {
+		return err
 	}
+	board, _ := s.Board(pin.Board)
+	draft := s.Drafts[pin.Draft]
 	// criando o byte array pra gerar o hash
 	hash := pin.Hashed()
 
@@ -1166,17 +1420,15 @@ func (s *State) Pin(pin *actions.Pin) error {
 	}
 	// coloca a proposta de pin criado nos proposals
 	s.Proposals.AddPin(&action, pin)
+	s.setProposalDeadline(pin.Epoch, hash, board.Collective)
 	return action.IncorporateVote(selfVote, s)
 }
 
 func (s *State) BoardEditor(action *actions.BoardEditor) error {
-	board, ok := s.Board(action.Board)
-	if !ok {
-		return errors.New("invalid board")
-	}
-	if s.IsMember(action.Editor); !ok { // should be
-		return errors.New("invalid editor")
+	if err := s.validateBoardEditor(action); err != nil {
+		return err
 	}
+	board, _ := s.Board(action.Board)
 	hash := action.Hashed()
 	proposal := BoardEditor{
 		Hash:   hash,
@@ -1194,5 +1446,155 @@ func (s *State) BoardEditor(action *actions.BoardEditor) error {
 		Approve: true,
 	}
 	s.Proposals.AddBoardEditor(&proposal, action)
+	s.setProposalDeadline(action.Epoch, hash, board.Collective)
 	return proposal.IncorporateVote(selfVote, s)
 }
+
+func (s *State) CreateJournal(create *actions.CreateJournal) error {
+	if err := s.validateCreateJournal(create); err != nil {
+		return err
+	}
+	collective, _ := s.Collective(create.OnBehalfOf)
+	hash := create.Hashed()
+	journal := Journal{
+		Name:        create.Name,
+		Description: create.Description,
+		Keywords:    create.Keywords,
+		Collective:  collective,
+		Editors: &UnamedCollective{
+			Members: map[crypto.Token]struct{}{create.Author: {}},
+		},
+		Issues: make([]*Issue, 0),
+		Hash:   hash,
+	}
+	vote := actions.Vote{
+		Epoch:   create.Epoch,
+		Author:  create.Author,
+		Reasons: "commit",
+		Hash:    hash,
+		Approve: true,
+	}
+	pending := &PendingJournal{
+		Origin:  create,
+		Journal: &journal,
+		Hash:    hash,
+		Votes:   []actions.Vote{},
+	}
+	s.Proposals.AddPendingJournal(pending, create)
+	s.setProposalDeadline(create.Epoch, hash, collective)
+	return pending.IncorporateVote(vote, s)
+}
+
+func (s *State) JournalEditor(action *actions.JournalEditor) error {
+	if err := s.validateJournalEditor(action); err != nil {
+		return err
+	}
+	journal, _ := s.Journal(action.Journal)
+	hash := action.Hashed()
+	proposal := JournalEditor{
+		Hash:    hash,
+		Epoch:   action.Epoch,
+		Journal: journal,
+		Editor:  action.Editor,
+		Insert:  action.Insert,
+		Votes:   make([]actions.Vote, 0),
+	}
+	selfVote := actions.Vote{
+		Epoch:   action.Epoch,
+		Author:  action.Author,
+		Reasons: "submission",
+		Hash:    hash,
+		Approve: true,
+	}
+	s.Proposals.AddJournalEditor(&proposal, action)
+	s.setProposalDeadline(action.Epoch, hash, journal.Collective)
+	return proposal.IncorporateVote(selfVote, s)
+}
+
+// JournalIssue incorpora a proposta de um novo numero de um periodico. Apenas
+// editores propoem, e apenas lancamentos com selo podem ser incluidos.
+func (s *State) JournalIssue(action *actions.JournalIssue) error {
+	if err := s.validateJournalIssue(action); err != nil {
+		return err
+	}
+	journal, _ := s.Journal(action.Journal)
+	issue := Issue{
+		Journal:     journal,
+		Title:       action.Title,
+		Description: action.Description,
+		Drafts:      make([]*Draft, 0, len(action.Drafts)),
+		Hash:        action.Hashed(),
+	}
+	for _, hash := range action.Drafts {
+		draft, _ := stamped(s, hash)
+		issue.Drafts = append(issue.Drafts, draft)
+	}
+	selfVote := actions.Vote{
+		Epoch:   action.Epoch,
+		Author:  action.Author,
+		Reasons: "submission",
+		Hash:    issue.Hash,
+		Approve: true,
+	}
+	pending := &PendingIssue{
+		Origin: action,
+		Issue:  &issue,
+		Hash:   issue.Hash,
+		Votes:  make([]actions.Vote, 0),
+	}
+	s.Proposals.AddPendingIssue(pending, action)
+	s.setProposalDeadline(action.Epoch, issue.Hash, journal.Collective)
+	return pending.IncorporateVote(selfVote, s)
+}
+
+// Comment incorpora um comentario sobre um draft, edit, evento ou proposta
+// pendente. Respostas devem apontar para um comentario sobre o mesmo objeto.
+func (s *State) Comment(comment *actions.Comment) error {
+	if err := s.validateComment(comment); err != nil {
+		return err
+	}
+	hash := comment.Hashed()
+	s.Comments[hash] = comment
+	if s.index != nil {
+		s.index.AddCommentToIndex(comment)
+	}
+	return nil
+}
+
+func (s *State) commentable(hash crypto.Hash) bool {
+	if _, ok := s.Drafts[hash]; ok {
+		return true
+	}
+	if _, ok := s.Edits[hash]; ok {
+		return true
+	}
+	if _, ok := s.Events[hash]; ok {
+		return true
+	}
+	return s.Proposals.Has(hash)
+}
+
+// um edit do tipo patch deve ser sobre um draft de texto e, quando enviado em
+// uma unica parte, deve aplicar sem conflito sobre o conteudo do draft
+func (s *State) validPatch(edit *actions.Edit) error {
+	draft, ok := s.Drafts[edit.EditedDraft]
+	if !ok {
+		return ErrUnknownDraft
+	}
+	if draft.DraftType != "txt" && draft.DraftType != "md" {
+		return ErrInvalidPatch
+	}
+	if edit.NumberOfParts > 1 {
+		return nil
+	}
+	hunks, err := diff.Parse(string(edit.Content))
+	if err != nil {
+		return err
+	}
+	content, ok := s.GetMedia(draft.DraftHash)
+	if !ok {
+		return ErrDraftUnavailable
+	}
+	_, err = diff.Apply(diff.Split(string(content)), hunks)
+	return err
+}
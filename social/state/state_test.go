@@ -0,0 +1,111 @@
+package state
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+// testIndexer ignores everything: the state tests only look at the state.
+type testIndexer struct{}
+
+func (testIndexer) AddBoardToCollective(*Board, *Collective)      {}
+func (testIndexer) RemoveBoardFromCollective(*Board, *Collective) {}
+func (testIndexer) AddJournalToCollective(*Journal, *Collective)  {}
+func (testIndexer) AddStampToCollective(*Stamp, *Collective)      {}
+func (testIndexer) AddReleaseToIndex(*Release)                    {}
+func (testIndexer) AddPinToIndex(*Pin)                            {}
+func (testIndexer) AddReactionToIndex(*actions.React)             {}
+func (testIndexer) AddEventToCollective(*Event, *Collective)      {}
+func (testIndexer) RemoveEventFromCollective(*Event, *Collective) {}
+func (testIndexer) IndexConsensus(crypto.Hash, ConsensusState)    {}
+func (testIndexer) IndexActionStatus(crypto.Hash, ConsensusState) {}
+func (testIndexer) IndexAction(action actions.Action)             {}
+func (testIndexer) IndexVoteHash(Consensual, crypto.Hash)         {}
+func (testIndexer) RemoveVoteHash(crypto.Hash)                    {}
+func (testIndexer) AddDraftToIndex(*Draft)                        {}
+func (testIndexer) AddEditToIndex(*Edit)                          {}
+func (testIndexer) AddCheckin(crypto.Token, *Event)               {}
+func (testIndexer) RemoveCheckin(crypto.Token, *Event)            {}
+func (testIndexer) AddMemberToIndex(crypto.Token, string)         {}
+func (testIndexer) AddCollectiveToIndex(*Collective)              {}
+func (testIndexer) AddCommentToIndex(*actions.Comment)            {}
+func (testIndexer) IndexBlock(uint64)                             {}
+
+// testState returns a state with n members
+func testState(n int) (*State, []crypto.Token) {
+	s := GenesisState(testIndexer{})
+	members := make([]crypto.Token, n)
+	for i := range members {
+		members[i], _ = crypto.RandomAsymetricKey()
+		handle := fmt.Sprintf("member%v", i)
+		s.Members[crypto.HashToken(members[i])] = handle
+		s.MembersIndex[handle] = members[i]
+	}
+	return s, members
+}
+
+// testCollective incorporates a collective with the given members and policy
+func testCollective(t *testing.T, s *State, name string, policy actions.Policy, members ...crypto.Token) *Collective {
+	t.Helper()
+	create := actions.CreateCollective{
+		Epoch:  s.Epoch,
+		Author: members[0],
+		Name:   name,
+		Policy: policy,
+	}
+	if err := s.Action(create.Serialize()); err != nil {
+		t.Fatalf("could not create collective: %v", err)
+	}
+	collective, _ := s.Collective(name)
+	for _, member := range members[1:] {
+		collective.IncludeMember(member)
+	}
+	return collective
+}
+
+func incorporate(t *testing.T, s *State, action actions.Action) {
+	t.Helper()
+	if err := s.Action(action.Serialize()); err != nil {
+		t.Fatalf("could not incorporate %T: %v", action, err)
+	}
+}
+
+func vote(s *State, author crypto.Token, hash crypto.Hash, approve bool) *actions.Vote {
+	return &actions.Vote{Epoch: s.Epoch, Author: author, Hash: hash, Approve: approve}
+}
+
+func TestProposalExpiry(t *testing.T) {
+	s, members := testState(4)
+	s.SetEpoch(1)
+	collective := testCollective(t, s, "expiry", actions.Policy{Majority: 50, SuperMajority: 50}, members[:3]...)
+	collective.Expiry = 10
+	request := &actions.RequestMembership{Epoch: 2, Author: members[3], Collective: "expiry", Include: true}
+	incorporate(t, s, request)
+	hash := request.Hashed()
+	if !s.Proposals.Has(hash) {
+		t.Fatal("request membership not pending")
+	}
+	for epoch := uint64(2); epoch < 12; epoch++ {
+		s.SetEpoch(epoch)
+		if !s.Proposals.Has(hash) {
+			t.Fatalf("proposal expired at epoch %v before its deadline", epoch)
+		}
+	}
+	s.SetEpoch(12)
+	if s.Proposals.Has(hash) {
+		t.Error("proposal not expired at its deadline")
+	}
+	if len(s.Deadline) != 0 {
+		t.Errorf("deadlines not removed: %v", s.Deadline)
+	}
+	// blocks skipped by a jump of epochs are also processed
+	request = &actions.RequestMembership{Epoch: 12, Author: members[3], Collective: "expiry", Include: true, Reasons: "again"}
+	incorporate(t, s, request)
+	s.SetEpoch(100)
+	if s.Proposals.Has(request.Hashed()) {
+		t.Error("proposal not expired after a jump of epochs")
+	}
+}
@@ -1,8 +1,6 @@
 package state
 
 import (
-	"errors"
-
 	"github.com/freehandle/breeze/crypto"
 	"github.com/freehandle/synergy/social/actions"
 )
@@ -10,11 +8,11 @@ import (
 // verifica se eh um voto novo e se o hash bate
 func IsNewValidVote(vote actions.Vote, voted []actions.Vote, hash crypto.Hash) error {
 	if vote.Hash != hash {
-		return errors.New("invalid hash")
+		return ErrHashMismatch
 	}
 	for _, cast := range voted {
 		if cast.Author == vote.Author {
-			return errors.New("vote already cast")
+			return ErrVoteCast
 		}
 	}
 	return nil
@@ -0,0 +1,540 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+	"github.com/freehandle/synergy/social/diff"
+)
+
+// Validate checks if the action would be accepted by Action on the current
+// state, without changing it. Proposals are only checked to be well formed:
+// whether they reach consensus is known only after incorporation. The web
+// interface calls it before dressing an action, so that invalid actions are
+// not broadcast. It may be called concurrently with Action and SetEpoch.
+func (s *State) Validate(data []byte) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if actions.ActionKind(data) == actions.AUnknown {
+		return ErrUnknownAction
+	}
+	action := actions.ParseAction(data)
+	if action == nil {
+		return ErrInvalidAction
+	}
+	switch v := action.(type) {
+	case *actions.Vote:
+		return s.validateVote(v)
+	case *actions.CreateCollective:
+		return s.validateCreateCollective(v)
+	case *actions.UpdateCollective:
+		return s.validateUpdateCollective(v)
+	case *actions.RequestMembership:
+		return s.validateRequestMembership(v)
+	case *actions.RemoveMember:
+		return s.validateRemoveMember(v)
+	case *actions.Draft:
+		return s.validateDraft(v)
+	case *actions.Edit:
+		return s.validateEdit(v)
+	case *actions.MultipartMedia:
+		return s.validateMultipartMedia(v)
+	case *actions.CreateBoard:
+		return s.validateCreateBoard(v)
+	case *actions.UpdateBoard:
+		return s.validateUpdateBoard(v)
+	case *actions.Pin:
+		return s.validatePin(v)
+	case *actions.BoardEditor:
+		return s.validateBoardEditor(v)
+	case *actions.ReleaseDraft:
+		return s.validateReleaseDraft(v)
+	case *actions.ImprintStamp:
+		return s.validateImprintStamp(v)
+	case *actions.React:
+		return s.validateReact(v)
+	case *actions.Signin:
+		return s.validateSignIn(v)
+	case *actions.CreateEvent:
+		return s.validateCreateEvent(v)
+	case *actions.CancelEvent:
+		return s.validateCancelEvent(v)
+	case *actions.UpdateEvent:
+		return s.validateUpdateEvent(v)
+	case *actions.CheckinEvent:
+		return s.validateCheckinEvent(v)
+	case *actions.GreetCheckinEvent:
+		return s.validateGreetCheckinEvent(v)
+	case *actions.CancelCheckinEvent:
+		return s.validateCancelCheckinEvent(v)
+	case *actions.Delegate:
+		return s.validateDelegate(v)
+	case *actions.Comment:
+		return s.validateComment(v)
+	case *actions.CreateJournal:
+		return s.validateCreateJournal(v)
+	case *actions.JournalEditor:
+		return s.validateJournalEditor(v)
+	case *actions.JournalIssue:
+		return s.validateJournalIssue(v)
+	}
+	return ErrUnknownAction
+}
+
+// As funcoes abaixo reunem as verificacoes de cada acao que dependem apenas
+// do estado. Sao chamadas pelo Validate e no inicio de cada acao, antes de
+// qualquer alteracao do estado.
+
+func (s *State) validateGreetCheckinEvent(greet *actions.GreetCheckinEvent) error {
+	event, ok := s.Events[greet.EventHash]
+	if !ok {
+		return ErrUnknownEvent
+	}
+	if _, ok := event.Checkin[greet.CheckedIn]; !ok {
+		return ErrUnknownCheckin
+	}
+	return nil
+}
+
+func (s *State) validateImprintStamp(stamp *actions.ImprintStamp) error {
+	if !s.IsMember(stamp.Author) {
+		return ErrNotMember
+	}
+	if _, ok := s.Releases[stamp.Hash]; !ok {
+		return ErrUnknownRelease
+	}
+	if _, ok := s.Collective(stamp.OnBehalfOf); !ok {
+		return ErrUnknownCollective
+	}
+	return nil
+}
+
+func (s *State) validateCheckinEvent(checkin *actions.CheckinEvent) error {
+	if !s.IsMember(checkin.Author) {
+		return ErrNotMember
+	}
+	event, ok := s.Events[checkin.EventHash]
+	if !ok {
+		return ErrUnknownEvent
+	}
+	if !event.Live && event.limitsAttendance() {
+		return ErrEventCancelled
+	}
+	if _, ok := event.Checkin[checkin.Author]; ok {
+		return ErrAlreadyCheckedIn
+	}
+	if event.WaitlistPosition(checkin.Author) > 0 {
+		return ErrAlreadyCheckedIn
+	}
+	if event.RSVPClosed(s.Epoch) {
+		return ErrRSVPClosed
+	}
+	return nil
+}
+
+func (s *State) validateCancelCheckinEvent(cancel *actions.CancelCheckinEvent) error {
+	event, ok := s.Events[cancel.EventHash]
+	if !ok {
+		return ErrUnknownEvent
+	}
+	if event.WaitlistPosition(cancel.Author) > 0 {
+		return nil
+	}
+	if _, ok := event.Checkin[cancel.Author]; !ok {
+		return ErrUnknownCheckin
+	}
+	return nil
+}
+
+func (s *State) validateUpdateEvent(update *actions.UpdateEvent) error {
+	event, ok := s.Events[update.EventHash]
+	if !ok {
+		return ErrUnknownEvent
+	}
+	if !event.Managers.IsMember(update.Author) {
+		return ErrNotManager
+	}
+	if update.Occurrence > 0 {
+		if _, ok := event.Occurrence(update.Occurrence); !ok || !event.Recurrence.Recurring() {
+			return ErrUnknownOccurrence
+		}
+	}
+	return nil
+}
+
+func (s *State) validateCancelEvent(cancel *actions.CancelEvent) error {
+	event, ok := s.Events[cancel.Hash]
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrUnknownEvent, crypto.EncodeHash(cancel.Hash))
+	}
+	if !event.Managers.IsMember(cancel.Author) {
+		return ErrNotManager
+	}
+	if cancel.Occurrence > 0 {
+		if _, ok := event.Occurrence(cancel.Occurrence); !ok || !event.Recurrence.Recurring() {
+			return ErrUnknownOccurrence
+		}
+	}
+	return nil
+}
+
+func (s *State) validateCreateEvent(create *actions.CreateEvent) error {
+	if !s.IsMember(create.Author) {
+		return ErrNotMember
+	}
+	if _, ok := s.Collective(create.OnBehalfOf); !ok {
+		return ErrUnknownCollective
+	}
+	if s.Proposals.Has(create.Hashed()) {
+		return ErrDuplicateProposal
+	}
+	return nil
+}
+
+func (s *State) validateMultipartMedia(media *actions.MultipartMedia) error {
+	pending, ok := s.PendingMedia[media.Hash]
+	if !ok {
+		return ErrUnknownMedia
+	}
+	if media.Of != pending.NumberOfParts || media.Part > media.Of-1 {
+		return ErrInvalidParts
+	}
+	return nil
+}
+
+func (s *State) validateReleaseDraft(release *actions.ReleaseDraft) error {
+	draft, ok := s.Drafts[release.ContentHash]
+	if !ok {
+		return ErrUnknownDraft
+	}
+	if !draft.Authors.IsMember(release.Author) {
+		return ErrNotAuthor
+	}
+	return nil
+}
+
+func (s *State) validateUpdateBoard(update *actions.UpdateBoard) error {
+	if !s.IsMember(update.Author) {
+		return ErrNotMember
+	}
+	board, ok := s.Board(update.Board)
+	if !ok {
+		return ErrUnknownBoard
+	}
+	if (!board.Collective.IsMember(update.Author)) && (!board.Editors.IsMember(update.Author)) {
+		return ErrNotEditor
+	}
+	return nil
+}
+
+func (s *State) validateCreateBoard(board *actions.CreateBoard) error {
+	if !s.IsMember(board.Author) {
+		return ErrNotMember
+	}
+	// boards e periodicos sao identificados pelo hash do nome
+	if _, ok := s.Board(board.Name); ok {
+		return ErrNameTaken
+	}
+	if _, ok := s.Journal(board.Name); ok {
+		return ErrNameTaken
+	}
+	if _, ok := s.Collective(board.OnBehalfOf); !ok {
+		return ErrUnknownCollective
+	}
+	return nil
+}
+
+func (s *State) validateSignIn(signin *actions.Signin) error {
+	if _, ok := s.Members[crypto.HashToken(signin.Author)]; ok {
+		return ErrAlreadyMember
+	}
+	if s.Axe == nil || s.Axe.Handle(signin.Author) == nil {
+		return ErrUnknownHandle
+	}
+	return nil
+}
+
+func (s *State) validateCreateCollective(create *actions.CreateCollective) error {
+	if !s.IsMember(create.Author) {
+		return ErrNotMember
+	}
+	if _, ok := s.Collective(create.Name); ok {
+		return ErrCollectiveExists
+	}
+	return create.Policy.Validate()
+}
+
+func (s *State) validateUpdateCollective(update *actions.UpdateCollective) error {
+	collective, ok := s.Collective(update.OnBehalfOf)
+	if !ok {
+		return ErrUnknownCollective
+	}
+	if !collective.IsMember(update.Author) {
+		return ErrNotCollectiveMember
+	}
+	return nil
+}
+
+func (s *State) validateRequestMembership(request *actions.RequestMembership) error {
+	if !s.IsMember(request.Author) {
+		return ErrNotMember
+	}
+	collective, ok := s.Collective(request.Collective)
+	if !ok {
+		return ErrUnknownCollective
+	}
+	if request.Include && collective.IsMember(request.Author) {
+		return ErrAlreadyMember
+	}
+	if (!request.Include) && (!collective.IsMember(request.Author)) {
+		return ErrNotCollectiveMember
+	}
+	return nil
+}
+
+func (s *State) validateRemoveMember(remove *actions.RemoveMember) error {
+	collective, ok := s.Collective(remove.OnBehalfOf)
+	if !ok {
+		return ErrUnknownCollective
+	}
+	if !collective.IsMember(remove.Author) {
+		return ErrNotCollectiveMember
+	}
+	if !collective.IsMember(remove.Member) {
+		return fmt.Errorf("%w: member to be removed", ErrNotCollectiveMember)
+	}
+	return nil
+}
+
+func (s *State) validateDelegate(delegate *actions.Delegate) error {
+	collective, ok := s.Collective(delegate.Collective)
+	if !ok {
+		return ErrUnknownCollective
+	}
+	if !collective.IsMember(delegate.Author) {
+		return ErrNotCollectiveMember
+	}
+	for _, kind := range delegate.Kinds {
+		if kind >= UnkownProposal {
+			return ErrInvalidProposalKind
+		}
+	}
+	if delegate.Revoke {
+		return nil
+	}
+	if delegate.Author.Equal(delegate.Delegate) {
+		return ErrSelfDelegation
+	}
+	if !collective.IsMember(delegate.Delegate) {
+		return fmt.Errorf("%w: delegate", ErrNotCollectiveMember)
+	}
+	return nil
+}
+
+func (s *State) validateReact(reaction *actions.React) error {
+	if reaction.Reaction >= ReactionsCount {
+		return ErrInvalidReaction
+	}
+	return nil
+}
+
+func (s *State) validateEdit(edit *actions.Edit) error {
+	if err := s.validateEditMedia(edit); err != nil {
+		return err
+	}
+	return s.validateEditDraft(edit)
+}
+
+// validateEditMedia checks what must hold before the edit claims its hash
+func (s *State) validateEditMedia(edit *actions.Edit) error {
+	if _, ok := s.Media[edit.ContentHash]; ok {
+		return ErrHashClaimed
+	}
+	if _, ok := s.PendingMedia[edit.ContentHash]; ok {
+		return ErrHashClaimed
+	}
+	return nil
+}
+
+// validateEditDraft checks the edited draft and the authorship of the edit.
+// As before it runs after the hash is claimed.
+func (s *State) validateEditDraft(edit *actions.Edit) error {
+	if _, ok := s.Drafts[edit.EditedDraft]; !ok {
+		return ErrUnknownDraft
+	}
+	if edit.ContentType == diff.PatchType {
+		if err := s.validPatch(edit); err != nil {
+			return err
+		}
+	}
+	if edit.OnBehalfOf != "" {
+		collective, ok := s.Collective(edit.OnBehalfOf)
+		if !ok {
+			return ErrUnknownCollective
+		}
+		if !collective.IsMember(edit.Author) {
+			return ErrNotCollectiveMember
+		}
+	}
+	return nil
+}
+
+func (s *State) validateDraft(draft *actions.Draft) error {
+	if err := s.validateDraftMedia(draft); err != nil {
+		return err
+	}
+	return s.validateDraftAuthors(draft)
+}
+
+// validateDraftMedia checks what must hold before the draft claims its hash
+func (s *State) validateDraftMedia(draft *actions.Draft) error {
+	if _, ok := s.Media[draft.ContentHash]; ok {
+		return ErrHashClaimed
+	}
+	if draft.NumberOfParts <= 1 && !crypto.Hasher(draft.Content).Equal(draft.ContentHash) {
+		return ErrHashMismatch
+	}
+	return nil
+}
+
+// validateDraftAuthors checks the previous version and the collective. As
+// before it runs after the hash is claimed.
+func (s *State) validateDraftAuthors(draft *actions.Draft) error {
+	if draft.PreviousDraft != crypto.ZeroHash && draft.PreviousDraft != crypto.ZeroValueHash {
+		previous, ok := s.Drafts[draft.PreviousDraft]
+		if !ok {
+			return ErrUnknownDraft
+		}
+		// apenas autores da versao anterior propoem uma nova versao
+		if !previous.Authors.IsMember(draft.Author) {
+			return ErrNotAuthor
+		}
+	}
+	if len(draft.CoAuthors) == 0 && draft.OnBehalfOf != "" {
+		if _, ok := s.Collective(draft.OnBehalfOf); !ok {
+			return ErrUnknownCollective
+		}
+	}
+	return nil
+}
+
+func (s *State) validateVote(vote *actions.Vote) error {
+	if draft, ok := s.Drafts[vote.Hash]; ok {
+		if draft.Aproved {
+			_, err := concludedVote(draft.DraftHash, *vote, draft.Votes)
+			return err
+		}
+		_, err := castVote(draft.DraftHash, *vote, draft.Votes)
+		return err
+	}
+	if !s.Proposals.Has(vote.Hash) {
+		return ErrProposalNotFound
+	}
+	return nil
+}
+
+func (s *State) validatePin(pin *actions.Pin) error {
+	if _, ok := s.Board(pin.Board); !ok {
+		return ErrUnknownBoard
+	}
+	if _, ok := s.Drafts[pin.Draft]; !ok {
+		return ErrUnknownDraft
+	}
+	return nil
+}
+
+func (s *State) validateBoardEditor(action *actions.BoardEditor) error {
+	if _, ok := s.Board(action.Board); !ok {
+		return ErrUnknownBoard
+	}
+	return nil
+}
+
+func (s *State) validateCreateJournal(create *actions.CreateJournal) error {
+	if !s.IsMember(create.Author) {
+		return ErrNotMember
+	}
+	if _, ok := s.Journal(create.Name); ok {
+		return ErrNameTaken
+	}
+	if _, ok := s.Board(create.Name); ok {
+		return ErrNameTaken
+	}
+	if _, ok := s.Collective(create.OnBehalfOf); !ok {
+		return ErrUnknownCollective
+	}
+	return nil
+}
+
+func (s *State) validateJournalEditor(action *actions.JournalEditor) error {
+	if _, ok := s.Journal(action.Journal); !ok {
+		return ErrUnknownJournal
+	}
+	if !s.IsMember(action.Editor) {
+		return ErrNotMember
+	}
+	return nil
+}
+
+func (s *State) validateJournalIssue(action *actions.JournalIssue) error {
+	journal, ok := s.Journal(action.Journal)
+	if !ok {
+		return ErrUnknownJournal
+	}
+	if !journal.Editors.IsMember(action.Author) {
+		return ErrNotEditor
+	}
+	if len(action.Drafts) == 0 {
+		return ErrEmptyIssue
+	}
+	drafts := make(map[*Draft]struct{})
+	for _, hash := range action.Drafts {
+		draft, err := stamped(s, hash)
+		if err != nil {
+			return err
+		}
+		if _, ok := drafts[draft]; ok {
+			return ErrDuplicateDraft
+		}
+		drafts[draft] = struct{}{}
+	}
+	if s.Proposals.Has(action.Hashed()) {
+		return ErrDuplicateProposal
+	}
+	return nil
+}
+
+func (s *State) validateComment(comment *actions.Comment) error {
+	if !s.IsMember(comment.Author) {
+		return ErrNotMember
+	}
+	if comment.Body == "" {
+		return ErrEmptyComment
+	}
+	if comment.OnBehalfOf != "" {
+		collective, ok := s.Collective(comment.OnBehalfOf)
+		if !ok {
+			return ErrUnknownCollective
+		}
+		if !collective.IsMember(comment.Author) {
+			return ErrNotCollectiveMember
+		}
+	}
+	if !s.commentable(comment.Target) {
+		return ErrInvalidTarget
+	}
+	if comment.ReplyTo != crypto.ZeroHash {
+		parent, ok := s.Comments[comment.ReplyTo]
+		if !ok {
+			return ErrUnknownComment
+		}
+		if parent.Target != comment.Target {
+			return ErrReplyOtherTarget
+		}
+	}
+	if _, ok := s.Comments[comment.Hashed()]; ok {
+		return ErrDuplicateComment
+	}
+	return nil
+}
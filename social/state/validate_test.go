@@ -0,0 +1,173 @@
+package state
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/freehandle/breeze/crypto"
+	"github.com/freehandle/synergy/social/actions"
+)
+
+func validateState(t *testing.T) (*State, []crypto.Token, *actions.Draft) {
+	t.Helper()
+	s, members := testState(2)
+	s.SetEpoch(1)
+	testCollective(t, s, "validacao", actions.Policy{Majority: 50, SuperMajority: 50}, members[0])
+	content := []byte("conteudo")
+	draft := &actions.Draft{
+		Epoch:         1,
+		Author:        members[0],
+		Title:         "esboco",
+		Keywords:      []string{"validacao"},
+		ContentType:   "md",
+		ContentHash:   crypto.Hasher(content),
+		NumberOfParts: 1,
+		Content:       content,
+	}
+	incorporate(t, s, draft)
+	incorporate(t, s, &actions.CreateBoard{
+		Epoch:       1,
+		Author:      members[0],
+		OnBehalfOf:  "validacao",
+		Name:        "mural",
+		Keywords:    []string{"mural"},
+		PinMajority: 1,
+	})
+	incorporate(t, s, &actions.ReleaseDraft{Epoch: 1, Author: members[0], ContentHash: draft.ContentHash})
+	return s, members, draft
+}
+
+// Validate accepts what Action accepted before it existed: these checks are
+// left to the incorporation of the proposals.
+func TestValidateVerdicts(t *testing.T) {
+	s, members, draft := validateState(t)
+	outsider, _ := crypto.RandomAsymetricKey()
+	tests := []struct {
+		name   string
+		action actions.Action
+	}{
+		{"vote on an approved draft", vote(s, members[1], draft.ContentHash, true)},
+		{"editor not a member of synergy", &actions.BoardEditor{Epoch: 1, Author: members[0], Board: "mural", Editor: outsider, Insert: true}},
+		{"stamp by a member outside the collective", &actions.ImprintStamp{Epoch: 1, Author: members[1], OnBehalfOf: "validacao", Hash: draft.ContentHash}},
+	}
+	for _, test := range tests {
+		if err := s.Validate(test.action.Serialize()); err != nil {
+			t.Errorf("%v: unexpected error %v", test.name, err)
+		}
+	}
+}
+
+func TestValidateConcurrent(t *testing.T) {
+	s, members, draft := validateState(t)
+	data := vote(s, members[1], draft.ContentHash, true).Serialize()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for n := 0; n < 100; n++ {
+			s.Validate(data)
+		}
+	}()
+	for epoch := uint64(2); epoch < 50; epoch++ {
+		s.SetEpoch(epoch)
+		s.Action((&actions.React{Epoch: epoch, Author: members[1], Hash: draft.ContentHash, Reaction: 1}).Serialize())
+	}
+	wg.Wait()
+}
+
+// votes on a concluded proposal are recorded without changing the outcome,
+// and Validate reaches the same verdict as Action
+func TestConcludedVote(t *testing.T) {
+	s, members, draft := validateState(t)
+	approved := s.Drafts[draft.ContentHash]
+	if approved == nil || !approved.Aproved {
+		t.Fatal("draft not approved")
+	}
+	retract := vote(s, members[0], draft.ContentHash, true)
+	retract.Retract = true
+	tests := []struct {
+		name string
+		vote *actions.Vote
+		err  error
+	}{
+		{"new vote", vote(s, members[1], draft.ContentHash, false), nil},
+		{"changed vote", vote(s, members[1], draft.ContentHash, true), ErrVoteCast},
+		{"retraction", retract, ErrProposalConcluded},
+	}
+	for _, test := range tests {
+		votes := len(approved.Votes)
+		data := test.vote.Serialize()
+		if err := s.Validate(data); !errors.Is(err, test.err) {
+			t.Errorf("%v: Validate returned %v, expected %v", test.name, err, test.err)
+		}
+		if err := s.Action(data); !errors.Is(err, test.err) {
+			t.Errorf("%v: Action returned %v, expected %v", test.name, err, test.err)
+		}
+		expected := votes
+		if test.err == nil {
+			expected++
+		}
+		if len(approved.Votes) != expected {
+			t.Errorf("%v: %v votes recorded, expected %v", test.name, len(approved.Votes), expected)
+		}
+		if !approved.Aproved {
+			t.Errorf("%v: outcome changed", test.name)
+		}
+	}
+}
+
+// a draft or edit rejected after its media was stored keeps the hash claimed
+// as it always did, while Validate leaves the state untouched
+func TestRejectedMediaClaim(t *testing.T) {
+	s, members, _ := validateState(t)
+	content := []byte("rejeitado")
+	draft := &actions.Draft{
+		Epoch:         1,
+		Author:        members[0],
+		OnBehalfOf:    "desconhecido",
+		Title:         "rejeitado",
+		Keywords:      []string{"rejeitado"},
+		ContentType:   "md",
+		ContentHash:   crypto.Hasher(content),
+		NumberOfParts: 1,
+		Content:       content,
+	}
+	edited := []byte("edicao rejeitada")
+	edit := &actions.Edit{
+		Epoch:         1,
+		Author:        members[0],
+		EditedDraft:   crypto.Hasher([]byte("desconhecido")),
+		ContentType:   "md",
+		ContentHash:   crypto.Hasher(edited),
+		NumberOfParts: 1,
+		Content:       edited,
+	}
+	tests := []struct {
+		name   string
+		action actions.Action
+		hash   crypto.Hash
+		err    error
+	}{
+		{"draft by an unknown collective", draft, draft.ContentHash, ErrUnknownCollective},
+		{"edit of an unknown draft", edit, edit.ContentHash, ErrUnknownDraft},
+	}
+	for _, test := range tests {
+		data := test.action.Serialize()
+		if err := s.Validate(data); !errors.Is(err, test.err) {
+			t.Errorf("%v: Validate returned %v, expected %v", test.name, err, test.err)
+		}
+		if _, ok := s.Media[test.hash]; ok {
+			t.Errorf("%v: hash claimed by Validate", test.name)
+		}
+		if err := s.Action(data); !errors.Is(err, test.err) {
+			t.Errorf("%v: Action returned %v, expected %v", test.name, err, test.err)
+		}
+		if _, ok := s.Media[test.hash]; !ok {
+			t.Errorf("%v: hash not claimed", test.name)
+		}
+		if err := s.Validate(data); !errors.Is(err, ErrHashClaimed) {
+			t.Errorf("%v: resubmission returned %v, expected %v", test.name, err, ErrHashClaimed)
+		}
+	}
+}